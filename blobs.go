@@ -126,17 +126,17 @@ func (r *blobsTable) PartitionRows(
 
 				return newBlobsIndexIter(
 					indexValues,
-					s.Pool,
+					s.QueryPool(ctx),
 					shouldReadContent(r.projection),
 					stringsToHashes(hashes),
 				), nil
 			}
 
 			return &blobRowIter{
-				hashes:        stringsToHashes(hashes),
-				repo:          repo,
-				readContent:   shouldReadContent(r.projection),
-				skipGitErrors: shouldSkipErrors(ctx),
+				hashes:      stringsToHashes(hashes),
+				repo:        repo,
+				readContent: shouldReadContent(r.projection),
+				skipper:     newGitErrorSkipper(ctx, BlobsTableName),
 			}, nil
 		},
 	)
@@ -171,12 +171,12 @@ func (r *blobsTable) IndexKeyValues(
 }
 
 type blobRowIter struct {
-	repo          *Repository
-	iter          *object.BlobIter
-	hashes        []plumbing.Hash
-	pos           int
-	readContent   bool
-	skipGitErrors bool
+	repo        *Repository
+	iter        *object.BlobIter
+	hashes      []plumbing.Hash
+	pos         int
+	readContent bool
+	skipper     *gitErrorSkipper
 }
 
 func (i *blobRowIter) init() error {
@@ -199,10 +199,12 @@ func (i *blobRowIter) nextByHash() (sql.Row, error) {
 			return nil, io.EOF
 		}
 
-		blob, err := i.repo.BlobObject(i.hashes[i.pos])
+		hash := i.hashes[i.pos]
+		blob, err := i.repo.BlobObject(hash)
 		i.pos++
 		if err != nil {
-			if err == plumbing.ErrObjectNotFound || i.skipGitErrors {
+			if err == plumbing.ErrObjectNotFound ||
+				i.skipper.skip(i.repo.ID(), hash, err) {
 				continue
 			}
 
@@ -217,7 +219,7 @@ func (i *blobRowIter) next() (sql.Row, error) {
 	for {
 		if i.iter == nil {
 			if err := i.init(); err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 				return nil, err
@@ -230,7 +232,7 @@ func (i *blobRowIter) next() (sql.Row, error) {
 				return nil, io.EOF
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 				continue
 			}
 			return nil, err
@@ -250,6 +252,14 @@ func (i *blobRowIter) Close() error {
 	return nil
 }
 
+// BlobContent returns the content of the blob as the blob_content column
+// of the blobs table does. It's empty if the blob is bigger than
+// GITBASE_BLOBS_MAX_SIZE or binary and GITBASE_BLOBS_ALLOW_BINARY is not
+// enabled.
+func BlobContent(c *object.Blob) ([]byte, error) {
+	return blobContent(c, true)
+}
+
 func blobContent(c *object.Blob, readContent bool) ([]byte, error) {
 	var content []byte
 	var isAllowed = blobsAllowBinary
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-borges"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+)
+
+const (
+	CommitGraphDescription = "Writes the commit-graph files of the repositories"
+	CommitGraphHelp        = CommitGraphDescription + "\n\n" +
+		"The commit-graph file keeps the parents, root tree and generation\n" +
+		"number of the commits of a repository, so the history can be\n" +
+		"traversed in the ref_commits table without decoding every commit.\n" +
+		"By default the file is only written for repositories without one,\n" +
+		"use --force to write it again for all of them. The ids of the\n" +
+		"repositories written are printed. Non rooted siva files cannot be\n" +
+		"modified, so their repositories are skipped."
+)
+
+// CommitGraph represents the `commit-graph` command of gitbase cli tool.
+type CommitGraph struct {
+	EngineOptions
+
+	stdout io.Writer
+
+	Force bool `long:"force" description:"Writes the commit-graph file even if the repository already has one"`
+}
+
+// Execute writes the commit-graph files of the repositories in the given
+// directories, it honors the go-flags.Commander interface.
+func (c *CommitGraph) Execute(args []string) error {
+	srv, err := c.buildServer()
+	if err != nil {
+		return err
+	}
+
+	ids, err := c.pendingRepositories(srv.pool)
+	if err != nil {
+		return err
+	}
+
+	stdout := c.stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	lib := srv.pool.Library()
+	for _, id := range ids {
+		if err := writeCommitGraph(lib, srv.sharedCache, id); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"repo":  id,
+				"error": err,
+			}).Warn("unable to write commit-graph file")
+			continue
+		}
+
+		if _, err := fmt.Fprintln(stdout, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingRepositories returns the ids of the repositories whose
+// commit-graph file has to be written.
+func (c *CommitGraph) pendingRepositories(
+	pool *gitbase.RepositoryPool,
+) ([]string, error) {
+	iter, err := pool.RepoIter()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var ids []string
+	for {
+		repo, err := iter.Next()
+		if err == io.EOF {
+			return ids, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := gitbase.HasCommitGraph(repo)
+		_ = repo.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok || c.Force {
+			ids = append(ids, repo.ID())
+		}
+	}
+}
+
+// writeCommitGraph opens the repository for writing and writes its
+// commit-graph file, committing it if the library is transactional.
+func writeCommitGraph(
+	lib borges.Library,
+	objectCache cache.Object,
+	id string,
+) error {
+	repo, err := lib.Get(borges.RepositoryID(id), borges.ReadWriteMode)
+	if err != nil {
+		return err
+	}
+
+	if err := gitbase.WriteCommitGraph(gitbase.NewRepository(lib, repo, objectCache)); err != nil {
+		_ = repo.Close()
+		return err
+	}
+
+	if err := repo.Commit(); err != nil && !borges.ErrNonTransactional.Is(err) {
+		_ = repo.Close()
+		return err
+	}
+
+	return repo.Close()
+}
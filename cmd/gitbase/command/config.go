@@ -0,0 +1,430 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/src-d/go-mysql-server/auth"
+	"gopkg.in/yaml.v2"
+)
+
+// serverConfig is the content of the YAML file given to the server with
+// --config. The settings use the long names of the server flags as keys,
+// and the file can also declare several libraries and users.
+type serverConfig struct {
+	DB            *string  `yaml:"db"`
+	Directories   []string `yaml:"directories"`
+	Format        *string  `yaml:"format"`
+	Bucket        *int     `yaml:"bucket"`
+	Bare          *bool    `yaml:"bare"`
+	NonBare       *bool    `yaml:"non-bare"`
+	NonRooted     *bool    `yaml:"non-rooted"`
+	Host          *string  `yaml:"host"`
+	Port          *int     `yaml:"port"`
+	User          *string  `yaml:"user"`
+	Password      *string  `yaml:"password"`
+	UserFile      *string  `yaml:"user-file"`
+	Timeout       *int     `yaml:"timeout"`
+	Index         *string  `yaml:"index"`
+	Cache         *int64   `yaml:"cache"`
+	Parallelism   *uint    `yaml:"parallelism"`
+	NoSquash      *bool    `yaml:"no-squash"`
+	Trace         *bool    `yaml:"trace"`
+	Metrics       *bool    `yaml:"metrics"`
+	MetricsPort   *int     `yaml:"metrics-port"`
+	ReadOnly      *bool    `yaml:"readonly"`
+	Watch         *bool    `yaml:"watch"`
+	Mailmap       *string  `yaml:"mailmap"`
+	Keyrings      *string  `yaml:"keyrings"`
+	Verbose       *bool    `yaml:"verbose"`
+	LogLevel      *string  `yaml:"log-level"`
+	SkipGitErrors *bool    `yaml:"skip-git-errors"`
+
+	Libraries []libraryConfig `yaml:"libraries"`
+	Users     []userConfig    `yaml:"users"`
+}
+
+// libraryConfig is a library declared in the configuration file. The
+// options not set are taken from the server ones.
+type libraryConfig struct {
+	Name   string `yaml:"name"`
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"`
+	Bucket *int   `yaml:"bucket"`
+	Bare   string `yaml:"bare"`
+	Rooted *bool  `yaml:"rooted"`
+}
+
+// userConfig is a user declared in the configuration file, with the same
+// fields as the entries of the user file.
+type userConfig struct {
+	Name        string   `yaml:"name" json:"name"`
+	Password    string   `yaml:"password" json:"password"`
+	Permissions []string `yaml:"permissions" json:"permissions,omitempty"`
+}
+
+var (
+	formats   = []string{"git", "siva"}
+	logLevels = []string{"info", "debug", "warning", "error", "fatal"}
+	bareOpts  = []string{"true", "false", "auto"}
+	userPerms = []string{"read", "write"}
+)
+
+// BindConfig makes the --config option load the configuration file into the
+// given command, which must be the one holding the server options. Values
+// in the file replace the defaults of the options, so flags and environment
+// variables still take precedence over them.
+func (c *Server) BindConfig(cmd *flags.Command) {
+	c.Config = func(path string) error {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+
+		c.configPath = path
+		return cfg.apply(c, cmd)
+	}
+}
+
+func loadConfig(path string) (*serverConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err = expandEnv(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	var cfg serverConfig
+	if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// expandEnv replaces ${VAR} and $VAR with the value of the environment
+// variable. ${VAR:-default} uses default when the variable is not set or
+// empty and $$ is replaced with $. It fails if a variable without default
+// is not set.
+func expandEnv(content []byte) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		var missing string
+		lines[i] = os.Expand(line, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+
+			var def string
+			var hasDefault bool
+			if idx := strings.Index(name, ":-"); idx >= 0 {
+				name, def, hasDefault = name[:idx], name[idx+2:], true
+			}
+
+			value, ok := os.LookupEnv(name)
+			switch {
+			case hasDefault && value == "":
+				return def
+			case !ok && missing == "":
+				missing = name
+			}
+
+			return value
+		})
+
+		if missing != "" {
+			return nil, fmt.Errorf(
+				"line %d: environment variable %s is not set",
+				i+1, missing,
+			)
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func invalidKey(key string, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...))
+}
+
+func checkChoice(key, value string, choices []string) error {
+	for _, c := range choices {
+		if value == c {
+			return nil
+		}
+	}
+
+	return invalidKey(key, "invalid value %q, it can only be %s",
+		value, strings.Join(choices, ", "))
+}
+
+func checkPort(key string, port *int) error {
+	if port != nil && (*port <= 0 || *port > 65535) {
+		return invalidKey(key, "invalid port %d", *port)
+	}
+	return nil
+}
+
+func checkNotNegative(key string, n *int) error {
+	if n != nil && *n < 0 {
+		return invalidKey(key, "cannot be negative")
+	}
+	return nil
+}
+
+func (cfg *serverConfig) validate() error {
+	if cfg.Format != nil {
+		if err := checkChoice("format", *cfg.Format, formats); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LogLevel != nil {
+		if err := checkChoice("log-level", *cfg.LogLevel, logLevels); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Bare != nil && cfg.NonBare != nil && *cfg.Bare && *cfg.NonBare {
+		return invalidKey("non-bare", "cannot be used with bare")
+	}
+
+	if err := checkNotNegative("bucket", cfg.Bucket); err != nil {
+		return err
+	}
+
+	if err := checkNotNegative("timeout", cfg.Timeout); err != nil {
+		return err
+	}
+
+	if cfg.Cache != nil && *cfg.Cache < 0 {
+		return invalidKey("cache", "cannot be negative")
+	}
+
+	if err := checkPort("port", cfg.Port); err != nil {
+		return err
+	}
+
+	if err := checkPort("metrics-port", cfg.MetricsPort); err != nil {
+		return err
+	}
+
+	names := make(map[string]string)
+	for i, l := range cfg.Libraries {
+		key := fmt.Sprintf("libraries[%d]", i)
+		if l.Path == "" {
+			return invalidKey(key+".path", "is required")
+		}
+
+		if l.Name != "" {
+			if other, ok := names[l.Name]; ok {
+				return invalidKey(key+".name",
+					"duplicated name %q, already used in %s", l.Name, other)
+			}
+			names[l.Name] = key
+		}
+
+		if l.Format != "" {
+			if err := checkChoice(key+".format", l.Format, formats); err != nil {
+				return err
+			}
+		}
+
+		if l.Bare != "" {
+			if err := checkChoice(key+".bare", l.Bare, bareOpts); err != nil {
+				return err
+			}
+		}
+
+		if err := checkNotNegative(key+".bucket", l.Bucket); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Users) > 0 {
+		if cfg.UserFile != nil && *cfg.UserFile != "" {
+			return invalidKey("users", "cannot be used with user-file")
+		}
+
+		if cfg.ReadOnly != nil && *cfg.ReadOnly {
+			return invalidKey("users", "cannot be used with readonly")
+		}
+	}
+
+	users := make(map[string]string)
+	for i, u := range cfg.Users {
+		key := fmt.Sprintf("users[%d]", i)
+		if u.Name == "" {
+			return invalidKey(key+".name", "is required")
+		}
+
+		if other, ok := users[u.Name]; ok {
+			return invalidKey(key+".name",
+				"duplicated user %q, already defined in %s", u.Name, other)
+		}
+		users[u.Name] = key
+
+		for j, p := range u.Permissions {
+			pkey := fmt.Sprintf("%s.permissions[%d]", key, j)
+			if err := checkChoice(pkey, strings.ToLower(p), userPerms); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply sets the values of the configuration as the defaults of the
+// options in the command and the libraries and users in the server.
+func (cfg *serverConfig) apply(c *Server, cmd *flags.Command) error {
+	defaults := []struct {
+		name  string
+		value interface{}
+	}{
+		{"db", cfg.DB},
+		{"format", cfg.Format},
+		{"bucket", cfg.Bucket},
+		{"bare", cfg.Bare},
+		{"non-bare", cfg.NonBare},
+		{"non-rooted", cfg.NonRooted},
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"user-file", cfg.UserFile},
+		{"timeout", cfg.Timeout},
+		{"index", cfg.Index},
+		{"cache", cfg.Cache},
+		{"parallelism", cfg.Parallelism},
+		{"no-squash", cfg.NoSquash},
+		{"trace", cfg.Trace},
+		{"metrics", cfg.Metrics},
+		{"metrics-port", cfg.MetricsPort},
+		{"readonly", cfg.ReadOnly},
+		{"watch", cfg.Watch},
+		{"mailmap", cfg.Mailmap},
+		{"keyrings", cfg.Keyrings},
+		{"verbose", cfg.Verbose},
+		{"log-level", cfg.LogLevel},
+	}
+
+	for _, d := range defaults {
+		v := reflect.ValueOf(d.value)
+		if v.IsNil() {
+			continue
+		}
+
+		opt, err := findOption(cmd, d.name)
+		if err != nil {
+			return err
+		}
+
+		opt.Default = []string{fmt.Sprint(v.Elem().Interface())}
+	}
+
+	if len(cfg.Directories) > 0 {
+		opt, err := findOption(cmd, "directories")
+		if err != nil {
+			return err
+		}
+
+		opt.Default = cfg.Directories
+	}
+
+	// GITBASE_SKIP_GIT_ERRORS is not an option, it's read in main.
+	_, ok := os.LookupEnv("GITBASE_SKIP_GIT_ERRORS")
+	if cfg.SkipGitErrors != nil && !ok {
+		c.SkipGitErrors = *cfg.SkipGitErrors
+	}
+
+	c.libraries = cfg.Libraries
+	c.users = cfg.Users
+
+	return nil
+}
+
+func findOption(cmd *flags.Command, name string) (*flags.Option, error) {
+	var opt *flags.Option
+	if name == "verbose" {
+		opt = cmd.FindOptionByShortName('v')
+	} else {
+		opt = cmd.FindOptionByLongName(name)
+	}
+
+	if opt == nil {
+		return nil, fmt.Errorf("option for configuration key %s not found", name)
+	}
+
+	return opt, nil
+}
+
+// directory returns the directory of the library, using the options of the
+// given one for the values not set.
+func (l libraryConfig) directory(defaults directory) directory {
+	dir := defaults
+	dir.Name = l.Name
+	dir.Path = l.Path
+
+	if l.Format != "" {
+		dir.Format = l.Format
+	}
+
+	if l.Bucket != nil {
+		dir.Bucket = *l.Bucket
+	}
+
+	if l.Rooted != nil {
+		dir.Rooted = *l.Rooted
+	}
+
+	switch l.Bare {
+	case "true":
+		dir.Bare = bareOn
+	case "false":
+		dir.Bare = bareOff
+	case "auto":
+		dir.Bare = bareAuto
+	}
+
+	return dir
+}
+
+// newUserAuth creates the authentication for the users in the
+// configuration. They are written to a temporary user file, as that's the
+// only way to create an authentication with several users.
+func newUserAuth(users []userConfig) (auth.Auth, error) {
+	content, err := json.Marshal(users)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile("", "gitbase-users")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return auth.NewNativeFile(f.Name())
+}
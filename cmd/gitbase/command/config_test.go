@@ -0,0 +1,205 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gitbase-config")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "gitbase.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(os.Setenv("GITBASE_TEST_PASSWORD", "s3cr3t"))
+	defer os.Unsetenv("GITBASE_TEST_PASSWORD")
+
+	path, cleanup := writeConfig(t, `
+host: 0.0.0.0
+port: 3307
+password: ${GITBASE_TEST_PASSWORD}
+index: ${GITBASE_TEST_INDEX:-/tmp/index}
+log-level: debug
+libraries:
+  - name: siva
+    path: /repos/siva
+    format: siva
+    bucket: 0
+    rooted: false
+  - path: /repos/git
+    bare: auto
+users:
+  - name: root
+    password: "$$ecret"
+    permissions: [read, write]
+  - name: reader
+`)
+	defer cleanup()
+
+	cfg, err := loadConfig(path)
+	require.NoError(err)
+
+	require.Equal("0.0.0.0", *cfg.Host)
+	require.Equal(3307, *cfg.Port)
+	require.Equal("s3cr3t", *cfg.Password)
+	require.Equal("/tmp/index", *cfg.Index)
+	require.Equal("debug", *cfg.LogLevel)
+	require.Nil(cfg.User)
+
+	bucket, rooted := 0, false
+	require.Equal([]libraryConfig{
+		{
+			Name:   "siva",
+			Path:   "/repos/siva",
+			Format: "siva",
+			Bucket: &bucket,
+			Rooted: &rooted,
+		},
+		{Path: "/repos/git", Bare: "auto"},
+	}, cfg.Libraries)
+
+	require.Equal([]userConfig{
+		{Name: "root", Password: "$ecret", Permissions: []string{"read", "write"}},
+		{Name: "reader"},
+	}, cfg.Users)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown key", "prot: 3306", "field prot not found"},
+		{"wrong type", "port: foo", "cannot unmarshal"},
+		{"missing env", "host: localhost\npassword: ${GITBASE_TEST_UNSET}", "line 2: environment variable GITBASE_TEST_UNSET is not set"},
+		{"format", "format: zip", "format: invalid value \"zip\""},
+		{"log level", "log-level: trace", "log-level: invalid value \"trace\""},
+		{"port", "port: 70000", "port: invalid port 70000"},
+		{"bare", "bare: true\nnon-bare: true", "non-bare: cannot be used with bare"},
+		{"library path", "libraries:\n  - name: foo", "libraries[0].path: is required"},
+		{"library format", "libraries:\n  - path: /foo\n  - path: /bar\n    format: zip", "libraries[1].format: invalid value \"zip\""},
+		{"library bare", "libraries:\n  - path: /foo\n    bare: maybe", "libraries[0].bare: invalid value \"maybe\""},
+		{"library name", "libraries:\n  - path: /foo\n    name: a\n  - path: /bar\n    name: a", "libraries[1].name: duplicated name \"a\", already used in libraries[0]"},
+		{"user name", "users:\n  - password: foo", "users[0].name: is required"},
+		{"user duplicated", "users:\n  - name: foo\n  - name: foo", "users[1].name: duplicated user \"foo\""},
+		{"user permission", "users:\n  - name: foo\n    permissions: [read, admin]", "users[0].permissions[1]: invalid value \"admin\""},
+		{"user file", "user-file: users.json\nusers:\n  - name: foo", "users: cannot be used with user-file"},
+		{"readonly", "readonly: true\nusers:\n  - name: foo", "users: cannot be used with readonly"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := writeConfig(t, tt.content)
+			defer cleanup()
+
+			_, err := loadConfig(path)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), path+": ")
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestBindConfig(t *testing.T) {
+	require := require.New(t)
+
+	path, cleanup := writeConfig(t, `
+port: 3307
+host: 0.0.0.0
+timeout: 10
+no-squash: true
+verbose: true
+skip-git-errors: true
+mailmap: /etc/gitbase/mailmap
+keyrings: /etc/gitbase/keyrings
+directories: [/foo, /bar]
+libraries:
+  - path: /repos
+`)
+	defer cleanup()
+
+	require.NoError(os.Setenv("GITBASE_CONNECTION_TIMEOUT", "20"))
+	defer os.Unsetenv("GITBASE_CONNECTION_TIMEOUT")
+
+	var srv Server
+	parser := flags.NewParser(nil, flags.None)
+	_, err := parser.AddGroup("server", "", &srv)
+	require.NoError(err)
+	srv.BindConfig(parser.Command)
+
+	_, err = parser.ParseArgs([]string{"--config", path, "--port", "3308"})
+	require.NoError(err)
+
+	require.Equal(3308, srv.Port)
+	require.Equal("0.0.0.0", srv.Host)
+	require.Equal(20, srv.ConnTimeout)
+	require.True(srv.DisableSquash)
+	require.True(srv.Verbose)
+	require.True(srv.SkipGitErrors)
+	require.Equal("/etc/gitbase/mailmap", srv.MailmapFile)
+	require.Equal("/etc/gitbase/keyrings", srv.KeyringsDir)
+	require.Equal([]string{"/foo", "/bar"}, srv.Directories)
+	require.Equal("root", srv.User)
+	require.Equal([]libraryConfig{{Path: "/repos"}}, srv.libraries)
+
+	_, err = parser.ParseArgs([]string{"--config", path, "-d", "/baz"})
+	require.NoError(err)
+	require.Equal(3307, srv.Port)
+	require.Equal([]string{"/baz"}, srv.Directories)
+}
+
+func TestLibraryDirectory(t *testing.T) {
+	require := require.New(t)
+
+	defaults := directory{
+		Format: "git",
+		Bucket: 2,
+		Rooted: true,
+		Bare:   bareOn,
+	}
+
+	bucket, rooted := 0, false
+	dir := libraryConfig{
+		Name:   "siva",
+		Path:   "/repos/siva",
+		Format: "siva",
+		Bucket: &bucket,
+		Rooted: &rooted,
+	}.directory(defaults)
+	require.Equal(directory{
+		Name:   "siva",
+		Path:   "/repos/siva",
+		Format: "siva",
+		Bucket: 0,
+		Rooted: false,
+		Bare:   bareOn,
+	}, dir)
+	require.Equal("siva", dir.id())
+
+	dir = libraryConfig{Path: "/repos/git", Bare: "false"}.directory(defaults)
+	require.Equal(directory{
+		Path:   "/repos/git",
+		Format: "git",
+		Bucket: 2,
+		Rooted: true,
+		Bare:   bareOff,
+	}, dir)
+	require.Equal("/repos/git", dir.id())
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/src-d/gitbase/internal/export"
+)
+
+const (
+	ExportDescription = "Exports the results of a SQL query to files"
+	ExportHelp        = ExportDescription + "\n\n" +
+		"The query is read from the arguments or, if none is given, from the\n" +
+		"standard input. Rows are streamed to Parquet, Arrow IPC or JSON lines\n" +
+		"files in the given directory, starting a new file each time the size\n" +
+		"of the rows written reaches --max-file-size. The paths of the files\n" +
+		"are printed once the export finishes.\n\n" +
+		"By default when gitbase encounters an error in a repository it\n" +
+		"stops the query. With GITBASE_SKIP_GIT_ERRORS variable it won't\n" +
+		"complain and just skip those rows or repositories."
+)
+
+// Export represents the `export` command of gitbase cli tool.
+type Export struct {
+	EngineOptions
+
+	stdin  io.Reader
+	stdout io.Writer
+
+	Output      string `short:"o" long:"output" default:"parquet" choice:"parquet" choice:"arrow" choice:"jsonl" description:"Format of the exported files"`
+	Dir         string `long:"dir" required:"true" description:"Directory where the exported files are written"`
+	MaxFileSize int64  `long:"max-file-size" default:"512" description:"Size in megabytes of the rows written to a file before starting a new one. 0 means no limit."`
+}
+
+// Execute runs the query given in the arguments or the standard input and
+// exports its results, it honors the go-flags.Commander interface.
+func (c *Export) Execute(args []string) error {
+	if c.MaxFileSize < 0 {
+		return fmt.Errorf("--max-file-size cannot be negative")
+	}
+
+	srv, err := c.buildServer()
+	if err != nil {
+		return err
+	}
+
+	query, err := readQuery(c.stdin, args)
+	if err != nil {
+		return err
+	}
+
+	schema, iter, err := srv.engine.Query(c.newContext(srv), query)
+	if err != nil {
+		return err
+	}
+
+	files, err := export.Export(schema, iter, export.Options{
+		Format:      c.Output,
+		Dir:         c.Dir,
+		MaxFileSize: c.MaxFileSize * 1024 * 1024,
+	})
+	if err != nil {
+		_ = iter.Close()
+		return err
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	stdout := c.stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	for _, f := range files {
+		if _, err := fmt.Fprintln(stdout, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
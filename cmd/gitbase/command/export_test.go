@@ -0,0 +1,50 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer func() {
+		require.NoError(os.RemoveAll(tmpDir))
+	}()
+
+	dir := filepath.Join(tmpDir, "export")
+	var buf bytes.Buffer
+	c := &Export{
+		EngineOptions: testEngineOptions(filepath.Join(tmpDir, "index")),
+		stdin: strings.NewReader(
+			"SELECT repository_id FROM repositories " +
+				"WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b'",
+		),
+		stdout: &buf,
+		Output: "jsonl",
+		Dir:    dir,
+	}
+
+	require.NoError(c.Execute(nil))
+
+	file := filepath.Join(dir, "part-00000.jsonl")
+	require.Equal(file+"\n", buf.String())
+
+	content, err := ioutil.ReadFile(file)
+	require.NoError(err)
+	require.Equal(
+		`{"repository_id":"015da2f4-6d89-7ec8-5ac9-a38329ea875b"}`+"\n",
+		string(content),
+	)
+
+	c.MaxFileSize = -1
+	require.Error(c.Execute([]string{"SELECT 1"}))
+}
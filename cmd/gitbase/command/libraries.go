@@ -0,0 +1,271 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-borges"
+	"github.com/src-d/go-borges/legacysiva"
+	"github.com/src-d/go-borges/libraries"
+	"github.com/src-d/go-borges/plain"
+	"github.com/src-d/go-borges/siva"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+// attachedDirectory is a directory whose repositories are served by the
+// server, either with its own siva library or as a location of the plain
+// library.
+type attachedDirectory struct {
+	dir directory
+	lib borges.Library
+	loc *plain.Location
+}
+
+func (c *Server) openDirectory(d directory) (*attachedDirectory, error) {
+	if d.Format == "siva" {
+		var lib borges.Library
+		var err error
+
+		if d.Rooted {
+			sivaOpts := &siva.LibraryOptions{
+				Transactional: true,
+				RootedRepo:    d.Rooted,
+				Cache:         c.sharedCache,
+				Bucket:        d.Bucket,
+				Performance:   true,
+				RegistryCache: 100000,
+			}
+
+			lib, err = siva.NewLibrary(d.id(), osfs.New(d.Path), sivaOpts)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			sivaOpts := &legacysiva.LibraryOptions{
+				Cache:         c.sharedCache,
+				Bucket:        d.Bucket,
+				RegistryCache: 100000,
+			}
+
+			lib, err = legacysiva.NewLibrary(d.id(), osfs.New(d.Path), sivaOpts)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &attachedDirectory{dir: d, lib: lib}, nil
+	}
+
+	bare, err := discoverBare(d)
+	if err != nil {
+		return nil, err
+	}
+
+	plainOpts := &plain.LocationOptions{
+		Cache:       c.sharedCache,
+		Performance: true,
+		Bare:        bare,
+	}
+
+	loc, err := plain.NewLocation(
+		borges.LocationID(d.id()),
+		osfs.New(d.Path),
+		plainOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachedDirectory{dir: d, loc: loc}, nil
+}
+
+// setLibrary builds a new root library with the attached directories and
+// sets it in the pool. The root library is never modified once it's in use,
+// and queries in flight keep using the previous one until they finish, as
+// they read the repositories from the pool pinned by Session.QueryPool.
+func (c *Server) setLibrary() error {
+	root := libraries.New(nil)
+
+	var plainLib *plain.Library
+	for _, a := range c.attached {
+		if a.lib != nil {
+			if err := root.Add(a.lib); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if plainLib == nil {
+			plainLib = plain.NewLibrary(borges.LibraryID("plain"), nil)
+			if err := root.Add(plainLib); err != nil {
+				return err
+			}
+		}
+
+		plainLib.AddLocation(a.loc)
+	}
+
+	c.rootLibrary = root
+	c.plainLibrary = plainLib
+	c.pool.SetLibrary(root)
+
+	return nil
+}
+
+// updateDirectories detaches the directories with the given ids and then
+// attaches the given ones while the server is running. Indexes that became
+// outdated with the change are dropped.
+func (c *Server) updateDirectories(detach []string, attach []directory) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var attached []*attachedDirectory
+	detached := make(map[string]bool)
+	for _, id := range detach {
+		detached[id] = true
+	}
+
+	for _, a := range c.attached {
+		id := a.dir.id()
+		if !detached[id] {
+			attached = append(attached, a)
+			continue
+		}
+
+		delete(detached, id)
+		logrus.WithField("directory", id).Info("directory detached")
+	}
+
+	for id := range detached {
+		return fmt.Errorf("directory %s is not attached", id)
+	}
+
+	for _, d := range attach {
+		for _, a := range attached {
+			if a.dir.id() == d.id() {
+				return fmt.Errorf("directory %s is already attached", d.id())
+			}
+		}
+
+		a, err := c.openDirectory(d)
+		if err != nil {
+			return err
+		}
+
+		attached = append(attached, a)
+		logrus.WithField("directory", d.id()).Info("directory attached")
+	}
+
+	previous := c.rootLibrary
+	c.attached = attached
+	if err := c.setLibrary(); err != nil {
+		return err
+	}
+
+	return c.dropOutdatedIndexes(previous)
+}
+
+// attachedDirectories returns the directories being served.
+func (c *Server) attachedDirectories() []directory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var dirs = make([]directory, len(c.attached))
+	for i, a := range c.attached {
+		dirs[i] = a.dir
+	}
+
+	return dirs
+}
+
+// dropOutdatedIndexes drops the indexes of the tables whose checksum is not
+// the one of the repositories served anymore, as they would give wrong
+// results. The indexes were created with the partitions of the previous
+// library, so their data is deleted with those.
+func (c *Server) dropOutdatedIndexes(previous *libraries.Libraries) error {
+	db, err := c.engine.Catalog.Database(c.Name)
+	if err != nil {
+		return err
+	}
+
+	session := gitbase.NewSession(
+		gitbase.NewRepositoryPool(c.sharedCache, previous),
+		gitbase.WithSkipGitErrors(c.SkipGitErrors),
+	)
+	ctx := sql.NewContext(context.Background(), sql.WithSession(session))
+
+	// All the tables have the same checksum, the one of the repositories,
+	// so it's only calculated once and only if there are indexes.
+	var checksum string
+	for name, table := range db.Tables() {
+		for _, idx := range c.engine.Catalog.IndexesByTable(c.Name, name) {
+			ic, ok := idx.(sql.Checksumable)
+			if !ok {
+				continue
+			}
+
+			tc, ok := table.(sql.Checksumable)
+			if !ok {
+				continue
+			}
+
+			if checksum == "" {
+				checksum, err = tc.Checksum()
+				if err != nil {
+					return err
+				}
+			}
+
+			idxChecksum, err := ic.Checksum()
+			if err != nil {
+				return err
+			}
+
+			if idxChecksum == checksum {
+				continue
+			}
+
+			if err := c.dropIndex(ctx, table, idx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Server) dropIndex(ctx *sql.Context, table sql.Table, idx sql.Index) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"id":    idx.ID(),
+		"table": idx.Table(),
+	})
+	logger.Warn("dropping index outdated by a change in the directories")
+
+	done, err := c.engine.Catalog.DeleteIndex(c.Name, idx.ID(), true)
+	if err != nil {
+		return err
+	}
+
+	driver := c.engine.Catalog.IndexDriver(idx.Driver())
+	if driver == nil {
+		return nil
+	}
+
+	go func() {
+		<-done
+
+		partitions, err := table.Partitions(ctx)
+		if err != nil {
+			logger.WithField("error", err).Error("unable to delete index")
+			return
+		}
+
+		if err := driver.Delete(idx, partitions); err != nil {
+			logger.WithField("error", err).Error("unable to delete index")
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/src-d/go-borges"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLibrariesServer(t *testing.T, dirs ...string) (*Server, func()) {
+	t.Helper()
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(t, err)
+
+	server := &Server{
+		DatabaseOptions: DatabaseOptions{
+			Name:        "gitbase",
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			Directories: dirs,
+			IndexDir:    tmpDir,
+		},
+		LogLevel: "debug",
+	}
+
+	require.NoError(t, server.buildDatabase())
+
+	return server, func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}
+}
+
+func poolRepositories(t *testing.T, server *Server) []string {
+	t.Helper()
+
+	iter, err := server.pool.RepoIter()
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var ids []string
+	for {
+		repo, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		ids = append(ids, repo.ID())
+		require.NoError(t, repo.Close())
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+func TestUpdateDirectories(t *testing.T) {
+	require := require.New(t)
+
+	server, cleanup := setupLibrariesServer(t)
+	defer cleanup()
+
+	require.Empty(poolRepositories(t, server))
+
+	iter, err := server.pool.RepoIter()
+	require.NoError(err)
+	defer iter.Close()
+
+	dir := server.defaultDirectory()
+	dir.Name = "testdata"
+	dir.Path = "../../../_testdata"
+
+	require.NoError(server.updateDirectories(nil, []directory{dir}))
+	require.Equal([]directory{dir}, server.attachedDirectories())
+	require.Equal([]string{
+		"015da2f4-6d89-7ec8-5ac9-a38329ea875b",
+		"015dcc49-9049-b00c-ba72-b6f5fa98cbe7",
+		"015dcc49-90e6-34f2-ac03-df879ee269f3",
+		"015dcc4d-0bdf-6aff-4aac-ffe68c752eb3",
+		"015dcc4d-2622-bdac-12a5-ec441e3f3508",
+	}, poolRepositories(t, server))
+
+	// iterators created before the change keep using the previous library
+	_, err = iter.Next()
+	require.Equal(io.EOF, err)
+
+	err = server.updateDirectories(nil, []directory{dir})
+	require.EqualError(err, "directory testdata is already attached")
+
+	err = server.updateDirectories([]string{"foo"}, nil)
+	require.EqualError(err, "directory foo is not attached")
+	require.Len(poolRepositories(t, server), 5)
+
+	require.NoError(server.updateDirectories([]string{"testdata"}, nil))
+	require.Empty(server.attachedDirectories())
+	require.Empty(poolRepositories(t, server))
+}
+
+func TestOpenDirectoryLibraryID(t *testing.T) {
+	server, cleanup := setupLibrariesServer(t)
+	defer cleanup()
+
+	dir := server.defaultDirectory()
+	dir.Path = "../../../_testdata"
+
+	for _, rooted := range []bool{true, false} {
+		dir.Rooted = rooted
+		a, err := server.openDirectory(dir)
+		require.NoError(t, err)
+		require.Equal(t, borges.LibraryID(dir.Path), a.lib.ID())
+	}
+}
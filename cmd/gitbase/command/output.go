@@ -0,0 +1,300 @@
+package command
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Output formats of the query results.
+const (
+	outputTable    = "table"
+	outputCSV      = "csv"
+	outputTSV      = "tsv"
+	outputJSON     = "json"
+	outputMarkdown = "markdown"
+)
+
+const (
+	nullValue = "NULL"
+	// timeLayout is the layout of the dates, as MySQL prints timestamps.
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// rowWriter writes the rows of a query result in some format.
+type rowWriter interface {
+	// WriteRow writes a row of the result.
+	WriteRow(sql.Row) error
+	// Close writes any pending output once all rows are written.
+	Close() error
+}
+
+func newRowWriter(format string, w io.Writer, schema sql.Schema) (rowWriter, error) {
+	switch format {
+	case outputTable:
+		return newTableWriter(w, schema, false), nil
+	case outputMarkdown:
+		return newTableWriter(w, schema, true), nil
+	case outputCSV:
+		return newCSVWriter(w, schema, ',')
+	case outputTSV:
+		return newCSVWriter(w, schema, '\t')
+	case outputJSON:
+		return newJSONWriter(w, schema), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// tableSampleRows is the number of rows kept in memory by tableWriter to
+// compute the width of the columns.
+const tableSampleRows = 1000
+
+var (
+	// tableEscaper escapes the line breaks of the values of text tables.
+	tableEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+	// markdownEscaper escapes the pipes and line breaks of the values of
+	// Markdown tables.
+	markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+)
+
+// tableWriter writes the rows as a text table. The width of the columns
+// depends on the values, so the first tableSampleRows rows are kept in
+// memory until the width is known, and the rest are written as they come.
+// Values of later rows longer than their column are not truncated, and
+// they overflow it.
+type tableWriter struct {
+	w        io.Writer
+	markdown bool
+	header   []string
+	rows     [][]string
+	widths   []int
+	started  bool
+}
+
+func newTableWriter(w io.Writer, schema sql.Schema, markdown bool) *tableWriter {
+	t := &tableWriter{w: w, markdown: markdown}
+	for _, col := range schema {
+		t.header = append(t.header, col.Name)
+		t.widths = append(t.widths, utf8.RuneCountInString(col.Name))
+	}
+
+	return t
+}
+
+func (t *tableWriter) WriteRow(row sql.Row) error {
+	var values = make([]string, len(row))
+	for i, v := range row {
+		values[i] = formatValue(v, nullValue)
+		if t.markdown {
+			values[i] = markdownEscaper.Replace(values[i])
+		} else {
+			values[i] = tableEscaper.Replace(values[i])
+		}
+	}
+
+	if t.started {
+		return t.writeLines(t.line(values))
+	}
+
+	for i, v := range values {
+		if n := utf8.RuneCountInString(v); i < len(t.widths) && n > t.widths[i] {
+			t.widths[i] = n
+		}
+	}
+
+	t.rows = append(t.rows, values)
+	if len(t.rows) >= tableSampleRows {
+		return t.start()
+	}
+
+	return nil
+}
+
+// start writes the header and the rows kept in memory.
+func (t *tableWriter) start() error {
+	var lines []string
+	if t.markdown {
+		lines = append(lines, t.line(t.header))
+		var sep = make([]string, len(t.widths))
+		for i, w := range t.widths {
+			sep[i] = strings.Repeat("-", w)
+		}
+		lines = append(lines, t.line(sep))
+	} else {
+		lines = append(lines, t.separator(), t.line(t.header), t.separator())
+	}
+
+	for _, r := range t.rows {
+		lines = append(lines, t.line(r))
+	}
+
+	t.started = true
+	t.rows = nil
+	return t.writeLines(lines...)
+}
+
+func (t *tableWriter) Close() error {
+	if !t.started {
+		if err := t.start(); err != nil {
+			return err
+		}
+	}
+
+	if !t.markdown {
+		return t.writeLines(t.separator())
+	}
+
+	return nil
+}
+
+func (t *tableWriter) writeLines(lines ...string) error {
+	_, err := io.WriteString(t.w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (t *tableWriter) separator() string {
+	var parts = make([]string, len(t.widths))
+	for i, w := range t.widths {
+		parts[i] = strings.Repeat("-", w+2)
+	}
+
+	return "+" + strings.Join(parts, "+") + "+"
+}
+
+func (t *tableWriter) line(values []string) string {
+	var parts = make([]string, len(values))
+	for i, v := range values {
+		var pad int
+		if i < len(t.widths) && utf8.RuneCountInString(v) < t.widths[i] {
+			pad = t.widths[i] - utf8.RuneCountInString(v)
+		}
+
+		parts[i] = " " + v + strings.Repeat(" ", pad) + " "
+	}
+
+	return "|" + strings.Join(parts, "|") + "|"
+}
+
+// csvWriter writes the rows as comma or tab separated values, with a header
+// with the column names.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, schema sql.Schema, comma rune) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	var header = make([]string, len(schema))
+	for i, col := range schema {
+		header[i] = col.Name
+	}
+
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{cw}, nil
+}
+
+func (c *csvWriter) WriteRow(row sql.Row) error {
+	var values = make([]string, len(row))
+	for i, v := range row {
+		values[i] = formatValue(v, nullValue)
+	}
+
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes every row as a JSON object in its own line, with the
+// column names as keys.
+type jsonWriter struct {
+	w      io.Writer
+	schema sql.Schema
+}
+
+func newJSONWriter(w io.Writer, schema sql.Schema) *jsonWriter {
+	return &jsonWriter{w, schema}
+}
+
+func (j *jsonWriter) WriteRow(row sql.Row) error {
+	var buf strings.Builder
+	buf.WriteString("{")
+	for i, v := range row {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		var name string
+		if i < len(j.schema) {
+			name = j.schema[i].Name
+		}
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return err
+		}
+
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	_, err := io.WriteString(j.w, buf.String())
+	return err
+}
+
+func (j *jsonWriter) Close() error { return nil }
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(timeLayout)
+	default:
+		return v
+	}
+}
+
+// formatValue returns the text representation of a value, using null for
+// NULL values. JSON documents and arrays are formatted as JSON.
+func formatValue(v interface{}, null string) string {
+	switch v := v.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(timeLayout)
+	case []interface{}, map[string]interface{}:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(bs)
+	default:
+		return fmt.Sprint(v)
+	}
+}
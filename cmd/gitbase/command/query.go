@@ -0,0 +1,203 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+)
+
+const (
+	QueryDescription = "Runs a SQL query and prints its results"
+	QueryHelp        = QueryDescription + "\n\n" +
+		"The query is read from the arguments or, if none is given, from the\n" +
+		"standard input. Results can be printed as a table, CSV, TSV, JSON\n" +
+		"lines or a Markdown table. The command exits with a non-zero code\n" +
+		"if the query fails.\n\n" +
+		"By default when gitbase encounters an error in a repository it\n" +
+		"stops the query. With GITBASE_SKIP_GIT_ERRORS variable it won't\n" +
+		"complain and just skip those rows or repositories."
+)
+
+// DatabaseOptions are the options used to build the database engine. They
+// are shared by the server and the commands that run queries in process.
+type DatabaseOptions struct {
+	Name          string         `long:"db" default:"gitbase" description:"Database name"`
+	Version       string         // Version of the application.
+	Directories   []string       `short:"d" long:"directories" description:"Path where standard git repositories are located, multiple directories can be defined."`
+	Format        string         `long:"format" default:"git" choice:"git" choice:"siva" description:"Library format"`
+	Bucket        int            `long:"bucket" default:"2" description:"Bucketing level to use with siva libraries"`
+	Bare          bool           `long:"bare" description:"Sets the library to use bare git repositories, used only with git format libraries"`
+	NonBare       bool           `long:"non-bare" description:"Sets the library to use non bare git repositories, used only with git format libraries"`
+	NonRooted     bool           `long:"non-rooted" description:"Disables treating siva files as rooted repositories"`
+	IndexDir      string         `short:"i" long:"index" default:"/var/lib/gitbase/index" description:"Directory where the gitbase indexes information will be persisted." env:"GITBASE_INDEX_DIR"`
+	CacheSize     cache.FileSize `long:"cache" default:"512" description:"Object cache size in megabytes" env:"GITBASE_CACHESIZE_MB"`
+	Parallelism   uint           `long:"parallelism" description:"Maximum number of parallel threads per table. By default, it's the number of CPU cores. 0 means default, 1 means disabled."`
+	DisableSquash bool           `long:"no-squash" description:"Disables the table squashing."`
+	MailmapFile   string         `long:"mailmap" env:"GITBASE_MAILMAP_FILE" description:"Mailmap file used for all repositories, taking precedence over their .mailmap files."`
+	KeyringsDir   string         `long:"keyrings" env:"GITBASE_KEYRINGS_DIR" description:"Directory with the keyrings used to verify signatures, with OpenPGP armored keys or SSH allowed signers."`
+	SkipGitErrors bool           // SkipGitErrors disables failing when Git errors are found.
+	Verbose       bool           `short:"v" description:"Activates the verbose mode (equivalent to debug logging level), overwriting any passed logging level"`
+}
+
+// EngineOptions are the options used by the commands that run queries
+// in process to build the database engine.
+type EngineOptions struct {
+	DatabaseOptions
+
+	LogLevel string `long:"log-level" env:"GITBASE_LOG_LEVEL" choice:"info" choice:"debug" choice:"warning" choice:"error" choice:"fatal" default:"warning" description:"logging level; ignored if using -v verbose flag"`
+}
+
+// Query represents the `query` command of gitbase cli tool.
+type Query struct {
+	EngineOptions
+
+	stdin  io.Reader
+	stdout io.Writer
+
+	Output string `short:"o" long:"output" default:"table" choice:"table" choice:"csv" choice:"tsv" choice:"json" choice:"markdown" description:"Format of the results"`
+}
+
+// Execute runs the query given in the arguments or the standard input and
+// prints its results, it honors the go-flags.Commander interface.
+func (c *Query) Execute(args []string) error {
+	srv, err := c.buildServer()
+	if err != nil {
+		return err
+	}
+
+	query, err := readQuery(c.stdin, args)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.run(c.newContext(srv), srv.engine, query)
+	return err
+}
+
+// buildServer returns a server with the database engine built from the
+// options, without listening for connections.
+func (c *EngineOptions) buildServer() (*Server, error) {
+	if c.Verbose {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		level, err := logrus.ParseLevel(c.LogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse log level: %s", err.Error())
+		}
+		logrus.SetLevel(level)
+	}
+
+	if c.Bare && c.NonBare {
+		return nil, fmt.Errorf("cannot use both --bare and --non-bare")
+	}
+
+	srv := &Server{
+		userAuth:        new(auth.None),
+		optionalIndexes: true,
+		DatabaseOptions: c.DatabaseOptions,
+	}
+
+	if err := srv.buildDatabase(); err != nil {
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+func (c *EngineOptions) newContext(srv *Server) *sql.Context {
+	session := gitbase.NewSession(srv.pool,
+		gitbase.WithSkipGitErrors(c.SkipGitErrors),
+		gitbase.WithMailmapFile(c.MailmapFile),
+		gitbase.WithKeyringsDir(c.KeyringsDir),
+	)
+
+	return sql.NewContext(context.Background(), sql.WithSession(session))
+}
+
+// run runs the given query and prints its results, returning the number of
+// rows printed.
+func (c *Query) run(ctx *sql.Context, engine *sqle.Engine, query string) (int, error) {
+	schema, iter, err := engine.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.printRows(schema, iter)
+	if err != nil {
+		_ = iter.Close()
+		return n, err
+	}
+
+	return n, iter.Close()
+}
+
+// readQuery returns the query in the given arguments, or the one in stdin
+// if there are no arguments. The standard input is used if stdin is nil.
+func readQuery(stdin io.Reader, args []string) (string, error) {
+	var query = strings.Join(args, " ")
+	if len(args) == 0 {
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+
+		bs, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return "", err
+		}
+
+		query = string(bs)
+	}
+
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return "", fmt.Errorf("no query given")
+	}
+
+	return query, nil
+}
+
+func (c *Query) printRows(schema sql.Schema, iter sql.RowIter) (int, error) {
+	w, err := newRowWriter(c.Output, c.output(), schema)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		if err := w.WriteRow(row); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, w.Close()
+}
+
+func (c *Query) output() io.Writer {
+	if c.stdout == nil {
+		return os.Stdout
+	}
+
+	return c.stdout
+}
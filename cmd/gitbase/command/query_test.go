@@ -0,0 +1,252 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/index/pilosa"
+	"github.com/stretchr/testify/require"
+)
+
+// testEngineOptions returns the options to build an engine with the siva
+// files in _testdata.
+func testEngineOptions(indexDir string) EngineOptions {
+	return EngineOptions{
+		DatabaseOptions: DatabaseOptions{
+			Name:        "gitbase",
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			Directories: []string{"../../../_testdata"},
+			IndexDir:    indexDir,
+		},
+		LogLevel: "warning",
+	}
+}
+
+func TestRowWriters(t *testing.T) {
+	schema := sql.Schema{
+		{Name: "name", Type: sql.Text},
+		{Name: "count", Type: sql.Int64},
+		{Name: "when", Type: sql.Timestamp, Nullable: true},
+	}
+
+	rows := []sql.Row{
+		sql.NewRow("foo", int64(1), time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)),
+		sql.NewRow("bär|baz", int64(22), nil),
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			outputTable,
+			"+---------+-------+---------------------+\n" +
+				"| name    | count | when                |\n" +
+				"+---------+-------+---------------------+\n" +
+				"| foo     | 1     | 2019-01-02 03:04:05 |\n" +
+				"| bär|baz | 22    | NULL                |\n" +
+				"+---------+-------+---------------------+\n",
+		},
+		{
+			outputMarkdown,
+			"| name     | count | when                |\n" +
+				"| -------- | ----- | ------------------- |\n" +
+				"| foo      | 1     | 2019-01-02 03:04:05 |\n" +
+				"| bär\\|baz | 22    | NULL                |\n",
+		},
+		{
+			outputCSV,
+			"name,count,when\n" +
+				"foo,1,2019-01-02 03:04:05\n" +
+				"bär|baz,22,NULL\n",
+		},
+		{
+			outputTSV,
+			"name\tcount\twhen\n" +
+				"foo\t1\t2019-01-02 03:04:05\n" +
+				"bär|baz\t22\tNULL\n",
+		},
+		{
+			outputJSON,
+			`{"name":"foo","count":1,"when":"2019-01-02 03:04:05"}` + "\n" +
+				`{"name":"bär|baz","count":22,"when":null}` + "\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.format, func(t *testing.T) {
+			require := require.New(t)
+
+			var buf bytes.Buffer
+			w, err := newRowWriter(tt.format, &buf, schema)
+			require.NoError(err)
+
+			for _, row := range rows {
+				require.NoError(w.WriteRow(row))
+			}
+
+			require.NoError(w.Close())
+			require.Equal(tt.expected, buf.String())
+		})
+	}
+
+	_, err := newRowWriter("foo", ioutil.Discard, schema)
+	require.Error(t, err)
+}
+
+func TestTableWriterLineBreaks(t *testing.T) {
+	schema := sql.Schema{{Name: "commit_message", Type: sql.Text}}
+	row := sql.NewRow("Fix bug|crash\r\n\nDetails\n")
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			outputTable,
+			"+------------------------------+\n" +
+				"| commit_message               |\n" +
+				"+------------------------------+\n" +
+				`| Fix bug|crash\r\n\nDetails\n |` + "\n" +
+				"+------------------------------+\n",
+		},
+		{
+			outputMarkdown,
+			"| commit_message                    |\n" +
+				"| --------------------------------- |\n" +
+				`| Fix bug\|crash<br><br>Details<br> |` + "\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.format, func(t *testing.T) {
+			require := require.New(t)
+
+			var buf bytes.Buffer
+			w, err := newRowWriter(tt.format, &buf, schema)
+			require.NoError(err)
+			require.NoError(w.WriteRow(row))
+			require.NoError(w.Close())
+			require.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func TestTableWriterStreaming(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	w := newTableWriter(&buf, sql.Schema{{Name: "n", Type: sql.Int64}}, false)
+	for i := 0; i < tableSampleRows; i++ {
+		require.NoError(w.WriteRow(sql.NewRow(int64(i % 10))))
+	}
+
+	// The sampled rows are written once the width of the columns is known.
+	require.Len(w.rows, 0)
+	lines := strings.Split(buf.String(), "\n")
+	require.Len(lines, tableSampleRows+4)
+	require.Equal("| 9 |", lines[len(lines)-2])
+
+	buf.Reset()
+	require.NoError(w.WriteRow(sql.NewRow(int64(1234))))
+	require.Equal("| 1234 |\n", buf.String())
+
+	buf.Reset()
+	require.NoError(w.Close())
+	require.Equal("+---+\n", buf.String())
+}
+
+func TestFormatValue(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("NULL", formatValue(nil, "NULL"))
+	require.Equal("foo", formatValue("foo", "NULL"))
+	require.Equal("foo", formatValue([]byte("foo"), "NULL"))
+	require.Equal("true", formatValue(true, "NULL"))
+	require.Equal("1.5", formatValue(1.5, "NULL"))
+	require.Equal(`["a",1]`, formatValue([]interface{}{"a", 1}, "NULL"))
+	require.Equal(`{"a":1}`, formatValue(map[string]interface{}{"a": 1}, "NULL"))
+}
+
+func TestReadQuery(t *testing.T) {
+	require := require.New(t)
+
+	query, err := readQuery(strings.NewReader("ignored"), []string{"SELECT", "1;"})
+	require.NoError(err)
+	require.Equal("SELECT 1", query)
+
+	query, err = readQuery(strings.NewReader("\n  SELECT 1 ;\n"), nil)
+	require.NoError(err)
+	require.Equal("SELECT 1", query)
+
+	_, err = readQuery(strings.NewReader(" ; "), nil)
+	require.Error(err)
+}
+
+func TestQuery(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer func() {
+		require.NoError(os.RemoveAll(tmpDir))
+	}()
+
+	var buf bytes.Buffer
+	c := &Query{
+		stdin: strings.NewReader(
+			"SELECT repository_id FROM repositories " +
+				"WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b';",
+		),
+		EngineOptions: testEngineOptions(tmpDir),
+		stdout:        &buf,
+		Output:        outputCSV,
+	}
+
+	require.NoError(c.Execute(nil))
+	require.Equal("repository_id\n015da2f4-6d89-7ec8-5ac9-a38329ea875b\n", buf.String())
+
+	c.stdout = ioutil.Discard
+	require.Error(c.Execute([]string{"SELECT * FROM foo"}))
+}
+
+func TestQueryIndexDir(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer func() {
+		require.NoError(os.RemoveAll(tmpDir))
+	}()
+
+	// The index directory can't be created inside a file.
+	file := filepath.Join(tmpDir, "file")
+	require.NoError(ioutil.WriteFile(file, nil, 0644))
+
+	cacheHome, ok := os.LookupEnv("XDG_CACHE_HOME")
+	require.NoError(os.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache")))
+	defer func() {
+		if ok {
+			require.NoError(os.Setenv("XDG_CACHE_HOME", cacheHome))
+		} else {
+			require.NoError(os.Unsetenv("XDG_CACHE_HOME"))
+		}
+	}()
+
+	opts := testEngineOptions(filepath.Join(file, "index"))
+	srv, err := opts.buildServer()
+	require.NoError(err)
+	require.NotNil(srv.engine.Catalog.IndexDriver(pilosa.DriverID))
+	require.DirExists(filepath.Join(tmpDir, "cache", "gitbase", "index"))
+
+	server := &Server{DatabaseOptions: opts.DatabaseOptions, LogLevel: "info"}
+	require.Error(server.buildDatabase())
+}
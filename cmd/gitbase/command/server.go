@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/metrics/prometheus"
@@ -25,10 +26,8 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 	"github.com/src-d/go-borges"
-	"github.com/src-d/go-borges/legacysiva"
 	"github.com/src-d/go-borges/libraries"
 	"github.com/src-d/go-borges/plain"
-	"github.com/src-d/go-borges/siva"
 	sqle "github.com/src-d/go-mysql-server"
 	"github.com/src-d/go-mysql-server/auth"
 	"github.com/src-d/go-mysql-server/server"
@@ -60,31 +59,36 @@ type Server struct {
 	plainLibrary *plain.Library
 	sharedCache  cache.Object
 
-	Name           string         `long:"db" default:"gitbase" description:"Database name"`
-	Version        string         // Version of the application.
-	Directories    []string       `short:"d" long:"directories" description:"Path where standard git repositories are located, multiple directories can be defined."`
-	Format         string         `long:"format" default:"git" choice:"git" choice:"siva" description:"Library format"`
-	Bucket         int            `long:"bucket" default:"2" description:"Bucketing level to use with siva libraries"`
-	Bare           bool           `long:"bare" description:"Sets the library to use bare git repositories, used only with git format libraries"`
-	NonBare        bool           `long:"non-bare" description:"Sets the library to use non bare git repositories, used only with git format libraries"`
-	NonRooted      bool           `long:"non-rooted" description:"Disables treating siva files as rooted repositories"`
-	Host           string         `long:"host" default:"localhost" description:"Host where the server is going to listen"`
-	Port           int            `short:"p" long:"port" default:"3306" description:"Port where the server is going to listen"`
-	User           string         `short:"u" long:"user" default:"root" description:"User name used for connection"`
-	Password       string         `short:"P" long:"password" default:"" description:"Password used for connection"`
-	UserFile       string         `short:"U" long:"user-file" env:"GITBASE_USER_FILE" default:"" description:"JSON file with credentials list"`
-	ConnTimeout    int            `short:"t" long:"timeout" env:"GITBASE_CONNECTION_TIMEOUT" description:"Timeout in seconds used for connections"`
-	IndexDir       string         `short:"i" long:"index" default:"/var/lib/gitbase/index" description:"Directory where the gitbase indexes information will be persisted." env:"GITBASE_INDEX_DIR"`
-	CacheSize      cache.FileSize `long:"cache" default:"512" description:"Object cache size in megabytes" env:"GITBASE_CACHESIZE_MB"`
-	Parallelism    uint           `long:"parallelism" description:"Maximum number of parallel threads per table. By default, it's the number of CPU cores. 0 means default, 1 means disabled."`
-	DisableSquash  bool           `long:"no-squash" description:"Disables the table squashing."`
-	TraceEnabled   bool           `long:"trace" env:"GITBASE_TRACE" description:"Enables jaeger tracing"`
-	MetricsEnabled bool           `long:"metrics" env:"GITBASE_METRICS" description:"Enables prometheus metrics"`
-	MetricsPort    int            `long:"metrics-port" env:"GITBASE_METRICS_PORT" default:"2112" description:"Port where the server is going to expose prometheus metrics"`
-	ReadOnly       bool           `short:"r" long:"readonly" description:"Only allow read queries. This disables creating and deleting indexes as well. Cannot be used with --user-file." env:"GITBASE_READONLY"`
-	SkipGitErrors  bool           // SkipGitErrors disables failing when Git errors are found.
-	Verbose        bool           `short:"v" description:"Activates the verbose mode (equivalent to debug logging level), overwriting any passed logging level"`
-	LogLevel       string         `long:"log-level" env:"GITBASE_LOG_LEVEL" choice:"info" choice:"debug" choice:"warning" choice:"error" choice:"fatal" default:"info" description:"logging level; ignored if using -v verbose flag"`
+	libraries  []libraryConfig
+	users      []userConfig
+	configPath string
+
+	mu       sync.Mutex
+	attached []*attachedDirectory
+
+	// optionalIndexes makes the index directory optional, falling back to
+	// the user cache directory or disabling the indexes when it can't be
+	// created.
+	optionalIndexes bool
+
+	// Config loads the configuration file. It must be the first option, so
+	// the file is loaded before the values of the rest are set.
+	Config func(string) error `short:"c" long:"config" env:"GITBASE_CONFIG" description:"YAML file with the server configuration. Flags and environment variables take precedence over its values."`
+
+	DatabaseOptions
+
+	Host           string `long:"host" default:"localhost" description:"Host where the server is going to listen"`
+	Port           int    `short:"p" long:"port" default:"3306" description:"Port where the server is going to listen"`
+	User           string `short:"u" long:"user" default:"root" description:"User name used for connection"`
+	Password       string `short:"P" long:"password" default:"" description:"Password used for connection"`
+	UserFile       string `short:"U" long:"user-file" env:"GITBASE_USER_FILE" default:"" description:"JSON file with credentials list"`
+	ConnTimeout    int    `short:"t" long:"timeout" env:"GITBASE_CONNECTION_TIMEOUT" description:"Timeout in seconds used for connections"`
+	TraceEnabled   bool   `long:"trace" env:"GITBASE_TRACE" description:"Enables jaeger tracing"`
+	MetricsEnabled bool   `long:"metrics" env:"GITBASE_METRICS" description:"Enables prometheus metrics"`
+	MetricsPort    int    `long:"metrics-port" env:"GITBASE_METRICS_PORT" default:"2112" description:"Port where the server is going to expose prometheus metrics"`
+	ReadOnly       bool   `short:"r" long:"readonly" description:"Only allow read queries. This disables creating and deleting indexes as well. Cannot be used with --user-file." env:"GITBASE_READONLY"`
+	Watch          bool   `short:"w" long:"watch" env:"GITBASE_WATCH" description:"Watches the directories and the configuration file, reloading, attaching or detaching directories when they change, without restarting the server."`
+	LogLevel       string `long:"log-level" env:"GITBASE_LOG_LEVEL" choice:"info" choice:"debug" choice:"warning" choice:"error" choice:"fatal" default:"info" description:"logging level; ignored if using -v verbose flag"`
 }
 
 type jaegerLogrus struct {
@@ -162,6 +166,15 @@ func (c *Server) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
+	} else if len(c.users) > 0 {
+		if c.ReadOnly {
+			return fmt.Errorf("cannot use both users in the configuration file and --readonly")
+		}
+
+		c.userAuth, err = newUserAuth(c.users)
+		if err != nil {
+			return err
+		}
 	} else {
 		permissions := auth.AllPermissions
 		if c.ReadOnly {
@@ -176,6 +189,16 @@ func (c *Server) Execute(args []string) error {
 		return err
 	}
 
+	if c.Watch {
+		w, err := newDirectoryWatcher(c, watchDelay)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		logrus.Info("watching directories for changes")
+	}
+
 	auth := mysql.NewAuthServerStatic()
 	auth.Entries[c.User] = []*mysql.AuthServerStaticEntry{
 		{Password: c.Password},
@@ -221,6 +244,8 @@ func (c *Server) Execute(args []string) error {
 		c.engine,
 		gitbase.NewSessionBuilder(c.pool,
 			gitbase.WithSkipGitErrors(c.SkipGitErrors),
+			gitbase.WithMailmapFile(c.MailmapFile),
+			gitbase.WithKeyringsDir(c.KeyringsDir),
 		),
 	)
 	if err != nil {
@@ -258,6 +283,7 @@ func (c *Server) buildDatabase() error {
 
 	c.rootLibrary = libraries.New(nil)
 	c.pool = gitbase.NewRepositoryPool(c.sharedCache, c.rootLibrary)
+	c.attached = nil
 
 	if err := c.addDirectories(); err != nil {
 		return err
@@ -285,51 +311,93 @@ func (c *Server) buildDatabase() error {
 }
 
 func (c *Server) registerDrivers() error {
-	if err := os.MkdirAll(c.IndexDir, 0755); err != nil {
+	dir, err := c.indexDir()
+	if err != nil {
 		return err
 	}
 
-	logrus.Debug("created index storage")
+	if dir == "" {
+		logrus.Info("index storage not available, indexes are disabled")
+		return nil
+	}
+
+	logrus.WithField("dir", dir).Debug("created index storage")
 
 	c.engine.Catalog.RegisterIndexDriver(
-		pilosa.NewDriver(filepath.Join(c.IndexDir, pilosa.DriverID)),
+		pilosa.NewDriver(filepath.Join(dir, pilosa.DriverID)),
 	)
 	logrus.Debug("registered pilosa index driver")
 
 	return nil
 }
 
+// indexDir creates the directory where the indexes are persisted and
+// returns it. With optional indexes the user cache directory is used when
+// the configured one can't be created, as the default one is usually only
+// writable by root, and an empty directory is returned if that fails too.
+func (c *Server) indexDir() (string, error) {
+	err := os.MkdirAll(c.IndexDir, 0755)
+	if err == nil || !c.optionalIndexes {
+		return c.IndexDir, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dir":   c.IndexDir,
+		"error": err,
+	}).Debug("unable to create index storage")
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", nil
+	}
+
+	dir := filepath.Join(cacheDir, "gitbase", "index")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"dir":   dir,
+			"error": err,
+		}).Debug("unable to create index storage")
+		return "", nil
+	}
+
+	return dir, nil
+}
+
 func (c *Server) addDirectories() error {
-	if len(c.Directories) == 0 {
+	if len(c.Directories) == 0 && len(c.libraries) == 0 {
 		logrus.Error("at least one folder should be provided.")
 	}
 
-	defaultBare := bareAuto
-	switch {
-	case c.Bare:
-		defaultBare = bareOn
-	case c.NonBare:
-		defaultBare = bareOff
-	}
+	defaults := c.defaultDirectory()
 
+	var dirs []directory
 	for _, d := range c.Directories {
-		dir := directory{
-			Path:   d,
-			Format: c.Format,
-			Bare:   defaultBare,
-			Bucket: c.Bucket,
-			Rooted: !c.NonRooted,
-		}
+		dir := defaults
+		dir.Path = d
 
 		dir, err := parseDirectory(dir)
 		if err != nil {
 			return err
 		}
 
-		err = c.addDirectory(dir)
+		dirs = append(dirs, dir)
+	}
+
+	for _, l := range c.libraries {
+		dirs = append(dirs, l.directory(defaults))
+	}
+
+	for _, d := range dirs {
+		a, err := c.openDirectory(d)
 		if err != nil {
 			return err
 		}
+
+		c.attached = append(c.attached, a)
+	}
+
+	if err := c.setLibrary(); err != nil {
+		return err
 	}
 
 	repos, err := c.rootLibrary.Repositories(borges.ReadOnlyMode)
@@ -345,76 +413,23 @@ func (c *Server) addDirectories() error {
 	})
 }
 
-func (c *Server) addDirectory(d directory) error {
-	if d.Format == "siva" {
-		var lib borges.Library
-		var err error
-
-		if d.Rooted {
-			sivaOpts := &siva.LibraryOptions{
-				Transactional: true,
-				RootedRepo:    d.Rooted,
-				Cache:         c.sharedCache,
-				Bucket:        d.Bucket,
-				Performance:   true,
-				RegistryCache: 100000,
-			}
-
-			lib, err = siva.NewLibrary("", osfs.New(d.Path), sivaOpts)
-			if err != nil {
-				return err
-			}
-		} else {
-			sivaOpts := &legacysiva.LibraryOptions{
-				Cache:         c.sharedCache,
-				Bucket:        d.Bucket,
-				RegistryCache: 100000,
-			}
-
-			lib, err = legacysiva.NewLibrary(d.Path, osfs.New(d.Path), sivaOpts)
-			if err != nil {
-				return err
-			}
-		}
-
-		err = c.rootLibrary.Add(lib)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	}
-
-	bare, err := discoverBare(d)
-	if err != nil {
-		return err
-	}
-
-	plainOpts := &plain.LocationOptions{
-		Cache:       c.sharedCache,
-		Performance: true,
-		Bare:        bare,
+// defaultDirectory returns a directory with the options given to the
+// server, used for the ones not set in each directory.
+func (c *Server) defaultDirectory() directory {
+	bare := bareAuto
+	switch {
+	case c.Bare:
+		bare = bareOn
+	case c.NonBare:
+		bare = bareOff
 	}
 
-	if c.plainLibrary == nil {
-		c.plainLibrary = plain.NewLibrary(borges.LibraryID("plain"), nil)
-		err := c.rootLibrary.Add(c.plainLibrary)
-		if err != nil {
-			return err
-		}
+	return directory{
+		Format: c.Format,
+		Bare:   bare,
+		Bucket: c.Bucket,
+		Rooted: !c.NonRooted,
 	}
-
-	loc, err := plain.NewLocation(
-		borges.LocationID(d.Path),
-		osfs.New(d.Path),
-		plainOpts)
-	if err != nil {
-		return err
-	}
-
-	c.plainLibrary.AddLocation(loc)
-
-	return nil
 }
 
 type bareOpt int
@@ -426,6 +441,7 @@ const (
 )
 
 type directory struct {
+	Name   string
 	Path   string
 	Format string
 	Bucket int
@@ -433,6 +449,16 @@ type directory struct {
 	Bare   bareOpt
 }
 
+// id returns the identifier of the library or location of the directory,
+// which is its name or, if it has none, its path.
+func (d directory) id() string {
+	if d.Name != "" {
+		return d.Name
+	}
+
+	return d.Path
+}
+
 var (
 	uriReg     = regexp.MustCompile(`^\w+:.*`)
 	ErrInvalid = fmt.Errorf("invalid option")
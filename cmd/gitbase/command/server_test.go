@@ -285,12 +285,14 @@ func TestCache(t *testing.T) {
 	}()
 
 	server := &Server{
-		CacheSize:   512,
-		Format:      "siva",
-		Bucket:      0,
-		LogLevel:    "debug",
-		Directories: []string{"../../../_testdata"},
-		IndexDir:    tmpDir,
+		DatabaseOptions: DatabaseOptions{
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			Directories: []string{"../../../_testdata"},
+			IndexDir:    tmpDir,
+		},
+		LogLevel: "debug",
 	}
 
 	err = server.buildDatabase()
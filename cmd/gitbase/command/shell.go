@@ -0,0 +1,409 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/gitbase/internal/function"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+const (
+	ShellDescription = "Starts an interactive SQL shell"
+	ShellHelp        = ShellDescription + "\n\n" +
+		"The shell runs the queries in process, without starting a server.\n" +
+		"Statements end with a semicolon and can span multiple lines. Tables,\n" +
+		"columns and functions are completed with the tab key. Type \\? to\n" +
+		"list the available meta-commands.\n\n" +
+		"By default when gitbase encounters an error in a repository it\n" +
+		"stops the query. With GITBASE_SKIP_GIT_ERRORS variable it won't\n" +
+		"complain and just skip those rows or repositories."
+
+	shellPrompt         = "gitbase> "
+	shellContinuePrompt = "      -> "
+)
+
+const shellUsage = `\d             list the tables
+\d <table>     describe the columns of a table
+\explain <sql> show the execution plan of a query and the squashed tables
+\timing        toggle printing the time taken by each query
+\?             show this help
+\q             exit the shell
+`
+
+// Shell represents the `shell` command of gitbase cli tool.
+type Shell struct {
+	Query
+
+	srv    *Server
+	ctx    *sql.Context
+	timing bool
+
+	History string `long:"history" env:"GITBASE_HISTORY_FILE" description:"File where the history of the shell is kept (default: ~/.gitbase_history)"`
+}
+
+// lineReader reads the lines typed by the user.
+type lineReader interface {
+	Readline() (string, error)
+	SetPrompt(string)
+	SaveHistory(string) error
+	Close() error
+}
+
+// Execute starts an interactive shell, it honors the go-flags.Commander
+// interface.
+func (c *Shell) Execute(args []string) error {
+	if err := c.init(); err != nil {
+		return err
+	}
+
+	history := c.History
+	if history == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			history = filepath.Join(home, ".gitbase_history")
+		}
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 shellPrompt,
+		HistoryFile:            history,
+		DisableAutoSaveHistory: true,
+		AutoComplete:           newShellCompleter(c.srv.engine.Catalog, c.Name),
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "exit",
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.repl(rl)
+}
+
+func (c *Shell) init() error {
+	srv, err := c.buildServer()
+	if err != nil {
+		return err
+	}
+
+	c.srv = srv
+	c.ctx = c.newContext(srv)
+	return nil
+}
+
+// repl reads statements from the given reader and runs them until the
+// reader is exhausted or the user exits the shell. Errors of statements are
+// printed and don't stop the shell.
+func (c *Shell) repl(rl lineReader) error {
+	defer rl.Close()
+
+	var lines []string
+	for {
+		if len(lines) == 0 {
+			rl.SetPrompt(shellPrompt)
+		} else {
+			rl.SetPrompt(shellContinuePrompt)
+		}
+
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			lines = nil
+			continue
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if len(lines) == 0 && isShellCommand(line) {
+			_ = rl.SaveHistory(line)
+			if c.isExit(line) {
+				return nil
+			}
+
+			c.runCommand(line)
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+		if !strings.HasSuffix(line, ";") {
+			continue
+		}
+
+		stmt := strings.Join(lines, " ")
+		lines = nil
+		_ = rl.SaveHistory(stmt)
+
+		query := strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+		if query == "" {
+			continue
+		}
+
+		c.runQuery(query)
+	}
+}
+
+func isShellCommand(line string) bool {
+	switch strings.ToLower(strings.TrimSuffix(line, ";")) {
+	case "exit", "quit":
+		return true
+	default:
+		return strings.HasPrefix(line, `\`)
+	}
+}
+
+func (c *Shell) isExit(line string) bool {
+	switch strings.ToLower(strings.TrimSuffix(line, ";")) {
+	case "exit", "quit", `\q`, `\quit`:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Shell) runQuery(query string) {
+	start := time.Now()
+	n, err := c.run(c.ctx, c.srv.engine, query)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+
+	if c.timing {
+		fmt.Fprintf(c.output(), "%d rows in set (%.2f sec)\n", n, time.Since(start).Seconds())
+	}
+}
+
+// runCommand runs a meta-command of the shell, which starts with a
+// backslash.
+func (c *Shell) runCommand(line string) {
+	line = strings.TrimSpace(strings.TrimSuffix(line, ";"))
+	var cmd, arg = line, ""
+	if idx := strings.IndexAny(line, " \t"); idx > 0 {
+		cmd, arg = line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+
+	var err error
+	switch cmd {
+	case `\?`, `\h`, `\help`:
+		_, err = io.WriteString(c.output(), shellUsage)
+	case `\d`:
+		if arg == "" {
+			err = c.listTables()
+		} else {
+			err = c.describeTable(arg)
+		}
+	case `\timing`:
+		c.timing = !c.timing
+		if c.timing {
+			_, err = io.WriteString(c.output(), "Timing is on.\n")
+		} else {
+			_, err = io.WriteString(c.output(), "Timing is off.\n")
+		}
+	case `\explain`:
+		err = c.explain(arg)
+	default:
+		err = fmt.Errorf("unknown command %s, type \\? for help", cmd)
+	}
+
+	if err != nil {
+		c.printError(err)
+	}
+}
+
+func (c *Shell) printError(err error) {
+	fmt.Fprintf(c.output(), "ERROR: %s\n", err)
+}
+
+func (c *Shell) listTables() error {
+	db, err := c.srv.engine.Catalog.Database(c.Name)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range db.Tables() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w, err := newRowWriter(c.Output, c.output(), sql.Schema{
+		{Name: "table", Type: sql.Text},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := w.WriteRow(sql.NewRow(name)); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func (c *Shell) describeTable(name string) error {
+	table, err := c.srv.engine.Catalog.Table(c.Name, name)
+	if err != nil {
+		return err
+	}
+
+	w, err := newRowWriter(c.Output, c.output(), sql.Schema{
+		{Name: "column", Type: sql.Text},
+		{Name: "type", Type: sql.Text},
+		{Name: "nullable", Type: sql.Boolean},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, col := range table.Schema() {
+		row := sql.NewRow(col.Name, col.Type.Type().String(), col.Nullable)
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// explain prints the execution plan of the given query and the tables that
+// were squashed by the SquashJoins rule, if any.
+func (c *Shell) explain(query string) error {
+	if query == "" {
+		return fmt.Errorf("no query given")
+	}
+
+	node, err := parse.Parse(c.ctx, query)
+	if err != nil {
+		return err
+	}
+
+	analyzed, err := c.srv.engine.Analyzer.Analyze(c.ctx, node)
+	if err != nil {
+		return err
+	}
+
+	var squashed []string
+	plan.Inspect(analyzed, func(n sql.Node) bool {
+		if rt, ok := n.(*plan.ResolvedTable); ok {
+			if t, ok := rt.Table.(*gitbase.SquashedTable); ok {
+				squashed = append(squashed, t.Name())
+			}
+		}
+		return true
+	})
+
+	var out = analyzed.String()
+	if len(squashed) > 0 {
+		out += "\nSquashed: " + strings.Join(squashed, ", ") + "\n"
+	} else {
+		out += "\nSquashed: no tables were squashed\n"
+	}
+
+	_, err = io.WriteString(c.output(), out)
+	return err
+}
+
+// shellCompleter completes the names of the tables, columns and functions
+// of gitbase. Columns can be completed on their own or prefixed by their
+// table name.
+type shellCompleter struct {
+	tables    []string
+	columns   map[string][]string
+	allCols   []string
+	functions []string
+}
+
+var _ readline.AutoCompleter = (*shellCompleter)(nil)
+
+func newShellCompleter(catalog *sql.Catalog, dbName string) *shellCompleter {
+	c := &shellCompleter{columns: make(map[string][]string)}
+
+	db, err := catalog.Database(dbName)
+	if err == nil {
+		var seen = make(map[string]bool)
+		for name, table := range db.Tables() {
+			c.tables = append(c.tables, name)
+			for _, col := range table.Schema() {
+				c.columns[name] = append(c.columns[name], col.Name)
+				if !seen[col.Name] {
+					seen[col.Name] = true
+					c.allCols = append(c.allCols, col.Name)
+				}
+			}
+		}
+	}
+
+	for _, f := range function.Functions {
+		c.functions = append(c.functions, f.FunctionName()+"(")
+	}
+
+	sort.Strings(c.tables)
+	sort.Strings(c.allCols)
+	sort.Strings(c.functions)
+	return c
+}
+
+// Do implements the readline.AutoCompleter interface.
+func (c *shellCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+
+	word := string(line[start:pos])
+	var candidates []string
+	if idx := strings.LastIndex(word, "."); idx >= 0 {
+		table, prefix := strings.ToLower(word[:idx]), word[idx+1:]
+		candidates = withPrefix(c.columns[table], prefix)
+		word = prefix
+	} else if word != "" {
+		candidates = append(candidates, withPrefix(c.tables, word)...)
+		candidates = append(candidates, withPrefix(c.allCols, word)...)
+		candidates = append(candidates, withPrefix(c.functions, word)...)
+	}
+
+	var result = make([][]rune, len(candidates))
+	for i, cand := range candidates {
+		result[i] = []rune(cand[len(word):])
+	}
+
+	return result, len([]rune(word))
+}
+
+func withPrefix(names []string, prefix string) []string {
+	var result []string
+	var lower = strings.ToLower(prefix)
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToLower(name), lower) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' ||
+		(r >= 'a' && r <= 'z') ||
+		(r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9')
+}
@@ -0,0 +1,188 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-borges/libraries"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLineReader struct {
+	lines   []string
+	prompts []string
+	history []string
+	closed  bool
+}
+
+func (r *fakeLineReader) Readline() (string, error) {
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, nil
+}
+
+func (r *fakeLineReader) SetPrompt(p string) { r.prompts = append(r.prompts, p) }
+
+func (r *fakeLineReader) SaveHistory(s string) error {
+	r.history = append(r.history, s)
+	return nil
+}
+
+func (r *fakeLineReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func setupShell(t *testing.T) (*Shell, *bytes.Buffer, func()) {
+	t.Helper()
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	c := &Shell{Query: Query{
+		EngineOptions: testEngineOptions(tmpDir),
+		stdout:        &buf,
+		Output:        outputCSV,
+	}}
+	require.NoError(c.init())
+
+	return c, &buf, func() {
+		require.NoError(os.RemoveAll(tmpDir))
+	}
+}
+
+func TestShellRepl(t *testing.T) {
+	require := require.New(t)
+	c, buf, cleanup := setupShell(t)
+	defer cleanup()
+
+	rl := &fakeLineReader{lines: []string{
+		"SELECT repository_id",
+		"FROM repositories",
+		"WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b';",
+		"SELECT * FROM foo;",
+		`\timing`,
+		"SELECT 1;",
+		"exit",
+		"SELECT 2;",
+	}}
+
+	require.NoError(c.repl(rl))
+	require.True(rl.closed)
+	require.Equal([]string{"SELECT 2;"}, rl.lines)
+	require.Equal([]string{
+		"SELECT repository_id FROM repositories WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b';",
+		"SELECT * FROM foo;",
+		`\timing`,
+		"SELECT 1;",
+		"exit",
+	}, rl.history)
+	require.Equal([]string{
+		shellPrompt,
+		shellContinuePrompt,
+		shellContinuePrompt,
+		shellPrompt,
+		shellPrompt,
+		shellPrompt,
+		shellPrompt,
+	}, rl.prompts)
+
+	lines := strings.Split(buf.String(), "\n")
+	require.Equal("repository_id", lines[0])
+	require.Equal("015da2f4-6d89-7ec8-5ac9-a38329ea875b", lines[1])
+	require.True(strings.HasPrefix(lines[2], "ERROR: "))
+	require.Equal("Timing is on.", lines[3])
+	require.Equal("1", lines[4])
+	require.Equal("1", lines[5])
+	require.True(strings.HasPrefix(lines[6], "1 rows in set ("))
+}
+
+func TestShellCommands(t *testing.T) {
+	require := require.New(t)
+	c, buf, cleanup := setupShell(t)
+	defer cleanup()
+
+	c.runCommand(`\d`)
+	tables := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal("table", tables[0])
+	require.Contains(tables, "commits")
+	require.Contains(tables, "repositories")
+
+	buf.Reset()
+	c.runCommand(`\d repositories`)
+	require.Equal("column,type,nullable\nrepository_id,TEXT,false\n", buf.String())
+
+	buf.Reset()
+	c.runCommand(`\d foo`)
+	require.True(strings.HasPrefix(buf.String(), "ERROR: "))
+
+	buf.Reset()
+	c.runCommand(`\explain SELECT * FROM refs NATURAL JOIN commits`)
+	require.Contains(buf.String(), "Squashed: SquashedTable(refs, commits)")
+
+	buf.Reset()
+	c.runCommand(`\explain SELECT * FROM repositories`)
+	require.Contains(buf.String(), "Squashed: no tables were squashed")
+
+	buf.Reset()
+	c.runCommand(`\foo`)
+	require.Equal("ERROR: unknown command \\foo, type \\? for help\n", buf.String())
+
+	buf.Reset()
+	c.runCommand(`\?`)
+	require.Equal(shellUsage, buf.String())
+}
+
+func TestShellCompleter(t *testing.T) {
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(gitbase.NewDatabase(
+		"gitbase",
+		gitbase.NewRepositoryPool(nil, libraries.New(nil)),
+	))
+
+	c := newShellCompleter(catalog, "gitbase")
+
+	testCases := []struct {
+		line     string
+		expected []string
+		length   int
+	}{
+		{"SELECT * FROM ", nil, 0},
+		{"SELECT * FROM repo", []string{"sitories", "sitory_id"}, 4},
+		{"SELECT * FROM REPO", []string{"sitories", "sitory_id"}, 4},
+		{"SELECT commit_a", []string{"uthor_email", "uthor_name", "uthor_when"}, 8},
+		{"SELECT repositories.rep", []string{"ository_id"}, 3},
+		{"SELECT foo.rep", nil, 3},
+		{"SELECT is_v", []string{"endor("}, 4},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.line, func(t *testing.T) {
+			require := require.New(t)
+			line := []rune(tt.line)
+			result, length := c.Do(line, len(line))
+
+			var suffixes []string
+			for _, r := range result {
+				suffixes = append(suffixes, string(r))
+			}
+			sort.Strings(suffixes)
+
+			require.Equal(tt.expected, suffixes)
+			require.Equal(tt.length, length)
+		})
+	}
+}
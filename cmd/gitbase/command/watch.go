@@ -0,0 +1,280 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// watchDelay is the time without changes the watcher waits before updating
+// the directories, as copying a siva file or a repository generates lots of
+// events.
+const watchDelay = 2 * time.Second
+
+// directoryWatcher watches the directories served and the configuration
+// file of a server. When they change:
+//
+//   - directories with new, modified or deleted files or repositories are
+//     reloaded, so the new repositories are seen and cached data dropped
+//   - directories that do not exist anymore are detached
+//   - libraries added, modified or deleted from the configuration file are
+//     attached, reloaded or detached
+type directoryWatcher struct {
+	srv     *Server
+	watcher *fsnotify.Watcher
+	delay   time.Duration
+
+	changed       map[string]bool
+	configChanged bool
+
+	wg sync.WaitGroup
+}
+
+func newDirectoryWatcher(srv *Server, delay time.Duration) (*directoryWatcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &directoryWatcher{
+		srv:     srv,
+		watcher: fw,
+		delay:   delay,
+		changed: make(map[string]bool),
+	}
+
+	if err := w.watch(); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// watch adds the directory of the configuration file, the directories
+// served and their subdirectories to the watcher. Subdirectories are
+// needed to see changes in buckets of siva libraries and repositories of
+// plain ones. Adding a path already watched does nothing.
+func (w *directoryWatcher) watch() error {
+	if w.srv.configPath != "" {
+		if err := w.watcher.Add(filepath.Dir(w.srv.configPath)); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range w.srv.attachedDirectories() {
+		if err := w.watcher.Add(d.Path); err != nil {
+			return err
+		}
+
+		entries, err := ioutil.ReadDir(d.Path)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+
+			path := filepath.Join(d.Path, e.Name())
+			if err := w.watcher.Add(path); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"path":  path,
+					"error": err,
+				}).Warn("unable to watch directory")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *directoryWatcher) run() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(w.delay)
+	timer.Stop()
+
+	for {
+		select {
+		case e, ok := <-w.watcher.Events:
+			if !ok {
+				timer.Stop()
+				return
+			}
+
+			if w.record(e) {
+				timer.Reset(w.delay)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				timer.Stop()
+				return
+			}
+
+			logrus.WithField("error", err).Error("error watching directories")
+		case <-timer.C:
+			w.update()
+		}
+	}
+}
+
+// record annotates the directory or configuration file changed by the
+// event and returns whether it has to be updated.
+func (w *directoryWatcher) record(e fsnotify.Event) bool {
+	if e.Op == fsnotify.Chmod {
+		return false
+	}
+
+	name := filepath.Clean(e.Name)
+	if w.srv.configPath != "" && name == filepath.Clean(w.srv.configPath) {
+		w.configChanged = true
+		return true
+	}
+
+	for _, d := range w.srv.attachedDirectories() {
+		rel, err := filepath.Rel(filepath.Clean(d.Path), name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		w.changed[d.id()] = true
+		return true
+	}
+
+	return false
+}
+
+func (w *directoryWatcher) update() {
+	if w.configChanged {
+		if err := w.srv.reloadConfig(); err != nil {
+			logrus.WithField("error", err).
+				Error("unable to update libraries from the configuration file")
+		}
+	}
+
+	var ids []string
+	for id := range w.changed {
+		ids = append(ids, id)
+	}
+
+	if err := w.srv.reloadDirectories(ids); err != nil {
+		logrus.WithField("error", err).Error("unable to reload directories")
+	}
+
+	w.changed = make(map[string]bool)
+	w.configChanged = false
+
+	if err := w.watch(); err != nil {
+		logrus.WithField("error", err).Error("unable to watch directories")
+	}
+}
+
+// Close stops watching the directories.
+func (w *directoryWatcher) Close() error {
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
+
+// reloadDirectories opens again the directories with the given ids, or
+// detaches them if they do not exist anymore.
+func (c *Server) reloadDirectories(ids []string) error {
+	reload := make(map[string]bool)
+	for _, id := range ids {
+		reload[id] = true
+	}
+
+	var detach []string
+	var attach []directory
+	for _, d := range c.attachedDirectories() {
+		if !reload[d.id()] {
+			continue
+		}
+
+		detach = append(detach, d.id())
+		if _, err := os.Stat(d.Path); err == nil {
+			attach = append(attach, d)
+		}
+	}
+
+	if len(detach) == 0 {
+		return nil
+	}
+
+	return c.updateDirectories(detach, attach)
+}
+
+// reloadConfig reads again the configuration file and attaches, reloads or
+// detaches the libraries added, modified or removed. Other settings are not
+// changed until the server is restarted.
+func (c *Server) reloadConfig() error {
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	defaults := c.defaultDirectory()
+	current := make(map[string]directory)
+	for _, l := range c.libraries {
+		d := l.directory(defaults)
+		current[d.id()] = d
+	}
+
+	attached := make(map[string]bool)
+	for _, d := range c.attachedDirectories() {
+		attached[d.id()] = true
+	}
+
+	var detach []string
+	var attach []directory
+	for id, d := range current {
+		if w, ok := cfgDirectory(cfg, defaults, id); (!ok || w != d) && attached[id] {
+			detach = append(detach, id)
+		}
+	}
+
+	for _, l := range cfg.Libraries {
+		d := l.directory(defaults)
+		cur, ok := current[d.id()]
+		if !ok || cur != d || !attached[d.id()] {
+			attach = append(attach, d)
+		}
+	}
+
+	// The libraries are only replaced once the directories are updated, so
+	// a failed reload is retried against the libraries still attached.
+	if len(detach) > 0 || len(attach) > 0 {
+		if err := c.updateDirectories(detach, attach); err != nil {
+			return err
+		}
+	}
+
+	c.libraries = cfg.Libraries
+	return nil
+}
+
+func cfgDirectory(
+	cfg *serverConfig,
+	defaults directory,
+	id string,
+) (directory, bool) {
+	for _, l := range cfg.Libraries {
+		d := l.directory(defaults)
+		if d.id() == id {
+			return d, true
+		}
+	}
+
+	return directory{}, false
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timeout waiting for "+msg)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestDirectoryWatcher(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase-watch")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	libDir := filepath.Join(tmpDir, "lib")
+	require.NoError(os.Mkdir(libDir, 0755))
+
+	server, cleanup := setupLibrariesServer(t, libDir)
+	defer cleanup()
+
+	require.Empty(poolRepositories(t, server))
+
+	w, err := newDirectoryWatcher(server, 50*time.Millisecond)
+	require.NoError(err)
+	defer w.Close()
+
+	const siva = "05893125684f2d3943cd84a7ab2b75e53668fba1.siva"
+	content, err := ioutil.ReadFile(filepath.Join("../../../_testdata", siva))
+	require.NoError(err)
+	require.NoError(ioutil.WriteFile(filepath.Join(libDir, siva), content, 0644))
+
+	waitFor(t, "the repositories to be added", func() bool {
+		return len(poolRepositories(t, server)) > 0
+	})
+
+	require.NoError(os.RemoveAll(libDir))
+
+	waitFor(t, "the directory to be detached", func() bool {
+		return len(server.attachedDirectories()) == 0
+	})
+	require.Empty(poolRepositories(t, server))
+}
+
+func TestReloadConfig(t *testing.T) {
+	require := require.New(t)
+
+	testdata, err := filepath.Abs("../../../_testdata")
+	require.NoError(err)
+
+	require.NoError(os.Setenv("GITBASE_TEST_TESTDATA", testdata))
+	defer os.Unsetenv("GITBASE_TEST_TESTDATA")
+
+	path, cleanupConfig := writeConfig(t, `
+libraries:
+  - name: testdata
+    path: ${GITBASE_TEST_TESTDATA}
+`)
+	defer cleanupConfig()
+
+	server, cleanup := setupLibrariesServer(t)
+	defer cleanup()
+
+	server.configPath = path
+	require.NoError(server.reloadConfig())
+	require.Len(server.attachedDirectories(), 1)
+	require.Len(poolRepositories(t, server), 5)
+
+	// nothing changed, so the directory is not reloaded
+	attached := server.attached[0]
+	require.NoError(server.reloadConfig())
+	require.True(attached == server.attached[0])
+
+	require.NoError(ioutil.WriteFile(path, []byte(`
+libraries:
+  - name: testdata
+    path: ${GITBASE_TEST_TESTDATA}
+    rooted: false
+`), 0644))
+	require.NoError(server.reloadConfig())
+	require.Len(server.attachedDirectories(), 1)
+	require.False(server.attachedDirectories()[0].Rooted)
+
+	require.NoError(ioutil.WriteFile(path, []byte("libraries: [{path: /foo, format: zip}]"), 0644))
+	require.Error(server.reloadConfig())
+	require.Len(server.attachedDirectories(), 1)
+
+	// the configuration file is not a directory, so it can't be attached
+	// and the previous libraries are kept
+	require.NoError(ioutil.WriteFile(path, []byte("libraries: [{path: "+path+"}]"), 0644))
+	require.Error(server.reloadConfig())
+	require.Len(server.attachedDirectories(), 1)
+	require.Equal("testdata", server.libraries[0].Name)
+
+	require.NoError(ioutil.WriteFile(path, []byte("libraries: []"), 0644))
+	require.NoError(server.reloadConfig())
+	require.Empty(server.attachedDirectories())
+	require.Empty(poolRepositories(t, server))
+}
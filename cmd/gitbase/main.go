@@ -30,11 +30,42 @@ func main() {
 		return append(append(args, "-d"), args[0]), nil
 	}
 
-	_, err := parser.AddCommand("server", command.ServerDescription, command.ServerHelp,
-		&command.Server{
-			SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
-			Version:       version,
-		})
+	dbOptions := command.DatabaseOptions{
+		SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
+		Version:       version,
+	}
+
+	engineOptions := command.EngineOptions{DatabaseOptions: dbOptions}
+	server := &command.Server{DatabaseOptions: dbOptions}
+
+	serverCmd, err := parser.AddCommand("server", command.ServerDescription, command.ServerHelp,
+		server)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	server.BindConfig(serverCmd)
+
+	_, err = parser.AddCommand("query", command.QueryDescription, command.QueryHelp,
+		&command.Query{EngineOptions: engineOptions})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	_, err = parser.AddCommand("shell", command.ShellDescription, command.ShellHelp,
+		&command.Shell{Query: command.Query{EngineOptions: engineOptions}})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	_, err = parser.AddCommand("export", command.ExportDescription, command.ExportHelp,
+		&command.Export{EngineOptions: engineOptions})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	_, err = parser.AddCommand("commit-graph", command.CommitGraphDescription,
+		command.CommitGraphHelp, &command.CommitGraph{EngineOptions: engineOptions})
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -102,11 +102,11 @@ func (t *commitBlobsTable) PartitionRows(
 			}
 
 			return &commitBlobsRowIter{
-				repo:          repo,
-				commits:       stringsToHashes(commits),
-				iter:          nil,
-				index:         indexValues,
-				skipGitErrors: shouldSkipErrors(ctx),
+				repo:    repo,
+				commits: stringsToHashes(commits),
+				iter:    nil,
+				index:   indexValues,
+				skipper: newGitErrorSkipper(ctx, CommitBlobsTableName),
 			}, nil
 		},
 	)
@@ -197,12 +197,12 @@ func (commitBlobsRowKeyMapper) toRow(data []byte) (sql.Row, error) {
 }
 
 type commitBlobsRowIter struct {
-	repo          *Repository
-	iter          object.CommitIter
-	currCommit    *object.Commit
-	filesIter     *object.FileIter
-	index         sql.IndexValueIter
-	skipGitErrors bool
+	repo       *Repository
+	iter       object.CommitIter
+	currCommit *object.Commit
+	filesIter  *object.FileIter
+	index      sql.IndexValueIter
+	skipper    *gitErrorSkipper
 
 	// selectors for faster filtering
 	commits []plumbing.Hash
@@ -221,7 +221,7 @@ func (i *commitBlobsRowIter) init() error {
 	if len(i.commits) > 0 {
 		i.iter = newCommitsByHashIter(i.repo, i.commits)
 	} else {
-		iter, err := newCommitIter(i.repo, i.skipGitErrors)
+		iter, err := newCommitIter(i.repo, i.skipper)
 		if err != nil {
 			return err
 		}
@@ -259,7 +259,7 @@ func (i *commitBlobsRowIter) next() (sql.Row, error) {
 	for {
 		if i.iter == nil {
 			if err := i.init(); err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
@@ -270,7 +270,7 @@ func (i *commitBlobsRowIter) next() (sql.Row, error) {
 		if i.currCommit == nil {
 			commit, err := i.iter.Next()
 			if err != nil {
-				if err != io.EOF && i.skipGitErrors {
+				if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -279,7 +279,7 @@ func (i *commitBlobsRowIter) next() (sql.Row, error) {
 
 			filesIter, err := commit.Files()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), commit.Hash, err) {
 					continue
 				}
 
@@ -299,7 +299,7 @@ func (i *commitBlobsRowIter) next() (sql.Row, error) {
 				continue
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), i.currCommit.Hash, err) {
 				continue
 			}
 
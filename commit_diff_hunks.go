@@ -0,0 +1,447 @@
+package gitbase
+
+import (
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	fdiff "gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// diffContextLines is the number of unchanged lines shown around the changed
+// lines of a hunk.
+const diffContextLines = 3
+
+type commitDiffHunksTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// CommitDiffHunksSchema is the schema for the commit diff hunks table.
+var CommitDiffHunksSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: CommitDiffHunksTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitDiffHunksTableName},
+	{Name: "parent_hash", Type: sql.VarChar(40), Nullable: true, Source: CommitDiffHunksTableName},
+	{Name: "from_path", Type: sql.Text, Nullable: true, Source: CommitDiffHunksTableName},
+	{Name: "to_path", Type: sql.Text, Nullable: true, Source: CommitDiffHunksTableName},
+	{Name: "hunk_index", Type: sql.Int64, Nullable: false, Source: CommitDiffHunksTableName},
+	{Name: "line_type", Type: sql.Text, Nullable: false, Source: CommitDiffHunksTableName},
+	{Name: "old_line", Type: sql.Int64, Nullable: true, Source: CommitDiffHunksTableName},
+	{Name: "new_line", Type: sql.Int64, Nullable: true, Source: CommitDiffHunksTableName},
+	{Name: "line", Type: sql.Text, Nullable: false, Source: CommitDiffHunksTableName},
+}
+
+func newCommitDiffHunksTable(pool *RepositoryPool) *commitDiffHunksTable {
+	return &commitDiffHunksTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*commitDiffHunksTable)(nil)
+
+func (commitDiffHunksTable) isGitbaseTable() {}
+
+func (t commitDiffHunksTable) String() string {
+	return printTable(
+		CommitDiffHunksTableName,
+		CommitDiffHunksSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (commitDiffHunksTable) Name() string { return CommitDiffHunksTableName }
+
+func (commitDiffHunksTable) Schema() sql.Schema { return CommitDiffHunksSchema }
+
+func (t *commitDiffHunksTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *commitDiffHunksTable) Filters() []sql.Expression { return t.filters }
+
+func (t *commitDiffHunksTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.CommitDiffHunksTable")
+	iter, err := rowIterWithSelectors(
+		ctx, CommitDiffHunksSchema, CommitDiffHunksTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			var parents []string
+			parents, err = selectors.textValues("parent_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			return &commitDiffHunksRowIter{
+				repo:         repo,
+				commitHashes: stringsToHashes(hashes),
+				parentHashes: stringsToHashes(parents),
+				skipper:      newGitErrorSkipper(ctx, CommitDiffHunksTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (commitDiffHunksTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(CommitDiffHunksTableName, CommitDiffHunksSchema, filters)
+}
+
+func (commitDiffHunksTable) handledColumns() []string {
+	return []string{"commit_hash", "parent_hash", "repository_id"}
+}
+
+// Line types of the rows in the commit_diff_hunks table.
+const (
+	lineTypeContext = " "
+	lineTypeAdd     = "+"
+	lineTypeDelete  = "-"
+)
+
+// diffLine is a single line of a diff.
+type diffLine struct {
+	lineType string
+	// oldLine and newLine are the 1-based line numbers in the old and new
+	// versions of the file, or 0 if the line is not in that version.
+	oldLine int64
+	newLine int64
+	text    string
+}
+
+// diffHunk is a group of changed lines surrounded by some unchanged lines.
+type diffHunk struct {
+	diff  commitDiff
+	index int64
+	lines []diffLine
+}
+
+// commitDiffHunks returns the hunks of all the text files changed between the
+// given commit and its parents. Binary files and files bigger than the
+// maximum blob size are skipped.
+func commitDiffHunks(
+	repo *Repository,
+	commit *object.Commit,
+	parents []plumbing.Hash,
+) ([]diffHunk, error) {
+	diffs, err := commitChanges(repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []diffHunk
+	for _, d := range diffs {
+		if len(parents) > 0 && !hashContains(parents, d.parent) {
+			continue
+		}
+
+		ok, err := isDiffableChange(d.change)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		patch, err := d.change.Patch()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fp := range patch.FilePatches() {
+			if fp.IsBinary() {
+				continue
+			}
+
+			lines := diffLines(fp.Chunks())
+			for i, h := range splitHunks(lines, diffContextLines) {
+				result = append(result, diffHunk{
+					diff:  d,
+					index: int64(i),
+					lines: h,
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// isDiffableChange returns whether the files of the change can be diffed
+// line by line, that is, none of them is binary or bigger than the maximum
+// blob size.
+func isDiffableChange(ch *object.Change) (bool, error) {
+	from, to, err := ch.Files()
+	if err != nil {
+		return false, err
+	}
+
+	for _, f := range []*object.File{from, to} {
+		if f == nil {
+			continue
+		}
+
+		if f.Size > int64(blobsMaxSize) {
+			return false, nil
+		}
+
+		bin, err := isBinary(&f.Blob)
+		if err != nil {
+			return false, err
+		}
+
+		if bin {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// diffLines returns all the lines of the given chunks with their line
+// numbers.
+func diffLines(chunks []fdiff.Chunk) []diffLine {
+	var result []diffLine
+	var oldLine, newLine int64
+	for _, c := range chunks {
+		for _, text := range splitLines(c.Content()) {
+			var l = diffLine{text: text}
+			switch c.Type() {
+			case fdiff.Add:
+				newLine++
+				l.lineType = lineTypeAdd
+				l.newLine = newLine
+			case fdiff.Delete:
+				oldLine++
+				l.lineType = lineTypeDelete
+				l.oldLine = oldLine
+			default:
+				oldLine++
+				newLine++
+				l.lineType = lineTypeContext
+				l.oldLine = oldLine
+				l.newLine = newLine
+			}
+
+			result = append(result, l)
+		}
+	}
+
+	return result
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// splitHunks groups the changed lines in hunks with the given number of
+// context lines around them. Hunks whose context lines overlap are merged.
+func splitHunks(lines []diffLine, context int) [][]diffLine {
+	var hunks [][]diffLine
+	var start, end = -1, -1
+	for i, l := range lines {
+		if l.lineType == lineTypeContext {
+			continue
+		}
+
+		from := i - context
+		if from < 0 {
+			from = 0
+		}
+
+		to := i + context + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		if start >= 0 && from <= end {
+			end = to
+			continue
+		}
+
+		if start >= 0 {
+			hunks = append(hunks, lines[start:end])
+		}
+
+		start, end = from, to
+	}
+
+	if start >= 0 {
+		hunks = append(hunks, lines[start:end])
+	}
+
+	return hunks
+}
+
+func newCommitDiffHunksRow(
+	repoID string,
+	commit *object.Commit,
+	h diffHunk,
+	l diffLine,
+) sql.Row {
+	var parent interface{}
+	if !h.diff.parent.IsZero() {
+		parent = h.diff.parent.String()
+	}
+
+	return sql.NewRow(
+		repoID,
+		commit.Hash.String(),
+		parent,
+		changeEntryPath(h.diff.from),
+		changeEntryPath(h.diff.to),
+		h.index,
+		l.lineType,
+		lineNumber(l.oldLine),
+		lineNumber(l.newLine),
+		l.text,
+	)
+}
+
+func lineNumber(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+
+	return n
+}
+
+type commitDiffHunksRowIter struct {
+	repo    *Repository
+	commits object.CommitIter
+	commit  *object.Commit
+	hunks   []diffHunk
+	hunkPos int
+	linePos int
+	skipper *gitErrorSkipper
+
+	// selectors for faster filtering
+	commitHashes []plumbing.Hash
+	parentHashes []plumbing.Hash
+}
+
+func (i *commitDiffHunksRowIter) init() error {
+	if len(i.commitHashes) > 0 {
+		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
+	} else {
+		iter, err := newCommitIter(i.repo, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.commits = iter
+	}
+
+	return nil
+}
+
+func (i *commitDiffHunksRowIter) Next() (sql.Row, error) {
+	for {
+		if i.commits == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if i.hunkPos >= len(i.hunks) {
+			var err error
+			i.commit, err = i.commits.Next()
+			if err != nil {
+				if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo": i.repo.ID(),
+						"err":  err,
+					}).Error("skipped commit in commit_diff_hunks")
+					continue
+				}
+
+				return nil, err
+			}
+
+			i.hunkPos, i.linePos = 0, 0
+			i.hunks, err = commitDiffHunks(i.repo, i.commit, i.parentHashes)
+			if err != nil {
+				if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo":   i.repo.ID(),
+						"err":    err,
+						"commit": i.commit.Hash.String(),
+					}).Error("can't get diff hunks for commit")
+					continue
+				}
+
+				return nil, err
+			}
+
+			continue
+		}
+
+		h := i.hunks[i.hunkPos]
+		if i.linePos >= len(h.lines) {
+			i.hunkPos++
+			i.linePos = 0
+			continue
+		}
+
+		l := h.lines[i.linePos]
+		i.linePos++
+
+		return newCommitDiffHunksRow(i.repo.ID(), i.commit, h, l), nil
+	}
+}
+
+func (i *commitDiffHunksRowIter) Close() error {
+	if i.commits != nil {
+		i.commits.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	fdiff "gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+)
+
+func TestCommitDiffHunksTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitDiffHunksTable(poolFromCtx(t, ctx))
+	require.NotNil(table)
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.NotEmpty(rows)
+
+	schema := table.Schema()
+	for idx, row := range rows {
+		require.NoError(schema.CheckRow(row), "row %d doesn't conform to schema", idx)
+	}
+}
+
+func TestCommitDiffHunksTablePushdown(t *testing.T) {
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	table := new(commitDiffHunksTable)
+
+	var tests = []struct {
+		name         string
+		filters      []sql.Expression
+		expectedRows []sql.Row
+	}{
+		{
+			name: "binary file",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(1, sql.Text, CommitDiffHunksTableName, "commit_hash", false),
+					expression.NewLiteral("35e85108805c84807bc66a02d91535e1e24b38b9", sql.Text),
+				),
+			},
+			expectedRows: nil,
+		},
+		{
+			name: "parent_hash filter",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(1, sql.Text, CommitDiffHunksTableName, "commit_hash", false),
+					expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+				),
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(2, sql.Text, CommitDiffHunksTableName, "parent_hash", true),
+					expression.NewLiteral("b029517f6300c2da0f4b651b8642506cd6aaf45d", sql.Text),
+				),
+			},
+			expectedRows: nil,
+		},
+		{
+			name: "repository_id filter",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(0, sql.Text, CommitDiffHunksTableName, "repository_id", false),
+					expression.NewLiteral("foo", sql.Text),
+				),
+			},
+			expectedRows: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			tbl := table.WithFilters(test.filters)
+			rows, err := tableToRows(ctx, tbl)
+			require.NoError(err)
+
+			require.ElementsMatch(test.expectedRows, rows)
+		})
+	}
+
+	t.Run("commit_hash filter", func(t *testing.T) {
+		require := require.New(t)
+		tbl := table.WithFilters([]sql.Expression{
+			expression.NewEquals(
+				expression.NewGetFieldWithTable(1, sql.Text, CommitDiffHunksTableName, "commit_hash", false),
+				expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+			),
+		})
+
+		rows, err := tableToRows(ctx, tbl)
+		require.NoError(err)
+		require.Len(rows, 7)
+
+		for i, row := range rows {
+			require.Equal(path, row[0])
+			require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", row[2])
+			require.Nil(row[3])
+			require.Equal("vendor/foo.go", row[4])
+			require.Equal(int64(0), row[5])
+			require.Equal(lineTypeAdd, row[6])
+			require.Nil(row[7])
+			require.Equal(int64(i+1), row[8])
+		}
+	})
+}
+
+type testChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c testChunk) Content() string       { return c.content }
+func (c testChunk) Type() fdiff.Operation { return c.op }
+
+func TestDiffLines(t *testing.T) {
+	require := require.New(t)
+
+	lines := diffLines([]fdiff.Chunk{
+		testChunk{"a\nb\n", fdiff.Equal},
+		testChunk{"c\n", fdiff.Delete},
+		testChunk{"d\ne", fdiff.Add},
+		testChunk{"f\n", fdiff.Equal},
+	})
+
+	expected := []diffLine{
+		{lineTypeContext, 1, 1, "a"},
+		{lineTypeContext, 2, 2, "b"},
+		{lineTypeDelete, 3, 0, "c"},
+		{lineTypeAdd, 0, 3, "d"},
+		{lineTypeAdd, 0, 4, "e"},
+		{lineTypeContext, 4, 5, "f"},
+	}
+
+	require.Equal(expected, lines)
+}
+
+func TestSplitHunks(t *testing.T) {
+	build := func(types ...string) []diffLine {
+		var lines []diffLine
+		for _, t := range types {
+			lines = append(lines, diffLine{lineType: t})
+		}
+		return lines
+	}
+
+	c, a, d := lineTypeContext, lineTypeAdd, lineTypeDelete
+
+	testCases := []struct {
+		name     string
+		lines    []diffLine
+		expected []int
+	}{
+		{"no changes", build(c, c, c), nil},
+		{"single change", build(c, c, c, c, c, a, c, c, c, c, c), []int{7}},
+		{"merged changes", build(a, c, c, c, c, c, c, d), []int{8}},
+		{"split changes", build(a, c, c, c, c, c, c, c, d), []int{4, 4}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var sizes []int
+			for _, h := range splitHunks(tt.lines, 3) {
+				sizes = append(sizes, len(h))
+			}
+
+			require.Equal(t, tt.expected, sizes)
+		})
+	}
+}
@@ -0,0 +1,576 @@
+package gitbase
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/renames"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+)
+
+type commitDiffsTable struct {
+	checksumable
+	partitioned
+	filters    []sql.Expression
+	projection []string
+	index      sql.IndexLookup
+}
+
+// CommitDiffsSchema is the schema for the commit diffs table.
+var CommitDiffsSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: CommitDiffsTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitDiffsTableName},
+	{Name: "parent_hash", Type: sql.VarChar(40), Nullable: true, Source: CommitDiffsTableName},
+	{Name: "change_type", Type: sql.Text, Nullable: false, Source: CommitDiffsTableName},
+	{Name: "from_path", Type: sql.Text, Nullable: true, Source: CommitDiffsTableName},
+	{Name: "to_path", Type: sql.Text, Nullable: true, Source: CommitDiffsTableName},
+	{Name: "from_blob_hash", Type: sql.VarChar(40), Nullable: true, Source: CommitDiffsTableName},
+	{Name: "to_blob_hash", Type: sql.VarChar(40), Nullable: true, Source: CommitDiffsTableName},
+	{Name: "additions", Type: sql.Int64, Nullable: false, Source: CommitDiffsTableName},
+	{Name: "deletions", Type: sql.Int64, Nullable: false, Source: CommitDiffsTableName},
+}
+
+func newCommitDiffsTable(pool *RepositoryPool) *commitDiffsTable {
+	return &commitDiffsTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*commitDiffsTable)(nil)
+var _ Squashable = (*commitDiffsTable)(nil)
+
+func (commitDiffsTable) isSquashable()   {}
+func (commitDiffsTable) isGitbaseTable() {}
+
+func (t commitDiffsTable) String() string {
+	return printTable(
+		CommitDiffsTableName,
+		CommitDiffsSchema,
+		t.projection,
+		t.filters,
+		t.index,
+	)
+}
+
+func (commitDiffsTable) Name() string { return CommitDiffsTableName }
+
+func (commitDiffsTable) Schema() sql.Schema { return CommitDiffsSchema }
+
+func (t *commitDiffsTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *commitDiffsTable) WithProjection(colNames []string) sql.Table {
+	nt := *t
+	nt.projection = colNames
+	return &nt
+}
+
+func (t *commitDiffsTable) WithIndexLookup(idx sql.IndexLookup) sql.Table {
+	nt := *t
+	nt.index = idx
+	return &nt
+}
+
+func (t *commitDiffsTable) IndexLookup() sql.IndexLookup { return t.index }
+func (t *commitDiffsTable) Filters() []sql.Expression    { return t.filters }
+func (t *commitDiffsTable) Projection() []string         { return t.projection }
+
+func (t *commitDiffsTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.CommitDiffsTable")
+	iter, err := rowIterWithSelectors(
+		ctx, CommitDiffsSchema, CommitDiffsTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			var index sql.IndexValueIter
+			if t.index != nil {
+				if index, err = t.index.Values(p); err != nil {
+					return nil, err
+				}
+			}
+
+			return &commitDiffsRowIter{
+				repo:         repo,
+				index:        index,
+				commitHashes: stringsToHashes(hashes),
+				lineStats:    shouldComputeLineStats(t.projection),
+				skipper:      newGitErrorSkipper(ctx, CommitDiffsTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (commitDiffsTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(CommitDiffsTableName, CommitDiffsSchema, filters)
+}
+
+func (commitDiffsTable) handledColumns() []string {
+	return []string{"commit_hash", "repository_id"}
+}
+
+// IndexKeyValues implements the sql.IndexableTable interface.
+func (t *commitDiffsTable) IndexKeyValues(
+	ctx *sql.Context,
+	colNames []string,
+) (sql.PartitionIndexKeyValueIter, error) {
+	return newTablePartitionIndexKeyValueIter(
+		ctx,
+		newCommitDiffsTable(t.pool),
+		CommitDiffsTableName,
+		colNames,
+		new(commitDiffsRowKeyMapper),
+	)
+}
+
+// Change types of the rows in the commit_diffs table.
+const (
+	changeTypeInsert = "insert"
+	changeTypeModify = "modify"
+	changeTypeDelete = "delete"
+	changeTypeRename = "rename"
+	changeTypeCopy   = "copy"
+)
+
+// renameOptions are the options used to detect renamed and copied files in
+// the diffs, read from the environment once.
+var renameOptions = renames.OptionsFromEnv()
+
+// commitDiff is a single file change between a commit and one of its
+// parents.
+type commitDiff struct {
+	// parent is the hash of the parent the commit is compared to, or the zero
+	// hash if the commit has no parents.
+	parent     plumbing.Hash
+	changeType string
+	from       object.ChangeEntry
+	to         object.ChangeEntry
+	// change is the change the diff was built from, going from the old file
+	// to the new one for renames and copies.
+	change    *object.Change
+	additions int64
+	deletions int64
+}
+
+// shouldComputeLineStats returns whether the number of lines added and
+// deleted is needed to return the given columns of the commit_diffs table.
+// No columns means all of them.
+func shouldComputeLineStats(columns []string) bool {
+	return columns == nil ||
+		stringContains(columns, "additions") ||
+		stringContains(columns, "deletions")
+}
+
+// commitDiffs returns the file changes between the given commit and each one
+// of its parents. If lineStats is true, the number of lines added and
+// deleted in them is computed too, except for binary files and files bigger
+// than the maximum blob size, which have no line stats.
+func commitDiffs(
+	repo *Repository,
+	commit *object.Commit,
+	lineStats bool,
+) ([]commitDiff, error) {
+	diffs, err := commitChanges(repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lineStats {
+		return diffs, nil
+	}
+
+	for i, d := range diffs {
+		ok, err := isDiffableChange(d.change)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		diffs[i].additions, diffs[i].deletions, err = changeLineStats(d.change)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return diffs, nil
+}
+
+// commitChanges returns the file changes between the given commit and each
+// one of its parents. Commits without parents are compared against an empty
+// tree.
+func commitChanges(repo *Repository, commit *object.Commit) ([]commitDiff, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	if commit.NumParents() == 0 {
+		return treeDiffs(repo, plumbing.ZeroHash, nil, tree)
+	}
+
+	var result []commitDiff
+	err = commit.Parents().ForEach(func(parent *object.Commit) error {
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+
+		diffs, err := treeDiffs(repo, parent.Hash, parentTree, tree)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, diffs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// treeDiffs returns the changes from one tree to another. Deleted and
+// inserted files with similar contents are reported as renames, and copies
+// are reported if enabled, as configured in the renameOptions.
+func treeDiffs(
+	repo *Repository,
+	parent plumbing.Hash,
+	from, to *object.Tree,
+) ([]commitDiff, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	detected, err := renames.Detect(repo.Storer, changes, renameOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]commitDiff, 0, len(detected))
+	for _, ch := range detected {
+		d := commitDiff{
+			parent: parent,
+			from:   ch.From,
+			to:     ch.To,
+			change: ch.Change,
+		}
+
+		switch {
+		case ch.IsRename():
+			d.changeType = changeTypeRename
+		case ch.Copy:
+			d.changeType = changeTypeCopy
+		default:
+			action, err := ch.Action()
+			if err != nil {
+				return nil, err
+			}
+
+			switch action {
+			case merkletrie.Insert:
+				d.changeType = changeTypeInsert
+			case merkletrie.Delete:
+				d.changeType = changeTypeDelete
+			default:
+				d.changeType = changeTypeModify
+			}
+		}
+
+		result = append(result, d)
+	}
+
+	return result, nil
+}
+
+// changeLineStats returns the number of lines added and deleted in the
+// given change.
+func changeLineStats(ch *object.Change) (additions, deletions int64, err error) {
+	patch, err := ch.Patch()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, s := range patch.Stats() {
+		additions += int64(s.Addition)
+		deletions += int64(s.Deletion)
+	}
+
+	return additions, deletions, nil
+}
+
+func newCommitDiffsRow(repoID string, commit *object.Commit, d commitDiff) sql.Row {
+	var parent interface{}
+	if !d.parent.IsZero() {
+		parent = d.parent.String()
+	}
+
+	return sql.NewRow(
+		repoID,
+		commit.Hash.String(),
+		parent,
+		d.changeType,
+		changeEntryPath(d.from),
+		changeEntryPath(d.to),
+		changeEntryHash(d.from),
+		changeEntryHash(d.to),
+		d.additions,
+		d.deletions,
+	)
+}
+
+func changeEntryPath(e object.ChangeEntry) interface{} {
+	if e.Name == "" {
+		return nil
+	}
+
+	return e.Name
+}
+
+func changeEntryHash(e object.ChangeEntry) interface{} {
+	if e.Name == "" {
+		return nil
+	}
+
+	return e.TreeEntry.Hash.String()
+}
+
+type commitDiffsRowIter struct {
+	repo    *Repository
+	index   sql.IndexValueIter
+	commits object.CommitIter
+	commit  *object.Commit
+	diffs   []commitDiff
+	pos     int
+	skipper *gitErrorSkipper
+	mapper  commitDiffsRowKeyMapper
+
+	// lineStats is whether the lines added and deleted are computed.
+	lineStats bool
+
+	// selectors for faster filtering
+	commitHashes []plumbing.Hash
+}
+
+func (i *commitDiffsRowIter) Next() (sql.Row, error) {
+	if i.index != nil {
+		return i.nextFromIndex()
+	}
+
+	return i.next()
+}
+
+func (i *commitDiffsRowIter) init() error {
+	if len(i.commitHashes) > 0 {
+		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
+	} else {
+		iter, err := newCommitIter(i.repo, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.commits = iter
+	}
+
+	return nil
+}
+
+var commitDiffsCommitHashIdx = CommitDiffsSchema.IndexOf("commit_hash", CommitDiffsTableName)
+
+func (i *commitDiffsRowIter) nextFromIndex() (sql.Row, error) {
+	for {
+		var err error
+		var data []byte
+		defer closeIndexOnError(&err, i.index)
+
+		data, err = i.index.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var row sql.Row
+		row, err = i.mapper.toRow(data)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := plumbing.NewHash(row[commitDiffsCommitHashIdx].(string))
+		if len(i.commitHashes) > 0 && !hashContains(i.commitHashes, hash) {
+			continue
+		}
+
+		return row, nil
+	}
+}
+
+func (i *commitDiffsRowIter) next() (sql.Row, error) {
+	for {
+		if i.commits == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if i.pos >= len(i.diffs) {
+			var err error
+			i.commit, err = i.commits.Next()
+			if err != nil {
+				if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo": i.repo.ID(),
+						"err":  err,
+					}).Error("skipped commit in commit_diffs")
+					continue
+				}
+
+				return nil, err
+			}
+
+			i.pos = 0
+			i.diffs, err = commitDiffs(i.repo, i.commit, i.lineStats)
+			if err != nil {
+				if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo":   i.repo.ID(),
+						"err":    err,
+						"commit": i.commit.Hash.String(),
+					}).Error("can't get diffs for commit")
+					continue
+				}
+
+				return nil, err
+			}
+
+			continue
+		}
+
+		d := i.diffs[i.pos]
+		i.pos++
+
+		return newCommitDiffsRow(i.repo.ID(), i.commit, d), nil
+	}
+}
+
+func (i *commitDiffsRowIter) Close() error {
+	if i.commits != nil {
+		i.commits.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	if i.index != nil {
+		return i.index.Close()
+	}
+
+	return nil
+}
+
+type commitDiffsRowKeyMapper struct{}
+
+func (commitDiffsRowKeyMapper) fromRow(row sql.Row) ([]byte, error) {
+	if len(row) != len(CommitDiffsSchema) {
+		return nil, errRowKeyMapperRowLength.New(len(CommitDiffsSchema), len(row))
+	}
+
+	var buf bytes.Buffer
+	for i, col := range CommitDiffsSchema {
+		switch v := row[i].(type) {
+		case string:
+			writeBool(&buf, true)
+			writeString(&buf, v)
+		case int64:
+			writeInt64(&buf, v)
+		case nil:
+			if !col.Nullable {
+				return nil, errRowKeyMapperColType.New(i, "", v)
+			}
+
+			writeBool(&buf, false)
+		default:
+			return nil, errRowKeyMapperColType.New(i, "", v)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (commitDiffsRowKeyMapper) toRow(data []byte) (sql.Row, error) {
+	var buf = bytes.NewBuffer(data)
+	var row = make(sql.Row, len(CommitDiffsSchema))
+
+	for i, col := range CommitDiffsSchema {
+		if col.Type == sql.Int64 {
+			n, err := readInt64(buf)
+			if err != nil {
+				return nil, fmt.Errorf("can't read commit diff %s: %s", col.Name, err)
+			}
+
+			row[i] = n
+			continue
+		}
+
+		ok, err := readBool(buf)
+		if err != nil {
+			return nil, fmt.Errorf("can't read commit diff %s: %s", col.Name, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		s, err := readString(buf)
+		if err != nil {
+			return nil, fmt.Errorf("can't read commit diff %s: %s", col.Name, err)
+		}
+
+		row[i] = s
+	}
+
+	return row, nil
+}
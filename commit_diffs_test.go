@@ -0,0 +1,254 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestCommitDiffsTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitDiffsTable(poolFromCtx(t, ctx))
+	require.NotNil(table)
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.NotEmpty(rows)
+
+	schema := table.Schema()
+	for idx, row := range rows {
+		require.NoError(schema.CheckRow(row), "row %d doesn't conform to schema", idx)
+	}
+}
+
+func TestCommitDiffsRootCommit(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitDiffsTable(poolFromCtx(t, ctx)).WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitDiffsTableName, "commit_hash", false),
+			expression.NewLiteral("b029517f6300c2da0f4b651b8642506cd6aaf45d", sql.Text),
+		),
+	})
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+
+	var paths []interface{}
+	for _, row := range rows {
+		require.Nil(row[2], "parent_hash")
+		require.Equal(changeTypeInsert, row[3])
+		require.Nil(row[4], "from_path")
+		require.Nil(row[6], "from_blob_hash")
+		require.True(row[8].(int64) > 0, "additions")
+		require.Equal(int64(0), row[9])
+		paths = append(paths, row[5])
+	}
+
+	require.ElementsMatch([]interface{}{".gitignore", "LICENSE"}, paths)
+}
+
+func TestCommitDiffsProjection(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitDiffsTable(poolFromCtx(t, ctx)).
+		WithFilters([]sql.Expression{
+			expression.NewEquals(
+				expression.NewGetFieldWithTable(1, sql.Text, CommitDiffsTableName, "commit_hash", false),
+				expression.NewLiteral("b029517f6300c2da0f4b651b8642506cd6aaf45d", sql.Text),
+			),
+		}).(*commitDiffsTable).
+		WithProjection([]string{"commit_hash", "to_path"})
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 2)
+
+	for _, row := range rows {
+		require.Equal(int64(0), row[8], "additions")
+		require.Equal(int64(0), row[9], "deletions")
+	}
+
+	require.True(shouldComputeLineStats(nil))
+	require.True(shouldComputeLineStats([]string{"deletions"}))
+	require.False(shouldComputeLineStats([]string{}))
+}
+
+func TestCommitDiffsTablePushdown(t *testing.T) {
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	table := new(commitDiffsTable)
+
+	var tests = []struct {
+		name         string
+		filters      []sql.Expression
+		expectedRows []sql.Row
+	}{
+		{
+			name: "binary file",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(1, sql.Text, CommitDiffsTableName, "commit_hash", false),
+					expression.NewLiteral("35e85108805c84807bc66a02d91535e1e24b38b9", sql.Text),
+				),
+			},
+			expectedRows: []sql.Row{
+				sql.NewRow(
+					path,
+					"35e85108805c84807bc66a02d91535e1e24b38b9",
+					"b029517f6300c2da0f4b651b8642506cd6aaf45d",
+					"insert",
+					nil,
+					"binary.jpg",
+					nil,
+					"d5c0f4ab811897cadf03aec358ae60d21f91c50d",
+					int64(0),
+					int64(0),
+				),
+			},
+		},
+		{
+			name: "commit with parent",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(1, sql.Text, CommitDiffsTableName, "commit_hash", false),
+					expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+				),
+			},
+			expectedRows: []sql.Row{
+				sql.NewRow(
+					path,
+					"6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+					"918c48b83bd081e863dbe1b80f8998f058cd8294",
+					"insert",
+					nil,
+					"vendor/foo.go",
+					nil,
+					"9dea2395f5403188298c1dabe8bdafe562c491e3",
+					int64(7),
+					int64(0),
+				),
+			},
+		},
+		{
+			name: "repository_id filter",
+			filters: []sql.Expression{
+				expression.NewEquals(
+					expression.NewGetFieldWithTable(0, sql.Text, CommitDiffsTableName, "repository_id", false),
+					expression.NewLiteral("foo", sql.Text),
+				),
+			},
+			expectedRows: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			tbl := table.WithFilters(test.filters)
+			rows, err := tableToRows(ctx, tbl)
+			require.NoError(err)
+
+			require.ElementsMatch(test.expectedRows, rows)
+		})
+	}
+}
+
+func TestTreeDiffsRename(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	repo, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(err)
+	defer repo.Close()
+
+	// the .gitignore file is the same in both commits, so moving it to
+	// another path in a tree built for this test must be seen as a rename.
+	from, err := repo.CommitObject(plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"))
+	require.NoError(err)
+
+	fromTree, err := from.Tree()
+	require.NoError(err)
+
+	renamed := *fromTree
+	renamed.Hash = plumbing.ZeroHash
+	renamed.Entries = nil
+	for _, e := range fromTree.Entries {
+		if e.Name == ".gitignore" {
+			e.Name = "ignored"
+		}
+		renamed.Entries = append(renamed.Entries, e)
+	}
+
+	diffs, err := treeDiffs(repo, from.Hash, fromTree, &renamed)
+	require.NoError(err)
+	require.Len(diffs, 1)
+
+	d := diffs[0]
+	require.Equal(changeTypeRename, d.changeType)
+	require.Equal(".gitignore", d.from.Name)
+	require.Equal("ignored", d.to.Name)
+	require.Equal(d.from.TreeEntry.Hash, d.to.TreeEntry.Hash)
+	require.Zero(d.additions)
+	require.Zero(d.deletions)
+}
+
+func TestCommitDiffsIndex(t *testing.T) {
+	testTableIndex(
+		t,
+		new(commitDiffsTable),
+		[]sql.Expression{expression.NewEquals(
+			expression.NewGetField(1, sql.Text, "commit_hash", false),
+			expression.NewLiteral("af2d6a6954d532f8ffb47615169c8fdf9d383a1a", sql.Text),
+		)},
+	)
+}
+
+func TestCommitDiffsRowKeyMapper(t *testing.T) {
+	require := require.New(t)
+	row := sql.Row{
+		"repo1",
+		plumbing.ZeroHash.String(),
+		nil,
+		"insert",
+		nil,
+		"foo/bar.md",
+		nil,
+		plumbing.ZeroHash.String(),
+		int64(10),
+		int64(0),
+	}
+	mapper := new(commitDiffsRowKeyMapper)
+
+	k, err := mapper.fromRow(row)
+	require.NoError(err)
+
+	row2, err := mapper.toRow(k)
+	require.NoError(err)
+
+	require.Equal(row, row2)
+}
+
+func TestCommitDiffsIndexIterClosed(t *testing.T) {
+	testTableIndexIterClosed(t, new(commitDiffsTable))
+}
+
+func TestCommitDiffsIterClosed(t *testing.T) {
+	testTableIterClosed(t, new(commitDiffsTable))
+}
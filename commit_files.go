@@ -114,11 +114,11 @@ func (t *commitFilesTable) PartitionRows(
 			}
 
 			return &commitFilesRowIter{
-				repo:          repo,
-				index:         index,
-				commitHashes:  stringsToHashes(hashes),
-				paths:         paths,
-				skipGitErrors: shouldSkipErrors(ctx),
+				repo:         repo,
+				index:        index,
+				commitHashes: stringsToHashes(hashes),
+				paths:        paths,
+				skipper:      newGitErrorSkipper(ctx, CommitFilesTableName),
 			}, nil
 		},
 	)
@@ -164,7 +164,7 @@ func (*commitFilesTable) WithProjectFiltersAndIndex(
 		return nil, err
 	}
 
-	var iter sql.RowIter = newCommitFilesIndexIter(index, s.Pool)
+	var iter sql.RowIter = newCommitFilesIndexIter(index, s.QueryPool(ctx))
 
 	if len(filters) > 0 {
 		iter = plan.NewFilterIter(ctx, expression.JoinAnd(filters...), iter)
@@ -177,10 +177,10 @@ type commitFilesRowIter struct {
 	repo  *Repository
 	index sql.IndexValueIter
 
-	commits       object.CommitIter
-	commit        *object.Commit
-	files         *object.FileIter
-	skipGitErrors bool
+	commits object.CommitIter
+	commit  *object.Commit
+	files   *object.FileIter
+	skipper *gitErrorSkipper
 
 	// selectors for faster filtering
 	commitHashes []plumbing.Hash
@@ -199,7 +199,7 @@ func (i *commitFilesRowIter) init() error {
 	if len(i.commitHashes) > 0 {
 		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
 	} else {
-		iter, err := newCommitIter(i.repo, i.skipGitErrors)
+		iter, err := newCommitIter(i.repo, i.skipper)
 		if err != nil {
 			return err
 		}
@@ -243,7 +243,7 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 	for {
 		if i.commits == nil {
 			if err := i.init(); err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
@@ -255,7 +255,7 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 			var err error
 			i.commit, err = i.commits.Next()
 			if err != nil {
-				if i.skipGitErrors && err != io.EOF {
+				if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					logrus.WithFields(logrus.Fields{
 						"repo": i.repo.ID(),
 						"err":  err,
@@ -267,7 +267,7 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 
 			i.files, err = i.commit.Files()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
 					logrus.WithFields(logrus.Fields{
 						"repo":   i.repo.ID(),
 						"err":    err,
@@ -285,7 +285,7 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 				continue
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
 				logrus.WithFields(logrus.Fields{
 					"repo":   i.repo.ID(),
 					"err":    err,
@@ -36,7 +36,7 @@ func TestCommitFilesTableRowIter(t *testing.T) {
 
 		require.NoError(err)
 
-		commits, err := newCommitIter(repo, false)
+		commits, err := newCommitIter(repo, nil)
 		require.NoError(err)
 
 		for {
@@ -0,0 +1,359 @@
+package gitbase
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	sivafs "gopkg.in/src-d/go-billy-siva.v4"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	commitgraphfmt "gopkg.in/src-d/go-git.v4/plumbing/format/commitgraph"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/object/commitgraph"
+)
+
+// maxGeneration is the highest generation number that fits in a
+// commit-graph file. Commits with a higher one are stored with this value,
+// as git does.
+const maxGeneration = 0x3FFFFFFF
+
+// commitGraphCacheSize is the maximum number of commit-graph files of a
+// pool kept in memory.
+const commitGraphCacheSize = 32
+
+func commitGraphPath(fs billy.Filesystem) string {
+	return fs.Join("objects", "info", "commit-graph")
+}
+
+// commitGraphCache keeps the last commit-graph files read from the
+// repositories of a pool, so they are not read again every time a
+// repository is opened. A file is read again if its size or modification
+// time change.
+type commitGraphCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type commitGraphEntry struct {
+	repo    string
+	size    int64
+	modTime time.Time
+	idx     commitgraphfmt.Index
+}
+
+func newCommitGraphCache() *commitGraphCache {
+	return &commitGraphCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached commit-graph file of the repository if it's still
+// the one in the given file info.
+func (c *commitGraphCache) get(repo string, fi os.FileInfo) commitgraphfmt.Index {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[repo]
+	if !ok {
+		return nil
+	}
+
+	entry := e.Value.(*commitGraphEntry)
+	if entry.size != fi.Size() || !entry.modTime.Equal(fi.ModTime()) {
+		c.lru.Remove(e)
+		delete(c.entries, repo)
+		return nil
+	}
+
+	c.lru.MoveToFront(e)
+	return entry.idx
+}
+
+// add caches the commit-graph file of the repository with the given file
+// info, removing the least recently used one if the cache is full.
+func (c *commitGraphCache) add(
+	repo string,
+	fi os.FileInfo,
+	idx commitgraphfmt.Index,
+) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[repo]; ok {
+		c.lru.Remove(e)
+	}
+
+	c.entries[repo] = c.lru.PushFront(&commitGraphEntry{
+		repo:    repo,
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+		idx:     idx,
+	})
+
+	for c.lru.Len() > commitGraphCacheSize {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*commitGraphEntry).repo)
+	}
+}
+
+// CommitNodeIndex returns the index used to traverse the history of the
+// repository. Commits are read from the commit-graph file of the repository
+// if it has one and decoded from the objects otherwise, which is also done
+// for the commits added after the file was written.
+func (r *Repository) CommitNodeIndex() commitgraph.CommitNodeIndex {
+	r.commitNodesOnce.Do(func() {
+		idx, err := readCommitGraph(r)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"repo":  r.ID(),
+				"error": err,
+			}).Warn("unable to read commit-graph file, commit objects will be used")
+		}
+
+		if idx == nil {
+			r.commitNodes = commitgraph.NewObjectCommitNodeIndex(r.Storer)
+			return
+		}
+
+		r.commitNodes = commitgraph.NewGraphCommitNodeIndex(idx, r.Storer)
+	})
+
+	return r.commitNodes
+}
+
+// readCommitGraph reads the commit-graph file of the repository, or returns
+// the one cached in its pool if the file did not change. It returns a nil
+// index if the repository does not have one. The file is kept in memory
+// instead of open, as the repository can be closed and used again while
+// traversing the history.
+func readCommitGraph(repo *Repository) (commitgraphfmt.Index, error) {
+	fs, err := repo.FS()
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := fs.(sivafs.SivaSync); ok {
+		defer s.Sync()
+	}
+
+	fs, err = findDotGit(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := fs.Stat(commitGraphPath(fs))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if idx := repo.commitGraphs.get(repo.ID(), fi); idx != nil {
+		return idx, nil
+	}
+
+	f, err := fs.Open(commitGraphPath(fs))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := commitgraphfmt.OpenFileIndex(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	repo.commitGraphs.add(repo.ID(), fi, idx)
+	return idx, nil
+}
+
+// HasCommitGraph returns whether the repository has a commit-graph file.
+func HasCommitGraph(repo *Repository) (bool, error) {
+	fs, err := repo.FS()
+	if err != nil {
+		return false, err
+	}
+
+	fs, err = findDotGit(fs)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = fs.Stat(commitGraphPath(fs))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// WriteCommitGraph writes the commit-graph file of the repository with all
+// its commits, replacing the existing one. Commits with missing ancestors,
+// as the ones in shallow repositories, are left out of the file. The file
+// is written to a temporary file first and renamed when it's complete, so
+// readers never see a partial file. Writing in transactional
+// libraries must be committed by the caller.
+func WriteCommitGraph(repo *Repository) error {
+	idx, err := buildCommitGraph(repo)
+	if err != nil {
+		return err
+	}
+
+	fs, err := repo.FS()
+	if err != nil {
+		return err
+	}
+
+	fs, err = findDotGit(fs)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.MkdirAll(fs.Join("objects", "info"), 0755); err != nil {
+		return err
+	}
+
+	f, err := fs.TempFile(fs.Join("objects", "info"), "tmp_graph_")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	if err := commitgraphfmt.NewEncoder(f).Encode(idx); err != nil {
+		_ = f.Close()
+		_ = fs.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = fs.Remove(tmp)
+		return err
+	}
+
+	if err := fs.Rename(tmp, commitGraphPath(fs)); err != nil {
+		_ = fs.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func buildCommitGraph(repo *Repository) (*commitgraphfmt.MemoryIndex, error) {
+	iter, err := repo.CommitObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make(map[plumbing.Hash]*commitgraphfmt.CommitData)
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits[c.Hash] = &commitgraphfmt.CommitData{
+			TreeHash:     c.TreeHash,
+			ParentHashes: c.ParentHashes,
+			When:         c.Committer.When,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idx := commitgraphfmt.NewMemoryIndex()
+	for hash, generation := range commitGenerations(commits) {
+		data := commits[hash]
+		data.Generation = generation
+		idx.Add(hash, data)
+	}
+
+	return idx, nil
+}
+
+// commitGenerations returns the generation number of the given commits
+// that have all their ancestors in the map. Root commits have generation 1
+// and the rest one more than the highest generation of their parents.
+func commitGenerations(
+	commits map[plumbing.Hash]*commitgraphfmt.CommitData,
+) map[plumbing.Hash]int {
+	const incomplete = -1
+	generations := make(map[plumbing.Hash]int, len(commits))
+
+	// The history is walked with a stack instead of recursion, as it can
+	// have millions of commits.
+	for hash := range commits {
+		stack := []plumbing.Hash{hash}
+		for len(stack) > 0 {
+			h := stack[len(stack)-1]
+			if _, ok := generations[h]; ok {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			data, ok := commits[h]
+			if !ok {
+				generations[h] = incomplete
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			var pending []plumbing.Hash
+			generation := 1
+			for _, p := range data.ParentHashes {
+				g, ok := generations[p]
+				switch {
+				case !ok:
+					pending = append(pending, p)
+				case g == incomplete || generation == incomplete:
+					generation = incomplete
+				case g+1 > generation:
+					generation = g + 1
+				}
+			}
+
+			if len(pending) > 0 {
+				stack = append(stack, pending...)
+				continue
+			}
+
+			if generation > maxGeneration {
+				generation = maxGeneration
+			}
+
+			generations[h] = generation
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for hash, generation := range generations {
+		if generation == incomplete {
+			delete(generations, hash)
+		}
+	}
+
+	return generations
+}
@@ -0,0 +1,131 @@
+package gitbase
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	commitgraphfmt "gopkg.in/src-d/go-git.v4/plumbing/format/commitgraph"
+)
+
+func TestCommitGenerations(t *testing.T) {
+	var (
+		root    = plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d")
+		a       = plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9")
+		b       = plumbing.NewHash("1669dce138d9b841a518c64b10914d88f5e488ea")
+		merge   = plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+		missing = plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881")
+		shallow = plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+		child   = plumbing.NewHash("af2d6a6954d532f8ffb47615169c8fdf9d383a1a")
+	)
+
+	commits := map[plumbing.Hash]*commitgraphfmt.CommitData{
+		root:    {},
+		a:       {ParentHashes: []plumbing.Hash{root}},
+		b:       {ParentHashes: []plumbing.Hash{a}},
+		merge:   {ParentHashes: []plumbing.Hash{a, b}},
+		shallow: {ParentHashes: []plumbing.Hash{missing}},
+		child:   {ParentHashes: []plumbing.Hash{shallow, root}},
+	}
+
+	require.Equal(t, map[plumbing.Hash]int{
+		root:  1,
+		a:     2,
+		b:     3,
+		merge: 4,
+	}, commitGenerations(commits))
+}
+
+func TestWriteCommitGraph(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	pool := poolFromCtx(t, ctx)
+	refCommits, err := tableToRows(ctx, newRefCommitsTable(pool))
+	require.NoError(err)
+
+	commits, err := tableToRows(ctx, newCommitsTable(pool))
+	require.NoError(err)
+
+	repo, err := pool.GetRepo(path)
+	require.NoError(err)
+
+	ok, err := HasCommitGraph(repo)
+	require.NoError(err)
+	require.False(ok)
+
+	require.NoError(WriteCommitGraph(repo))
+	require.NoError(repo.Close())
+
+	repo, err = pool.GetRepo(path)
+	require.NoError(err)
+	defer repo.Close()
+
+	ok, err = HasCommitGraph(repo)
+	require.NoError(err)
+	require.True(ok)
+
+	// Commits out of the commit-graph file have the highest generation.
+	hash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	node, err := repo.CommitNodeIndex().Get(hash)
+	require.NoError(err)
+	require.NotZero(node.Generation())
+	require.NotEqual(uint64(math.MaxUint64), node.Generation())
+
+	rows, err := tableToRows(ctx, newRefCommitsTable(pool))
+	require.NoError(err)
+	require.Equal(refCommits, rows)
+
+	rows, err = tableToRows(ctx, newCommitsTable(pool))
+	require.NoError(err)
+	require.Equal(commits, rows)
+}
+
+func TestReadCommitGraphCache(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	pool := poolFromCtx(t, ctx)
+	repo, err := pool.GetRepo(path)
+	require.NoError(err)
+	require.NoError(WriteCommitGraph(repo))
+	require.NoError(repo.Close())
+
+	read := func() commitgraphfmt.Index {
+		repo, err := pool.GetRepo(path)
+		require.NoError(err)
+		defer repo.Close()
+
+		idx, err := readCommitGraph(repo)
+		require.NoError(err)
+		require.NotNil(idx)
+		return idx
+	}
+
+	idx := read()
+	require.True(idx == read(), "commit-graph file is cached")
+
+	repo, err = pool.GetRepo(path)
+	require.NoError(err)
+	defer repo.Close()
+
+	fs, err := repo.FS()
+	require.NoError(err)
+	fs, err = findDotGit(fs)
+	require.NoError(err)
+
+	files, err := fs.ReadDir(fs.Join("objects", "info"))
+	require.NoError(err)
+	for _, f := range files {
+		require.False(strings.HasPrefix(f.Name(), "tmp_graph_"), "temporary file is removed")
+	}
+
+	require.NoError(fs.Remove(commitGraphPath(fs)))
+	idx, err = readCommitGraph(repo)
+	require.NoError(err)
+	require.Nil(idx)
+}
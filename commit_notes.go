@@ -0,0 +1,360 @@
+package gitbase
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// notesRefPrefix is the prefix of the references with git notes.
+const notesRefPrefix = "refs/notes/"
+
+type commitNotesTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// CommitNotesSchema is the schema for the commit notes table.
+var CommitNotesSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: CommitNotesTableName},
+	{Name: "notes_ref", Type: sql.Text, Nullable: false, Source: CommitNotesTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitNotesTableName},
+	{Name: "note_blob_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitNotesTableName},
+	{Name: "note_content", Type: sql.Text, Nullable: false, Source: CommitNotesTableName},
+}
+
+func newCommitNotesTable(pool *RepositoryPool) *commitNotesTable {
+	return &commitNotesTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*commitNotesTable)(nil)
+var _ Squashable = (*commitNotesTable)(nil)
+
+func (commitNotesTable) isSquashable()   {}
+func (commitNotesTable) isGitbaseTable() {}
+
+func (t commitNotesTable) String() string {
+	return printTable(
+		CommitNotesTableName,
+		CommitNotesSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (commitNotesTable) Name() string { return CommitNotesTableName }
+
+func (commitNotesTable) Schema() sql.Schema { return CommitNotesSchema }
+
+func (t *commitNotesTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *commitNotesTable) Filters() []sql.Expression { return t.filters }
+
+func (t *commitNotesTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.CommitNotesTable")
+	iter, err := rowIterWithSelectors(
+		ctx, CommitNotesSchema, CommitNotesTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var refNames []string
+			refNames, err = selectors.textValues("notes_ref")
+			if err != nil {
+				return nil, err
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			return &commitNotesRowIter{
+				repo:         repo,
+				refNames:     refNames,
+				commitHashes: stringsToHashes(hashes),
+				skipper:      newGitErrorSkipper(ctx, CommitNotesTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (commitNotesTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(CommitNotesTableName, CommitNotesSchema, filters)
+}
+
+func (commitNotesTable) handledColumns() []string {
+	return []string{"commit_hash", "notes_ref", "repository_id"}
+}
+
+// notesRef holds the notes of a notes reference.
+type notesRef struct {
+	name string
+	// notes are the hashes of the blobs of the notes by the hash of the
+	// object they annotate.
+	notes map[plumbing.Hash]plumbing.Hash
+}
+
+// hashes returns the hashes of the annotated objects, sorted.
+func (r *notesRef) hashes() []plumbing.Hash {
+	hashes := make([]plumbing.Hash, 0, len(r.notes))
+	for h := range r.notes {
+		hashes = append(hashes, h)
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	return hashes
+}
+
+// repositoryNotes returns the notes of the notes references of the
+// repository, sorted by name. If names are given only the references with
+// those names are read.
+func repositoryNotes(repo *Repository, names []string) ([]*notesRef, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []*notesRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if ref.Type() != plumbing.HashReference ||
+			!strings.HasPrefix(name, notesRefPrefix) ||
+			(len(names) > 0 && !stringContains(names, name)) {
+			return nil
+		}
+
+		notes, err := readNotes(repo, ref.Hash())
+		if err != nil {
+			return err
+		}
+
+		refs = append(refs, &notesRef{name: name, notes: notes})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].name < refs[j].name
+	})
+	return refs, nil
+}
+
+// readNotes returns the notes in the tree of the given notes commit.
+func readNotes(repo *Repository, hash plumbing.Hash) (map[plumbing.Hash]plumbing.Hash, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make(map[plumbing.Hash]plumbing.Hash)
+	if err := walkNotesTree(tree, "", notes); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// walkNotesTree adds to notes the notes in the tree. Each note is a blob
+// whose path, without slashes, is the hash of the annotated object. Large
+// notes trees use a fanout, splitting the hashes in directories with the
+// first bytes, like "6e/cf0ef2c2dffb796033e5a02219af86ec6584e5", so these
+// directories are also walked. Any other entry is ignored, as git does.
+func walkNotesTree(
+	tree *object.Tree,
+	prefix string,
+	notes map[plumbing.Hash]plumbing.Hash,
+) error {
+	for _, e := range tree.Entries {
+		name := prefix + e.Name
+		if len(name) > 40 || !isHexHash(e.Name) {
+			continue
+		}
+
+		switch {
+		case e.Mode == filemode.Dir && len(name) < 40:
+			subtree, err := tree.Tree(e.Name)
+			if err != nil {
+				return err
+			}
+
+			if err := walkNotesTree(subtree, name, notes); err != nil {
+				return err
+			}
+		case e.Mode.IsFile() && len(name) == 40:
+			notes[plumbing.NewHash(name)] = e.Hash
+		}
+	}
+
+	return nil
+}
+
+func newCommitNotesRow(
+	repo *Repository,
+	ref string,
+	hash, blob plumbing.Hash,
+) (sql.Row, error) {
+	b, err := repo.BlobObject(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := b.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var content bytes.Buffer
+	if _, err := io.Copy(&content, r); err != nil {
+		return nil, err
+	}
+
+	return sql.NewRow(
+		repo.ID(),
+		ref,
+		hash.String(),
+		blob.String(),
+		content.String(),
+	), nil
+}
+
+type commitNotesRowIter struct {
+	repo    *Repository
+	loaded  bool
+	refs    []*notesRef
+	hashes  []plumbing.Hash
+	skipper *gitErrorSkipper
+
+	// selectors for faster filtering
+	refNames     []string
+	commitHashes []plumbing.Hash
+}
+
+// annotated returns the hashes of the annotated objects of the notes
+// reference to iterate.
+func (i *commitNotesRowIter) annotated(ref *notesRef) []plumbing.Hash {
+	if len(i.commitHashes) == 0 {
+		return ref.hashes()
+	}
+
+	var hashes []plumbing.Hash
+	for _, h := range i.commitHashes {
+		if _, ok := ref.notes[h]; ok {
+			hashes = append(hashes, h)
+		}
+	}
+
+	return hashes
+}
+
+func (i *commitNotesRowIter) Next() (sql.Row, error) {
+	if !i.loaded {
+		i.loaded = true
+
+		var err error
+		i.refs, err = repositoryNotes(i.repo, i.refNames)
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				return nil, io.EOF
+			}
+
+			return nil, err
+		}
+
+		if len(i.refs) > 0 {
+			i.hashes = i.annotated(i.refs[0])
+		}
+	}
+
+	for {
+		if len(i.refs) == 0 {
+			return nil, io.EOF
+		}
+
+		if len(i.hashes) == 0 {
+			i.refs = i.refs[1:]
+			if len(i.refs) > 0 {
+				i.hashes = i.annotated(i.refs[0])
+			}
+
+			continue
+		}
+
+		ref := i.refs[0]
+		hash := i.hashes[0]
+		i.hashes = i.hashes[1:]
+
+		row, err := newCommitNotesRow(i.repo, ref.name, hash, ref.notes[hash])
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), ref.notes[hash], err) {
+				logrus.WithFields(logrus.Fields{
+					"repo":   i.repo.ID(),
+					"err":    err,
+					"ref":    ref.name,
+					"commit": hash.String(),
+				}).Error("can't read note of commit")
+				continue
+			}
+
+			return nil, err
+		}
+
+		return row, nil
+	}
+}
+
+func (i *commitNotesRowIter) Close() error {
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
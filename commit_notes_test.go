@@ -0,0 +1,161 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const (
+	notedCommit      = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+	otherNotedCommit = "918c48b83bd081e863dbe1b80f8998f058cd8294"
+)
+
+// setupNotes returns a context with a pool containing only the worktree
+// fixture with two notes references: refs/notes/ci, with its notes in a
+// fanout tree, and refs/notes/review.
+func setupNotes(t *testing.T) (*sql.Context, CleanupFunc) {
+	require := require.New(t)
+	t.Helper()
+
+	ctx, path, cleanup := setup(t)
+
+	r, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(err)
+	defer r.Close()
+
+	passed := storeBlob(t, r.Storer, "Build passed\n")
+	failed := storeBlob(t, r.Storer, "Build failed\n")
+	fanout := storeTree(t, r.Storer, object.TreeEntry{
+		Name: notedCommit[2:], Mode: filemode.Regular, Hash: passed,
+	})
+	storeNotes(t, r, "refs/notes/ci",
+		object.TreeEntry{Name: notedCommit[:2], Mode: filemode.Dir, Hash: fanout},
+		object.TreeEntry{Name: otherNotedCommit, Mode: filemode.Regular, Hash: failed},
+		object.TreeEntry{Name: "README", Mode: filemode.Regular, Hash: failed},
+	)
+
+	lgtm := storeBlob(t, r.Storer, "LGTM\n")
+	storeNotes(t, r, "refs/notes/review",
+		object.TreeEntry{Name: notedCommit, Mode: filemode.Regular, Hash: lgtm},
+	)
+
+	return ctx, cleanup
+}
+
+func storeNotes(t *testing.T, r *Repository, name string, entries ...object.TreeEntry) {
+	t.Helper()
+
+	c := &object.Commit{
+		Message:  "Notes added by 'git notes add'\n",
+		TreeHash: storeTree(t, r.Storer, entries...),
+	}
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(t, c.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	ref := plumbing.NewHashReference(plumbing.ReferenceName(name), hash)
+	require.NoError(t, r.Storer.SetReference(ref))
+}
+
+func TestCommitNotesTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cleanup := setupNotes(t)
+	defer cleanup()
+
+	table := newCommitNotesTable(poolFromCtx(t, ctx))
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 3)
+
+	for idx, row := range rows {
+		require.NoError(CommitNotesSchema.CheckRow(row), "row %d doesn't conform to schema", idx)
+	}
+
+	var notes [][]interface{}
+	for _, row := range rows {
+		notes = append(notes, []interface{}{row[1], row[2], row[4]})
+	}
+
+	require.Equal([][]interface{}{
+		{"refs/notes/ci", notedCommit, "Build passed\n"},
+		{"refs/notes/ci", otherNotedCommit, "Build failed\n"},
+		{"refs/notes/review", notedCommit, "LGTM\n"},
+	}, notes)
+}
+
+func TestCommitNotesPushdown(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cleanup := setupNotes(t)
+	defer cleanup()
+
+	table := newCommitNotesTable(poolFromCtx(t, ctx))
+
+	rows, err := tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(2, sql.Text, CommitNotesTableName, "commit_hash", false),
+			expression.NewLiteral(notedCommit, sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 2)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitNotesTableName, "notes_ref", false),
+			expression.NewLiteral("refs/notes/review", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("LGTM\n", rows[0][4])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitNotesTableName, "notes_ref", false),
+			expression.NewLiteral("refs/heads/master", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestWalkNotesTree(t *testing.T) {
+	require := require.New(t)
+
+	s := memory.NewStorage()
+	note := storeBlob(t, s, "note\n")
+	inner := storeTree(t, s, object.TreeEntry{
+		Name: notedCommit[4:], Mode: filemode.Regular, Hash: note,
+	})
+	outer := storeTree(t, s,
+		object.TreeEntry{Name: notedCommit[2:4], Mode: filemode.Dir, Hash: inner},
+		// Too long for a fanout directory.
+		object.TreeEntry{Name: otherNotedCommit[2:], Mode: filemode.Dir, Hash: inner},
+	)
+	root := storeTree(t, s,
+		object.TreeEntry{Name: notedCommit[:2], Mode: filemode.Dir, Hash: outer},
+		object.TreeEntry{Name: otherNotedCommit, Mode: filemode.Executable, Hash: note},
+		object.TreeEntry{Name: "not-a-hash", Mode: filemode.Regular, Hash: note},
+		object.TreeEntry{Name: otherNotedCommit[:8], Mode: filemode.Regular, Hash: note},
+	)
+
+	tree, err := object.GetTree(s, root)
+	require.NoError(err)
+
+	notes := make(map[plumbing.Hash]plumbing.Hash)
+	require.NoError(walkNotesTree(tree, "", notes))
+	require.Equal(map[plumbing.Hash]plumbing.Hash{
+		plumbing.NewHash(notedCommit):      note,
+		plumbing.NewHash(otherNotedCommit): note,
+	}, notes)
+}
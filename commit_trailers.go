@@ -0,0 +1,196 @@
+package gitbase
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/trailer"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+type commitTrailersTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// CommitTrailersSchema is the schema for the commit trailers table.
+var CommitTrailersSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: CommitTrailersTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitTrailersTableName},
+	{Name: "trailer_key", Type: sql.Text, Nullable: false, Source: CommitTrailersTableName},
+	{Name: "trailer_value", Type: sql.Text, Nullable: false, Source: CommitTrailersTableName},
+}
+
+func newCommitTrailersTable(pool *RepositoryPool) *commitTrailersTable {
+	return &commitTrailersTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*commitTrailersTable)(nil)
+
+func (commitTrailersTable) isGitbaseTable() {}
+
+func (t commitTrailersTable) String() string {
+	return printTable(
+		CommitTrailersTableName,
+		CommitTrailersSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (commitTrailersTable) Name() string { return CommitTrailersTableName }
+
+func (commitTrailersTable) Schema() sql.Schema { return CommitTrailersSchema }
+
+func (t *commitTrailersTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *commitTrailersTable) Filters() []sql.Expression { return t.filters }
+
+func (t *commitTrailersTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.CommitTrailersTable")
+	iter, err := rowIterWithSelectors(
+		ctx, CommitTrailersSchema, CommitTrailersTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			return &commitTrailersRowIter{
+				repo:         repo,
+				commitHashes: stringsToHashes(hashes),
+				skipper:      newGitErrorSkipper(ctx, CommitTrailersTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (commitTrailersTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(CommitTrailersTableName, CommitTrailersSchema, filters)
+}
+
+func (commitTrailersTable) handledColumns() []string {
+	return []string{"commit_hash", "repository_id"}
+}
+
+func newCommitTrailersRows(repoID string, c *object.Commit) []sql.Row {
+	var rows []sql.Row
+	for _, t := range trailer.Parse(c.Message) {
+		rows = append(rows, sql.NewRow(
+			repoID,
+			c.Hash.String(),
+			t.Key,
+			t.Value,
+		))
+	}
+
+	return rows
+}
+
+type commitTrailersRowIter struct {
+	repo    *Repository
+	commits object.CommitIter
+	rows    []sql.Row
+	skipper *gitErrorSkipper
+
+	// selectors for faster filtering
+	commitHashes []plumbing.Hash
+}
+
+func (i *commitTrailersRowIter) init() error {
+	if len(i.commitHashes) > 0 {
+		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
+	} else {
+		iter, err := newCommitIter(i.repo, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.commits = iter
+	}
+
+	return nil
+}
+
+func (i *commitTrailersRowIter) Next() (sql.Row, error) {
+	for {
+		if i.commits == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if len(i.rows) > 0 {
+			row := i.rows[0]
+			i.rows = i.rows[1:]
+			return row, nil
+		}
+
+		c, err := i.commits.Next()
+		if err != nil {
+			if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo": i.repo.ID(),
+					"err":  err,
+				}).Error("skipped commit in commit_trailers")
+				continue
+			}
+
+			return nil, err
+		}
+
+		i.rows = newCommitTrailersRows(i.repo.ID(), c)
+	}
+}
+
+func (i *commitTrailersRowIter) Close() error {
+	if i.commits != nil {
+		i.commits.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestCommitTrailersTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitTrailersTable(poolFromCtx(t, ctx)).WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitTrailersTableName, "commit_hash", false),
+			expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+		),
+	})
+
+	// None of the commits of the fixture have trailers.
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestCommitTrailersRows(t *testing.T) {
+	require := require.New(t)
+
+	c := &object.Commit{
+		Hash: plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+		Message: "Fix foo\n\nSome details.\n\n" +
+			"Co-authored-by: Jane Doe <jane@doe.com>\n" +
+			"Signed-off-by: John Doe <john@doe.com>\n" +
+			"Co-authored-by: Alice <alice@example.com>\n",
+	}
+
+	rows := newCommitTrailersRows("foo", c)
+	expected := []sql.Row{
+		sql.NewRow("foo", c.Hash.String(), "Co-authored-by", "Jane Doe <jane@doe.com>"),
+		sql.NewRow("foo", c.Hash.String(), "Signed-off-by", "John Doe <john@doe.com>"),
+		sql.NewRow("foo", c.Hash.String(), "Co-authored-by", "Alice <alice@example.com>"),
+	}
+	require.Equal(expected, rows)
+
+	schema := CommitTrailersSchema
+	for idx, row := range rows {
+		require.NoError(schema.CheckRow(row), "row %d doesn't conform to schema", idx)
+	}
+
+	require.Len(newCommitTrailersRows("foo", &object.Commit{Message: "Fix foo\n"}), 0)
+}
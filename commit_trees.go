@@ -96,10 +96,10 @@ func (t *commitTreesTable) PartitionRows(
 			}
 
 			return &commitTreesRowIter{
-				ctx:           ctx,
-				repo:          repo,
-				commitHashes:  stringsToHashes(hashes),
-				skipGitErrors: shouldSkipErrors(ctx),
+				ctx:          ctx,
+				repo:         repo,
+				commitHashes: stringsToHashes(hashes),
+				skipper:      newGitErrorSkipper(ctx, CommitTreesTableName),
 			}, nil
 		},
 	)
@@ -190,10 +190,10 @@ func (commitTreesRowKeyMapper) toRow(data []byte) (sql.Row, error) {
 }
 
 type commitTreesRowIter struct {
-	ctx           *sql.Context
-	repo          *Repository
-	skipGitErrors bool
-	index         sql.IndexValueIter
+	ctx     *sql.Context
+	repo    *Repository
+	skipper *gitErrorSkipper
+	index   sql.IndexValueIter
 
 	commits object.CommitIter
 	commit  *object.Commit
@@ -216,7 +216,7 @@ func (i *commitTreesRowIter) init() error {
 	if len(i.commitHashes) > 0 {
 		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
 	} else {
-		iter, err := newCommitIter(i.repo, i.skipGitErrors)
+		iter, err := newCommitIter(i.repo, i.skipper)
 		if err != nil {
 			return err
 		}
@@ -254,7 +254,7 @@ func (i *commitTreesRowIter) next() (sql.Row, error) {
 	for {
 		if i.commits == nil {
 			if err := i.init(); err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
@@ -271,7 +271,7 @@ func (i *commitTreesRowIter) next() (sql.Row, error) {
 					return nil, io.EOF
 				}
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -280,7 +280,7 @@ func (i *commitTreesRowIter) next() (sql.Row, error) {
 
 			tree, err = commit.Tree()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), commit.Hash, err) {
 					continue
 				}
 
@@ -304,7 +304,7 @@ func (i *commitTreesRowIter) next() (sql.Row, error) {
 			i.trees.Close()
 			i.trees = nil
 
-			if err == io.EOF || i.skipGitErrors {
+			if err == io.EOF || i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
 				continue
 			}
 
@@ -3,10 +3,12 @@ package gitbase
 import (
 	"io"
 
+	"github.com/src-d/gitbase/internal/signature"
 	"github.com/src-d/go-mysql-server/sql"
 
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/object/commitgraph"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 )
 
@@ -30,6 +32,8 @@ var CommitsSchema = sql.Schema{
 	{Name: "commit_message", Type: sql.Text, Nullable: false, Source: CommitsTableName},
 	{Name: "tree_hash", Type: sql.VarChar(40), Nullable: false, Source: CommitsTableName},
 	{Name: "commit_parents", Type: sql.Array(sql.VarChar(40)), Nullable: false, Source: CommitsTableName},
+	{Name: "commit_signature", Type: sql.Text, Nullable: true, Source: CommitsTableName},
+	{Name: "commit_signature_type", Type: sql.Text, Nullable: true, Source: CommitsTableName},
 }
 
 func newCommitsTable(pool *RepositoryPool) *commitsTable {
@@ -84,10 +88,16 @@ func (r *commitsTable) PartitionRows(
 		return nil, err
 	}
 
+	filters, ok := resolveRevisionFilters(repo, r.filters)
+	if !ok {
+		repo.Close()
+		return sql.RowsToRowIter(), nil
+	}
+
 	span, ctx := ctx.Span("gitbase.CommitsTable")
 	iter, err := rowIterWithSelectors(
 		ctx, CommitsSchema, CommitsTableName,
-		r.filters,
+		filters,
 		r.handledColumns(),
 		func(selectors selectors) (sql.RowIter, error) {
 			var hashes []string
@@ -111,23 +121,24 @@ func (r *commitsTable) PartitionRows(
 
 				return newCommitsIndexIter(
 					indexValues,
-					s.Pool,
+					s.QueryPool(ctx),
 					stringsToHashes(hashes),
 				), nil
 			}
 
+			skipper := newGitErrorSkipper(ctx, CommitsTableName)
 			var iter object.CommitIter
 			if len(hashes) > 0 {
 				iter = newCommitsByHashIter(repo, stringsToHashes(hashes))
 			} else {
 				var err error
-				iter, err = newCommitIter(repo, shouldSkipErrors(ctx))
+				iter, err = newCommitIter(repo, skipper)
 				if err != nil {
 					return nil, err
 				}
 			}
 
-			return &commitRowIter{repo, iter, shouldSkipErrors(ctx)}, nil
+			return &commitRowIter{repo, iter, skipper}, nil
 		},
 	)
 
@@ -161,9 +172,9 @@ func (r *commitsTable) IndexKeyValues(
 }
 
 type commitRowIter struct {
-	repo          *Repository
-	iter          object.CommitIter
-	skipGitErrors bool
+	repo    *Repository
+	iter    object.CommitIter
+	skipper *gitErrorSkipper
 }
 
 func (i *commitRowIter) Next() (sql.Row, error) {
@@ -174,7 +185,7 @@ func (i *commitRowIter) Next() (sql.Row, error) {
 				return nil, io.EOF
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 				continue
 			}
 			return nil, err
@@ -190,30 +201,30 @@ func (i *commitRowIter) Close() error {
 }
 
 type commitIter struct {
-	repo          *Repository
-	skipGitErrors bool
-	refs          storer.ReferenceIter
-	seen          map[plumbing.Hash]struct{}
-	ref           *plumbing.Reference
-	queue         []plumbing.Hash
+	repo    *Repository
+	skipper *gitErrorSkipper
+	refs    storer.ReferenceIter
+	seen    map[plumbing.Hash]struct{}
+	ref     *plumbing.Reference
+	queue   []plumbing.Hash
 }
 
 func newCommitIter(
 	repo *Repository,
-	skipGitErrors bool,
+	skipper *gitErrorSkipper,
 ) (*commitIter, error) {
 	refs, err := repo.References()
 	if err != nil {
-		if !skipGitErrors {
+		if !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 			return nil, err
 		}
 	}
 
 	return &commitIter{
-		skipGitErrors: skipGitErrors,
-		refs:          refs,
-		repo:          repo,
-		seen:          make(map[plumbing.Hash]struct{}),
+		skipper: skipper,
+		refs:    refs,
+		repo:    repo,
+		seen:    make(map[plumbing.Hash]struct{}),
 	}, nil
 }
 
@@ -225,7 +236,7 @@ func (i *commitIter) loadNextRef() (err error) {
 
 		i.ref, err = i.refs.Next()
 		if err != nil {
-			if err != io.EOF && i.skipGitErrors {
+			if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 				continue
 			}
 
@@ -243,6 +254,8 @@ func (i *commitIter) loadNextRef() (err error) {
 func (i *commitIter) Next() (*object.Commit, error) {
 	for {
 		var commit *object.Commit
+		var hash plumbing.Hash
+		var parents []plumbing.Hash
 		var err error
 
 		if i.ref == nil {
@@ -255,7 +268,8 @@ func (i *commitIter) Next() (*object.Commit, error) {
 			}
 			i.seen[i.ref.Hash()] = struct{}{}
 
-			commit, err = resolveCommit(i.repo, i.ref.Hash())
+			hash = i.ref.Hash()
+			commit, err = resolveCommit(i.repo, hash)
 			if errInvalidCommit.Is(err) {
 				i.ref = nil
 				continue
@@ -266,25 +280,37 @@ func (i *commitIter) Next() (*object.Commit, error) {
 				continue
 			}
 
-			hash := i.queue[0]
+			hash = i.queue[0]
 			i.queue = i.queue[1:]
 			if _, ok := i.seen[hash]; ok {
 				continue
 			}
 			i.seen[hash] = struct{}{}
 
-			commit, err = i.repo.CommitObject(hash)
+			// Parents are read from the commit-graph file when the
+			// repository has one, but the commit is still decoded for
+			// the row.
+			var node commitgraph.CommitNode
+			node, err = i.repo.CommitNodeIndex().Get(hash)
+			if err == nil {
+				parents = node.ParentHashes()
+				commit, err = node.Commit()
+			}
 		}
 
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), hash, err) {
 				continue
 			}
 
 			return nil, err
 		}
 
-		i.queue = append(i.queue, commit.ParentHashes...)
+		if parents == nil {
+			parents = commit.ParentHashes
+		}
+
+		i.queue = append(i.queue, parents...)
 
 		return commit, nil
 	}
@@ -307,6 +333,7 @@ func (i *commitIter) ForEach(cb func(*object.Commit) error) error {
 }
 
 func commitToRow(repoID string, c *object.Commit) sql.Row {
+	sig, sigType := signatureValues(c.PGPSignature)
 	return sql.NewRow(
 		repoID,
 		c.Hash.String(),
@@ -319,9 +346,25 @@ func commitToRow(repoID string, c *object.Commit) sql.Row {
 		c.Message,
 		c.TreeHash.String(),
 		getParentHashes(c),
+		sig,
+		sigType,
 	)
 }
 
+// signatureValues returns the values of the signature and signature type
+// columns for the given signature, which are NULL if there is none.
+func signatureValues(sig string) (interface{}, interface{}) {
+	if sig == "" {
+		return nil, nil
+	}
+
+	if typ := signature.Type(sig); typ != "" {
+		return sig, typ
+	}
+
+	return sig, nil
+}
+
 func getParentHashes(c *object.Commit) []interface{} {
 	parentHashes := make([]interface{}, 0, len(c.ParentHashes))
 	for _, plumbingHash := range c.ParentHashes {
@@ -414,7 +457,7 @@ func newCommitsKeyValueIter(
 		return nil, err
 	}
 
-	commits, err := newCommitIter(repo, false)
+	commits, err := newCommitIter(repo, nil)
 	if err != nil {
 		return nil, err
 	}
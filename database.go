@@ -27,40 +27,81 @@ const (
 	CommitFilesTableName = "commit_files"
 	// FilesTableName is the name of the files table.
 	FilesTableName = "files"
+	// CommitDiffsTableName is the name of the commit diffs table.
+	CommitDiffsTableName = "commit_diffs"
+	// CommitDiffHunksTableName is the name of the commit diff hunks table.
+	CommitDiffHunksTableName = "commit_diff_hunks"
+	// FileBlameTableName is the name of the file blame table.
+	FileBlameTableName = "file_blame"
+	// CommitTrailersTableName is the name of the commit trailers table.
+	CommitTrailersTableName = "commit_trailers"
+	// SubmodulesTableName is the name of the submodules table.
+	SubmodulesTableName = "submodules"
+	// ReflogTableName is the name of the reflog table.
+	ReflogTableName = "reflog"
+	// CommitNotesTableName is the name of the commit notes table.
+	CommitNotesTableName = "commit_notes"
+	// ObjectsTableName is the name of the objects table.
+	ObjectsTableName = "objects"
+	// TagsTableName is the name of the tags table.
+	TagsTableName = "tags"
+	// GitbaseErrorsTableName is the name of the table with the git errors
+	// skipped in the session.
+	GitbaseErrorsTableName = "gitbase_errors"
 )
 
 // Database holds all git repository tables
 type Database struct {
-	name         string
-	commits      sql.Table
-	references   sql.Table
-	treeEntries  sql.Table
-	blobs        sql.Table
-	repositories sql.Table
-	remotes      sql.Table
-	refCommits   sql.Table
-	commitTrees  sql.Table
-	commitBlobs  sql.Table
-	commitFiles  sql.Table
-	files        sql.Table
+	name            string
+	commits         sql.Table
+	references      sql.Table
+	treeEntries     sql.Table
+	blobs           sql.Table
+	repositories    sql.Table
+	remotes         sql.Table
+	refCommits      sql.Table
+	commitTrees     sql.Table
+	commitBlobs     sql.Table
+	commitFiles     sql.Table
+	files           sql.Table
+	commitDiffs     sql.Table
+	commitDiffHunks sql.Table
+	fileBlame       sql.Table
+	commitTrailers  sql.Table
+	submodules      sql.Table
+	reflog          sql.Table
+	commitNotes     sql.Table
+	objects         sql.Table
+	tags            sql.Table
+	gitbaseErrors   sql.Table
 }
 
 // NewDatabase creates a new Database structure and initializes its
 // tables with the given pool
 func NewDatabase(name string, pool *RepositoryPool) sql.Database {
 	return &Database{
-		name:         name,
-		commits:      newCommitsTable(pool),
-		references:   newReferencesTable(pool),
-		blobs:        newBlobsTable(pool),
-		treeEntries:  newTreeEntriesTable(pool),
-		repositories: newRepositoriesTable(pool),
-		remotes:      newRemotesTable(pool),
-		refCommits:   newRefCommitsTable(pool),
-		commitTrees:  newCommitTreesTable(pool),
-		commitBlobs:  newCommitBlobsTable(pool),
-		commitFiles:  newCommitFilesTable(pool),
-		files:        newFilesTable(pool),
+		name:            name,
+		commits:         newCommitsTable(pool),
+		references:      newReferencesTable(pool),
+		blobs:           newBlobsTable(pool),
+		treeEntries:     newTreeEntriesTable(pool),
+		repositories:    newRepositoriesTable(pool),
+		remotes:         newRemotesTable(pool),
+		refCommits:      newRefCommitsTable(pool),
+		commitTrees:     newCommitTreesTable(pool),
+		commitBlobs:     newCommitBlobsTable(pool),
+		commitFiles:     newCommitFilesTable(pool),
+		files:           newFilesTable(pool),
+		commitDiffs:     newCommitDiffsTable(pool),
+		commitDiffHunks: newCommitDiffHunksTable(pool),
+		fileBlame:       newFileBlameTable(pool),
+		commitTrailers:  newCommitTrailersTable(pool),
+		submodules:      newSubmodulesTable(pool),
+		reflog:          newReflogTable(pool),
+		commitNotes:     newCommitNotesTable(pool),
+		objects:         newObjectsTable(pool),
+		tags:            newTagsTable(pool),
+		gitbaseErrors:   newGitbaseErrorsTable(),
 	}
 }
 
@@ -72,16 +113,26 @@ func (d *Database) Name() string {
 // Tables returns a map with all initialized tables
 func (d *Database) Tables() map[string]sql.Table {
 	return map[string]sql.Table{
-		CommitsTableName:      d.commits,
-		ReferencesTableName:   d.references,
-		BlobsTableName:        d.blobs,
-		TreeEntriesTableName:  d.treeEntries,
-		RepositoriesTableName: d.repositories,
-		RemotesTableName:      d.remotes,
-		RefCommitsTableName:   d.refCommits,
-		CommitTreesTableName:  d.commitTrees,
-		CommitBlobsTableName:  d.commitBlobs,
-		CommitFilesTableName:  d.commitFiles,
-		FilesTableName:        d.files,
+		CommitsTableName:         d.commits,
+		ReferencesTableName:      d.references,
+		BlobsTableName:           d.blobs,
+		TreeEntriesTableName:     d.treeEntries,
+		RepositoriesTableName:    d.repositories,
+		RemotesTableName:         d.remotes,
+		RefCommitsTableName:      d.refCommits,
+		CommitTreesTableName:     d.commitTrees,
+		CommitBlobsTableName:     d.commitBlobs,
+		CommitFilesTableName:     d.commitFiles,
+		FilesTableName:           d.files,
+		CommitDiffsTableName:     d.commitDiffs,
+		CommitDiffHunksTableName: d.commitDiffHunks,
+		FileBlameTableName:       d.fileBlame,
+		CommitTrailersTableName:  d.commitTrailers,
+		SubmodulesTableName:      d.submodules,
+		ReflogTableName:          d.reflog,
+		CommitNotesTableName:     d.commitNotes,
+		ObjectsTableName:         d.objects,
+		TagsTableName:            d.tags,
+		GitbaseErrorsTableName:   d.gitbaseErrors,
 	}
 }
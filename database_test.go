@@ -36,6 +36,16 @@ func TestDatabase_Tables(t *testing.T) {
 		CommitBlobsTableName,
 		FilesTableName,
 		CommitFilesTableName,
+		CommitDiffsTableName,
+		CommitDiffHunksTableName,
+		FileBlameTableName,
+		CommitTrailersTableName,
+		SubmodulesTableName,
+		ReflogTableName,
+		CommitNotesTableName,
+		ObjectsTableName,
+		TagsTableName,
+		GitbaseErrorsTableName,
 	}
 	sort.Strings(expected)
 
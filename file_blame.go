@@ -0,0 +1,360 @@
+package gitbase
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/blame"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+type fileBlameTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// FileBlameSchema is the schema for the file blame table.
+var FileBlameSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: FileBlameTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: FileBlameTableName},
+	{Name: "file_path", Type: sql.Text, Nullable: false, Source: FileBlameTableName},
+	{Name: "line_number", Type: sql.Int64, Nullable: false, Source: FileBlameTableName},
+	{Name: "origin_commit_hash", Type: sql.VarChar(40), Nullable: false, Source: FileBlameTableName},
+	{Name: "author_email", Type: sql.VarChar(254), Nullable: false, Source: FileBlameTableName},
+	{Name: "author_when", Type: sql.Timestamp, Nullable: false, Source: FileBlameTableName},
+	{Name: "line_text", Type: sql.Text, Nullable: false, Source: FileBlameTableName},
+}
+
+func newFileBlameTable(pool *RepositoryPool) *fileBlameTable {
+	return &fileBlameTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*fileBlameTable)(nil)
+var _ Squashable = (*fileBlameTable)(nil)
+
+func (fileBlameTable) isSquashable()   {}
+func (fileBlameTable) isGitbaseTable() {}
+
+func (t fileBlameTable) String() string {
+	return printTable(
+		FileBlameTableName,
+		FileBlameSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (fileBlameTable) Name() string { return FileBlameTableName }
+
+func (fileBlameTable) Schema() sql.Schema { return FileBlameSchema }
+
+func (t *fileBlameTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *fileBlameTable) Filters() []sql.Expression { return t.filters }
+
+func (t *fileBlameTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.FileBlameTable")
+	iter, err := rowIterWithSelectors(
+		ctx, FileBlameSchema, FileBlameTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			var paths []string
+			paths, err = selectors.textValues("file_path")
+			if err != nil {
+				return nil, err
+			}
+
+			return &fileBlameRowIter{
+				repo:         repo,
+				commitHashes: stringsToHashes(hashes),
+				paths:        paths,
+				skipper:      newGitErrorSkipper(ctx, FileBlameTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (fileBlameTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(FileBlameTableName, FileBlameSchema, filters)
+}
+
+func (fileBlameTable) handledColumns() []string {
+	return []string{"commit_hash", "repository_id", "file_path"}
+}
+
+// fileBlameLines iterates over the blamed lines of some files of a commit.
+type fileBlameLines struct {
+	commit *object.Commit
+	// paths are the files that are not blamed yet.
+	paths []string
+	path  string
+	lines []blame.Line
+	pos   int
+}
+
+// newFileBlameLines returns the blamed lines of the files of the commit in
+// the given paths, or of all its files if there are no paths.
+func newFileBlameLines(commit *object.Commit, paths []string) (*fileBlameLines, error) {
+	if len(paths) == 0 {
+		files, err := commit.Files()
+		if err != nil {
+			return nil, err
+		}
+
+		err = files.ForEach(func(f *object.File) error {
+			paths = append(paths, f.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileBlameLines{commit: commit, paths: paths}, nil
+}
+
+// next returns the path and the next blamed line. The lines of a file that
+// cannot be blamed are skipped after returning the error, and io.EOF is
+// returned when there are no more lines.
+func (l *fileBlameLines) next() (string, blame.Line, error) {
+	for l.pos >= len(l.lines) {
+		if len(l.paths) == 0 {
+			return "", blame.Line{}, io.EOF
+		}
+
+		l.path, l.paths = l.paths[0], l.paths[1:]
+		l.pos = 0
+
+		var err error
+		l.lines, err = blameFile(l.commit, l.path)
+		if err != nil {
+			return l.path, blame.Line{}, err
+		}
+	}
+
+	line := l.lines[l.pos]
+	l.pos++
+	return l.path, line, nil
+}
+
+// blameFile returns the blamed lines of the file in the path of the commit.
+// It returns no lines if there is no such file, or it's binary or bigger
+// than the maximum blob size.
+func blameFile(commit *object.Commit, path string) ([]blame.Line, error) {
+	f, err := commit.File(path)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Size > int64(blobsMaxSize) {
+		return nil, nil
+	}
+
+	bin, err := isBinary(&f.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	if bin {
+		return nil, nil
+	}
+
+	return blame.Blame(commit, path, blame.Options{})
+}
+
+// fileBlamePaths returns the paths the file_path column of the file_blame
+// table is compared to in the given filters, so only those files are
+// blamed.
+func fileBlamePaths(filters sql.Expression) ([]string, error) {
+	if filters == nil {
+		return nil, nil
+	}
+
+	var exprs []sql.Expression
+	var split func(sql.Expression)
+	split = func(e sql.Expression) {
+		if and, ok := e.(*expression.And); ok {
+			split(and.Left)
+			split(and.Right)
+			return
+		}
+
+		exprs = append(exprs, e)
+	}
+	split(filters)
+
+	selectors, _, err := classifyFilters(
+		FileBlameSchema, FileBlameTableName,
+		exprs,
+		"file_path",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectors.textValues("file_path")
+}
+
+func newFileBlameRow(repoID string, commit *object.Commit, path string, l blame.Line) sql.Row {
+	return sql.NewRow(
+		repoID,
+		commit.Hash.String(),
+		path,
+		int64(l.Number),
+		l.Commit.Hash.String(),
+		l.Commit.Author.Email,
+		l.Commit.Author.When,
+		l.Text,
+	)
+}
+
+type fileBlameRowIter struct {
+	repo    *Repository
+	commits object.CommitIter
+	commit  *object.Commit
+	lines   *fileBlameLines
+	skipper *gitErrorSkipper
+
+	// selectors for faster filtering
+	commitHashes []plumbing.Hash
+	paths        []string
+}
+
+func (i *fileBlameRowIter) init() error {
+	if len(i.commitHashes) > 0 {
+		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
+	} else {
+		iter, err := newCommitIter(i.repo, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.commits = iter
+	}
+
+	return nil
+}
+
+func (i *fileBlameRowIter) Next() (sql.Row, error) {
+	for {
+		if i.commits == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if i.lines == nil {
+			var err error
+			i.commit, err = i.commits.Next()
+			if err != nil {
+				if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo": i.repo.ID(),
+						"err":  err,
+					}).Error("skipped commit in file_blame")
+					continue
+				}
+
+				return nil, err
+			}
+
+			i.lines, err = newFileBlameLines(i.commit, i.paths)
+			if err != nil {
+				if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo":   i.repo.ID(),
+						"err":    err,
+						"commit": i.commit.Hash.String(),
+					}).Error("can't get files of commit")
+					i.lines = nil
+					continue
+				}
+
+				return nil, err
+			}
+		}
+
+		path, line, err := i.lines.next()
+		if err == io.EOF {
+			i.lines = nil
+			continue
+		}
+
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), i.commit.Hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo":   i.repo.ID(),
+					"err":    err,
+					"commit": i.commit.Hash.String(),
+					"path":   path,
+				}).Error("can't blame file")
+				continue
+			}
+
+			return nil, err
+		}
+
+		return newFileBlameRow(i.repo.ID(), i.commit, path, line), nil
+	}
+}
+
+func (i *fileBlameRowIter) Close() error {
+	if i.commits != nil {
+		i.commits.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
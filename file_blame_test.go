@@ -0,0 +1,65 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBlameTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newFileBlameTable(poolFromCtx(t, ctx)).WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, FileBlameTableName, "commit_hash", false),
+			expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+		),
+	})
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.NotEmpty(rows)
+
+	schema := table.Schema()
+	for idx, row := range rows {
+		require.NoError(schema.CheckRow(row), "row %d doesn't conform to schema", idx)
+		require.NotEqual("binary.jpg", row[2])
+	}
+}
+
+func TestFileBlameTablePushdown(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newFileBlameTable(poolFromCtx(t, ctx)).WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, FileBlameTableName, "commit_hash", false),
+			expression.NewLiteral("918c48b83bd081e863dbe1b80f8998f058cd8294", sql.Text),
+		),
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(2, sql.Text, FileBlameTableName, "file_path", false),
+			expression.NewLiteral(".gitignore", sql.Text),
+		),
+	})
+
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 12)
+
+	for i, row := range rows {
+		require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", row[1])
+		require.Equal(".gitignore", row[2])
+		require.Equal(int64(i), row[3])
+		require.Equal("b029517f6300c2da0f4b651b8642506cd6aaf45d", row[4])
+		require.Equal("mcuadros@gmail.com", row[5])
+	}
+
+	require.Equal("*.class", rows[0][7])
+}
@@ -121,7 +121,7 @@ func (r *filesTable) PartitionRows(
 
 				return newFilesIndexIter(
 					values,
-					session.Pool,
+					session.QueryPool(ctx),
 					shouldReadContent(r.projection),
 					stringsToHashes(treeHashes),
 					stringsToHashes(blobHashes),
@@ -130,12 +130,12 @@ func (r *filesTable) PartitionRows(
 			}
 
 			return &filesRowIter{
-				repo:          repo,
-				treeHashes:    stringsToHashes(treeHashes),
-				blobHashes:    stringsToHashes(blobHashes),
-				filePaths:     filePaths,
-				readContent:   shouldReadContent(r.projection),
-				skipGitErrors: shouldSkipErrors(ctx),
+				repo:        repo,
+				treeHashes:  stringsToHashes(treeHashes),
+				blobHashes:  stringsToHashes(blobHashes),
+				filePaths:   filePaths,
+				readContent: shouldReadContent(r.projection),
+				skipper:     newGitErrorSkipper(ctx, FilesTableName),
 			}, nil
 		},
 	)
@@ -186,8 +186,8 @@ type filesRowIter struct {
 	files    *object.FileIter
 	treeHash plumbing.Hash
 
-	readContent   bool
-	skipGitErrors bool
+	readContent bool
+	skipper     *gitErrorSkipper
 
 	// selectors for faster filtering
 	filePaths  []string
@@ -198,7 +198,7 @@ type filesRowIter struct {
 func (i *filesRowIter) init() error {
 	var err error
 	i.seen = make(map[plumbing.Hash]struct{})
-	i.commits, err = newCommitIter(i.repo, i.skipGitErrors)
+	i.commits, err = newCommitIter(i.repo, i.skipper)
 	return err
 }
 
@@ -229,7 +229,7 @@ func (i *filesRowIter) shouldVisitFile(file *object.File) bool {
 func (i *filesRowIter) Next() (sql.Row, error) {
 	if i.commits == nil {
 		if err := i.init(); err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 				return nil, io.EOF
 			}
 			return nil, err
@@ -241,7 +241,7 @@ func (i *filesRowIter) Next() (sql.Row, error) {
 			for {
 				commit, err := i.commits.Next()
 				if err != nil {
-					if err != io.EOF && i.skipGitErrors {
+					if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 						continue
 					}
 
@@ -256,7 +256,7 @@ func (i *filesRowIter) Next() (sql.Row, error) {
 				i.seen[commit.TreeHash] = struct{}{}
 
 				if i.files, err = commit.Files(); err != nil {
-					if i.skipGitErrors {
+					if i.skipper.skip(i.repo.ID(), commit.Hash, err) {
 						continue
 					}
 
@@ -274,7 +274,7 @@ func (i *filesRowIter) Next() (sql.Row, error) {
 				continue
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), i.treeHash, err) {
 				continue
 			}
 
@@ -416,7 +416,7 @@ func newFilesKeyValueIter(pool *RepositoryPool, repo *Repository, columns []stri
 		return nil, err
 	}
 
-	commits, err := newCommitIter(repo, false)
+	commits, err := newCommitIter(repo, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,125 @@
+package gitbase
+
+import (
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// gitbaseErrorsTable lists the git errors skipped by the queries of the
+// session. It's not partitioned by repository, as the errors are kept in the
+// session and not in the repositories.
+type gitbaseErrorsTable struct{}
+
+// GitbaseErrorsSchema is the schema for the gitbase_errors table.
+var GitbaseErrorsSchema = sql.Schema{
+	{Name: "query_id", Type: sql.Int64, Nullable: false, Source: GitbaseErrorsTableName},
+	{Name: "repository_id", Type: sql.Text, Nullable: true, Source: GitbaseErrorsTableName},
+	{Name: "table_name", Type: sql.Text, Nullable: true, Source: GitbaseErrorsTableName},
+	{Name: "object_hash", Type: sql.Text, Nullable: true, Source: GitbaseErrorsTableName},
+	{Name: "error", Type: sql.Text, Nullable: false, Source: GitbaseErrorsTableName},
+	{Name: "created_at", Type: sql.Timestamp, Nullable: false, Source: GitbaseErrorsTableName},
+}
+
+func newGitbaseErrorsTable() *gitbaseErrorsTable {
+	return new(gitbaseErrorsTable)
+}
+
+var _ sql.Table = (*gitbaseErrorsTable)(nil)
+
+func (gitbaseErrorsTable) Name() string {
+	return GitbaseErrorsTableName
+}
+
+func (gitbaseErrorsTable) Schema() sql.Schema {
+	return GitbaseErrorsSchema
+}
+
+func (gitbaseErrorsTable) String() string {
+	return printTable(GitbaseErrorsTableName, GitbaseErrorsSchema, nil, nil, nil)
+}
+
+func (gitbaseErrorsTable) Partitions(*sql.Context) (sql.PartitionIter, error) {
+	return &gitbaseErrorsPartitionIter{}, nil
+}
+
+func (gitbaseErrorsTable) PartitionRows(
+	ctx *sql.Context,
+	_ sql.Partition,
+) (sql.RowIter, error) {
+	s, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gitbaseErrorsRowIter{errors: s.GitErrors()}, nil
+}
+
+// gitbaseErrorsPartition is the only partition of the gitbase_errors table.
+type gitbaseErrorsPartition struct{}
+
+func (gitbaseErrorsPartition) Key() []byte {
+	return []byte(GitbaseErrorsTableName)
+}
+
+type gitbaseErrorsPartitionIter struct {
+	done bool
+}
+
+func (i *gitbaseErrorsPartitionIter) Next() (sql.Partition, error) {
+	if i.done {
+		return nil, io.EOF
+	}
+
+	i.done = true
+	return gitbaseErrorsPartition{}, nil
+}
+
+func (i *gitbaseErrorsPartitionIter) Close() error {
+	i.done = true
+	return nil
+}
+
+type gitbaseErrorsRowIter struct {
+	errors []GitError
+	pos    int
+}
+
+func (i *gitbaseErrorsRowIter) Next() (sql.Row, error) {
+	if i.pos >= len(i.errors) {
+		return nil, io.EOF
+	}
+
+	e := i.errors[i.pos]
+	i.pos++
+
+	return gitErrorToRow(e), nil
+}
+
+func (i *gitbaseErrorsRowIter) Close() error {
+	return nil
+}
+
+func gitErrorToRow(e GitError) sql.Row {
+	var repo, table, hash interface{}
+	if e.RepositoryID != "" {
+		repo = e.RepositoryID
+	}
+
+	if e.Table != "" {
+		table = e.Table
+	}
+
+	if !e.Hash.IsZero() {
+		hash = e.Hash.String()
+	}
+
+	return sql.NewRow(
+		int64(e.QueryID),
+		repo,
+		table,
+		hash,
+		e.Error,
+		e.Time,
+	)
+}
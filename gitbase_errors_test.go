@@ -0,0 +1,62 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitbaseErrorsTable(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	require := require.New(t)
+
+	ctx, cleanup := setupErrorRepos(t)
+	defer cleanup()
+
+	table := getTable(t, GitbaseErrorsTableName, ctx)
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 0)
+
+	_, err = tableToRows(ctx, getTable(t, CommitsTableName, ctx))
+	require.NoError(err)
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+
+	errs := session.GitErrors()
+	require.NotEmpty(errs)
+	for _, e := range errs {
+		require.Contains([]string{"packfile", "index"}, e.RepositoryID)
+		require.Equal(CommitsTableName, e.Table)
+		require.NotEmpty(e.Error)
+		require.False(e.Time.IsZero())
+	}
+
+	rows, err = tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, len(errs))
+
+	for i, row := range rows {
+		require.Len(row, len(GitbaseErrorsSchema))
+		require.Equal(errs[i].RepositoryID, row[1])
+		require.Equal(CommitsTableName, row[2])
+		require.Equal(errs[i].Error, row[4])
+	}
+}
+
+func TestGitbaseErrorsNotSkipped(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cleanup := setupErrorRepos(t)
+	defer cleanup()
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+	session.SkipGitErrors = false
+
+	_, err = tableToRows(ctx, getTable(t, CommitsTableName, ctx))
+	require.Error(err)
+	require.Empty(session.GitErrors())
+}
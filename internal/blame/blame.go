@@ -0,0 +1,343 @@
+// Package blame finds the commit that last changed each line of a file.
+package blame
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/hex"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+)
+
+// IgnoreRevsFile is the name of the file, in the root directory of the
+// repository, with the commits ignored when blaming files.
+const IgnoreRevsFile = ".git-blame-ignore-revs"
+
+// Types of the changes returned by diff.Do.
+const (
+	diffDelete = -1
+	diffEqual  = 0
+	diffInsert = 1
+)
+
+// Line is a line of a blamed file.
+type Line struct {
+	// Number is the number of the line in the file, starting at 0.
+	Number int
+	// OrigNumber is the number of the line in the file as it was in the
+	// commit that last changed it, starting at 0.
+	OrigNumber int
+	// Commit is the commit that last changed the line.
+	Commit *object.Commit
+	// Text is the content of the line, without the line ending.
+	Text string
+}
+
+// Options of a blame.
+type Options struct {
+	// IgnoreRevs are commits whose changes are ignored. Lines changed by
+	// them are blamed on the commit that last changed the line they
+	// replaced, if any.
+	IgnoreRevs map[plumbing.Hash]struct{}
+}
+
+// Blame returns the lines of the file in the given path of the commit,
+// with the commit that last changed each one of them. As git does, the
+// history is followed through every parent with the same content, and
+// lines not changed in a merge are blamed on the first parent they come
+// from. Renamed files are not followed.
+func Blame(c *object.Commit, path string, opts Options) ([]Line, error) {
+	f, err := c.File(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := splitLines(content)
+	b := &blamer{
+		path:    path,
+		opts:    opts,
+		result:  make([]Line, len(lines)),
+		pending: make(map[plumbing.Hash]*suspect),
+	}
+
+	origins := make([]origin, len(lines))
+	for i := range origins {
+		origins[i] = origin{line: i, orig: i}
+	}
+	b.push(c, f.Hash, content, origins)
+
+	for b.queue.Len() > 0 {
+		s := heap.Pop(&b.queue).(*suspect)
+		delete(b.pending, s.commit.Hash)
+		if err := b.blame(s); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, l := range lines {
+		b.result[i].Number = i
+		b.result[i].Text = l
+	}
+
+	return b.result, nil
+}
+
+// ReadIgnoreRevs returns the commits listed in the IgnoreRevsFile of the
+// commit. The file has a full commit hash in each line, and comments
+// starting with #. No commits are returned if the file does not exist.
+func ReadIgnoreRevs(c *object.Commit) (map[plumbing.Hash]struct{}, error) {
+	f, err := c.File(IgnoreRevsFile)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	revs := make(map[plumbing.Hash]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		line = strings.TrimSpace(line)
+		if len(line) != 40 {
+			continue
+		}
+
+		if _, err := hex.DecodeString(line); err != nil {
+			continue
+		}
+
+		revs[plumbing.NewHash(line)] = struct{}{}
+	}
+
+	return revs, scanner.Err()
+}
+
+// origin is a line of the blamed file which is in a line of the file of a
+// suspect.
+type origin struct {
+	// line is the number of the line in the blamed file.
+	line int
+	// orig is the number of the line in the file of the suspect.
+	orig int
+}
+
+// suspect is a commit that may have changed some lines of the file.
+type suspect struct {
+	commit  *object.Commit
+	blob    plumbing.Hash
+	content string
+	origins []origin
+}
+
+// suspects is a queue of suspects with the most recent commits first, so
+// the lines of a commit are known before its parents are blamed.
+type suspects []*suspect
+
+func (s suspects) Len() int { return len(s) }
+func (s suspects) Less(i, j int) bool {
+	return s[i].commit.Committer.When.After(s[j].commit.Committer.When)
+}
+func (s suspects) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *suspects) Push(x interface{}) { *s = append(*s, x.(*suspect)) }
+func (s *suspects) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[:n-1]
+	return x
+}
+
+type blamer struct {
+	path    string
+	opts    Options
+	result  []Line
+	queue   suspects
+	pending map[plumbing.Hash]*suspect
+}
+
+// push adds the lines to the commit, which is added to the queue if it
+// does not have lines yet.
+func (b *blamer) push(
+	c *object.Commit,
+	blob plumbing.Hash,
+	content string,
+	origins []origin,
+) {
+	if s, ok := b.pending[c.Hash]; ok {
+		s.origins = append(s.origins, origins...)
+		return
+	}
+
+	s := &suspect{commit: c, blob: blob, content: content, origins: origins}
+	b.pending[c.Hash] = s
+	heap.Push(&b.queue, s)
+}
+
+// blame passes the lines of the suspect not changed by its commit to its
+// parents, and blames the commit for the rest of them.
+func (b *blamer) blame(s *suspect) error {
+	origins := s.origins
+
+	// The first parent with the file is where the lines changed by an
+	// ignored commit are looked for.
+	var first struct {
+		commit  *object.Commit
+		blob    plumbing.Hash
+		content string
+		similar []int
+	}
+
+	err := s.commit.Parents().ForEach(func(p *object.Commit) error {
+		if len(origins) == 0 {
+			return storer.ErrStop
+		}
+
+		f, err := p.File(b.path)
+		if err == object.ErrFileNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if f.Hash == s.blob {
+			b.push(p, f.Hash, s.content, origins)
+			origins = nil
+			return storer.ErrStop
+		}
+
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+
+		equal, similar := mapLines(content, s.content)
+		var passed, rest []origin
+		for _, o := range origins {
+			if l := equal[o.orig]; l >= 0 {
+				passed = append(passed, origin{line: o.line, orig: l})
+			} else {
+				rest = append(rest, o)
+			}
+		}
+
+		if len(passed) > 0 {
+			b.push(p, f.Hash, content, passed)
+		}
+
+		if first.commit == nil {
+			first.commit, first.blob = p, f.Hash
+			first.content, first.similar = content, similar
+		}
+
+		origins = rest
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, ok := b.opts.IgnoreRevs[s.commit.Hash]; ok && first.commit != nil {
+		var passed, rest []origin
+		for _, o := range origins {
+			if l := first.similar[o.orig]; l >= 0 {
+				passed = append(passed, origin{line: o.line, orig: l})
+			} else {
+				rest = append(rest, o)
+			}
+		}
+
+		if len(passed) > 0 {
+			b.push(first.commit, first.blob, first.content, passed)
+		}
+
+		origins = rest
+	}
+
+	for _, o := range origins {
+		b.result[o.line] = Line{OrigNumber: o.orig, Commit: s.commit}
+	}
+
+	return nil
+}
+
+// mapLines returns, for each line of to, the number of the same line in
+// from, and the number of the line it replaced, or -1 if there is none.
+// A line added in place of some removed ones replaces the one with the
+// same offset in the change.
+func mapLines(from, to string) (equal, similar []int) {
+	n := countLines(to)
+	equal = make([]int, n)
+	similar = make([]int, n)
+
+	var src, dst, start, deleted, inserted int
+	for _, d := range diff.Do(from, to) {
+		lines := countLines(d.Text)
+		switch d.Type {
+		case diffEqual:
+			for i := 0; i < lines; i++ {
+				equal[dst], similar[dst] = src, src
+				src++
+				dst++
+			}
+			start, deleted, inserted = src, 0, 0
+		case diffDelete:
+			deleted += lines
+			src += lines
+		case diffInsert:
+			for i := 0; i < lines; i++ {
+				equal[dst], similar[dst] = -1, -1
+				if inserted < deleted {
+					similar[dst] = start + inserted
+				}
+				inserted++
+				dst++
+			}
+		}
+	}
+
+	return equal, similar
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+
+	n := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		n++
+	}
+
+	return n
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,200 @@
+package blame
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestBlame(t *testing.T) {
+	r := newTestRepo(t)
+	c1 := r.commit(1, map[string]string{"file": "a\nb\nc\n"})
+	c2 := r.commit(2, map[string]string{"file": "a\nB\nc\nd\n"}, c1)
+	c3 := r.commit(3, map[string]string{"file": "A\nB\nc\nd\n"}, c2)
+	c4 := r.commit(4, map[string]string{"file": "a\nB\nc\nd\ne\n"}, c2)
+	c5 := r.commit(5, map[string]string{"file": "A\nB\nc\nd\ne\n"}, c3, c4)
+	c6 := r.commit(6, map[string]string{"file": "x\na\nb\nc"}, c1)
+	c7 := r.commit(7, map[string]string{"other": "a\nb\nc\n"}, c6)
+	c8 := r.commit(8, map[string]string{"file": "a\nb\nc\n"}, c7)
+
+	type blamed struct {
+		commit *object.Commit
+		orig   int
+		text   string
+	}
+
+	testCases := []struct {
+		name     string
+		commit   *object.Commit
+		ignore   []*object.Commit
+		expected []blamed
+	}{
+		{
+			"changed lines",
+			c3,
+			nil,
+			[]blamed{{c3, 0, "A"}, {c2, 1, "B"}, {c1, 2, "c"}, {c2, 3, "d"}},
+		},
+		{
+			"ignored commit",
+			c3,
+			[]*object.Commit{c3},
+			[]blamed{{c1, 0, "A"}, {c2, 1, "B"}, {c1, 2, "c"}, {c2, 3, "d"}},
+		},
+		{
+			"ignored commit adding lines",
+			c2,
+			[]*object.Commit{c2},
+			[]blamed{{c1, 0, "a"}, {c1, 1, "B"}, {c1, 2, "c"}, {c2, 3, "d"}},
+		},
+		{
+			"merge",
+			c5,
+			nil,
+			[]blamed{{c3, 0, "A"}, {c2, 1, "B"}, {c1, 2, "c"}, {c2, 3, "d"}, {c4, 4, "e"}},
+		},
+		{
+			"moved lines",
+			c6,
+			nil,
+			[]blamed{{c6, 0, "x"}, {c1, 0, "a"}, {c1, 1, "b"}, {c6, 3, "c"}},
+		},
+		{
+			"removed and added again",
+			c8,
+			nil,
+			[]blamed{{c8, 0, "a"}, {c8, 1, "b"}, {c8, 2, "c"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			if len(tt.ignore) > 0 {
+				opts.IgnoreRevs = make(map[plumbing.Hash]struct{})
+				for _, c := range tt.ignore {
+					opts.IgnoreRevs[c.Hash] = struct{}{}
+				}
+			}
+
+			lines, err := Blame(tt.commit, "file", opts)
+			require.NoError(t, err)
+			require.Len(t, lines, len(tt.expected))
+
+			for i, l := range lines {
+				e := tt.expected[i]
+				require.Equal(t, i, l.Number)
+				require.Equal(t, e.commit.Hash, l.Commit.Hash, "line %d", i)
+				require.Equal(t, e.orig, l.OrigNumber, "line %d", i)
+				require.Equal(t, e.text, l.Text, "line %d", i)
+			}
+		})
+	}
+
+	_, err := Blame(c7, "file", Options{})
+	require.Equal(t, object.ErrFileNotFound, err)
+}
+
+func TestReadIgnoreRevs(t *testing.T) {
+	require := require.New(t)
+
+	r := newTestRepo(t)
+	c1 := r.commit(1, map[string]string{"file": "a\n"})
+
+	revs, err := ReadIgnoreRevs(c1)
+	require.NoError(err)
+	require.Len(revs, 0)
+
+	c2 := r.commit(2, map[string]string{
+		IgnoreRevsFile: fmt.Sprintf(
+			"# Reformat\n%s # comment\n\n  not-a-hash\n%s\n",
+			c1.Hash, c1.Hash.String()[:7],
+		),
+	}, c1)
+
+	revs, err = ReadIgnoreRevs(c2)
+	require.NoError(err)
+	require.Equal(map[plumbing.Hash]struct{}{c1.Hash: {}}, revs)
+}
+
+type testRepo struct {
+	t *testing.T
+	s *memory.Storage
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	return &testRepo{t, memory.NewStorage()}
+}
+
+// commit creates a commit with the given files in the root directory,
+// made at the given number of hours since the epoch.
+func (r *testRepo) commit(
+	hours int,
+	files map[string]string,
+	parents ...*object.Commit,
+) *object.Commit {
+	r.t.Helper()
+	require := require.New(r.t)
+
+	var tree object.Tree
+	for name, content := range files {
+		obj := r.s.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(err)
+		_, err = w.Write([]byte(content))
+		require.NoError(err)
+		require.NoError(w.Close())
+
+		hash, err := r.s.SetEncodedObject(obj)
+		require.NoError(err)
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{
+			Name: name,
+			Mode: filemode.Regular,
+			Hash: hash,
+		})
+	}
+
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return tree.Entries[i].Name < tree.Entries[j].Name
+	})
+
+	obj := r.s.NewEncodedObject()
+	require.NoError(tree.Encode(obj))
+	treeHash, err := r.s.SetEncodedObject(obj)
+	require.NoError(err)
+
+	sig := object.Signature{
+		Name:  "John Doe",
+		Email: "john@doe.com",
+		When:  time.Unix(int64(hours)*3600, 0).UTC(),
+	}
+
+	commit := &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   fmt.Sprintf("commit %d", hours),
+		TreeHash:  treeHash,
+	}
+
+	for _, p := range parents {
+		commit.ParentHashes = append(commit.ParentHashes, p.Hash)
+	}
+
+	obj = r.s.NewEncodedObject()
+	require.NoError(commit.Encode(obj))
+	hash, err := r.s.SetEncodedObject(obj)
+	require.NoError(err)
+
+	c, err := object.GetCommit(r.s, hash)
+	require.NoError(err)
+	return c
+}
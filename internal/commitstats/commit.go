@@ -36,9 +36,10 @@ func (s *CommitStats) String() string {
 
 // Calculate calculates the CommitStats for from commit to another.
 // if from is nil the first parent is used, if the commit is orphan the stats
-// are compared against a empty commit.
-func Calculate(r *git.Repository, from, to *object.Commit) (*CommitStats, error) {
-	fs, err := CalculateByFile(r, from, to)
+// are compared against a empty commit. Renamed and copied files are detected
+// as configured in the given options.
+func Calculate(r *git.Repository, from, to *object.Commit, opts Options) (*CommitStats, error) {
+	fs, err := CalculateByFile(r, from, to, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +91,7 @@ func TestCalculate(t *testing.T) {
 				require.NoError(err)
 			}
 
-			stats, err := Calculate(r, from, to)
+			stats, err := Calculate(r, from, to, Options{})
 			require.NoError(err)
 
 			assert.Equal(t, test.expected, stats)
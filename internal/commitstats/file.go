@@ -9,6 +9,7 @@ import (
 
 	"github.com/hhatto/gocloc"
 	"github.com/src-d/enry/v2"
+	"github.com/src-d/gitbase/internal/renames"
 	"github.com/src-d/go-git/utils/binary"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
@@ -16,6 +17,9 @@ import (
 	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
 )
 
+// Options are the options used to calculate the stats.
+type Options = renames.Options
+
 // CommitFileStats represents the stats for a file in a commit.
 type CommitFileStats struct {
 	Path     string
@@ -25,12 +29,18 @@ type CommitFileStats struct {
 	Blank    KindStats
 	Other    KindStats
 	Total    KindStats
+	// OldPath is the previous path of the file if it was renamed or copied.
+	OldPath string
+	// Similarity is the similarity percentage between the old and new
+	// contents of a renamed or copied file.
+	Similarity int
 }
 
 // CalculateByFile calculates the stats for all files from a commit to another.
 // If from is nil, the first parent is used. if the commit is an orphan,
-// the stats are compared against an empty commit.
-func CalculateByFile(r *git.Repository, from, to *object.Commit) ([]CommitFileStats, error) {
+// the stats are compared against an empty commit. Renamed and copied files
+// are detected as configured in the given options.
+func CalculateByFile(r *git.Repository, from, to *object.Commit, opts Options) ([]CommitFileStats, error) {
 	var err error
 	if to.NumParents() != 0 && from == nil {
 		from, err = to.Parent(0)
@@ -43,7 +53,7 @@ func CalculateByFile(r *git.Repository, from, to *object.Commit) ([]CommitFileSt
 		return fileStatsFromCommit(to)
 	}
 
-	return fileStatsFromDiff(r, from, to)
+	return fileStatsFromDiff(r, from, to, opts)
 }
 
 func fileStatsFromCommit(c *object.Commit) ([]CommitFileStats, error) {
@@ -99,14 +109,19 @@ func commitFileStatsFromFileStats(fi fileStats, path, lang string) CommitFileSta
 	return stats
 }
 
-func fileStatsFromDiff(r *git.Repository, from, to *object.Commit) ([]CommitFileStats, error) {
+func fileStatsFromDiff(r *git.Repository, from, to *object.Commit, opts Options) ([]CommitFileStats, error) {
 	ch, err := computeDiff(from, to)
 	if err != nil {
 		return nil, err
 	}
 
+	changes, err := renames.Detect(r.Storer, ch, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	var result []CommitFileStats
-	for _, change := range ch {
+	for _, change := range changes {
 		s, err := fileStatsFromChange(r, change)
 		if err != nil {
 			if err == errIgnored {
@@ -121,7 +136,7 @@ func fileStatsFromDiff(r *git.Repository, from, to *object.Commit) ([]CommitFile
 	return result, nil
 }
 
-func fileStatsFromChange(r *git.Repository, ch *object.Change) (CommitFileStats, error) {
+func fileStatsFromChange(r *git.Repository, ch renames.Change) (CommitFileStats, error) {
 	a, err := ch.Action()
 	if err != nil {
 		return CommitFileStats{}, err
@@ -167,7 +182,13 @@ func fileStatsFromChange(r *git.Repository, ch *object.Change) (CommitFileStats,
 		fi = dst
 	}
 
-	return commitFileStatsFromFileStats(fi, name, getLanguage(name)), nil
+	stats := commitFileStatsFromFileStats(fi, name, getLanguage(name))
+	if ch.Similarity > 0 {
+		stats.OldPath = ch.From.Name
+		stats.Similarity = ch.Similarity
+	}
+
+	return stats, nil
 }
 
 var errIgnored = errors.New("ignored file")
@@ -1,16 +1,22 @@
 package commitstats
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/src-d/gitbase/internal/renames"
 	fixtures "github.com/src-d/go-git-fixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
 func TestNewFileStats(t *testing.T) {
@@ -244,10 +250,81 @@ func TestCalculateByFile(t *testing.T) {
 				require.NoError(err)
 			}
 
-			stats, err := CalculateByFile(r, from, to)
+			stats, err := CalculateByFile(r, from, to, Options{})
 			require.NoError(err)
 
 			assert.Equal(t, test.expected, stats)
 		})
 	}
 }
+
+func TestFileStatsFromRename(t *testing.T) {
+	require := require.New(t)
+
+	r, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(err)
+
+	var lines string
+	for i := 0; i < 10; i++ {
+		lines += fmt.Sprintf("line %d\n", i)
+	}
+
+	from := testTree(t, r, map[string]string{"a.go": lines})
+	to := testTree(t, r, map[string]string{
+		"moved.go": strings.Replace(lines, "line 9", "LINE 9", 1),
+	})
+
+	changes, err := object.DiffTree(from, to)
+	require.NoError(err)
+
+	fcs, err := renames.Detect(r.Storer, changes, Options{RenameSimilarity: 50})
+	require.NoError(err)
+	require.Len(fcs, 1)
+
+	stats, err := fileStatsFromChange(r, fcs[0])
+	require.NoError(err)
+	require.Equal("moved.go", stats.Path)
+	require.Equal("a.go", stats.OldPath)
+	require.Equal(90, stats.Similarity)
+	require.Equal(KindStats{Additions: 1, Deletions: 1}, stats.Total)
+}
+
+func testTree(t *testing.T, r *git.Repository, files map[string]string) *object.Tree {
+	t.Helper()
+	require := require.New(t)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tree object.Tree
+	for _, name := range names {
+		obj := r.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(err)
+		_, err = w.Write([]byte(files[name]))
+		require.NoError(err)
+		require.NoError(w.Close())
+
+		hash, err := r.Storer.SetEncodedObject(obj)
+		require.NoError(err)
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{
+			Name: name,
+			Mode: filemode.Regular,
+			Hash: hash,
+		})
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(tree.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(err)
+
+	result, err := r.TreeObject(hash)
+	require.NoError(err)
+	return result
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// arrowBatchSize is the number of rows of each record batch.
+const arrowBatchSize = 4096
+
+// arrowWriter writes the rows in an Arrow IPC file, in batches of
+// arrowBatchSize rows. All columns are nullable. Timestamps are stored with
+// millisecond precision, JSON documents as strings and arrays as lists of
+// strings.
+type arrowWriter struct {
+	cols []column
+	w    *ipc.FileWriter
+	b    *array.RecordBuilder
+	rows int
+}
+
+func newArrowWriter(w io.WriteSeeker, cols []column) (*arrowWriter, error) {
+	var fields = make([]arrow.Field, len(cols))
+	for i, c := range cols {
+		fields[i] = arrow.Field{
+			Name:     c.name,
+			Type:     arrowType(c.kind),
+			Nullable: true,
+		}
+	}
+
+	mem := memory.NewGoAllocator()
+	schema := arrow.NewSchema(fields, nil)
+	fw, err := ipc.NewFileWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	if err != nil {
+		return nil, err
+	}
+
+	return &arrowWriter{
+		cols: cols,
+		w:    fw,
+		b:    array.NewRecordBuilder(mem, schema),
+	}, nil
+}
+
+func arrowType(k kind) arrow.DataType {
+	switch k {
+	case kindBinary:
+		return arrow.BinaryTypes.Binary
+	case kindInt:
+		return arrow.PrimitiveTypes.Int64
+	case kindUint:
+		return arrow.PrimitiveTypes.Uint64
+	case kindFloat:
+		return arrow.PrimitiveTypes.Float64
+	case kindBool:
+		return arrow.FixedWidthTypes.Boolean
+	case kindTimestamp:
+		return arrow.FixedWidthTypes.Timestamp_ms
+	case kindList:
+		return arrow.ListOf(arrow.BinaryTypes.String)
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func (w *arrowWriter) Write(row sql.Row) error {
+	for i, c := range w.cols {
+		field := w.b.Field(i)
+		if i >= len(row) || row[i] == nil {
+			field.AppendNull()
+			continue
+		}
+
+		v, err := convert(c.kind, row[i])
+		if err != nil {
+			return err
+		}
+
+		switch b := field.(type) {
+		case *array.BinaryBuilder:
+			b.Append(v.([]byte))
+		case *array.Int64Builder:
+			b.Append(v.(int64))
+		case *array.Uint64Builder:
+			b.Append(v.(uint64))
+		case *array.Float64Builder:
+			b.Append(v.(float64))
+		case *array.BooleanBuilder:
+			b.Append(v.(bool))
+		case *array.TimestampBuilder:
+			ms := v.(time.Time).UnixNano() / int64(time.Millisecond)
+			b.Append(arrow.Timestamp(ms))
+		case *array.ListBuilder:
+			b.Append(true)
+			values := b.ValueBuilder().(*array.StringBuilder)
+			for _, s := range v.([]string) {
+				values.Append(s)
+			}
+		case *array.StringBuilder:
+			b.Append(v.(string))
+		}
+	}
+
+	w.rows++
+	if w.rows >= arrowBatchSize {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *arrowWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	rec := w.b.NewRecord()
+	defer rec.Release()
+
+	w.rows = 0
+	return w.w.Write(rec)
+}
+
+func (w *arrowWriter) Close() error {
+	defer w.b.Release()
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	return w.w.Close()
+}
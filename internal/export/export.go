@@ -0,0 +1,328 @@
+// Package export writes the results of queries to files in formats that can
+// be loaded by other tools, such as Parquet, Arrow IPC or JSON lines.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// Formats of the exported files.
+const (
+	JSONL   = "jsonl"
+	Parquet = "parquet"
+	Arrow   = "arrow"
+)
+
+// ErrUnknownFormat is returned when the format to export is not supported.
+var ErrUnknownFormat = errors.NewKind("unknown export format: %s")
+
+// Options are the options used to export the rows.
+type Options struct {
+	// Format is the format of the files.
+	Format string
+	// Dir is the directory where the files are written. It's created if it
+	// does not exist.
+	Dir string
+	// MaxFileSize is the size in bytes of the row data after which a new file
+	// is started. The files may be a bit bigger, as rows are never split
+	// between files, or smaller, depending on the encoding and compression of
+	// the format. Everything is written to a single file if it's 0.
+	MaxFileSize int64
+}
+
+// Writer writes rows to a file.
+type Writer interface {
+	// Write writes a row.
+	Write(sql.Row) error
+	// Close writes any pending rows and the footer of the file, if any. It
+	// does not close the underlying file.
+	Close() error
+}
+
+// NewWriter creates a writer of the given format for rows with the given
+// schema.
+func NewWriter(format string, w io.WriteSeeker, schema sql.Schema) (Writer, error) {
+	cols := columnsOf(schema)
+	switch format {
+	case JSONL:
+		return newJSONLWriter(w, cols), nil
+	case Parquet:
+		return newParquetWriter(w, cols)
+	case Arrow:
+		return newArrowWriter(w, cols)
+	default:
+		return nil, ErrUnknownFormat.New(format)
+	}
+}
+
+// Extension returns the file extension used for the given format.
+func Extension(format string) string {
+	return "." + format
+}
+
+// Export writes all the rows of the iterator to files in the given options
+// and returns their paths. The iterator is not closed. At least one file is
+// always written, even if there are no rows, so the schema is kept.
+func Export(
+	schema sql.Schema,
+	iter sql.RowIter,
+	opts Options,
+) ([]string, error) {
+	switch opts.Format {
+	case JSONL, Parquet, Arrow:
+	default:
+		return nil, ErrUnknownFormat.New(opts.Format)
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	var f *os.File
+	var w Writer
+	var size int64
+
+	closeFile := func() error {
+		if err := w.Close(); err != nil {
+			_ = f.Close()
+			return err
+		}
+
+		w = nil
+		return f.Close()
+	}
+
+	defer func() {
+		if w != nil {
+			_ = w.Close()
+			_ = f.Close()
+		}
+	}()
+
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return files, err
+		}
+
+		if w == nil {
+			f, w, err = newFile(opts, len(files), schema)
+			if err != nil {
+				return files, err
+			}
+
+			files = append(files, f.Name())
+			size = 0
+		}
+
+		if err := w.Write(row); err != nil {
+			return files, err
+		}
+
+		size += rowSize(row)
+		if opts.MaxFileSize > 0 && size >= opts.MaxFileSize {
+			if err := closeFile(); err != nil {
+				return files, err
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		var err error
+		f, w, err = newFile(opts, 0, schema)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, f.Name())
+	}
+
+	if w != nil {
+		if err := closeFile(); err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+func newFile(opts Options, n int, schema sql.Schema) (*os.File, Writer, error) {
+	path := filepath.Join(
+		opts.Dir,
+		fmt.Sprintf("part-%05d%s", n, Extension(opts.Format)),
+	)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w, err := NewWriter(opts.Format, f, schema)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+
+	return f, w, nil
+}
+
+// rowSize returns an estimation of the size of the data in a row.
+func rowSize(row sql.Row) int64 {
+	var size int64
+	for _, v := range row {
+		size += valueSize(v)
+	}
+	return size
+}
+
+func valueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case []interface{}:
+		var size int64
+		for _, e := range v {
+			size += valueSize(e)
+		}
+		return size
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int64, uint64, float64, int, uint, time.Time:
+		return 8
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return 0
+		}
+		return int64(len(bs))
+	}
+}
+
+// kind is the kind of values of a column, which decides its type in the
+// exported files.
+type kind byte
+
+const (
+	kindString kind = iota
+	kindBinary
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+	kindTimestamp
+	kindJSON
+	kindList
+)
+
+type column struct {
+	name string
+	kind kind
+}
+
+func columnsOf(schema sql.Schema) []column {
+	var cols = make([]column, len(schema))
+	for i, c := range schema {
+		cols[i] = column{name: c.Name, kind: kindOf(c.Type)}
+	}
+	return cols
+}
+
+func kindOf(t sql.Type) kind {
+	switch {
+	case sql.IsArray(t):
+		return kindList
+	case t == sql.Boolean:
+		return kindBool
+	case t == sql.Blob:
+		return kindBinary
+	case t == sql.JSON:
+		return kindJSON
+	case sql.IsTime(t):
+		return kindTimestamp
+	case sql.IsSigned(t):
+		return kindInt
+	case sql.IsUnsigned(t):
+		return kindUint
+	case sql.IsDecimal(t):
+		return kindFloat
+	default:
+		return kindString
+	}
+}
+
+// convert returns the value converted to the Go type used for the given
+// kind: string, []byte, int64, uint64, float64, bool, time.Time, string with
+// the JSON document or []string.
+func convert(k kind, v interface{}) (interface{}, error) {
+	switch k {
+	case kindBinary:
+		return sql.Blob.Convert(v)
+	case kindInt:
+		return sql.Int64.Convert(v)
+	case kindUint:
+		return sql.Uint64.Convert(v)
+	case kindFloat:
+		return sql.Float64.Convert(v)
+	case kindBool:
+		return sql.Boolean.Convert(v)
+	case kindTimestamp:
+		return sql.Timestamp.Convert(v)
+	case kindJSON:
+		return jsonString(v)
+	case kindList:
+		elems, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expecting array, got %T", v)
+		}
+
+		var result = make([]string, len(elems))
+		for i, e := range elems {
+			s, err := jsonString(e)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = s
+		}
+		return result, nil
+	default:
+		return sql.Text.Convert(v)
+	}
+}
+
+// jsonString returns the given value as a string, encoding it as JSON unless
+// it's already a string.
+func jsonString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bs), nil
+	}
+}
@@ -0,0 +1,284 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+var testSchema = sql.Schema{
+	{Name: "commit_hash", Type: sql.VarChar(40)},
+	{Name: "commit_author_when", Type: sql.Timestamp},
+	{Name: "commit_parents", Type: sql.Array(sql.VarChar(40))},
+	{Name: "blob_size", Type: sql.Int64},
+	{Name: "blob_content", Type: sql.Blob, Nullable: true},
+	{Name: "is_vendor", Type: sql.Boolean},
+	{Name: "commit_stats(commit_hash)", Type: sql.JSON, Nullable: true},
+}
+
+var testRows = []sql.Row{
+	sql.NewRow(
+		"e8d3ffab552895c19b9fcf7aa264d277cde33881",
+		time.Date(2015, 3, 31, 11, 47, 14, 0, time.UTC),
+		[]interface{}{
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+		},
+		int64(5),
+		[]byte{0, 1, 2},
+		true,
+		map[string]interface{}{"files": 2},
+	),
+	sql.NewRow(
+		"918c48b83bd081e863dbe1b80f8998f058cd8294",
+		time.Date(2015, 3, 31, 11, 44, 52, 0, time.UTC),
+		[]interface{}{},
+		int64(0),
+		nil,
+		false,
+		nil,
+	),
+}
+
+func setupDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gitbase-export")
+	require.NoError(t, err)
+	return dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	require := require.New(t)
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	files, err := Export(testSchema, sql.RowsToRowIter(testRows...), Options{
+		Format: JSONL,
+		Dir:    dir,
+	})
+	require.NoError(err)
+	require.Equal([]string{filepath.Join(dir, "part-00000.jsonl")}, files)
+
+	content, err := ioutil.ReadFile(files[0])
+	require.NoError(err)
+
+	expected := `{"commit_hash":"e8d3ffab552895c19b9fcf7aa264d277cde33881",` +
+		`"commit_author_when":"2015-03-31T11:47:14Z",` +
+		`"commit_parents":["918c48b83bd081e863dbe1b80f8998f058cd8294","af2d6a6954d532f8ffb47615169c8fdf9d383a1a"],` +
+		`"blob_size":5,"blob_content":"AAEC","is_vendor":true,` +
+		`"commit_stats(commit_hash)":{"files":2}}` + "\n" +
+		`{"commit_hash":"918c48b83bd081e863dbe1b80f8998f058cd8294",` +
+		`"commit_author_when":"2015-03-31T11:44:52Z",` +
+		`"commit_parents":[],"blob_size":0,"blob_content":null,"is_vendor":false,` +
+		`"commit_stats(commit_hash)":null}` + "\n"
+	require.Equal(expected, string(content))
+}
+
+func TestExportMaxFileSize(t *testing.T) {
+	require := require.New(t)
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	var rows []sql.Row
+	for i := 0; i < 5; i++ {
+		rows = append(rows, sql.NewRow("0123456789"))
+	}
+
+	schema := sql.Schema{{Name: "foo", Type: sql.Text}}
+	files, err := Export(schema, sql.RowsToRowIter(rows...), Options{
+		Format:      JSONL,
+		Dir:         dir,
+		MaxFileSize: 20,
+	})
+	require.NoError(err)
+	require.Equal([]string{
+		filepath.Join(dir, "part-00000.jsonl"),
+		filepath.Join(dir, "part-00001.jsonl"),
+		filepath.Join(dir, "part-00002.jsonl"),
+	}, files)
+
+	var lines []int
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f)
+		require.NoError(err)
+		lines = append(lines, len(content)/len(`{"foo":"0123456789"}`+"\n"))
+	}
+	require.Equal([]int{2, 2, 1}, lines)
+}
+
+func TestExportEmpty(t *testing.T) {
+	require := require.New(t)
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	files, err := Export(testSchema, sql.RowsToRowIter(), Options{
+		Format: Arrow,
+		Dir:    filepath.Join(dir, "empty"),
+	})
+	require.NoError(err)
+	require.Equal([]string{filepath.Join(dir, "empty", "part-00000.arrow")}, files)
+
+	f, err := os.Open(files[0])
+	require.NoError(err)
+	defer f.Close()
+
+	r, err := ipc.NewFileReader(f)
+	require.NoError(err)
+	require.Equal(0, r.NumRecords())
+	require.Len(r.Schema().Fields(), len(testSchema))
+}
+
+func TestExportArrow(t *testing.T) {
+	require := require.New(t)
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	files, err := Export(testSchema, sql.RowsToRowIter(testRows...), Options{
+		Format: Arrow,
+		Dir:    dir,
+	})
+	require.NoError(err)
+	require.Len(files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(err)
+	defer f.Close()
+
+	r, err := ipc.NewFileReader(f)
+	require.NoError(err)
+	defer r.Close()
+
+	var names []string
+	for _, field := range r.Schema().Fields() {
+		names = append(names, field.Name)
+	}
+	require.Equal([]string{
+		"commit_hash",
+		"commit_author_when",
+		"commit_parents",
+		"blob_size",
+		"blob_content",
+		"is_vendor",
+		"commit_stats(commit_hash)",
+	}, names)
+
+	require.Equal(1, r.NumRecords())
+	rec, err := r.Record(0)
+	require.NoError(err)
+	require.Equal(int64(2), rec.NumRows())
+
+	hashes := rec.Column(0).(*array.String)
+	require.Equal("e8d3ffab552895c19b9fcf7aa264d277cde33881", hashes.Value(0))
+
+	when := rec.Column(1).(*array.Timestamp)
+	require.Equal(int64(1427802434000), int64(when.Value(0)))
+
+	parents := rec.Column(2).(*array.List)
+	require.Equal([]int32{0, 2, 2}, parents.Offsets())
+
+	content := rec.Column(4).(*array.Binary)
+	require.Equal([]byte{0, 1, 2}, content.Value(0))
+	require.True(content.IsNull(1))
+
+	stats := rec.Column(6).(*array.String)
+	require.Equal(`{"files":2}`, stats.Value(0))
+	require.True(stats.IsNull(1))
+}
+
+func TestExportParquet(t *testing.T) {
+	require := require.New(t)
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	files, err := Export(testSchema, sql.RowsToRowIter(testRows...), Options{
+		Format: Parquet,
+		Dir:    dir,
+	})
+	require.NoError(err)
+	require.Len(files, 1)
+
+	f, err := local.NewLocalFileReader(files[0])
+	require.NoError(err)
+	defer f.Close()
+
+	r, err := reader.NewParquetReader(f, nil, 1)
+	require.NoError(err)
+	defer r.ReadStop()
+
+	require.Equal(int64(2), r.GetNumRows())
+
+	var names []string
+	for _, e := range r.Footer.Schema[1:] {
+		names = append(names, e.Name)
+	}
+	require.Equal([]string{
+		"commit_hash",
+		"commit_author_when",
+		"commit_parents",
+		"list",
+		"element",
+		"blob_size",
+		"blob_content",
+		"is_vendor",
+		"commit_stats_commit_hash_",
+	}, names)
+}
+
+func TestParquetName(t *testing.T) {
+	require := require.New(t)
+	require.Equal("commit_hash", parquetName("commit_hash"))
+	require.Equal("COUNT___", parquetName("COUNT(*)"))
+	require.Equal("a_b_c", parquetName("a, b=c"))
+	require.Equal("_", parquetName(""))
+}
+
+func TestKindOf(t *testing.T) {
+	testCases := []struct {
+		typ      sql.Type
+		expected kind
+	}{
+		{sql.Text, kindString},
+		{sql.VarChar(40), kindString},
+		{sql.Blob, kindBinary},
+		{sql.Int32, kindInt},
+		{sql.Int64, kindInt},
+		{sql.Uint64, kindUint},
+		{sql.Float64, kindFloat},
+		{sql.Boolean, kindBool},
+		{sql.Timestamp, kindTimestamp},
+		{sql.Date, kindTimestamp},
+		{sql.JSON, kindJSON},
+		{sql.Array(sql.Text), kindList},
+		{sql.Array(sql.JSON), kindList},
+	}
+
+	for _, tt := range testCases {
+		require.Equal(t, tt.expected, kindOf(tt.typ), "type %v", tt.typ)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	dir, cleanup := setupDir(t)
+	defer cleanup()
+
+	_, err := Export(testSchema, sql.RowsToRowIter(testRows...), Options{
+		Format: "csv",
+		Dir:    dir,
+	})
+	require.True(t, ErrUnknownFormat.Is(err))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 0)
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// jsonlWriter writes every row as a JSON object in its own line, with the
+// column names as keys. Binary values are encoded in base64, timestamps in
+// RFC 3339 and JSON documents and arrays are kept as JSON.
+type jsonlWriter struct {
+	w    *bufio.Writer
+	cols []column
+	keys [][]byte
+}
+
+func newJSONLWriter(w io.Writer, cols []column) *jsonlWriter {
+	var keys = make([][]byte, len(cols))
+	for i, c := range cols {
+		// Marshaling a string never fails.
+		keys[i], _ = json.Marshal(c.name)
+	}
+
+	return &jsonlWriter{bufio.NewWriter(w), cols, keys}
+}
+
+func (w *jsonlWriter) Write(row sql.Row) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, v := range row {
+		if i >= len(w.cols) {
+			break
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		value, err := w.value(w.cols[i].kind, v)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(w.keys[i])
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *jsonlWriter) value(k kind, v interface{}) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+
+	switch k {
+	case kindJSON:
+		s, err := jsonString(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if json.Valid([]byte(s)) {
+			return []byte(s), nil
+		}
+
+		return json.Marshal(s)
+	case kindList:
+		return json.Marshal(v)
+	default:
+		cv, err := convert(k, v)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(cv)
+	}
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.w.Flush()
+}
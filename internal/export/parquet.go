@@ -0,0 +1,138 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetParallelism is the number of goroutines used to encode the pages.
+const parquetParallelism = 4
+
+// parquetWriter writes the rows in a Parquet file. All columns are optional.
+// Binary values are stored as byte arrays, timestamps with millisecond
+// precision, JSON documents as strings and arrays as lists of strings.
+type parquetWriter struct {
+	cols []column
+	typ  reflect.Type
+	w    *writer.ParquetWriter
+}
+
+func newParquetWriter(w io.Writer, cols []column) (*parquetWriter, error) {
+	typ := parquetStruct(cols)
+	pw, err := writer.NewParquetWriterFromWriter(w, reflect.New(typ).Interface(), parquetParallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return &parquetWriter{cols, typ, pw}, nil
+}
+
+// parquetStruct returns a struct type with a field for each column, which
+// is used by the parquet writer to know the schema of the file.
+func parquetStruct(cols []column) reflect.Type {
+	var names = make(map[string]bool)
+	var fields = make([]reflect.StructField, len(cols))
+	for i, c := range cols {
+		name := parquetName(c.name)
+		for n := 1; names[name]; n++ {
+			name = fmt.Sprintf("%s_%d", parquetName(c.name), n)
+		}
+		names[name] = true
+
+		typ, tag := parquetType(c.kind)
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("Col%d", i),
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"name=%s, %s"`, name, tag)),
+		}
+	}
+
+	return reflect.StructOf(fields)
+}
+
+// parquetName returns the column name with all characters that are not
+// letters, digits or underscores replaced with underscores, because they
+// can't be used in the tags of the parquet writer.
+func parquetName(name string) string {
+	var result = []rune(name)
+	for i, r := range result {
+		if r != '_' &&
+			!(r >= 'a' && r <= 'z') &&
+			!(r >= 'A' && r <= 'Z') &&
+			!(r >= '0' && r <= '9') {
+			result[i] = '_'
+		}
+	}
+
+	if len(result) == 0 {
+		return "_"
+	}
+
+	return string(result)
+}
+
+func parquetType(k kind) (reflect.Type, string) {
+	switch k {
+	case kindBinary:
+		return reflect.TypeOf((*string)(nil)), "type=BYTE_ARRAY"
+	case kindInt:
+		return reflect.TypeOf((*int64)(nil)), "type=INT64"
+	case kindUint:
+		return reflect.TypeOf((*uint64)(nil)), "type=UINT_64"
+	case kindFloat:
+		return reflect.TypeOf((*float64)(nil)), "type=DOUBLE"
+	case kindBool:
+		return reflect.TypeOf((*bool)(nil)), "type=BOOLEAN"
+	case kindTimestamp:
+		return reflect.TypeOf((*int64)(nil)), "type=TIMESTAMP_MILLIS"
+	case kindList:
+		return reflect.TypeOf([]string(nil)), "type=LIST, valuetype=UTF8"
+	default:
+		return reflect.TypeOf((*string)(nil)), "type=UTF8"
+	}
+}
+
+func (w *parquetWriter) Write(row sql.Row) error {
+	obj := reflect.New(w.typ).Elem()
+	for i, v := range row {
+		if i >= len(w.cols) || v == nil {
+			continue
+		}
+
+		cv, err := convert(w.cols[i].kind, v)
+		if err != nil {
+			return err
+		}
+
+		switch cv := cv.(type) {
+		case []byte:
+			v = string(cv)
+		case time.Time:
+			v = cv.UnixNano() / int64(time.Millisecond)
+		default:
+			v = cv
+		}
+
+		field := obj.Field(i)
+		if field.Kind() == reflect.Ptr {
+			ptr := reflect.New(field.Type().Elem())
+			ptr.Elem().Set(reflect.ValueOf(v))
+			field.Set(ptr)
+		} else {
+			field.Set(reflect.ValueOf(v))
+		}
+	}
+
+	return w.w.Write(obj.Interface())
+}
+
+func (w *parquetWriter) Close() error {
+	return w.w.WriteStop()
+}
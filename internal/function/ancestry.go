@@ -0,0 +1,470 @@
+package function
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/object/commitgraph"
+)
+
+// MergeBase returns the hash of the best common ancestor of two commits,
+// as `git merge-base` does.
+type MergeBase struct {
+	Repository sql.Expression
+	Left       sql.Expression
+	Right      sql.Expression
+}
+
+// NewMergeBase creates a new MERGE_BASE function.
+func NewMergeBase(repo, left, right sql.Expression) sql.Expression {
+	return &MergeBase{repo, left, right}
+}
+
+func (f *MergeBase) String() string {
+	return fmt.Sprintf("merge_base(%s, %s, %s)", f.Repository, f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (*MergeBase) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *MergeBase) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewMergeBase(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *MergeBase) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Left, f.Right}
+}
+
+// IsNullable implements the Expression interface.
+func (*MergeBase) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *MergeBase) Resolved() bool {
+	return f.Repository.Resolved() && f.Left.Resolved() && f.Right.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *MergeBase) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalAncestryFunc(
+		ctx,
+		"merge_base",
+		row,
+		f.Repository, f.Left, f.Right,
+		func(left, right commitgraph.CommitNode) (interface{}, error) {
+			base, err := mergeBase(left, right)
+			if err != nil || base == nil {
+				return nil, err
+			}
+
+			return base.ID().String(), nil
+		},
+	)
+}
+
+// IsAncestor returns whether a commit is an ancestor of another one, as
+// `git merge-base --is-ancestor` does. A commit is an ancestor of itself.
+type IsAncestor struct {
+	Repository sql.Expression
+	Ancestor   sql.Expression
+	Commit     sql.Expression
+}
+
+// NewIsAncestor creates a new IS_ANCESTOR function.
+func NewIsAncestor(repo, ancestor, commit sql.Expression) sql.Expression {
+	return &IsAncestor{repo, ancestor, commit}
+}
+
+func (f *IsAncestor) String() string {
+	return fmt.Sprintf("is_ancestor(%s, %s, %s)", f.Repository, f.Ancestor, f.Commit)
+}
+
+// Type implements the Expression interface.
+func (*IsAncestor) Type() sql.Type {
+	return sql.Boolean
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsAncestor) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewIsAncestor(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *IsAncestor) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Ancestor, f.Commit}
+}
+
+// IsNullable implements the Expression interface.
+func (*IsAncestor) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *IsAncestor) Resolved() bool {
+	return f.Repository.Resolved() && f.Ancestor.Resolved() && f.Commit.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *IsAncestor) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalAncestryFunc(
+		ctx,
+		"is_ancestor",
+		row,
+		f.Repository, f.Ancestor, f.Commit,
+		func(ancestor, commit commitgraph.CommitNode) (interface{}, error) {
+			return isAncestor(ancestor, commit)
+		},
+	)
+}
+
+// CommitDistance returns the number of commits a commit is ahead and behind
+// a base one, as `git rev-list --left-right --count base...commit` does.
+type CommitDistance struct {
+	Repository sql.Expression
+	Base       sql.Expression
+	Commit     sql.Expression
+}
+
+// Distance is the result of the commit_distance function. Ahead is the
+// number of commits reachable from the commit but not from the base and
+// Behind the number of commits reachable from the base but not from the
+// commit.
+type Distance struct {
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+}
+
+// NewCommitDistance creates a new COMMIT_DISTANCE function.
+func NewCommitDistance(repo, base, commit sql.Expression) sql.Expression {
+	return &CommitDistance{repo, base, commit}
+}
+
+func (f *CommitDistance) String() string {
+	return fmt.Sprintf("commit_distance(%s, %s, %s)", f.Repository, f.Base, f.Commit)
+}
+
+// Type implements the Expression interface.
+func (*CommitDistance) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *CommitDistance) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewCommitDistance(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *CommitDistance) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Base, f.Commit}
+}
+
+// IsNullable implements the Expression interface.
+func (*CommitDistance) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *CommitDistance) Resolved() bool {
+	return f.Repository.Resolved() && f.Base.Resolved() && f.Commit.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *CommitDistance) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalAncestryFunc(
+		ctx,
+		"commit_distance",
+		row,
+		f.Repository, f.Base, f.Commit,
+		func(base, commit commitgraph.CommitNode) (interface{}, error) {
+			return commitDistance(base, commit)
+		},
+	)
+}
+
+// evalAncestryFunc resolves the repository and the two commits, which can
+// be given as hashes or revisions, and calls fn with their commit nodes.
+// The result is NULL if any of the commits is NULL. As with the stats
+// functions, errors are reported as warnings and the result is NULL too.
+func evalAncestryFunc(
+	ctx *sql.Context,
+	name string,
+	row sql.Row,
+	repoExpr, leftExpr, rightExpr sql.Expression,
+	fn func(left, right commitgraph.CommitNode) (interface{}, error),
+) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase." + name)
+	defer span.Finish()
+
+	r, err := resolveRepo(ctx, row, repoExpr)
+	if err != nil {
+		ctx.Warn(0, name+": unable to resolve repository")
+		logrus.WithField("err", err).Error(name + ": unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	log := logrus.WithField("repository", r)
+	nodes := r.CommitNodeIndex()
+
+	var commits [2]commitgraph.CommitNode
+	for i, e := range []sql.Expression{leftExpr, rightExpr} {
+		rev, err := exprToString(ctx, e, row)
+		if err == nil && rev == "" {
+			return nil, nil
+		}
+
+		var c *object.Commit
+		if err == nil {
+			c, err = revisionCommit(r, rev)
+		}
+
+		if err == nil {
+			commits[i], err = nodes.Get(c.Hash)
+		}
+
+		if err != nil {
+			ctx.Warn(0, name+": unable to resolve commit %q of repository: %v", rev, r)
+			log.WithField("err", err).Error(name + ": unable to resolve commit")
+			return nil, nil
+		}
+	}
+
+	result, err := fn(commits[0], commits[1])
+	if err != nil {
+		ctx.Warn(0, name+": unable to calculate for repository: %v", r)
+		log.WithField("err", err).Error(name + ": unable to calculate")
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// walkHistory calls fn with the commit and its ancestors, each one only
+// once. The parents of a commit are not visited if fn returns false.
+func walkHistory(
+	start commitgraph.CommitNode,
+	fn func(commitgraph.CommitNode) bool,
+) error {
+	seen := map[plumbing.Hash]struct{}{start.ID(): {}}
+	queue := []commitgraph.CommitNode{start}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		if !fn(c) {
+			continue
+		}
+
+		err := c.ParentNodes().ForEach(func(p commitgraph.CommitNode) error {
+			if _, ok := seen[p.ID()]; !ok {
+				seen[p.ID()] = struct{}{}
+				queue = append(queue, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ancestors returns the hashes of the commit and all its ancestors.
+func ancestors(c commitgraph.CommitNode) (map[plumbing.Hash]struct{}, error) {
+	history := make(map[plumbing.Hash]struct{})
+	err := walkHistory(c, func(c commitgraph.CommitNode) bool {
+		history[c.ID()] = struct{}{}
+		return true
+	})
+
+	return history, err
+}
+
+// Flags of the commits walked by commitDistance, with the commits they are
+// reachable from.
+const (
+	fromBase = 1 << iota
+	fromCommit
+	fromBoth = fromBase | fromCommit
+)
+
+// commitDistance returns the number of commits reachable only from commit
+// and only from base. Both histories are walked at once, from the newest
+// commits to the oldest, and the walk stops when all the commits left are
+// reachable from both, as all their ancestors are too. Commits are ordered
+// by their generation, so the result is exact with a commit-graph, or by
+// their commit time otherwise, as git does.
+func commitDistance(base, commit commitgraph.CommitNode) (*Distance, error) {
+	flags := make(map[plumbing.Hash]uint8)
+	var queue nodeQueue
+	// pending is the number of commits in the queue that were not reachable
+	// from both when they were added.
+	var pending int
+	push := func(c commitgraph.CommitNode, f uint8) {
+		old := flags[c.ID()]
+		if old|f == old {
+			return
+		}
+
+		flags[c.ID()] = old | f
+		partial := old|f != fromBoth
+		if partial {
+			pending++
+		}
+
+		heap.Push(&queue, queuedNode{c, partial})
+	}
+
+	push(base, fromBase)
+	push(commit, fromCommit)
+	for pending > 0 {
+		n := heap.Pop(&queue).(queuedNode)
+		if n.partial {
+			pending--
+		}
+
+		f := flags[n.ID()]
+		err := n.ParentNodes().ForEach(func(p commitgraph.CommitNode) error {
+			push(p, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var d Distance
+	for _, f := range flags {
+		switch f {
+		case fromCommit:
+			d.Ahead++
+		case fromBase:
+			d.Behind++
+		}
+	}
+
+	return &d, nil
+}
+
+// queuedNode is a commit in the queue of commitDistance, and whether it was
+// reachable from only one of the commits when it was added.
+type queuedNode struct {
+	commitgraph.CommitNode
+	partial bool
+}
+
+// nodeQueue is a priority queue of commits, with the newest ones first.
+type nodeQueue []queuedNode
+
+func (q nodeQueue) Len() int { return len(q) }
+
+func (q nodeQueue) Less(i, j int) bool {
+	if gi, gj := q[i].Generation(), q[j].Generation(); gi != gj {
+		return gi > gj
+	}
+
+	return q[i].CommitTime().After(q[j].CommitTime())
+}
+
+func (q nodeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(queuedNode)) }
+
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// isAncestor returns whether ancestor is reachable from commit. Commits
+// with a generation number lower than the one of the ancestor cannot reach
+// it, so their parents are not visited.
+func isAncestor(ancestor, commit commitgraph.CommitNode) (bool, error) {
+	var found bool
+	generation := ancestor.Generation()
+	err := walkHistory(commit, func(c commitgraph.CommitNode) bool {
+		if found || c.ID() == ancestor.ID() {
+			found = true
+			return false
+		}
+
+		return c.Generation() >= generation
+	})
+
+	return found, err
+}
+
+// mergeBase returns the best common ancestor of the two commits, that is,
+// a common ancestor that is not an ancestor of other common ones. If there
+// are several of them, the most recent one is returned, and nil if the
+// commits do not have a common history.
+func mergeBase(left, right commitgraph.CommitNode) (commitgraph.CommitNode, error) {
+	leftHistory, err := ancestors(left)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first common commits found in each path from right are the
+	// candidates, their ancestors are common too but never the best ones.
+	var candidates []commitgraph.CommitNode
+	err = walkHistory(right, func(c commitgraph.CommitNode) bool {
+		if _, ok := leftHistory[c.ID()]; ok {
+			candidates = append(candidates, c)
+			return false
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var best commitgraph.CommitNode
+	for i, c := range candidates {
+		independent := true
+		for j, other := range candidates {
+			if i == j {
+				continue
+			}
+
+			ok, err := isAncestor(c, other)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				independent = false
+				break
+			}
+		}
+
+		if independent && (best == nil || c.CommitTime().After(best.CommitTime())) {
+			best = c
+		}
+	}
+
+	return best, nil
+}
@@ -0,0 +1,141 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAncestryFunctions(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	repo := expression.NewGetField(0, sql.Text, "repository_id", false)
+	left := expression.NewGetField(1, sql.Text, "left", true)
+	right := expression.NewGetField(2, sql.Text, "right", true)
+
+	testCases := []struct {
+		name     string
+		fn       func(repo, left, right sql.Expression) sql.Expression
+		row      sql.Row
+		expected interface{}
+	}{
+		{
+			"merge base of branches",
+			NewMergeBase,
+			sql.NewRow("worktree", "HEAD", "refs/remotes/origin/branch"),
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+		},
+		{
+			"merge base of ancestor",
+			NewMergeBase,
+			sql.NewRow("worktree", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", "1669dce138d9b841a518c64b10914d88f5e488ea"),
+			"1669dce138d9b841a518c64b10914d88f5e488ea",
+		},
+		{
+			"merge base of unrelated histories",
+			NewMergeBase,
+			sql.NewRow("worktree", "35e85108805c84807bc66a02d91535e1e24b38b9", "a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69"),
+			nil,
+		},
+		{
+			"is ancestor",
+			NewIsAncestor,
+			sql.NewRow("worktree", "b029517f6300c2da0f4b651b8642506cd6aaf45d", "refs/heads/master"),
+			true,
+		},
+		{
+			"is ancestor of itself",
+			NewIsAncestor,
+			sql.NewRow("worktree", "HEAD", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+			true,
+		},
+		{
+			"is not ancestor",
+			NewIsAncestor,
+			sql.NewRow("worktree", "refs/remotes/origin/branch", "HEAD"),
+			false,
+		},
+		{
+			"distance of branches",
+			NewCommitDistance,
+			sql.NewRow("worktree", "HEAD", "refs/remotes/origin/branch"),
+			&Distance{Ahead: 1, Behind: 1},
+		},
+		{
+			"distance to ancestor",
+			NewCommitDistance,
+			sql.NewRow("worktree", "1669dce138d9b841a518c64b10914d88f5e488ea", "HEAD"),
+			&Distance{Ahead: 3, Behind: 0},
+		},
+		{
+			"distance to descendant",
+			NewCommitDistance,
+			sql.NewRow("worktree", "HEAD", "1669dce138d9b841a518c64b10914d88f5e488ea"),
+			&Distance{Ahead: 0, Behind: 3},
+		},
+		{
+			"distance to itself",
+			NewCommitDistance,
+			sql.NewRow("worktree", "HEAD", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+			&Distance{Ahead: 0, Behind: 0},
+		},
+		{
+			"null commit",
+			NewIsAncestor,
+			sql.NewRow("worktree", nil, "HEAD"),
+			nil,
+		},
+		{
+			"invalid commit",
+			NewCommitDistance,
+			sql.NewRow("worktree", "HEAD", "foobar"),
+			nil,
+		},
+		{
+			"invalid repository",
+			NewMergeBase,
+			sql.NewRow("foobar", "HEAD", "HEAD"),
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(repo, left, right).Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestAncestryFunctionsWarning(t *testing.T) {
+	require := require.New(t)
+
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	f := NewCommitDistance(
+		expression.NewGetField(0, sql.Text, "repository_id", false),
+		expression.NewGetField(1, sql.Text, "base", true),
+		expression.NewGetField(2, sql.Text, "commit", true),
+	)
+
+	result, err := f.Eval(ctx, sql.NewRow("worktree", "HEAD", "foobar"))
+	require.NoError(err)
+	require.Nil(result)
+
+	warnings := session.Warnings()
+	require.Len(warnings, 1)
+	require.Contains(warnings[0].Message, `"foobar"`)
+}
@@ -3,12 +3,10 @@ package function
 import (
 	"fmt"
 	"io"
+	"time"
 
-	"github.com/src-d/gitbase"
+	"github.com/src-d/gitbase/internal/blame"
 	"github.com/src-d/go-mysql-server/sql"
-	"gopkg.in/src-d/go-git.v4"
-
-	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
@@ -17,11 +15,11 @@ type BlameGenerator struct {
 	commit  *object.Commit
 	file    string
 	curLine int
-	lines   []*git.Line
+	lines   []blame.Line
 }
 
-func NewBlameGenerator(ctx *sql.Context, c *object.Commit, f string) (*BlameGenerator, error) {
-	result, err := git.Blame(c, f)
+func NewBlameGenerator(ctx *sql.Context, c *object.Commit, f string, opts blame.Options) (*BlameGenerator, error) {
+	result, err := blame.Blame(c, f, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -30,7 +28,7 @@ func NewBlameGenerator(ctx *sql.Context, c *object.Commit, f string) (*BlameGene
 		commit:  c,
 		file:    f,
 		curLine: 0,
-		lines:   result.Lines,
+		lines:   result,
 	}, nil
 }
 
@@ -47,9 +45,14 @@ func (g *BlameGenerator) Next() (interface{}, error) {
 
 	l := g.lines[g.curLine]
 	b := BlameLine{
-		LineNum: g.curLine,
-		Author:  l.Author,
-		Text:    l.Text,
+		LineNum:     l.Number,
+		OrigLineNum: l.OrigNumber,
+		CommitHash:  l.Commit.Hash.String(),
+		Author:      l.Commit.Author.Email,
+		AuthorName:  l.Commit.Author.Name,
+		AuthorEmail: l.Commit.Author.Email,
+		AuthorWhen:  l.Commit.Author.When,
+		Text:        l.Text,
 	}
 	g.curLine++
 	return b, nil
@@ -67,19 +70,35 @@ type (
 		repo   sql.Expression
 		commit sql.Expression
 		file   sql.Expression
+		// ignoreRevs is whether the commits in the .git-blame-ignore-revs
+		// file of the commit are ignored, nil if not given.
+		ignoreRevs sql.Expression
 	}
 
-	// BlameLine represents each line of git blame's output
+	// BlameLine represents each line of git blame's output. Line numbers
+	// start at 0 and Author is the same as AuthorEmail.
 	BlameLine struct {
-		LineNum int    `json:"linenum"`
-		Author  string `json:"author"`
-		Text    string `json:"text"`
+		LineNum     int       `json:"linenum"`
+		OrigLineNum int       `json:"orig_linenum"`
+		CommitHash  string    `json:"commit_hash"`
+		Author      string    `json:"author"`
+		AuthorName  string    `json:"author_name"`
+		AuthorEmail string    `json:"author_email"`
+		AuthorWhen  time.Time `json:"author_when"`
+		Text        string    `json:"text"`
 	}
 )
 
 // NewBlame constructor
-func NewBlame(repo, commit, file sql.Expression) sql.Expression {
-	return &Blame{repo, commit, file}
+func NewBlame(args ...sql.Expression) (sql.Expression, error) {
+	switch len(args) {
+	case 3:
+		return &Blame{args[0], args[1], args[2], nil}, nil
+	case 4:
+		return &Blame{args[0], args[1], args[2], args[3]}, nil
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("BLAME", "3 or 4", len(args))
+	}
 }
 
 func (b *Blame) String() string {
@@ -92,16 +111,21 @@ func (*Blame) Type() sql.Type {
 }
 
 func (b *Blame) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	if len(children) != 3 {
-		return nil, sql.ErrInvalidChildrenNumber.New(b, len(children), 2)
+	expected := len(b.Children())
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(b, len(children), expected)
 	}
 
-	return NewBlame(children[0], children[1], children[2]), nil
+	return NewBlame(children...)
 }
 
 // Children implements the Expression interface.
 func (b *Blame) Children() []sql.Expression {
-	return []sql.Expression{b.repo, b.commit, b.file}
+	if b.ignoreRevs == nil {
+		return []sql.Expression{b.repo, b.commit, b.file}
+	}
+
+	return []sql.Expression{b.repo, b.commit, b.file, b.ignoreRevs}
 }
 
 // IsNullable implements the Expression interface.
@@ -111,7 +135,8 @@ func (b *Blame) IsNullable() bool {
 
 // Resolved implements the Expression interface.
 func (b *Blame) Resolved() bool {
-	return b.repo.Resolved() && b.commit.Resolved() && b.file.Resolved()
+	return b.repo.Resolved() && b.commit.Resolved() && b.file.Resolved() &&
+		(b.ignoreRevs == nil || b.ignoreRevs.Resolved())
 }
 
 // Eval implements the sql.Expression interface.
@@ -119,60 +144,57 @@ func (b *Blame) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 	span, ctx := ctx.Span("gitbase.Blame")
 	defer span.Finish()
 
-	repo, err := b.resolveRepo(ctx, row)
+	repo, err := resolveRepo(ctx, row, b.repo)
 	if err != nil {
 		ctx.Warn(0, err.Error())
 		return nil, nil
 	}
+	defer repo.Close()
 
-	commit, err := b.resolveCommit(ctx, repo, row)
+	commit, err := resolveCommit(ctx, repo, row, b.commit)
 	if err != nil {
 		ctx.Warn(0, err.Error())
 		return nil, nil
 	}
 
-	file, err := exprToString(ctx, b.file, row)
-	if err != nil {
-		ctx.Warn(0, err.Error())
+	if commit == nil {
 		return nil, nil
 	}
 
-	bg, err := NewBlameGenerator(ctx, commit, file)
+	file, err := exprToString(ctx, b.file, row)
 	if err != nil {
 		ctx.Warn(0, err.Error())
 		return nil, nil
 	}
 
-	return bg, nil
-}
+	var opts blame.Options
+	if b.ignoreRevs != nil {
+		v, err := b.ignoreRevs.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
 
-func (b *Blame) resolveCommit(ctx *sql.Context, repo *gitbase.Repository, row sql.Row) (*object.Commit, error) {
-	str, err := exprToString(ctx, b.commit, row)
-	if err != nil {
-		return nil, err
-	}
+		if v != nil {
+			v, err = sql.Boolean.Convert(v)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	commitHash, err := repo.ResolveRevision(plumbing.Revision(str))
-	if err != nil {
-		h := plumbing.NewHash(str)
-		commitHash = &h
+		if v == true {
+			opts.IgnoreRevs, err = blame.ReadIgnoreRevs(commit)
+			if err != nil {
+				ctx.Warn(0, err.Error())
+				return nil, nil
+			}
+		}
 	}
-	to, err := repo.CommitObject(*commitHash)
-	if err != nil {
-		return nil, err
-	}
-
-	return to, nil
-}
 
-func (b *Blame) resolveRepo(ctx *sql.Context, r sql.Row) (*gitbase.Repository, error) {
-	repoID, err := exprToString(ctx, b.repo, r)
+	bg, err := NewBlameGenerator(ctx, commit, file, opts)
 	if err != nil {
-		return nil, err
-	}
-	s, ok := ctx.Session.(*gitbase.Session)
-	if !ok {
-		return nil, gitbase.ErrInvalidGitbaseSession.New(ctx.Session)
+		ctx.Warn(0, err.Error())
+		return nil, nil
 	}
-	return s.Pool.GetRepo(repoID)
+
+	return bg, nil
 }
@@ -3,6 +3,7 @@ package function
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/src-d/gitbase"
 	"github.com/src-d/go-mysql-server/sql"
@@ -44,9 +45,12 @@ func TestBlameEval(t *testing.T) {
 			testedLine: 0,
 			lineCount:  12,
 			expected: BlameLine{
-				0,
-				"mcuadros@gmail.com",
-				"*.class",
+				LineNum:     0,
+				OrigLineNum: 0,
+				CommitHash:  "b029517f6300c2da0f4b651b8642506cd6aaf45d",
+				Author:      "mcuadros@gmail.com",
+				AuthorEmail: "mcuadros@gmail.com",
+				Text:        "*.class",
 			},
 			expectedNil: false,
 		},
@@ -59,9 +63,12 @@ func TestBlameEval(t *testing.T) {
 			testedLine: 0,
 			lineCount:  1,
 			expected: BlameLine{
-				0,
-				"daniel@lordran.local",
-				"Initial changelog",
+				LineNum:     0,
+				OrigLineNum: 0,
+				CommitHash:  "b8e471f58bcbca63b07bda20e428190409c2db47",
+				Author:      "daniel@lordran.local",
+				AuthorEmail: "daniel@lordran.local",
+				Text:        "Initial changelog",
 			},
 			expectedNil: false,
 		},
@@ -102,7 +109,9 @@ func TestBlameEval(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			blame := NewBlame(tc.repo, tc.commit, tc.file)
+			blame, err := NewBlame(tc.repo, tc.commit, tc.file)
+			require.NoError(t, err)
+
 			blameGen, err := blame.Eval(ctx, tc.row)
 			require.NoError(t, err)
 
@@ -124,6 +133,9 @@ func TestBlameEval(t *testing.T) {
 					continue
 				}
 				lineCount++
+				require.NotEmpty(t, i.AuthorName)
+				require.False(t, i.AuthorWhen.IsZero())
+				i.AuthorName, i.AuthorWhen = "", time.Time{}
 				require.EqualValues(t, tc.expected, i)
 			}
 			require.Equal(t, tc.lineCount, lineCount)
@@ -98,7 +98,7 @@ func (f *CommitFileStats) Eval(ctx *sql.Context, row sql.Row) (interface{}, erro
 		row,
 		f.Repository, f.From, f.To,
 		func(r *git.Repository, from, to *object.Commit) (interface{}, error) {
-			stats, err := commitstats.CalculateByFile(r, from, to)
+			stats, err := commitstats.CalculateByFile(r, from, to, commitStatsOptions)
 			if err != nil {
 				return nil, err
 			}
@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/src-d/gitbase"
 	"github.com/src-d/gitbase/internal/commitstats"
+	"github.com/src-d/gitbase/internal/renames"
 
 	"github.com/src-d/go-mysql-server/sql"
 	"gopkg.in/src-d/go-git.v4"
@@ -13,6 +14,10 @@ import (
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
+// commitStatsOptions are the options used to calculate the commit stats,
+// read from the environment once.
+var commitStatsOptions = renames.OptionsFromEnv()
+
 // CommitStats calculates the diff stats for a given commit. Vendored files
 // are completely ignored for the output of this function.
 type CommitStats struct {
@@ -101,7 +106,7 @@ func (f *CommitStats) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 		row,
 		f.Repository, f.From, f.To,
 		func(r *git.Repository, from, to *object.Commit) (interface{}, error) {
-			return commitstats.Calculate(r, from, to)
+			return commitstats.Calculate(r, from, to, commitStatsOptions)
 		},
 	)
 }
@@ -120,7 +125,7 @@ func resolveRepo(
 	if !ok {
 		return nil, gitbase.ErrInvalidGitbaseSession.New(ctx.Session)
 	}
-	return s.Pool.GetRepo(repoID)
+	return s.QueryPool(ctx).GetRepo(repoID)
 }
 
 func resolveCommit(
@@ -138,13 +143,18 @@ func resolveCommit(
 		return nil, nil
 	}
 
-	commitHash, err := r.ResolveRevision(plumbing.Revision(str))
+	return revisionCommit(r, str)
+}
+
+// revisionCommit returns the commit of the given revision, which can also
+// be a hash.
+func revisionCommit(r *gitbase.Repository, rev string) (*object.Commit, error) {
+	hash, err := gitbase.ResolveRevision(r, rev)
 	if err != nil {
-		h := plumbing.NewHash(str)
-		commitHash = &h
+		hash = plumbing.NewHash(rev)
 	}
 
-	return r.CommitObject(*commitHash)
+	return r.CommitObject(hash)
 }
 
 func evalStatsFunc(
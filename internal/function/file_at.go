@@ -0,0 +1,486 @@
+package function
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// FileAt returns the content of a file at a revision.
+type FileAt struct {
+	Repository sql.Expression
+	Revision   sql.Expression
+	Path       sql.Expression
+}
+
+// NewFileAt creates a new FILE_AT function.
+func NewFileAt(repo, rev, path sql.Expression) sql.Expression {
+	return &FileAt{repo, rev, path}
+}
+
+func (f *FileAt) String() string {
+	return fmt.Sprintf("file_at(%s, %s, %s)", f.Repository, f.Revision, f.Path)
+}
+
+// Type implements the Expression interface.
+func (*FileAt) Type() sql.Type {
+	return sql.Blob
+}
+
+// WithChildren implements the Expression interface.
+func (f *FileAt) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewFileAt(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *FileAt) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Revision, f.Path}
+}
+
+// IsNullable implements the Expression interface.
+func (*FileAt) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *FileAt) Resolved() bool {
+	return f.Repository.Resolved() && f.Revision.Resolved() && f.Path.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *FileAt) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalFileAtFunc(
+		ctx,
+		"file_at",
+		row,
+		f.Repository, f.Revision, f.Path,
+		func(r *gitbase.Repository, tree *object.Tree, path string) (interface{}, error) {
+			entry, err := findEntry(tree, path)
+			if err != nil || entry == nil || !entry.Mode.IsFile() {
+				return nil, err
+			}
+
+			blob, err := r.BlobObject(entry.Hash)
+			if err != nil {
+				return nil, err
+			}
+
+			return gitbase.BlobContent(blob)
+		},
+	)
+}
+
+// FileHashAt returns the hash of the object of a file or directory at a
+// revision.
+type FileHashAt struct {
+	Repository sql.Expression
+	Revision   sql.Expression
+	Path       sql.Expression
+}
+
+// NewFileHashAt creates a new FILE_HASH_AT function.
+func NewFileHashAt(repo, rev, path sql.Expression) sql.Expression {
+	return &FileHashAt{repo, rev, path}
+}
+
+func (f *FileHashAt) String() string {
+	return fmt.Sprintf("file_hash_at(%s, %s, %s)", f.Repository, f.Revision, f.Path)
+}
+
+// Type implements the Expression interface.
+func (*FileHashAt) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *FileHashAt) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewFileHashAt(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *FileHashAt) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Revision, f.Path}
+}
+
+// IsNullable implements the Expression interface.
+func (*FileHashAt) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *FileHashAt) Resolved() bool {
+	return f.Repository.Resolved() && f.Revision.Resolved() && f.Path.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *FileHashAt) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalFileAtFunc(
+		ctx,
+		"file_hash_at",
+		row,
+		f.Repository, f.Revision, f.Path,
+		func(_ *gitbase.Repository, tree *object.Tree, path string) (interface{}, error) {
+			if path == "" {
+				return tree.Hash.String(), nil
+			}
+
+			entry, err := findEntry(tree, path)
+			if err != nil || entry == nil {
+				return nil, err
+			}
+
+			return entry.Hash.String(), nil
+		},
+	)
+}
+
+// FileModeAt returns the mode of a file or directory at a revision, as
+// the tree_entry_mode column of the tree_entries table.
+type FileModeAt struct {
+	Repository sql.Expression
+	Revision   sql.Expression
+	Path       sql.Expression
+}
+
+// NewFileModeAt creates a new FILE_MODE_AT function.
+func NewFileModeAt(repo, rev, path sql.Expression) sql.Expression {
+	return &FileModeAt{repo, rev, path}
+}
+
+func (f *FileModeAt) String() string {
+	return fmt.Sprintf("file_mode_at(%s, %s, %s)", f.Repository, f.Revision, f.Path)
+}
+
+// Type implements the Expression interface.
+func (*FileModeAt) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *FileModeAt) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewFileModeAt(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *FileModeAt) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Revision, f.Path}
+}
+
+// IsNullable implements the Expression interface.
+func (*FileModeAt) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *FileModeAt) Resolved() bool {
+	return f.Repository.Resolved() && f.Revision.Resolved() && f.Path.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *FileModeAt) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalFileAtFunc(
+		ctx,
+		"file_mode_at",
+		row,
+		f.Repository, f.Revision, f.Path,
+		func(_ *gitbase.Repository, tree *object.Tree, path string) (interface{}, error) {
+			if path == "" {
+				return fileModeString(filemode.Dir), nil
+			}
+
+			entry, err := findEntry(tree, path)
+			if err != nil || entry == nil {
+				return nil, err
+			}
+
+			return fileModeString(entry.Mode), nil
+		},
+	)
+}
+
+// TreeAt returns the entries of a directory at a revision, or of the root
+// directory if no path is given.
+type TreeAt struct {
+	Repository sql.Expression
+	Revision   sql.Expression
+	Path       sql.Expression
+}
+
+// TreeAtEntry is each one of the entries returned by the tree_at function.
+type TreeAtEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+}
+
+// NewTreeAt creates a new TREE_AT function.
+func NewTreeAt(args ...sql.Expression) (sql.Expression, error) {
+	switch len(args) {
+	case 2:
+		return &TreeAt{args[0], args[1], nil}, nil
+	case 3:
+		return &TreeAt{args[0], args[1], args[2]}, nil
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("TREE_AT", "2 or 3", len(args))
+	}
+}
+
+func (f *TreeAt) String() string {
+	if f.Path == nil {
+		return fmt.Sprintf("tree_at(%s, %s)", f.Repository, f.Revision)
+	}
+
+	return fmt.Sprintf("tree_at(%s, %s, %s)", f.Repository, f.Revision, f.Path)
+}
+
+// Type implements the Expression interface.
+func (*TreeAt) Type() sql.Type {
+	return sql.Array(sql.JSON)
+}
+
+// WithChildren implements the Expression interface.
+func (f *TreeAt) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := len(f.Children())
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
+	}
+
+	return NewTreeAt(children...)
+}
+
+// Children implements the Expression interface.
+func (f *TreeAt) Children() []sql.Expression {
+	if f.Path == nil {
+		return []sql.Expression{f.Repository, f.Revision}
+	}
+
+	return []sql.Expression{f.Repository, f.Revision, f.Path}
+}
+
+// IsNullable implements the Expression interface.
+func (*TreeAt) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *TreeAt) Resolved() bool {
+	return f.Repository.Resolved() && f.Revision.Resolved() &&
+		(f.Path == nil || f.Path.Resolved())
+}
+
+// Eval implements the Expression interface.
+func (f *TreeAt) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalFileAtFunc(
+		ctx,
+		"tree_at",
+		row,
+		f.Repository, f.Revision, f.Path,
+		func(_ *gitbase.Repository, tree *object.Tree, path string) (interface{}, error) {
+			if path != "" {
+				entry, err := findEntry(tree, path)
+				if err != nil || entry == nil || entry.Mode != filemode.Dir {
+					return nil, err
+				}
+
+				tree, err = tree.Tree(path)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return NewTreeGenerator(ctx, tree, path), nil
+		},
+	)
+}
+
+// TreeGenerator is the generator returned by the tree_at function with the
+// entries of a tree.
+type TreeGenerator struct {
+	ctx     *sql.Context
+	path    string
+	entries []object.TreeEntry
+	pos     int
+}
+
+var _ sql.Generator = (*TreeGenerator)(nil)
+
+// NewTreeGenerator creates a generator with the entries of the tree, which
+// is in the given path.
+func NewTreeGenerator(ctx *sql.Context, tree *object.Tree, path string) *TreeGenerator {
+	return &TreeGenerator{
+		ctx:     ctx,
+		path:    path,
+		entries: tree.Entries,
+	}
+}
+
+// Next implements the sql.Generator interface.
+func (g *TreeGenerator) Next() (interface{}, error) {
+	select {
+	case <-g.ctx.Done():
+		return nil, io.EOF
+	default:
+	}
+
+	if g.pos >= len(g.entries) {
+		return nil, io.EOF
+	}
+
+	e := g.entries[g.pos]
+	g.pos++
+
+	var path = e.Name
+	if g.path != "" {
+		path = g.path + "/" + e.Name
+	}
+
+	return TreeAtEntry{
+		Name: e.Name,
+		Path: path,
+		Hash: e.Hash.String(),
+		Mode: fileModeString(e.Mode),
+		Type: entryType(e.Mode).String(),
+	}, nil
+}
+
+// Close implements the sql.Generator interface.
+func (g *TreeGenerator) Close() error {
+	return nil
+}
+
+// evalFileAtFunc resolves the repository, the revision and the path and
+// calls fn with the tree of the commit and the path relative to it. The
+// root directory has an empty path. The result is NULL if the revision or
+// the path are NULL or the path does not exist at the revision, and errors
+// are reported as warnings with a NULL result.
+func evalFileAtFunc(
+	ctx *sql.Context,
+	name string,
+	row sql.Row,
+	repoExpr, revExpr, pathExpr sql.Expression,
+	fn func(r *gitbase.Repository, tree *object.Tree, path string) (interface{}, error),
+) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase." + name)
+	defer span.Finish()
+
+	var path string
+	if pathExpr != nil {
+		v, err := pathExpr.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if v == nil {
+			return nil, nil
+		}
+
+		v, err = sql.Text.Convert(v)
+		if err != nil {
+			return nil, err
+		}
+
+		path = strings.Trim(v.(string), "/")
+	}
+
+	r, err := resolveRepo(ctx, row, repoExpr)
+	if err != nil {
+		ctx.Warn(0, name+": unable to resolve repository")
+		logrus.WithField("err", err).Error(name + ": unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	log := logrus.WithField("repository", r)
+	rev, err := exprToString(ctx, revExpr, row)
+	if err == nil && rev == "" {
+		return nil, nil
+	}
+
+	var commit *object.Commit
+	if err == nil {
+		commit, err = revisionCommit(r, rev)
+	}
+
+	if err != nil {
+		ctx.Warn(0, name+": unable to resolve revision %q of repository: %v", rev, r)
+		log.WithField("err", err).Error(name + ": unable to resolve revision")
+		return nil, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		ctx.Warn(0, name+": unable to get tree of commit %s of repository: %v", commit.Hash, r)
+		log.WithField("err", err).Error(name + ": unable to get tree")
+		return nil, nil
+	}
+
+	result, err := fn(r, tree, path)
+	if err != nil {
+		ctx.Warn(0, name+": unable to read %s at commit %s of repository: %v", path, commit.Hash, r)
+		log.WithFields(logrus.Fields{
+			"err":    err,
+			"commit": commit.Hash,
+			"path":   path,
+		}).Error(name + ": unable to read path")
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// findEntry returns the entry with the given path in the tree, or nil if
+// there is no such path.
+func findEntry(tree *object.Tree, path string) (*object.TreeEntry, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	entry, err := tree.FindEntry(path)
+	switch err {
+	case nil:
+		return entry, nil
+	case object.ErrEntryNotFound, object.ErrDirectoryNotFound,
+		plumbing.ErrObjectNotFound:
+		// Parent directories which are files are not found as trees.
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func fileModeString(m filemode.FileMode) string {
+	return strconv.FormatInt(int64(m), 8)
+}
+
+func entryType(m filemode.FileMode) plumbing.ObjectType {
+	switch m {
+	case filemode.Dir:
+		return plumbing.TreeObject
+	case filemode.Submodule:
+		return plumbing.CommitObject
+	default:
+		return plumbing.BlobObject
+	}
+}
@@ -0,0 +1,199 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAt(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	repo := expression.NewGetField(0, sql.Text, "repository_id", false)
+	rev := expression.NewGetField(1, sql.Text, "revision", true)
+	path := expression.NewGetField(2, sql.Text, "path", true)
+
+	testCases := []struct {
+		name     string
+		fn       func(repo, rev, path sql.Expression) sql.Expression
+		row      sql.Row
+		expected interface{}
+	}{
+		{
+			"content",
+			NewFileAt,
+			sql.NewRow("worktree", "HEAD", "CHANGELOG"),
+			[]byte("Initial changelog\n"),
+		},
+		{
+			"content of directory",
+			NewFileAt,
+			sql.NewRow("worktree", "HEAD", "go"),
+			nil,
+		},
+		{
+			"content of missing file",
+			NewFileAt,
+			sql.NewRow("worktree", "HEAD", "go/missing.go"),
+			nil,
+		},
+		{
+			"content of file in file",
+			NewFileAt,
+			sql.NewRow("worktree", "HEAD", "CHANGELOG/foo"),
+			nil,
+		},
+		{
+			"hash",
+			NewFileHashAt,
+			sql.NewRow("worktree", "HEAD", "/go/example.go"),
+			"880cd14280f4b9b6ed3986d6671f907d7cc2a198",
+		},
+		{
+			"hash of directory",
+			NewFileHashAt,
+			sql.NewRow("worktree", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", "vendor/"),
+			"cf4aa3b38974fb7d81f367c0830f7d78d65ab86b",
+		},
+		{
+			"hash of root directory",
+			NewFileHashAt,
+			sql.NewRow("worktree", "HEAD", ""),
+			"a8d315b2b1c615d43042c3a62402b8a54288cf5c",
+		},
+		{
+			"mode",
+			NewFileModeAt,
+			sql.NewRow("worktree", "HEAD", "LICENSE"),
+			"100644",
+		},
+		{
+			"mode of directory",
+			NewFileModeAt,
+			sql.NewRow("worktree", "HEAD", "json"),
+			"40000",
+		},
+		{
+			"null path",
+			NewFileAt,
+			sql.NewRow("worktree", "HEAD", nil),
+			nil,
+		},
+		{
+			"null revision",
+			NewFileHashAt,
+			sql.NewRow("worktree", nil, "LICENSE"),
+			nil,
+		},
+		{
+			"invalid revision",
+			NewFileModeAt,
+			sql.NewRow("worktree", "foo", "LICENSE"),
+			nil,
+		},
+		{
+			"invalid repository",
+			NewFileAt,
+			sql.NewRow("foo", "HEAD", "LICENSE"),
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(repo, rev, path).Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestFileAtWarning(t *testing.T) {
+	require := require.New(t)
+
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	f := NewFileAt(
+		expression.NewGetField(0, sql.Text, "repository_id", false),
+		expression.NewGetField(1, sql.Text, "revision", true),
+		expression.NewGetField(2, sql.Text, "path", true),
+	)
+
+	result, err := f.Eval(ctx, sql.NewRow("worktree", "foo", "LICENSE"))
+	require.NoError(err)
+	require.Nil(result)
+
+	warnings := session.Warnings()
+	require.Len(warnings, 1)
+	require.Contains(warnings[0].Message, `"foo"`)
+}
+
+func TestTreeAt(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	repo := expression.NewGetField(0, sql.Text, "repository_id", false)
+	rev := expression.NewGetField(1, sql.Text, "revision", true)
+	path := expression.NewGetField(2, sql.Text, "path", true)
+
+	entries := func(v interface{}) []TreeAtEntry {
+		g, ok := v.(*TreeGenerator)
+		require.True(t, ok)
+		defer g.Close()
+
+		var result []TreeAtEntry
+		for e, err := g.Next(); err == nil; e, err = g.Next() {
+			result = append(result, e.(TreeAtEntry))
+		}
+		return result
+	}
+
+	f, err := NewTreeAt(repo, rev)
+	require.NoError(t, err)
+
+	result, err := f.Eval(ctx, sql.NewRow("worktree", "HEAD"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range entries(result) {
+		names = append(names, e.Name)
+	}
+	require.Equal(t, []string{
+		".gitignore", "CHANGELOG", "LICENSE", "binary.jpg",
+		"go", "json", "php", "vendor",
+	}, names)
+
+	f, err = NewTreeAt(repo, rev, path)
+	require.NoError(t, err)
+
+	result, err = f.Eval(ctx, sql.NewRow("worktree", "HEAD", "go"))
+	require.NoError(t, err)
+	require.Equal(t, []TreeAtEntry{{
+		Name: "example.go",
+		Path: "go/example.go",
+		Hash: "880cd14280f4b9b6ed3986d6671f907d7cc2a198",
+		Mode: "100644",
+		Type: "blob",
+	}}, entries(result))
+
+	for _, p := range []interface{}{"LICENSE", "missing", nil} {
+		result, err = f.Eval(ctx, sql.NewRow("worktree", "HEAD", p))
+		require.NoError(t, err)
+		require.Nil(t, result)
+	}
+}
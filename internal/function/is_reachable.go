@@ -0,0 +1,95 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// IsReachable returns whether an object of a repository is reachable from
+// any of its references or reflog entries.
+type IsReachable struct {
+	Repository sql.Expression
+	Hash       sql.Expression
+}
+
+// NewIsReachable creates a new IS_REACHABLE function.
+func NewIsReachable(repo, hash sql.Expression) sql.Expression {
+	return &IsReachable{repo, hash}
+}
+
+func (f *IsReachable) String() string {
+	return fmt.Sprintf("is_reachable(%s, %s)", f.Repository, f.Hash)
+}
+
+// Type implements the Expression interface.
+func (*IsReachable) Type() sql.Type {
+	return sql.Boolean
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsReachable) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewIsReachable(children[0], children[1]), nil
+}
+
+// Children implements the Expression interface.
+func (f *IsReachable) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Hash}
+}
+
+// IsNullable implements the Expression interface.
+func (*IsReachable) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *IsReachable) Resolved() bool {
+	return f.Repository.Resolved() && f.Hash.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *IsReachable) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.is_reachable")
+	defer span.Finish()
+
+	hash, err := exprToString(ctx, f.Hash, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash == "" {
+		return nil, nil
+	}
+
+	s, ok := ctx.Session.(*gitbase.Session)
+	if !ok {
+		return nil, gitbase.ErrInvalidGitbaseSession.New(ctx.Session)
+	}
+
+	r, err := resolveRepo(ctx, row, f.Repository)
+	if err != nil {
+		ctx.Warn(0, "is_reachable: unable to resolve repository")
+		logrus.WithField("err", err).Error("is_reachable: unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	reachable, err := s.ReachableObjects(r)
+	if err != nil {
+		ctx.Warn(0, "is_reachable: unable to find reachable objects of repository: %v", r)
+		logrus.WithFields(logrus.Fields{
+			"repository": r,
+			"err":        err,
+		}).Error("is_reachable: unable to find reachable objects")
+		return nil, nil
+	}
+
+	return reachable.Contains(plumbing.NewHash(hash)), nil
+}
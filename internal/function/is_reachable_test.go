@@ -0,0 +1,59 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestIsReachable(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	r, err := pool.GetRepo("worktree")
+	require.NoError(t, err)
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("dangling\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	dangling, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	f := NewIsReachable(
+		expression.NewGetField(0, sql.Text, "repository_id", false),
+		expression.NewGetField(1, sql.Text, "hash", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"head", sql.NewRow("worktree", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"), true},
+		{"blob", sql.NewRow("worktree", "d5c0f4ab811897cadf03aec358ae60d21f91c50d"), true},
+		{"dangling", sql.NewRow("worktree", dangling.String()), false},
+		{"null hash", sql.NewRow("worktree", nil), nil},
+		{"invalid repository", sql.NewRow("foo", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := f.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Mailmap returns the canonical identity of an author or committer, in the
+// form `Name <email>`, using the mailmap of the repository.
+type Mailmap struct {
+	Repository sql.Expression
+	Name       sql.Expression
+	Email      sql.Expression
+}
+
+// NewMailmap creates a new MAILMAP function.
+func NewMailmap(repo, name, email sql.Expression) sql.Expression {
+	return &Mailmap{repo, name, email}
+}
+
+func (f *Mailmap) String() string {
+	return fmt.Sprintf("mailmap(%s, %s, %s)", f.Repository, f.Name, f.Email)
+}
+
+// Type implements the Expression interface.
+func (*Mailmap) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *Mailmap) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewMailmap(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *Mailmap) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Name, f.Email}
+}
+
+// IsNullable implements the Expression interface.
+func (*Mailmap) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *Mailmap) Resolved() bool {
+	return f.Repository.Resolved() && f.Name.Resolved() && f.Email.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *Mailmap) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalMailmapFunc(
+		ctx,
+		"mailmap",
+		row,
+		f.Repository, f.Name, f.Email,
+		func(name, email string) interface{} {
+			return fmt.Sprintf("%s <%s>", name, email)
+		},
+	)
+}
+
+// MailmapName returns the canonical name of an author or committer using
+// the mailmap of the repository.
+type MailmapName struct {
+	Repository sql.Expression
+	Name       sql.Expression
+	Email      sql.Expression
+}
+
+// NewMailmapName creates a new MAILMAP_NAME function.
+func NewMailmapName(repo, name, email sql.Expression) sql.Expression {
+	return &MailmapName{repo, name, email}
+}
+
+func (f *MailmapName) String() string {
+	return fmt.Sprintf("mailmap_name(%s, %s, %s)", f.Repository, f.Name, f.Email)
+}
+
+// Type implements the Expression interface.
+func (*MailmapName) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *MailmapName) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewMailmapName(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *MailmapName) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Name, f.Email}
+}
+
+// IsNullable implements the Expression interface.
+func (*MailmapName) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *MailmapName) Resolved() bool {
+	return f.Repository.Resolved() && f.Name.Resolved() && f.Email.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *MailmapName) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalMailmapFunc(
+		ctx,
+		"mailmap_name",
+		row,
+		f.Repository, f.Name, f.Email,
+		func(name, _ string) interface{} { return name },
+	)
+}
+
+// MailmapEmail returns the canonical email of an author or committer using
+// the mailmap of the repository.
+type MailmapEmail struct {
+	Repository sql.Expression
+	Name       sql.Expression
+	Email      sql.Expression
+}
+
+// NewMailmapEmail creates a new MAILMAP_EMAIL function.
+func NewMailmapEmail(repo, name, email sql.Expression) sql.Expression {
+	return &MailmapEmail{repo, name, email}
+}
+
+func (f *MailmapEmail) String() string {
+	return fmt.Sprintf("mailmap_email(%s, %s, %s)", f.Repository, f.Name, f.Email)
+}
+
+// Type implements the Expression interface.
+func (*MailmapEmail) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *MailmapEmail) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewMailmapEmail(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *MailmapEmail) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Name, f.Email}
+}
+
+// IsNullable implements the Expression interface.
+func (*MailmapEmail) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *MailmapEmail) Resolved() bool {
+	return f.Repository.Resolved() && f.Name.Resolved() && f.Email.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *MailmapEmail) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return evalMailmapFunc(
+		ctx,
+		"mailmap_email",
+		row,
+		f.Repository, f.Name, f.Email,
+		func(_, email string) interface{} { return email },
+	)
+}
+
+// evalMailmapFunc maps the name and the email with the mailmap of the
+// repository and returns the result of fn with them. It returns NULL if
+// both the name and the email are NULL.
+func evalMailmapFunc(
+	ctx *sql.Context,
+	name string,
+	row sql.Row,
+	repoExpr, nameExpr, emailExpr sql.Expression,
+	fn func(name, email string) interface{},
+) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase." + name)
+	defer span.Finish()
+
+	var values [2]string
+	var null = true
+	for i, e := range []sql.Expression{nameExpr, emailExpr} {
+		v, err := e.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if v == nil {
+			continue
+		}
+
+		v, err = sql.Text.Convert(v)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = v.(string)
+		null = false
+	}
+
+	if null {
+		return nil, nil
+	}
+
+	s, ok := ctx.Session.(*gitbase.Session)
+	if !ok {
+		return nil, gitbase.ErrInvalidGitbaseSession.New(ctx.Session)
+	}
+
+	r, err := resolveRepo(ctx, row, repoExpr)
+	if err != nil {
+		ctx.Warn(0, name+": unable to resolve repository")
+		logrus.WithField("err", err).Error(name + ": unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	m, err := s.Mailmap(r)
+	if err != nil {
+		ctx.Warn(0, name+": unable to read mailmap of repository: %v", r)
+		logrus.WithFields(logrus.Fields{
+			"repository": r,
+			"err":        err,
+		}).Error(name + ": unable to read mailmap")
+		return nil, nil
+	}
+
+	return fn(m.Map(values[0], values[1])), nil
+}
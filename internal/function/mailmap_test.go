@@ -0,0 +1,95 @@
+package function
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailmap(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "gitbase-mailmap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "mailmap")
+	require.NoError(t, ioutil.WriteFile(
+		file,
+		[]byte("Maximo Cuadros <mcuadros@example.com> <mcuadros@gmail.com>\n"),
+		0644,
+	))
+
+	session := gitbase.NewSession(pool, gitbase.WithMailmapFile(file))
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	repo := expression.NewGetField(0, sql.Text, "repository_id", false)
+	name := expression.NewGetField(1, sql.Text, "name", true)
+	email := expression.NewGetField(2, sql.Text, "email", true)
+
+	testCases := []struct {
+		name     string
+		fn       func(repo, name, email sql.Expression) sql.Expression
+		row      sql.Row
+		expected interface{}
+	}{
+		{
+			"identity",
+			NewMailmap,
+			sql.NewRow("worktree", "Máximo Cuadros", "mcuadros@gmail.com"),
+			"Maximo Cuadros <mcuadros@example.com>",
+		},
+		{
+			"name",
+			NewMailmapName,
+			sql.NewRow("worktree", "Máximo Cuadros", "MCuadros@gmail.com"),
+			"Maximo Cuadros",
+		},
+		{
+			"email",
+			NewMailmapEmail,
+			sql.NewRow("worktree", "Máximo Cuadros", "mcuadros@gmail.com"),
+			"mcuadros@example.com",
+		},
+		{
+			"not in mailmap",
+			NewMailmap,
+			sql.NewRow("worktree", "John Doe", "john@doe.com"),
+			"John Doe <john@doe.com>",
+		},
+		{
+			"null name",
+			NewMailmapName,
+			sql.NewRow("worktree", nil, "mcuadros@gmail.com"),
+			"Maximo Cuadros",
+		},
+		{
+			"null name and email",
+			NewMailmap,
+			sql.NewRow("worktree", nil, nil),
+			nil,
+		},
+		{
+			"invalid repository",
+			NewMailmapEmail,
+			sql.NewRow("foo", "Máximo Cuadros", "mcuadros@gmail.com"),
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(repo, name, email).Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -17,5 +17,19 @@ var Functions = []sql.Function{
 	sql.Function1{Name: "uast_children", Fn: NewUASTChildren},
 	sql.Function1{Name: "uast_imports", Fn: NewUASTImports},
 	sql.Function1{Name: "is_vendor", Fn: NewIsVendor},
-	sql.Function3{Name: "blame", Fn: NewBlame},
+	sql.FunctionN{Name: "blame", Fn: NewBlame},
+	sql.Function3{Name: "merge_base", Fn: NewMergeBase},
+	sql.Function3{Name: "is_ancestor", Fn: NewIsAncestor},
+	sql.Function3{Name: "commit_distance", Fn: NewCommitDistance},
+	sql.Function2{Name: "rev_parse", Fn: NewRevParse},
+	sql.Function3{Name: "file_at", Fn: NewFileAt},
+	sql.Function3{Name: "file_hash_at", Fn: NewFileHashAt},
+	sql.Function3{Name: "file_mode_at", Fn: NewFileModeAt},
+	sql.FunctionN{Name: "tree_at", Fn: NewTreeAt},
+	sql.Function2{Name: "trailer", Fn: NewTrailer},
+	sql.Function3{Name: "mailmap", Fn: NewMailmap},
+	sql.Function3{Name: "mailmap_name", Fn: NewMailmapName},
+	sql.Function3{Name: "mailmap_email", Fn: NewMailmapEmail},
+	sql.Function3{Name: "verify_signature", Fn: NewVerifySignature},
+	sql.Function2{Name: "is_reachable", Fn: NewIsReachable},
 }
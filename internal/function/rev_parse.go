@@ -0,0 +1,97 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// RevParse resolves a revision, like HEAD~3, v1.2^{commit},
+// master@{upstream} or an abbreviated hash, to the hash of its commit.
+type RevParse struct {
+	Repository sql.Expression
+	Revision   sql.Expression
+}
+
+var _ gitbase.RevisionExpression = (*RevParse)(nil)
+
+// NewRevParse creates a new REV_PARSE function.
+func NewRevParse(repo, rev sql.Expression) sql.Expression {
+	return &RevParse{repo, rev}
+}
+
+func (f *RevParse) String() string {
+	return fmt.Sprintf("rev_parse(%s, %s)", f.Repository, f.Revision)
+}
+
+// Type implements the Expression interface.
+func (*RevParse) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *RevParse) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewRevParse(children[0], children[1]), nil
+}
+
+// Children implements the Expression interface.
+func (f *RevParse) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Revision}
+}
+
+// IsNullable implements the Expression interface.
+func (*RevParse) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *RevParse) Resolved() bool {
+	return f.Repository.Resolved() && f.Revision.Resolved()
+}
+
+// RevisionArguments implements the gitbase.RevisionExpression interface.
+func (f *RevParse) RevisionArguments() (sql.Expression, sql.Expression) {
+	return f.Repository, f.Revision
+}
+
+// Eval implements the Expression interface.
+func (f *RevParse) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.RevParse")
+	defer span.Finish()
+
+	rev, err := exprToString(ctx, f.Revision, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if rev == "" {
+		return nil, nil
+	}
+
+	r, err := resolveRepo(ctx, row, f.Repository)
+	if err != nil {
+		ctx.Warn(0, "rev_parse: unable to resolve repository")
+		logrus.WithField("err", err).Error("rev_parse: unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	hash, err := gitbase.ResolveRevision(r, rev)
+	if err != nil {
+		ctx.Warn(0, "rev_parse: unable to resolve revision %s of repository: %v", rev, r)
+		logrus.WithFields(logrus.Fields{
+			"repository": r,
+			"revision":   rev,
+			"err":        err,
+		}).Error("rev_parse: unable to resolve revision")
+		return nil, nil
+	}
+
+	return hash.String(), nil
+}
@@ -0,0 +1,48 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevParse(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	f := NewRevParse(
+		expression.NewGetField(0, sql.Text, "repository_id", false),
+		expression.NewGetField(1, sql.Text, "revision", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"reference", sql.NewRow("worktree", "HEAD"), "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"},
+		{"ancestor", sql.NewRow("worktree", "HEAD~3"), "1669dce138d9b841a518c64b10914d88f5e488ea"},
+		{"second parent", sql.NewRow("worktree", "HEAD~3^2"), "a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69"},
+		{"abbreviated hash", sql.NewRow("worktree", "6ecf0ef2"), "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"},
+		{"remote branch", sql.NewRow("worktree", "origin/branch"), "e8d3ffab552895c19b9fcf7aa264d277cde33881"},
+		{"unsupported revision", sql.NewRow("worktree", "HEAD:README"), nil},
+		{"invalid revision", sql.NewRow("worktree", "foobar"), nil},
+		{"null revision", sql.NewRow("worktree", nil), nil},
+		{"invalid repository", sql.NewRow("foobar", "HEAD"), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := f.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package function
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/gitbase/internal/trailer"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// Trailer returns the values of a trailer of a commit message, like
+// Signed-off-by or Co-authored-by.
+type Trailer struct {
+	expression.BinaryExpression
+}
+
+// NewTrailer creates a new TRAILER function.
+func NewTrailer(message, key sql.Expression) sql.Expression {
+	return &Trailer{expression.BinaryExpression{Left: message, Right: key}}
+}
+
+func (f *Trailer) String() string {
+	return fmt.Sprintf("trailer(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (*Trailer) Type() sql.Type {
+	return sql.Text
+}
+
+// IsNullable implements the Expression interface.
+func (*Trailer) IsNullable() bool {
+	return true
+}
+
+// WithChildren implements the Expression interface.
+func (f *Trailer) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewTrailer(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface. The keys are compared without
+// case, and if the trailer appears more than once its values are returned
+// in separate lines, like git log --format='%(trailers:key=<key>,valueonly)'
+// does. It returns NULL if the message does not have the trailer.
+func (f *Trailer) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.Trailer")
+	defer span.Finish()
+
+	msg, err := exprToString(ctx, f.Left, row)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := exprToString(ctx, f.Right, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg == "" || key == "" {
+		return nil, nil
+	}
+
+	var values []string
+	for _, t := range trailer.Parse(msg) {
+		if strings.EqualFold(t.Key, key) {
+			values = append(values, t.Value)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	return strings.Join(values, "\n"), nil
+}
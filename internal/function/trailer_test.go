@@ -0,0 +1,43 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailer(t *testing.T) {
+	f := NewTrailer(
+		expression.NewGetField(0, sql.Text, "commit_message", true),
+		expression.NewGetField(1, sql.Text, "key", true),
+	)
+
+	msg := "Fix foo\n\nSome details.\n\n" +
+		"Co-authored-by: Jane Doe <jane@doe.com>\n" +
+		"Signed-off-by: John Doe <john@doe.com>\n" +
+		"Co-authored-by: Alice <alice@example.com>\n"
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"trailer", sql.NewRow(msg, "Signed-off-by"), "John Doe <john@doe.com>"},
+		{"key case", sql.NewRow(msg, "signed-off-by"), "John Doe <john@doe.com>"},
+		{"many values", sql.NewRow(msg, "Co-authored-by"), "Jane Doe <jane@doe.com>\nAlice <alice@example.com>"},
+		{"missing trailer", sql.NewRow(msg, "Change-Id"), nil},
+		{"no trailers", sql.NewRow("Signed-off-by: John Doe\n", "Signed-off-by"), nil},
+		{"null message", sql.NewRow(nil, "Signed-off-by"), nil},
+		{"null key", sql.NewRow(msg, nil), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/gitbase/internal/signature"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// VerifySignature verifies the signature of a commit or tag with a keyring
+// of the keyrings directory, returning the signer and whether it's valid.
+type VerifySignature struct {
+	Repository sql.Expression
+	Hash       sql.Expression
+	Keyring    sql.Expression
+}
+
+// NewVerifySignature creates a new VERIFY_SIGNATURE function.
+func NewVerifySignature(repo, hash, keyring sql.Expression) sql.Expression {
+	return &VerifySignature{repo, hash, keyring}
+}
+
+func (f *VerifySignature) String() string {
+	return fmt.Sprintf("verify_signature(%s, %s, %s)", f.Repository, f.Hash, f.Keyring)
+}
+
+// Type implements the Expression interface.
+func (*VerifySignature) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *VerifySignature) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewVerifySignature(children[0], children[1], children[2]), nil
+}
+
+// Children implements the Expression interface.
+func (f *VerifySignature) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Hash, f.Keyring}
+}
+
+// IsNullable implements the Expression interface.
+func (*VerifySignature) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *VerifySignature) Resolved() bool {
+	return f.Repository.Resolved() && f.Hash.Resolved() && f.Keyring.Resolved()
+}
+
+// Eval implements the Expression interface.
+func (f *VerifySignature) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.VerifySignature")
+	defer span.Finish()
+
+	hash, err := exprToString(ctx, f.Hash, row)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := exprToString(ctx, f.Keyring, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash == "" || name == "" {
+		return nil, nil
+	}
+
+	r, err := resolveRepo(ctx, row, f.Repository)
+	if err != nil {
+		ctx.Warn(0, "verify_signature: unable to resolve repository")
+		logrus.WithField("err", err).Error("verify_signature: unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	log := logrus.WithField("repository", r)
+
+	// resolveRepo already checked the session is a gitbase session.
+	keyring, err := ctx.Session.(*gitbase.Session).Keyring(name)
+	if err != nil {
+		ctx.Warn(0, "verify_signature: unable to read keyring %s", name)
+		log.WithFields(logrus.Fields{
+			"err":     err,
+			"keyring": name,
+		}).Error("verify_signature: unable to read keyring")
+		return nil, nil
+	}
+
+	sig, payload, err := objectSignature(r, plumbing.NewHash(hash))
+	if err != nil {
+		ctx.Warn(0, "verify_signature: unable to read object %s of repository: %v", hash, r)
+		log.WithFields(logrus.Fields{
+			"err":  err,
+			"hash": hash,
+		}).Error("verify_signature: unable to read object")
+		return nil, nil
+	}
+
+	if sig == "" {
+		return nil, nil
+	}
+
+	result, err := keyring.Verify(payload, sig)
+	if err != nil {
+		ctx.Warn(0, "verify_signature: unable to verify object %s of repository: %v", hash, r)
+		log.WithFields(logrus.Fields{
+			"err":  err,
+			"hash": hash,
+		}).Error("verify_signature: unable to verify signature")
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// objectSignature returns the signature of the commit or tag with the given
+// hash and the payload signed by it. The signature is empty if the object
+// is not signed or is of any other type.
+func objectSignature(r *gitbase.Repository, hash plumbing.Hash) (string, []byte, error) {
+	obj, err := r.Object(plumbing.AnyObject, hash)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch obj := obj.(type) {
+	case *object.Commit:
+		return signature.Commit(obj)
+	case *object.Tag:
+		return signature.Tag(obj)
+	default:
+		return "", nil, nil
+	}
+}
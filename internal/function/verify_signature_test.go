@@ -0,0 +1,139 @@
+package function
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/gitbase/internal/signature"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	require := require.New(t)
+
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	entity, err := openpgp.NewEntity("John Doe", "", "john@doe.com", nil)
+	require.NoError(err)
+
+	dir, err := ioutil.TempDir("", "gitbase-keyrings")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	var keys bytes.Buffer
+	w, err := armor.Encode(&keys, openpgp.PublicKeyType, nil)
+	require.NoError(err)
+	require.NoError(entity.Serialize(w))
+	require.NoError(w.Close())
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "team.asc"), keys.Bytes(), 0644))
+
+	// Add to the repository a signed copy of the HEAD commit.
+	r, err := pool.GetRepo("worktree")
+	require.NoError(err)
+
+	c, err := r.CommitObject(plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
+	require.NoError(err)
+
+	unsigned := &plumbing.MemoryObject{}
+	require.NoError(c.EncodeWithoutSignature(unsigned))
+	reader, err := unsigned.Reader()
+	require.NoError(err)
+
+	var sig bytes.Buffer
+	require.NoError(openpgp.ArmoredDetachSign(&sig, entity, reader, nil))
+	c.PGPSignature = sig.String()
+
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(c.Encode(obj))
+	signed, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(err)
+	r.Close()
+
+	session := gitbase.NewSession(pool, gitbase.WithKeyringsDir(dir))
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewVerifySignature(
+		expression.NewGetField(0, sql.Text, "repository_id", false),
+		expression.NewGetField(1, sql.Text, "hash", true),
+		expression.NewGetField(2, sql.Text, "keyring", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{
+			"signed commit",
+			sql.NewRow("worktree", signed.String(), "team.asc"),
+			&signature.Result{
+				Type:   signature.GPG,
+				Signer: "John Doe <john@doe.com>",
+				Key:    fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint[:]),
+				Valid:  true,
+			},
+		},
+		{
+			"unsigned commit",
+			sql.NewRow("worktree", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", "team.asc"),
+			nil,
+		},
+		{
+			"blob",
+			sql.NewRow("worktree", "d3ff53e0564a9f87d8e84b6e28e5060e517008aa", "team.asc"),
+			nil,
+		},
+		{
+			"missing object",
+			sql.NewRow("worktree", "0000000000000000000000000000000000000001", "team.asc"),
+			nil,
+		},
+		{
+			"missing keyring",
+			sql.NewRow("worktree", signed.String(), "missing.asc"),
+			nil,
+		},
+		{
+			"keyring outside directory",
+			sql.NewRow("worktree", signed.String(), "../team.asc"),
+			nil,
+		},
+		{
+			"null keyring",
+			sql.NewRow("worktree", signed.String(), nil),
+			nil,
+		},
+		{
+			"invalid repository",
+			sql.NewRow("foo", signed.String(), "team.asc"),
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(err)
+			require.Equal(tt.expected, result)
+		})
+	}
+
+	// Without keyrings directory no signature can be verified.
+	session = gitbase.NewSession(pool)
+	ctx = sql.NewContext(context.TODO(), sql.WithSession(session))
+	result, err := fn.Eval(ctx, sql.NewRow("worktree", signed.String(), "team.asc"))
+	require.NoError(err)
+	require.Nil(result)
+}
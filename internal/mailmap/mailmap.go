@@ -0,0 +1,185 @@
+// Package mailmap maps the names and emails of authors and committers to
+// their canonical ones with mailmap files, as git does.
+package mailmap
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// FileName is the name of the mailmap file in the root directory of a
+// repository.
+const FileName = ".mailmap"
+
+// Mailmap maps names and emails to the canonical ones. The zero value and
+// nil are empty mailmaps, which do not change any identity.
+type Mailmap struct {
+	// entries are indexed by the lowercase email they replace.
+	entries map[string]*entry
+}
+
+type identity struct {
+	// name and email are the canonical ones, and they are empty if they
+	// are not replaced.
+	name  string
+	email string
+}
+
+type entry struct {
+	identity
+	// names are the identities of the entries that replace a name and an
+	// email, indexed by the lowercase name.
+	names map[string]identity
+}
+
+// Read adds the entries of the mailmap file in r, which replace the ones
+// already added for the same identity. Each line of the file has one of
+// these forms, and comments start with #:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func (m *Mailmap) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m.readLine(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+func (m *Mailmap) readLine(line string) {
+	if strings.HasPrefix(line, "#") {
+		return
+	}
+
+	name1, email1, rest, ok := parseNameAndEmail(line, false)
+	if !ok {
+		return
+	}
+
+	name2, email2, _, ok := parseNameAndEmail(rest, true)
+	if !ok {
+		// There is only the commit email.
+		name2, email2 = "", email1
+		email1 = ""
+	}
+
+	if m.entries == nil {
+		m.entries = make(map[string]*entry)
+	}
+
+	key := strings.ToLower(email2)
+	e, ok := m.entries[key]
+	if !ok {
+		e = new(entry)
+		m.entries[key] = e
+	}
+
+	if name2 == "" {
+		if name1 != "" {
+			e.name = name1
+		}
+
+		if email1 != "" {
+			e.email = email1
+		}
+
+		return
+	}
+
+	if e.names == nil {
+		e.names = make(map[string]identity)
+	}
+
+	e.names[strings.ToLower(name2)] = identity{name: name1, email: email1}
+}
+
+// Merge adds the entries of other, which replace the ones already added
+// for the same identity, as if its file was read after the ones of m.
+func (m *Mailmap) Merge(other *Mailmap) {
+	if other == nil {
+		return
+	}
+
+	for key, oe := range other.entries {
+		if m.entries == nil {
+			m.entries = make(map[string]*entry)
+		}
+
+		e, ok := m.entries[key]
+		if !ok {
+			e = new(entry)
+			m.entries[key] = e
+		}
+
+		if oe.name != "" {
+			e.name = oe.name
+		}
+
+		if oe.email != "" {
+			e.email = oe.email
+		}
+
+		for name, id := range oe.names {
+			if e.names == nil {
+				e.names = make(map[string]identity)
+			}
+
+			e.names[name] = id
+		}
+	}
+}
+
+// parseNameAndEmail returns the name and the email of the identity at the
+// start of s, in the form `Name <email>`, and the text after it.
+func parseNameAndEmail(s string, allowEmptyEmail bool) (name, email, rest string, ok bool) {
+	left := strings.IndexByte(s, '<')
+	if left < 0 {
+		return "", "", "", false
+	}
+
+	right := strings.IndexByte(s[left+1:], '>')
+	if right < 0 {
+		return "", "", "", false
+	}
+	right += left + 1
+
+	email = s[left+1 : right]
+	if email == "" && !allowEmptyEmail {
+		return "", "", "", false
+	}
+
+	return strings.TrimSpace(s[:left]), email, s[right+1:], true
+}
+
+// Map returns the canonical name and email of the given ones. Names and
+// emails are compared without case, and they are returned unchanged if
+// there is no entry for them.
+func (m *Mailmap) Map(name, email string) (string, string) {
+	if m == nil {
+		return name, email
+	}
+
+	e, ok := m.entries[strings.ToLower(email)]
+	if !ok {
+		return name, email
+	}
+
+	id := e.identity
+	if named, ok := e.names[strings.ToLower(name)]; ok {
+		id = named
+	}
+
+	if id.name != "" {
+		name = id.name
+	}
+
+	if id.email != "" {
+		email = id.email
+	}
+
+	return name, email
+}
@@ -0,0 +1,106 @@
+package mailmap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testMailmap = `# Comment
+Jane Doe <jane@doe.com>
+<john@doe.com> <john@old.com>
+John Doe <john@doe.com> <John@Laptop.local>
+Jane Doe <jane@doe.com> jd <shared@example.com>
+John Doe <john@doe.com> JOHN <shared@example.com>
+Someone Else <else@example.com> # trailing comment
+`
+
+func TestMap(t *testing.T) {
+	var m Mailmap
+	require.NoError(t, m.Read(strings.NewReader(testMailmap)))
+
+	testCases := []struct {
+		name, email       string
+		expName, expEmail string
+	}{
+		{"jane", "jane@doe.com", "Jane Doe", "jane@doe.com"},
+		{"jane", "JANE@doe.com", "Jane Doe", "JANE@doe.com"},
+		{"John", "john@old.com", "John", "john@doe.com"},
+		{"john", "john@laptop.local", "John Doe", "john@doe.com"},
+		{"JD", "shared@example.com", "Jane Doe", "jane@doe.com"},
+		{"John", "shared@example.com", "John Doe", "john@doe.com"},
+		{"Other", "shared@example.com", "Other", "shared@example.com"},
+		{"else", "else@example.com", "Someone Else", "else@example.com"},
+		{"Unknown", "unknown@example.com", "Unknown", "unknown@example.com"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name+" "+tt.email, func(t *testing.T) {
+			name, email := m.Map(tt.name, tt.email)
+			require.Equal(t, tt.expName, name)
+			require.Equal(t, tt.expEmail, email)
+		})
+	}
+}
+
+func TestReadOverrides(t *testing.T) {
+	require := require.New(t)
+
+	var m Mailmap
+	require.NoError(m.Read(strings.NewReader("Jane <jane@doe.com>\n")))
+	require.NoError(m.Read(strings.NewReader("<jane@example.com> <jane@doe.com>\n")))
+
+	name, email := m.Map("jd", "jane@doe.com")
+	require.Equal("Jane", name)
+	require.Equal("jane@example.com", email)
+
+	require.NoError(m.Read(strings.NewReader("Jane Doe <jane@doe.com>\n")))
+	name, _ = m.Map("jd", "jane@doe.com")
+	require.Equal("Jane Doe", name)
+}
+
+func TestMerge(t *testing.T) {
+	require := require.New(t)
+
+	var m Mailmap
+	require.NoError(m.Read(strings.NewReader(
+		"Jane <jane@doe.com>\n" +
+			"<john@example.com> <john@doe.com>\n" +
+			"Jim <jim@example.com> jd <jim@doe.com>\n",
+	)))
+
+	var other Mailmap
+	require.NoError(other.Read(strings.NewReader(
+		"<jane@example.com> <jane@doe.com>\n" +
+			"John Doe <john@doe.com>\n" +
+			"James <james@example.com> jd <jim@doe.com>\n",
+	)))
+
+	m.Merge(&other)
+	m.Merge(nil)
+
+	name, email := m.Map("jd", "jane@doe.com")
+	require.Equal("Jane", name)
+	require.Equal("jane@example.com", email)
+
+	name, email = m.Map("jd", "john@doe.com")
+	require.Equal("John Doe", name)
+	require.Equal("john@example.com", email)
+
+	name, email = m.Map("jd", "jim@doe.com")
+	require.Equal("James", name)
+	require.Equal("james@example.com", email)
+
+	// The merged mailmap is not changed.
+	name, email = other.Map("jd", "jane@doe.com")
+	require.Equal("jd", name)
+	require.Equal("jane@example.com", email)
+}
+
+func TestMapNil(t *testing.T) {
+	var m *Mailmap
+	name, email := m.Map("jane", "jane@doe.com")
+	require.Equal(t, "jane", name)
+	require.Equal(t, "jane@doe.com", email)
+}
@@ -0,0 +1,413 @@
+// Package renames detects the files renamed or copied between two trees,
+// pairing the deleted and added files with similar contents.
+package renames
+
+import (
+	"bytes"
+	"hash/fnv"
+	stdioutil "io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/src-d/enry/v2"
+	"github.com/src-d/go-git/utils/binary"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/utils/ioutil"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+)
+
+const (
+	// SimilarityKey is the environment variable with the minimum similarity
+	// percentage of renamed files.
+	SimilarityKey = "GITBASE_RENAME_SIMILARITY"
+	// DefaultSimilarity is the similarity used when SimilarityKey is not set
+	// or is not valid.
+	DefaultSimilarity = 50
+	// DetectCopiesKey is the environment variable that enables the detection
+	// of copies when it's set.
+	DetectCopiesKey = "GITBASE_DETECT_COPIES"
+
+	// limit is the maximum number of added and deleted files for which
+	// renames are detected comparing their contents. Above this limit only
+	// files with the exact same content are detected as renamed.
+	limit = 1000
+	// maxFileSize is the maximum size of the files compared to detect
+	// renames. Bigger files are only detected as renamed if their content
+	// did not change.
+	maxFileSize = 1024 * 1024
+)
+
+// Options are the options used to detect renames.
+type Options struct {
+	// RenameSimilarity is the minimum similarity percentage between a
+	// deleted and an added file for them to be considered a rename. Rename
+	// detection is disabled if it's 0.
+	RenameSimilarity int
+	// DetectCopies enables the detection of added files that are copies of
+	// modified or deleted files, using the same similarity threshold as
+	// renames.
+	DetectCopies bool
+}
+
+// OptionsFromEnv returns the options set in the environment with
+// SimilarityKey and DetectCopiesKey.
+func OptionsFromEnv() Options {
+	return Options{
+		RenameSimilarity: similarityFromEnv(),
+		DetectCopies:     detectCopiesFromEnv(),
+	}
+}
+
+// similarityFromEnv returns the similarity percentage set in the
+// environment, or DefaultSimilarity if it's not set or not a percentage. A
+// similarity of 0 disables the detection.
+func similarityFromEnv() int {
+	similarity, err := strconv.Atoi(os.Getenv(SimilarityKey))
+	if err != nil || similarity < 0 || similarity > 100 {
+		return DefaultSimilarity
+	}
+
+	return similarity
+}
+
+func detectCopiesFromEnv() bool {
+	_, ok := os.LookupEnv(DetectCopiesKey)
+	return ok
+}
+
+// Change is a change of a file. Renamed and copied files have a change with
+// the old file as the source and the new one as the destination.
+type Change struct {
+	*object.Change
+	// Similarity is the similarity percentage between the old and new
+	// contents of a renamed or copied file, and 0 for the rest of changes.
+	Similarity int
+	// Copy is whether the file was copied, so the old file is still there.
+	Copy bool
+}
+
+// IsRename returns whether the change is a renamed file.
+func (c Change) IsRename() bool {
+	return c.Similarity > 0 && !c.Copy
+}
+
+// match is a pair of similar files.
+type match struct {
+	src, dst   *object.Change
+	similarity int
+}
+
+// Detect returns the given changes, replacing the deleted and added files
+// that are similar enough with a single change for the renamed file. Copies
+// of modified or deleted files are also detected if enabled in the options.
+// The order of the changes is kept, with renames and copies in the position
+// of their added file. Only regular files are compared, and vendored files
+// are never paired.
+func Detect(
+	s storer.EncodedObjectStorer,
+	changes object.Changes,
+	opts Options,
+) ([]Change, error) {
+	var added, deleted, modified []*object.Change
+	for _, ch := range changes {
+		a, err := ch.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		switch a {
+		case merkletrie.Insert:
+			added = append(added, ch)
+		case merkletrie.Delete:
+			deleted = append(deleted, ch)
+		default:
+			modified = append(modified, ch)
+		}
+	}
+
+	var matches = make(map[*object.Change]match)
+	var renamed = make(map[*object.Change]bool)
+	var copied = make(map[*object.Change]bool)
+	var sigs = make(signatures)
+	if opts.RenameSimilarity > 0 && len(added) > 0 && len(deleted) > 0 {
+		renames, err := matchFiles(s, sigs, deleted, added, opts.RenameSimilarity, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range renames {
+			matches[m.dst] = m
+			renamed[m.src] = true
+		}
+	}
+
+	if opts.RenameSimilarity > 0 && opts.DetectCopies {
+		var targets []*object.Change
+		for _, ch := range added {
+			if _, ok := matches[ch]; !ok {
+				targets = append(targets, ch)
+			}
+		}
+
+		if len(targets) > 0 {
+			sources := append(append([]*object.Change(nil), modified...), deleted...)
+			copies, err := matchFiles(s, sigs, sources, targets, opts.RenameSimilarity, false)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, m := range copies {
+				matches[m.dst] = m
+				copied[m.dst] = true
+			}
+		}
+	}
+
+	var result = make([]Change, 0, len(changes))
+	for _, ch := range changes {
+		if renamed[ch] {
+			continue
+		}
+
+		m, ok := matches[ch]
+		if !ok {
+			result = append(result, Change{Change: ch})
+			continue
+		}
+
+		result = append(result, Change{
+			Change:     &object.Change{From: m.src.From, To: m.dst.To},
+			Similarity: m.similarity,
+			Copy:       copied[ch],
+		})
+	}
+
+	return result, nil
+}
+
+// isComparable returns whether the file of the change entry can be paired
+// with another one.
+func isComparable(e object.ChangeEntry) bool {
+	return e.TreeEntry.Mode.IsFile() && !enry.IsVendor(e.Name)
+}
+
+// matchFiles returns the pairs of the old file of a source change and the
+// new file of a destination change whose similarity is at least the given
+// threshold. The most similar pairs are matched first, and every destination
+// is matched at most once. If exclusive is true, every source is matched at
+// most once too. Files with the same content are found by their hash, and
+// the rest are only compared if there are not too many of them.
+func matchFiles(
+	s storer.EncodedObjectStorer,
+	sigs signatures,
+	srcs, dsts []*object.Change,
+	threshold int,
+	exclusive bool,
+) ([]match, error) {
+	var sources []*object.Change
+	var byHash = make(map[plumbing.Hash][]*object.Change)
+	for _, src := range srcs {
+		if isComparable(src.From) {
+			sources = append(sources, src)
+			byHash[src.From.TreeEntry.Hash] = append(byHash[src.From.TreeEntry.Hash], src)
+		}
+	}
+
+	var candidates []match
+	var compared []*object.Change
+	for _, dst := range dsts {
+		if !isComparable(dst.To) {
+			continue
+		}
+
+		compared = append(compared, dst)
+		for _, src := range byHash[dst.To.TreeEntry.Hash] {
+			if src.From.TreeEntry.Mode == dst.To.TreeEntry.Mode {
+				candidates = append(candidates, match{src, dst, 100})
+			}
+		}
+	}
+
+	if len(sources)*len(compared) <= limit*limit {
+		for _, dst := range compared {
+			b, err := sigs.get(s, dst.To.TreeEntry.Hash)
+			if err != nil {
+				return nil, err
+			}
+
+			if b == nil {
+				continue
+			}
+
+			for _, src := range sources {
+				if src.From.TreeEntry.Hash == dst.To.TreeEntry.Hash ||
+					src.From.TreeEntry.Mode != dst.To.TreeEntry.Mode {
+					continue
+				}
+
+				a, err := sigs.get(s, src.From.TreeEntry.Hash)
+				if err != nil {
+					return nil, err
+				}
+
+				if a == nil {
+					continue
+				}
+
+				if sim := a.similarity(b); sim >= threshold {
+					candidates = append(candidates, match{src, dst, sim})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	var result []match
+	var usedSrcs = make(map[*object.Change]bool)
+	var usedDsts = make(map[*object.Change]bool)
+	for _, c := range candidates {
+		if usedDsts[c.dst] || (exclusive && usedSrcs[c.src]) {
+			continue
+		}
+
+		usedSrcs[c.src] = true
+		usedDsts[c.dst] = true
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// line is a distinct line of a blob.
+type line struct {
+	count int
+	size  int
+}
+
+// signature holds the lines of a blob by their hash, so blobs can be
+// compared without reading their contents again.
+type signature struct {
+	lines map[uint64]line
+	size  int
+}
+
+// newSignature returns the signature of the given content.
+func newSignature(content []byte) *signature {
+	sig := &signature{lines: make(map[uint64]line), size: len(content)}
+	for len(content) > 0 {
+		n := bytes.IndexByte(content, '\n') + 1
+		if n == 0 {
+			n = len(content)
+		}
+
+		h := fnv.New64a()
+		_, _ = h.Write(content[:n])
+		key := h.Sum64()
+
+		l := sig.lines[key]
+		l.count++
+		l.size = n
+		sig.lines[key] = l
+
+		content = content[n:]
+	}
+
+	return sig
+}
+
+// similarity returns how similar two blobs are as a percentage. It's the
+// size of the lines both blobs have in common relative to the size of the
+// biggest one.
+func (a *signature) similarity(b *signature) int {
+	max := a.size
+	if b.size > max {
+		max = b.size
+	}
+
+	if max == 0 {
+		return 100
+	}
+
+	var common int
+	for key, la := range a.lines {
+		lb, ok := b.lines[key]
+		if !ok {
+			continue
+		}
+
+		count := la.count
+		if lb.count < count {
+			count = lb.count
+		}
+
+		common += count * la.size
+	}
+
+	return common * 100 / max
+}
+
+// signatures caches the signatures of the blobs by their hash. Binary blobs
+// and blobs too big to be compared have a nil signature.
+type signatures map[plumbing.Hash]*signature
+
+// get returns the signature of the blob with the given hash, computing it
+// the first time it's requested.
+func (sigs signatures) get(
+	s storer.EncodedObjectStorer,
+	hash plumbing.Hash,
+) (*signature, error) {
+	if sig, ok := sigs[hash]; ok {
+		return sig, nil
+	}
+
+	blob, err := object.GetBlob(s, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if blob.Size > maxFileSize {
+		sigs[hash] = nil
+		return nil, nil
+	}
+
+	content, err := blobContent(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	if content == nil {
+		sigs[hash] = nil
+		return nil, nil
+	}
+
+	sigs[hash] = newSignature(content)
+	return sigs[hash], nil
+}
+
+// blobContent returns the content of the blob, or nil if it's binary.
+func blobContent(blob *object.Blob) (content []byte, err error) {
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	defer ioutil.CheckClose(r, &err)
+
+	content, err = stdioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bin, err := binary.IsBinary(bytes.NewReader(content))
+	if err != nil || bin {
+		return nil, err
+	}
+
+	return content, nil
+}
@@ -0,0 +1,190 @@
+package renames
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	testCases := []struct {
+		name       string
+		similarity string
+		copies     bool
+		expected   Options
+	}{
+		{"default", "", false, Options{RenameSimilarity: DefaultSimilarity}},
+		{"similarity", "80", false, Options{RenameSimilarity: 80}},
+		{"disabled", "0", false, Options{RenameSimilarity: 0}},
+		{"not a number", "foo", false, Options{RenameSimilarity: DefaultSimilarity}},
+		{"negative", "-1", false, Options{RenameSimilarity: DefaultSimilarity}},
+		{"too big", "101", false, Options{RenameSimilarity: DefaultSimilarity}},
+		{"copies", "", true, Options{RenameSimilarity: DefaultSimilarity, DetectCopies: true}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			if tt.similarity != "" {
+				require.NoError(os.Setenv(SimilarityKey, tt.similarity))
+				defer os.Unsetenv(SimilarityKey)
+			}
+
+			if tt.copies {
+				require.NoError(os.Setenv(DetectCopiesKey, ""))
+				defer os.Unsetenv(DetectCopiesKey)
+			}
+
+			require.Equal(tt.expected, OptionsFromEnv())
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     string
+		expected int
+	}{
+		{"empty", "", "", 100},
+		{"equal", "a\nb\n", "a\nb\n", 100},
+		{"different", "a\nb\n", "c\nd\n", 0},
+		{"one line changed", "aa\nbb\ncc\ndd\n", "aa\nbb\ncc\nee\n", 75},
+		{"lines added", "aa\nbb\n", "aa\nbb\ncc\ndd\n", 50},
+		{"reordered", "aa\nbb\n", "bb\naa\n", 100},
+		{"repeated lines", "aa\naa\nbb\n", "aa\nbb\nbb\n", 66},
+		{"no final newline", "aa\nbb", "aa\nbb\n", 50},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSignature([]byte(tt.a))
+			b := newSignature([]byte(tt.b))
+			require.Equal(t, tt.expected, a.similarity(b))
+			require.Equal(t, tt.expected, b.similarity(a))
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	require := require.New(t)
+
+	r, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(err)
+
+	var lines string
+	for i := 0; i < 10; i++ {
+		lines += fmt.Sprintf("line %d\n", i)
+	}
+
+	from := testTree(t, r, map[string]string{
+		"a.go":     lines,
+		"b.txt":    "foo\nbar\n",
+		"c.txt":    "quux\nquuz\n",
+		"same.txt": "same\n",
+	})
+
+	to := testTree(t, r, map[string]string{
+		"b.txt":    "foo\nbaz\n",
+		"copy.txt": "foo\nbar\n",
+		"moved.go": strings.Replace(lines, "line 9", "LINE 9", 1),
+		"new.txt":  "qux\n",
+		"same.md":  "same\n",
+	})
+
+	changes, err := object.DiffTree(from, to)
+	require.NoError(err)
+
+	type change struct {
+		from, to   string
+		similarity int
+		copy       bool
+	}
+
+	toChanges := func(chs []Change) []change {
+		var result []change
+		for _, ch := range chs {
+			result = append(result, change{ch.From.Name, ch.To.Name, ch.Similarity, ch.Copy})
+		}
+		return result
+	}
+
+	chs, err := Detect(r.Storer, changes, Options{})
+	require.NoError(err)
+	require.Len(chs, len(changes))
+
+	chs, err = Detect(r.Storer, changes, Options{RenameSimilarity: DefaultSimilarity})
+	require.NoError(err)
+	require.ElementsMatch([]change{
+		{"a.go", "moved.go", 90, false},
+		{"b.txt", "b.txt", 0, false},
+		{"c.txt", "", 0, false},
+		{"same.txt", "same.md", 100, false},
+		{"", "copy.txt", 0, false},
+		{"", "new.txt", 0, false},
+	}, toChanges(chs))
+
+	chs, err = Detect(r.Storer, changes, Options{
+		RenameSimilarity: DefaultSimilarity,
+		DetectCopies:     true,
+	})
+	require.NoError(err)
+	require.ElementsMatch([]change{
+		{"a.go", "moved.go", 90, false},
+		{"b.txt", "b.txt", 0, false},
+		{"c.txt", "", 0, false},
+		{"same.txt", "same.md", 100, false},
+		{"b.txt", "copy.txt", 100, true},
+		{"", "new.txt", 0, false},
+	}, toChanges(chs))
+}
+
+func testTree(t *testing.T, r *git.Repository, files map[string]string) *object.Tree {
+	t.Helper()
+	require := require.New(t)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tree object.Tree
+	for _, name := range names {
+		obj := r.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(err)
+		_, err = w.Write([]byte(files[name]))
+		require.NoError(err)
+		require.NoError(w.Close())
+
+		hash, err := r.Storer.SetEncodedObject(obj)
+		require.NoError(err)
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{
+			Name: name,
+			Mode: filemode.Regular,
+			Hash: hash,
+		})
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(tree.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(err)
+
+	result, err := r.TreeObject(hash)
+	require.NoError(err)
+	return result
+}
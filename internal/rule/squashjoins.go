@@ -295,7 +295,7 @@ func buildSquashedTable(
 					return nil, err
 				}
 
-				iter = gitbase.NewAllReposIter(f)
+				iter = gitbase.NewAllReposIter(f, columns)
 			default:
 				return nil, errInvalidIteratorChain.New("repositories", iter)
 			}
@@ -378,6 +378,37 @@ func buildSquashedTable(
 				addUnsquashable(gitbase.ReferencesTableName)
 				continue
 			}
+		case gitbase.TagsTableName:
+			switch it := iter.(type) {
+			case gitbase.RefsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.ReferencesTableName,
+					gitbase.TagsTableName,
+					filters,
+					append(it.Schema(), gitbase.TagsSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewRefTagsIter(it, f)
+			case nil:
+				var f sql.Expression
+				f, filters, err = filtersForTable(
+					gitbase.TagsTableName,
+					filters,
+					gitbase.TagsSchema,
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewAllTagsIter(f)
+			default:
+				addUnsquashable(gitbase.TagsTableName)
+				continue
+			}
 		case gitbase.RefCommitsTableName:
 			switch it := iter.(type) {
 			case gitbase.ReposIter:
@@ -476,6 +507,19 @@ func buildSquashedTable(
 				}
 
 				iter = gitbase.NewRefHEADCommitsIter(it, f, false)
+			case gitbase.TagsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.TagsTableName,
+					gitbase.CommitsTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitsSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewTagCommitsIter(it, f)
 			case gitbase.CommitsIter:
 				var f sql.Expression
 				f, filters, err = filtersForJoin(
@@ -829,6 +873,204 @@ func buildSquashedTable(
 				addUnsquashable(gitbase.CommitFilesTableName)
 				continue
 			}
+		case gitbase.CommitDiffsTableName:
+			lineStats := len(columns) == 0 ||
+				stringInSlice(columns, "additions") ||
+				stringInSlice(columns, "deletions")
+
+			switch it := iter.(type) {
+			case gitbase.RefsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.ReferencesTableName,
+					gitbase.CommitDiffsTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitDiffsSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitDiffsIter(
+					gitbase.NewRefHEADCommitsIter(it, nil, true),
+					f,
+					lineStats,
+				)
+			case gitbase.RefCommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.RefCommitsTableName,
+					gitbase.CommitDiffsTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitDiffsSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitDiffsIter(it, f, lineStats)
+			case gitbase.CommitFilesIter:
+				// commit files iterators are also commits iterators, but
+				// there is one row for each file of the commit.
+				addUnsquashable(gitbase.CommitDiffsTableName)
+				continue
+			case gitbase.CommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.CommitsTableName,
+					gitbase.CommitDiffsTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitDiffsSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitDiffsIter(it, f, lineStats)
+			case nil:
+				var f sql.Expression
+				f, filters, err = filtersForTable(
+					gitbase.CommitDiffsTableName,
+					filters,
+					gitbase.CommitDiffsSchema,
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewAllCommitDiffsIter(f, lineStats)
+			default:
+				addUnsquashable(gitbase.CommitDiffsTableName)
+				continue
+			}
+		case gitbase.FileBlameTableName:
+			switch it := iter.(type) {
+			case gitbase.RefsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.ReferencesTableName,
+					gitbase.FileBlameTableName,
+					filters,
+					append(it.Schema(), gitbase.FileBlameSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewFileBlameIter(
+					gitbase.NewRefHEADCommitsIter(it, nil, true),
+					f,
+				)
+			case gitbase.RefCommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.RefCommitsTableName,
+					gitbase.FileBlameTableName,
+					filters,
+					append(it.Schema(), gitbase.FileBlameSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewFileBlameIter(it, f)
+			case gitbase.CommitFilesIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.CommitFilesTableName,
+					gitbase.FileBlameTableName,
+					filters,
+					append(it.Schema(), gitbase.FileBlameSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitFileBlameIter(it, f)
+			case gitbase.CommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.CommitsTableName,
+					gitbase.FileBlameTableName,
+					filters,
+					append(it.Schema(), gitbase.FileBlameSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewFileBlameIter(it, f)
+			case nil:
+				var f sql.Expression
+				f, filters, err = filtersForTable(
+					gitbase.FileBlameTableName,
+					filters,
+					gitbase.FileBlameSchema,
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewAllFileBlameIter(f)
+			default:
+				addUnsquashable(gitbase.FileBlameTableName)
+				continue
+			}
+		case gitbase.CommitNotesTableName:
+			switch it := iter.(type) {
+			case gitbase.RefsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.ReferencesTableName,
+					gitbase.CommitNotesTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitNotesSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitNotesIter(
+					gitbase.NewRefHEADCommitsIter(it, nil, true),
+					f,
+				)
+			case gitbase.RefCommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.RefCommitsTableName,
+					gitbase.CommitNotesTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitNotesSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitNotesIter(it, f)
+			case gitbase.CommitFilesIter:
+				// commit files iterators are also commits iterators, but
+				// there is one row for each file of the commit.
+				addUnsquashable(gitbase.CommitNotesTableName)
+				continue
+			case gitbase.CommitsIter:
+				var f sql.Expression
+				f, filters, err = filtersForJoin(
+					gitbase.CommitsTableName,
+					gitbase.CommitNotesTableName,
+					filters,
+					append(it.Schema(), gitbase.CommitNotesSchema...),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				iter = gitbase.NewCommitNotesIter(it, f)
+			default:
+				// notes can annotate any object, not only commits, so
+				// all of them are only returned by the table.
+				addUnsquashable(gitbase.CommitNotesTableName)
+				continue
+			}
 		case gitbase.FilesTableName:
 			readContent := stringInSlice(columns, "blob_content")
 
@@ -993,6 +1235,7 @@ var tableHierarchy = []string{
 	gitbase.RepositoriesTableName,
 	gitbase.RemotesTableName,
 	gitbase.ReferencesTableName,
+	gitbase.TagsTableName,
 	gitbase.RefCommitsTableName,
 	gitbase.CommitsTableName,
 	gitbase.CommitTreesTableName,
@@ -1001,6 +1244,9 @@ var tableHierarchy = []string{
 	gitbase.CommitFilesTableName,
 	gitbase.BlobsTableName,
 	gitbase.FilesTableName,
+	gitbase.CommitDiffsTableName,
+	gitbase.FileBlameTableName,
+	gitbase.CommitNotesTableName,
 }
 
 func orderedTableNames(tables []sql.Table) []string {
@@ -1380,6 +1626,16 @@ var (
 		isCol(gitbase.CommitFilesTableName, "blob_hash"),
 		isCol(gitbase.FilesTableName, "blob_hash"),
 	)
+
+	isBlameCommitHashFilter = isEq(
+		isCol(gitbase.CommitFilesTableName, "commit_hash"),
+		isCol(gitbase.FileBlameTableName, "commit_hash"),
+	)
+
+	isBlameFilePathFilter = isEq(
+		isCol(gitbase.CommitFilesTableName, "file_path"),
+		isCol(gitbase.FileBlameTableName, "file_path"),
+	)
 )
 
 // hasRedundantCompoindFilter returns whether there is any compound redundant
@@ -1403,6 +1659,19 @@ func hasRedundantCompoundFilter(filters []sql.Expression, t1, t2 string) bool {
 		return filePath && treeHash && blobHash
 	}
 
+	if t1 == gitbase.CommitFilesTableName && t2 == gitbase.FileBlameTableName {
+		var commitHash, filePath bool
+		for _, f := range filters {
+			if isBlameCommitHashFilter(f) {
+				commitHash = true
+			} else if isBlameFilePathFilter(f) {
+				filePath = true
+			}
+		}
+
+		return commitHash && filePath
+	}
+
 	return false
 }
 
@@ -1424,6 +1693,17 @@ func removeRedundantCompoundFilters(
 		return result
 	}
 
+	if t1 == gitbase.CommitFilesTableName && t2 == gitbase.FileBlameTableName {
+		var result []sql.Expression
+		for _, f := range filters {
+			if !isBlameCommitHashFilter(f) && !isBlameFilePathFilter(f) {
+				result = append(result, f)
+			}
+		}
+
+		return result
+	}
+
 	return filters
 }
 
@@ -1456,6 +1736,16 @@ func isRedundantFilter(f sql.Expression, t1, t2 string) bool {
 			isCol(gitbase.ReferencesTableName, "commit_hash"),
 			isCol(gitbase.CommitsTableName, "commit_hash"),
 		)(f)
+	case t1 == gitbase.ReferencesTableName && t2 == gitbase.TagsTableName:
+		return isEq(
+			isCol(gitbase.ReferencesTableName, "ref_name"),
+			isCol(gitbase.TagsTableName, "ref_name"),
+		)(f)
+	case t1 == gitbase.TagsTableName && t2 == gitbase.CommitsTableName:
+		return isEq(
+			isCol(gitbase.TagsTableName, "target_hash"),
+			isCol(gitbase.CommitsTableName, "commit_hash"),
+		)(f)
 	case t1 == gitbase.ReferencesTableName && t2 == gitbase.RefCommitsTableName:
 		return isEq(
 			isCol(gitbase.ReferencesTableName, "ref_name"),
@@ -1558,6 +1848,51 @@ func isRedundantFilter(f sql.Expression, t1, t2 string) bool {
 			isCol(gitbase.CommitFilesTableName, "blob_hash"),
 			isCol(gitbase.BlobsTableName, "blob_hash"),
 		)(f)
+	case t1 == gitbase.ReferencesTableName && t2 == gitbase.CommitDiffsTableName:
+		return isEq(
+			isCol(gitbase.ReferencesTableName, "commit_hash"),
+			isCol(gitbase.CommitDiffsTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.RefCommitsTableName && t2 == gitbase.CommitDiffsTableName:
+		return isEq(
+			isCol(gitbase.RefCommitsTableName, "commit_hash"),
+			isCol(gitbase.CommitDiffsTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.CommitsTableName && t2 == gitbase.CommitDiffsTableName:
+		return isEq(
+			isCol(gitbase.CommitsTableName, "commit_hash"),
+			isCol(gitbase.CommitDiffsTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.ReferencesTableName && t2 == gitbase.FileBlameTableName:
+		return isEq(
+			isCol(gitbase.ReferencesTableName, "commit_hash"),
+			isCol(gitbase.FileBlameTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.RefCommitsTableName && t2 == gitbase.FileBlameTableName:
+		return isEq(
+			isCol(gitbase.RefCommitsTableName, "commit_hash"),
+			isCol(gitbase.FileBlameTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.CommitsTableName && t2 == gitbase.FileBlameTableName:
+		return isEq(
+			isCol(gitbase.CommitsTableName, "commit_hash"),
+			isCol(gitbase.FileBlameTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.ReferencesTableName && t2 == gitbase.CommitNotesTableName:
+		return isEq(
+			isCol(gitbase.ReferencesTableName, "commit_hash"),
+			isCol(gitbase.CommitNotesTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.RefCommitsTableName && t2 == gitbase.CommitNotesTableName:
+		return isEq(
+			isCol(gitbase.RefCommitsTableName, "commit_hash"),
+			isCol(gitbase.CommitNotesTableName, "commit_hash"),
+		)(f)
+	case t1 == gitbase.CommitsTableName && t2 == gitbase.CommitNotesTableName:
+		return isEq(
+			isCol(gitbase.CommitsTableName, "commit_hash"),
+			isCol(gitbase.CommitNotesTableName, "commit_hash"),
+		)(f)
 	}
 	return false
 }
@@ -1706,6 +2041,14 @@ func tableSchema(t string) sql.Schema {
 		return gitbase.BlobsSchema
 	case gitbase.FilesTableName:
 		return gitbase.FilesSchema
+	case gitbase.CommitDiffsTableName:
+		return gitbase.CommitDiffsSchema
+	case gitbase.FileBlameTableName:
+		return gitbase.FileBlameSchema
+	case gitbase.CommitNotesTableName:
+		return gitbase.CommitNotesSchema
+	case gitbase.TagsTableName:
+		return gitbase.TagsSchema
 	default:
 		return nil
 	}
@@ -148,6 +148,7 @@ func TestSquashJoins(t *testing.T) {
 									lit(3),
 									lit(4),
 								),
+								nil,
 							),
 							nil,
 							false,
@@ -155,7 +156,11 @@ func TestSquashJoins(t *testing.T) {
 						nil,
 						false,
 					),
-					[]int{4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 0, 1, 2, 3},
+					[]int{
+						14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24,
+						0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10,
+						11, 12, 13,
+					},
 					[]sql.Expression{
 						eq(
 							col(0, gitbase.ReferencesTableName, "commit_hash"),
@@ -306,7 +311,7 @@ func TestSquashJoinsPartial(t *testing.T) {
 			plan.NewResolvedTable(
 				gitbase.NewSquashedTable(
 					gitbase.NewRepoRefsIter(
-						gitbase.NewAllReposIter(lit(4)),
+						gitbase.NewAllReposIter(lit(4), nil),
 						nil,
 						false,
 					),
@@ -390,6 +395,10 @@ func TestBuildSquashedTable(t *testing.T) {
 	commitBlobs := tables[gitbase.CommitBlobsTableName]
 	commitFiles := tables[gitbase.CommitFilesTableName]
 	files := tables[gitbase.FilesTableName]
+	commitDiffs := tables[gitbase.CommitDiffsTableName]
+	fileBlame := tables[gitbase.FileBlameTableName]
+	commitNotes := tables[gitbase.CommitNotesTableName]
+	tags := tables[gitbase.TagsTableName]
 
 	repoRefCommitsSchema := append(gitbase.RepositoriesSchema, gitbase.RefCommitsSchema...)
 	remoteRefsSchema := append(gitbase.RemotesSchema, gitbase.RefsSchema...)
@@ -413,6 +422,11 @@ func TestBuildSquashedTable(t *testing.T) {
 	commitsCommitFilesSchema := append(gitbase.CommitsSchema, gitbase.CommitFilesSchema...)
 	commitFilesFilesSchema := append(gitbase.CommitFilesSchema, gitbase.FilesSchema...)
 	commitFilesBlobsSchema := append(gitbase.CommitFilesSchema, gitbase.BlobsSchema...)
+	commitsCommitDiffsSchema := append(gitbase.CommitsSchema, gitbase.CommitDiffsSchema...)
+	commitFilesFileBlameSchema := append(gitbase.CommitFilesSchema, gitbase.FileBlameSchema...)
+	commitsCommitNotesSchema := append(gitbase.CommitsSchema, gitbase.CommitNotesSchema...)
+	refsTagsSchema := append(gitbase.RefsSchema, gitbase.TagsSchema...)
+	tagsCommitsSchema := append(gitbase.TagsSchema, gitbase.CommitsSchema...)
 
 	repoFilter := eq(
 		col(0, gitbase.RepositoriesTableName, "repository_id"),
@@ -729,6 +743,81 @@ func TestBuildSquashedTable(t *testing.T) {
 		col(0, gitbase.BlobsTableName, "blob_size"),
 	)
 
+	commitDiffsFilter := eq(
+		col(0, gitbase.CommitDiffsTableName, "change_type"),
+		col(0, gitbase.CommitDiffsTableName, "change_type"),
+	)
+
+	commitsCommitDiffsFilter := eq(
+		col(0, gitbase.CommitsTableName, "tree_hash"),
+		col(0, gitbase.CommitDiffsTableName, "to_blob_hash"),
+	)
+
+	commitsCommitDiffsRedundantFilter := eq(
+		col(0, gitbase.CommitsTableName, "commit_hash"),
+		col(0, gitbase.CommitDiffsTableName, "commit_hash"),
+	)
+
+	commitNotesFilter := eq(
+		col(0, gitbase.CommitNotesTableName, "notes_ref"),
+		col(0, gitbase.CommitNotesTableName, "notes_ref"),
+	)
+
+	commitsCommitNotesFilter := eq(
+		col(0, gitbase.CommitsTableName, "committer_email"),
+		col(0, gitbase.CommitNotesTableName, "note_content"),
+	)
+
+	commitsCommitNotesRedundantFilter := eq(
+		col(0, gitbase.CommitsTableName, "commit_hash"),
+		col(0, gitbase.CommitNotesTableName, "commit_hash"),
+	)
+
+	fileBlameFilter := eq(
+		col(0, gitbase.FileBlameTableName, "author_email"),
+		col(0, gitbase.FileBlameTableName, "author_email"),
+	)
+
+	commitFilesFileBlameFilter := eq(
+		col(0, gitbase.CommitFilesTableName, "blob_hash"),
+		col(0, gitbase.FileBlameTableName, "origin_commit_hash"),
+	)
+
+	commitFilesFileBlameCommitHashRedundantFilter := eq(
+		col(0, gitbase.CommitFilesTableName, "commit_hash"),
+		col(0, gitbase.FileBlameTableName, "commit_hash"),
+	)
+
+	commitFilesFileBlameFilePathRedundantFilter := eq(
+		col(0, gitbase.CommitFilesTableName, "file_path"),
+		col(0, gitbase.FileBlameTableName, "file_path"),
+	)
+
+	tagFilter := eq(
+		col(0, gitbase.TagsTableName, "target_type"),
+		col(0, gitbase.TagsTableName, "target_type"),
+	)
+
+	refsTagsFilter := eq(
+		col(0, gitbase.ReferencesTableName, "commit_hash"),
+		col(0, gitbase.TagsTableName, "tag_hash"),
+	)
+
+	refsTagsRedundantFilter := eq(
+		col(0, gitbase.ReferencesTableName, "ref_name"),
+		col(0, gitbase.TagsTableName, "ref_name"),
+	)
+
+	tagsCommitsFilter := eq(
+		col(0, gitbase.TagsTableName, "tagger_email"),
+		col(0, gitbase.CommitsTableName, "committer_email"),
+	)
+
+	tagsCommitsRedundantFilter := eq(
+		col(0, gitbase.TagsTableName, "target_hash"),
+		col(0, gitbase.CommitsTableName, "commit_hash"),
+	)
+
 	idx1, idx2 := &dummyLookup{1}, &dummyLookup{2}
 
 	testCases := []struct {
@@ -754,7 +843,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRepoRemotesIter(
-					gitbase.NewAllReposIter(repoFilter),
+					gitbase.NewAllReposIter(repoFilter, nil),
 					and(repoRemotesFilter, remotesFilter),
 				),
 				nil,
@@ -817,7 +906,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRepoRefsIter(
-					gitbase.NewAllReposIter(repoFilter),
+					gitbase.NewAllReposIter(repoFilter, nil),
 					and(
 						refFilter,
 						repoRefsFilter,
@@ -872,6 +961,75 @@ func TestBuildSquashedTable(t *testing.T) {
 				gitbase.CommitsTableName,
 			)),
 		},
+		{
+			"refs with tags",
+			[]sql.Table{refs, tags},
+			[]sql.Expression{
+				refFilter,
+				tagFilter,
+				refsTagsFilter,
+				refsTagsRedundantFilter,
+			},
+			nil,
+			nil,
+			nil,
+			plan.NewResolvedTable(gitbase.NewSquashedTable(
+				gitbase.NewRefTagsIter(
+					gitbase.NewAllRefsIter(
+						fixIdx(t, refFilter, gitbase.RefsSchema),
+						false,
+					),
+					and(
+						fixIdx(t, tagFilter, refsTagsSchema),
+						fixIdx(t, refsTagsFilter, refsTagsSchema),
+					),
+				),
+				nil,
+				[]sql.Expression{
+					refFilter,
+					tagFilter,
+					refsTagsFilter,
+					refsTagsRedundantFilter,
+				},
+				nil,
+				gitbase.ReferencesTableName,
+				gitbase.TagsTableName,
+			)),
+		},
+		{
+			"tags with commits",
+			[]sql.Table{tags, commits},
+			[]sql.Expression{
+				tagFilter,
+				commitFilter,
+				tagsCommitsFilter,
+				tagsCommitsRedundantFilter,
+			},
+			nil,
+			nil,
+			nil,
+			plan.NewResolvedTable(gitbase.NewSquashedTable(
+				gitbase.NewTagCommitsIter(
+					gitbase.NewAllTagsIter(
+						fixIdx(t, tagFilter, gitbase.TagsSchema),
+					),
+					and(
+						fixIdx(t, commitFilter, tagsCommitsSchema),
+						fixIdx(t, tagsCommitsFilter, tagsCommitsSchema),
+					),
+				),
+				nil,
+				[]sql.Expression{
+					tagFilter,
+					commitFilter,
+					tagsCommitsFilter,
+					tagsCommitsRedundantFilter,
+				},
+				nil,
+				gitbase.TagsTableName,
+				gitbase.CommitsTableName,
+			)),
+		},
 		{
 			"remotes with commits",
 			[]sql.Table{remotes, commits},
@@ -886,12 +1044,12 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewProject(
 				[]sql.Expression{
-					colT(11, sql.Text, gitbase.RemotesTableName, "repository_id"),
-					colT(12, sql.Text, gitbase.RemotesTableName, "remote_name"),
-					colT(13, sql.Text, gitbase.RemotesTableName, "remote_push_url"),
-					colT(14, sql.Text, gitbase.RemotesTableName, "remote_fetch_url"),
-					colT(15, sql.Text, gitbase.RemotesTableName, "remote_push_refspec"),
-					colT(16, sql.Text, gitbase.RemotesTableName, "remote_fetch_refspec"),
+					colT(13, sql.Text, gitbase.RemotesTableName, "repository_id"),
+					colT(14, sql.Text, gitbase.RemotesTableName, "remote_name"),
+					colT(15, sql.Text, gitbase.RemotesTableName, "remote_push_url"),
+					colT(16, sql.Text, gitbase.RemotesTableName, "remote_fetch_url"),
+					colT(17, sql.Text, gitbase.RemotesTableName, "remote_push_refspec"),
+					colT(18, sql.Text, gitbase.RemotesTableName, "remote_fetch_refspec"),
 					colT(0, sql.Text, gitbase.CommitsTableName, "repository_id"),
 					colT(1, sql.VarChar(40), gitbase.CommitsTableName, "commit_hash"),
 					colT(2, sql.Text, gitbase.CommitsTableName, "commit_author_name"),
@@ -903,6 +1061,8 @@ func TestBuildSquashedTable(t *testing.T) {
 					colT(8, sql.Text, gitbase.CommitsTableName, "commit_message"),
 					colT(9, sql.VarChar(40), gitbase.CommitsTableName, "tree_hash"),
 					colT(10, sql.Array(sql.VarChar(40)), gitbase.CommitsTableName, "commit_parents"),
+					expression.NewGetFieldWithTable(11, sql.Text, gitbase.CommitsTableName, "commit_signature", true),
+					expression.NewGetFieldWithTable(12, sql.Text, gitbase.CommitsTableName, "commit_signature_type", true),
 				},
 				plan.NewInnerJoin(
 					plan.NewExchange(2,
@@ -916,7 +1076,7 @@ func TestBuildSquashedTable(t *testing.T) {
 						gitbase.RemotesTableName,
 					)),
 					eq(
-						col(11, gitbase.RemotesTableName, "repository_id"),
+						col(13, gitbase.RemotesTableName, "repository_id"),
 						col(0, gitbase.CommitsTableName, "repository_id"),
 					),
 				),
@@ -1189,6 +1349,8 @@ func TestBuildSquashedTable(t *testing.T) {
 					colT(12, sql.Text, gitbase.CommitsTableName, "commit_message"),
 					colT(13, sql.VarChar(40), gitbase.CommitsTableName, "tree_hash"),
 					colT(14, sql.Array(sql.VarChar(40)), gitbase.CommitsTableName, "commit_parents"),
+					expression.NewGetFieldWithTable(15, sql.Text, gitbase.CommitsTableName, "commit_signature", true),
+					expression.NewGetFieldWithTable(16, sql.Text, gitbase.CommitsTableName, "commit_signature_type", true),
 					colT(0, sql.Text, gitbase.BlobsTableName, "repository_id"),
 					colT(1, sql.VarChar(40), gitbase.BlobsTableName, "blob_hash"),
 					colT(2, sql.Int64, gitbase.BlobsTableName, "blob_size"),
@@ -1226,7 +1388,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRepoCommitsIter(
-					gitbase.NewAllReposIter(repoFilter),
+					gitbase.NewAllReposIter(repoFilter, nil),
 					and(
 						fixIdx(t, commitFilter, repoCommitsSchema),
 						fixIdx(t, repoCommitsFilter, repoCommitsSchema),
@@ -1363,7 +1525,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRepoTreeEntriesIter(
-					gitbase.NewAllReposIter(repoFilter),
+					gitbase.NewAllReposIter(repoFilter, nil),
 					and(
 						fixIdx(t, treeEntryFilter, repoTreeEntriesSchema),
 						fixIdx(t, repoTreeEntriesFilter, repoTreeEntriesSchema),
@@ -1396,7 +1558,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRefRefCommitsIter(
 					gitbase.NewRepoRefsIter(
-						gitbase.NewAllReposIter(repoFilter),
+						gitbase.NewAllReposIter(repoFilter, nil),
 						nil,
 						true,
 					),
@@ -1432,7 +1594,7 @@ func TestBuildSquashedTable(t *testing.T) {
 			nil,
 			plan.NewResolvedTable(gitbase.NewSquashedTable(
 				gitbase.NewRepoBlobsIter(
-					gitbase.NewAllReposIter(repoFilter),
+					gitbase.NewAllReposIter(repoFilter, nil),
 					and(
 						fixIdx(t, blobFilter, repoBlobsSchema),
 						fixIdx(t, repoBlobsFilter, repoBlobsSchema),
@@ -2062,6 +2224,113 @@ func TestBuildSquashedTable(t *testing.T) {
 				gitbase.CommitFilesTableName,
 			)),
 		},
+		{
+			"commits with commit_diffs",
+			[]sql.Table{commits, commitDiffs},
+			[]sql.Expression{
+				commitFilter,
+				commitDiffsFilter,
+				commitsCommitDiffsFilter,
+				commitsCommitDiffsRedundantFilter,
+			},
+			nil,
+			nil,
+			nil,
+			plan.NewResolvedTable(gitbase.NewSquashedTable(
+				gitbase.NewCommitDiffsIter(
+					gitbase.NewAllCommitsIter(
+						fixIdx(t, commitFilter, gitbase.CommitsSchema),
+						false,
+					),
+					and(
+						fixIdx(t, commitDiffsFilter, commitsCommitDiffsSchema),
+						fixIdx(t, commitsCommitDiffsFilter, commitsCommitDiffsSchema),
+					),
+					true,
+				),
+				nil,
+				[]sql.Expression{
+					commitFilter,
+					commitDiffsFilter,
+					commitsCommitDiffsFilter,
+					commitsCommitDiffsRedundantFilter,
+				},
+				nil,
+				gitbase.CommitsTableName,
+				gitbase.CommitDiffsTableName,
+			)),
+		},
+		{
+			"commits with commit_notes",
+			[]sql.Table{commits, commitNotes},
+			[]sql.Expression{
+				commitFilter,
+				commitNotesFilter,
+				commitsCommitNotesFilter,
+				commitsCommitNotesRedundantFilter,
+			},
+			nil,
+			nil,
+			nil,
+			plan.NewResolvedTable(gitbase.NewSquashedTable(
+				gitbase.NewCommitNotesIter(
+					gitbase.NewAllCommitsIter(
+						fixIdx(t, commitFilter, gitbase.CommitsSchema),
+						false,
+					),
+					and(
+						fixIdx(t, commitNotesFilter, commitsCommitNotesSchema),
+						fixIdx(t, commitsCommitNotesFilter, commitsCommitNotesSchema),
+					),
+				),
+				nil,
+				[]sql.Expression{
+					commitFilter,
+					commitNotesFilter,
+					commitsCommitNotesFilter,
+					commitsCommitNotesRedundantFilter,
+				},
+				nil,
+				gitbase.CommitsTableName,
+				gitbase.CommitNotesTableName,
+			)),
+		},
+		{
+			"commit_files with file_blame",
+			[]sql.Table{commitFiles, fileBlame},
+			[]sql.Expression{
+				commitFilesFilter,
+				fileBlameFilter,
+				commitFilesFileBlameFilter,
+				commitFilesFileBlameCommitHashRedundantFilter,
+				commitFilesFileBlameFilePathRedundantFilter,
+			},
+			nil,
+			nil,
+			nil,
+			plan.NewResolvedTable(gitbase.NewSquashedTable(
+				gitbase.NewCommitFileBlameIter(
+					gitbase.NewAllCommitFilesIter(
+						fixIdx(t, commitFilesFilter, gitbase.CommitFilesSchema),
+					),
+					and(
+						fixIdx(t, fileBlameFilter, commitFilesFileBlameSchema),
+						fixIdx(t, commitFilesFileBlameFilter, commitFilesFileBlameSchema),
+					),
+				),
+				nil,
+				[]sql.Expression{
+					commitFilesFilter,
+					fileBlameFilter,
+					commitFilesFileBlameFilter,
+					commitFilesFileBlameCommitHashRedundantFilter,
+					commitFilesFileBlameFilePathRedundantFilter,
+				},
+				nil,
+				gitbase.CommitFilesTableName,
+				gitbase.FileBlameTableName,
+			)),
+		},
 		{
 			"commit_files with files",
 			[]sql.Table{commitFiles, files},
@@ -2731,6 +3000,87 @@ func TestIsRedundantFilter(t *testing.T) {
 			),
 			true,
 		},
+		{
+			gitbase.CommitsTableName,
+			gitbase.CommitDiffsTableName,
+			eq(
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+				col(0, gitbase.CommitDiffsTableName, "commit_hash"),
+			),
+			true,
+		},
+		{
+			gitbase.ReferencesTableName,
+			gitbase.TagsTableName,
+			eq(
+				col(0, gitbase.ReferencesTableName, "ref_name"),
+				col(0, gitbase.TagsTableName, "ref_name"),
+			),
+			true,
+		},
+		{
+			gitbase.ReferencesTableName,
+			gitbase.TagsTableName,
+			eq(
+				col(0, gitbase.ReferencesTableName, "commit_hash"),
+				col(0, gitbase.TagsTableName, "target_hash"),
+			),
+			false,
+		},
+		{
+			gitbase.TagsTableName,
+			gitbase.CommitsTableName,
+			eq(
+				col(0, gitbase.TagsTableName, "target_hash"),
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+			),
+			true,
+		},
+		{
+			gitbase.CommitsTableName,
+			gitbase.CommitDiffsTableName,
+			eq(
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+				col(0, gitbase.CommitDiffsTableName, "parent_hash"),
+			),
+			false,
+		},
+		{
+			gitbase.CommitsTableName,
+			gitbase.FileBlameTableName,
+			eq(
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+				col(0, gitbase.FileBlameTableName, "commit_hash"),
+			),
+			true,
+		},
+		{
+			gitbase.CommitsTableName,
+			gitbase.FileBlameTableName,
+			eq(
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+				col(0, gitbase.FileBlameTableName, "origin_commit_hash"),
+			),
+			false,
+		},
+		{
+			gitbase.RefCommitsTableName,
+			gitbase.CommitNotesTableName,
+			eq(
+				col(0, gitbase.RefCommitsTableName, "commit_hash"),
+				col(0, gitbase.CommitNotesTableName, "commit_hash"),
+			),
+			true,
+		},
+		{
+			gitbase.CommitsTableName,
+			gitbase.CommitNotesTableName,
+			eq(
+				col(0, gitbase.CommitsTableName, "commit_hash"),
+				col(0, gitbase.CommitNotesTableName, "note_blob_hash"),
+			),
+			false,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -2784,6 +3134,32 @@ func TestHasRedundantCompoundFilter(t *testing.T) {
 			},
 			true,
 		},
+		{
+			gitbase.CommitFilesTableName,
+			gitbase.FileBlameTableName,
+			[]sql.Expression{
+				eq(
+					col(0, gitbase.CommitFilesTableName, "commit_hash"),
+					col(0, gitbase.FileBlameTableName, "commit_hash"),
+				),
+				eq(
+					col(0, gitbase.CommitFilesTableName, "file_path"),
+					col(0, gitbase.FileBlameTableName, "file_path"),
+				),
+			},
+			true,
+		},
+		{
+			gitbase.CommitFilesTableName,
+			gitbase.FileBlameTableName,
+			[]sql.Expression{
+				eq(
+					col(0, gitbase.CommitFilesTableName, "commit_hash"),
+					col(0, gitbase.FileBlameTableName, "commit_hash"),
+				),
+			},
+			false,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -0,0 +1,358 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"golang.org/x/crypto/ssh"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrUnsupportedType is returned when verifying a signature whose type
+	// can't be verified.
+	ErrUnsupportedType = errors.NewKind("unsupported signature type: %q")
+	// ErrInvalidAllowedSigners is returned when reading a malformed allowed
+	// signers file.
+	ErrInvalidAllowedSigners = errors.NewKind("invalid allowed signers in line %d: %s")
+
+	errInvalidSSHSignature = errors.NewKind("invalid SSH signature: %s")
+)
+
+const (
+	pgpPublicKeyBlock = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+	// sshMagic is the preamble of SSH signatures.
+	sshMagic = "SSHSIG"
+	// sshNamespace is the namespace of the SSH signatures made by git.
+	sshNamespace = "git"
+)
+
+// Keyring has the trusted keys signatures are verified with. They are
+// OpenPGP public keys or the SSH keys of an allowed signers file.
+type Keyring struct {
+	entities openpgp.EntityList
+	signers  []allowedSigner
+}
+
+// allowedSigner is an entry of an allowed signers file, as described in
+// ssh-keygen(1).
+type allowedSigner struct {
+	principals string
+	// namespaces the key is allowed to sign in, or nil for all of them.
+	namespaces []string
+	key        ssh.PublicKey
+}
+
+// Result is the verification of a signature.
+type Result struct {
+	// Type is the type of the signature.
+	Type string `json:"type"`
+	// Signer is the identity of the key that made the signature: the
+	// primary user ID of an OpenPGP key or the principals of an SSH key.
+	// It's empty if the key is not in the keyring.
+	Signer string `json:"signer"`
+	// Key identifies the key that made the signature: the fingerprint of
+	// the key, or the OpenPGP key ID when the key is not in the keyring.
+	// It's empty if the signature can't be parsed.
+	Key string `json:"key"`
+	// Valid is true if the signature was made by a key of the keyring and
+	// matches the signed payload.
+	Valid bool `json:"valid"`
+}
+
+// Read adds to the keyring the keys read from r, which has either an
+// armored OpenPGP keyring, as exported by `gpg --export --armor`, or an
+// allowed signers file of SSH keys. Certificate authorities and the
+// valid-after and valid-before options of allowed signers are not
+// supported, so their entries are ignored.
+func (k *Keyring) Read(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Contains(data, []byte(pgpPublicKeyBlock)) {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		k.entities = append(k.entities, entities...)
+		return nil
+	}
+
+	return k.readAllowedSigners(data)
+}
+
+func (k *Keyring) readAllowedSigners(data []byte) error {
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		principals, rest := splitPrincipals(line)
+		key, _, options, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return ErrInvalidAllowedSigners.New(i+1, err)
+		}
+
+		signer := allowedSigner{principals: principals, key: key}
+		supported := true
+		for _, opt := range options {
+			name, value := opt, ""
+			if idx := strings.IndexByte(opt, '='); idx >= 0 {
+				name, value = opt[:idx], strings.Trim(opt[idx+1:], `"`)
+			}
+
+			switch strings.ToLower(name) {
+			case "namespaces":
+				signer.namespaces = strings.Split(value, ",")
+			case "cert-authority", "valid-after", "valid-before":
+				supported = false
+			}
+		}
+
+		if supported {
+			k.signers = append(k.signers, signer)
+		}
+	}
+
+	return nil
+}
+
+// splitPrincipals returns the principals at the start of a line of an
+// allowed signers file, which may be quoted, and the rest of the line.
+func splitPrincipals(line string) (string, string) {
+	if line[0] == '"' {
+		if idx := strings.IndexByte(line[1:], '"'); idx >= 0 {
+			return line[1 : idx+1], line[idx+2:]
+		}
+	}
+
+	if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+		return line[:idx], line[idx+1:]
+	}
+
+	return line, ""
+}
+
+// Verify verifies the signature of the payload. A signature that can't be
+// parsed, or was not made by a key of the keyring, is not valid. Only
+// OpenPGP and SSH signatures are supported.
+func (k *Keyring) Verify(payload []byte, sig string) (*Result, error) {
+	switch typ := Type(sig); typ {
+	case GPG:
+		return k.verifyGPG(payload, sig), nil
+	case SSH:
+		return k.verifySSH(payload, sig), nil
+	default:
+		return nil, ErrUnsupportedType.New(typ)
+	}
+}
+
+func (k *Keyring) verifyGPG(payload []byte, sig string) *Result {
+	result := &Result{Type: GPG}
+
+	block, err := armor.Decode(strings.NewReader(sig))
+	if err != nil {
+		return result
+	}
+
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return result
+	}
+
+	var issuer uint64
+	switch s := p.(type) {
+	case *packet.Signature:
+		if s.IssuerKeyId == nil {
+			return result
+		}
+		issuer = *s.IssuerKeyId
+	case *packet.SignatureV3:
+		issuer = s.IssuerKeyId
+	default:
+		return result
+	}
+
+	keys := k.entities.KeysById(issuer)
+	if len(keys) == 0 {
+		result.Key = fmt.Sprintf("%016X", issuer)
+		return result
+	}
+
+	result.Signer = primaryIdentity(keys[0].Entity)
+	result.Key = fmt.Sprintf("%X", keys[0].PublicKey.Fingerprint[:])
+
+	_, err = openpgp.CheckArmoredDetachedSignature(
+		k.entities,
+		bytes.NewReader(payload),
+		strings.NewReader(sig),
+	)
+	result.Valid = err == nil
+	return result
+}
+
+// primaryIdentity returns the name of the primary identity of the entity,
+// or the first one in alphabetical order if none is marked as primary.
+func primaryIdentity(e *openpgp.Entity) string {
+	var names []string
+	for name, id := range e.Identities {
+		s := id.SelfSignature
+		if s != nil && s.IsPrimaryId != nil && *s.IsPrimaryId {
+			return name
+		}
+
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+	return names[0]
+}
+
+func (k *Keyring) verifySSH(payload []byte, sig string) *Result {
+	result := &Result{Type: SSH}
+
+	s, err := parseSSHSignature(sig)
+	if err != nil {
+		return result
+	}
+
+	result.Key = ssh.FingerprintSHA256(s.key)
+	signer := k.allowedSigner(s.key)
+	if signer == nil {
+		return result
+	}
+
+	result.Signer = signer.principals
+	result.Valid = s.namespace == sshNamespace &&
+		signer.allows(s.namespace) &&
+		s.verify(payload) == nil
+	return result
+}
+
+// allowedSigner returns the first allowed signer with the key, or nil if
+// there is none.
+func (k *Keyring) allowedSigner(key ssh.PublicKey) *allowedSigner {
+	data := key.Marshal()
+	for i, s := range k.signers {
+		if bytes.Equal(s.key.Marshal(), data) {
+			return &k.signers[i]
+		}
+	}
+
+	return nil
+}
+
+func (s *allowedSigner) allows(namespace string) bool {
+	if s.namespaces == nil {
+		return true
+	}
+
+	for _, ns := range s.namespaces {
+		if strings.TrimSpace(ns) == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sshSignature is an armored SSH signature, in the format described in
+// the PROTOCOL.sshsig file of OpenSSH.
+type sshSignature struct {
+	key       ssh.PublicKey
+	namespace string
+	hash      string
+	signature *ssh.Signature
+}
+
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return nil, errInvalidSSHSignature.New("not armored")
+	}
+
+	if !bytes.HasPrefix(block.Bytes, []byte(sshMagic)) {
+		return nil, errInvalidSSHSignature.New("missing preamble")
+	}
+
+	var blob struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}
+
+	if err := ssh.Unmarshal(block.Bytes[len(sshMagic):], &blob); err != nil {
+		return nil, errInvalidSSHSignature.New(err)
+	}
+
+	if blob.Version != 1 {
+		return nil, errInvalidSSHSignature.New(
+			fmt.Sprintf("unsupported version %d", blob.Version),
+		)
+	}
+
+	key, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return nil, errInvalidSSHSignature.New(err)
+	}
+
+	signature := new(ssh.Signature)
+	if err := ssh.Unmarshal(blob.Signature, signature); err != nil {
+		return nil, errInvalidSSHSignature.New(err)
+	}
+
+	return &sshSignature{
+		key:       key,
+		namespace: blob.Namespace,
+		hash:      blob.HashAlgorithm,
+		signature: signature,
+	}, nil
+}
+
+func (s *sshSignature) verify(payload []byte) error {
+	var h hash.Hash
+	switch s.hash {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return errInvalidSSHSignature.New(
+			fmt.Sprintf("unsupported hash algorithm %q", s.hash),
+		)
+	}
+
+	h.Write(payload)
+
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{s.namespace, nil, s.hash, h.Sum(nil)})
+
+	return s.key.Verify(append([]byte(sshMagic), signed...), s.signature)
+}
@@ -0,0 +1,127 @@
+// Package signature reads and verifies the signatures of commits and tags,
+// made with OpenPGP, SSH or X.509 keys.
+package signature
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Types of signatures.
+const (
+	// GPG is an OpenPGP signature.
+	GPG = "gpg"
+	// SSH is a signature made with an SSH key.
+	SSH = "ssh"
+	// X509 is an S/MIME signature made with an X.509 certificate.
+	X509 = "x509"
+)
+
+// beginnings are the first lines of each type of signature, as git
+// recognizes them.
+var beginnings = []struct {
+	typ  string
+	line string
+}{
+	{GPG, "-----BEGIN PGP SIGNATURE-----"},
+	{GPG, "-----BEGIN PGP MESSAGE-----"},
+	{X509, "-----BEGIN SIGNED MESSAGE-----"},
+	{SSH, "-----BEGIN SSH SIGNATURE-----"},
+}
+
+// Type returns the type of the signature, or an empty string if it's not
+// of any known type.
+func Type(sig string) string {
+	for _, b := range beginnings {
+		if strings.HasPrefix(sig, b.line) {
+			return b.typ
+		}
+	}
+
+	return ""
+}
+
+// Split returns the message before the first signature found at the start
+// of a line, and the signature. An empty signature is returned if there is
+// none.
+func Split(msg string) (string, string) {
+	for pos := 0; pos < len(msg); {
+		if Type(msg[pos:]) != "" {
+			return msg[:pos], msg[pos:]
+		}
+
+		idx := strings.IndexByte(msg[pos:], '\n')
+		if idx < 0 {
+			break
+		}
+
+		pos += idx + 1
+	}
+
+	return msg, ""
+}
+
+// TagMessage returns the message and the signature of the tag. Only the
+// OpenPGP signatures of tags are parsed by go-git, so the others are split
+// from the message.
+func TagMessage(t *object.Tag) (string, string) {
+	if t.PGPSignature != "" {
+		return t.Message, t.PGPSignature
+	}
+
+	return Split(t.Message)
+}
+
+// Commit returns the signature of the commit and the payload signed by it,
+// which is the commit without the signature. The signature is empty if the
+// commit is not signed.
+func Commit(c *object.Commit) (string, []byte, error) {
+	if c.PGPSignature == "" {
+		return "", nil, nil
+	}
+
+	payload, err := encode(c.EncodeWithoutSignature)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return c.PGPSignature, payload, nil
+}
+
+// Tag returns the signature of the tag and the payload signed by it, which
+// is the tag without the signature. The signature is empty if the tag is
+// not signed.
+func Tag(t *object.Tag) (string, []byte, error) {
+	msg, sig := TagMessage(t)
+	if sig == "" {
+		return "", nil, nil
+	}
+
+	unsigned := *t
+	unsigned.Message = msg
+	unsigned.PGPSignature = ""
+	payload, err := encode(unsigned.EncodeWithoutSignature)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sig, payload, nil
+}
+
+func encode(fn func(plumbing.EncodedObject) error) ([]byte, error) {
+	obj := new(plumbing.MemoryObject)
+	if err := fn(obj); err != nil {
+		return nil, err
+	}
+
+	r, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
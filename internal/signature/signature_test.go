@@ -0,0 +1,275 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestType(t *testing.T) {
+	testCases := []struct {
+		sig      string
+		expected string
+	}{
+		{"-----BEGIN PGP SIGNATURE-----\n\nfoo\n", GPG},
+		{"-----BEGIN PGP MESSAGE-----\n\nfoo\n", GPG},
+		{"-----BEGIN SSH SIGNATURE-----\nfoo\n", SSH},
+		{"-----BEGIN SIGNED MESSAGE-----\nfoo\n", X509},
+		{"foo\n-----BEGIN SSH SIGNATURE-----\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range testCases {
+		require.Equal(t, tt.expected, Type(tt.sig), tt.sig)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	sig := "-----BEGIN SSH SIGNATURE-----\nfoo\n-----END SSH SIGNATURE-----\n"
+
+	msg, s := Split("v1.0.0\n\nRelease\n" + sig)
+	require.Equal(t, "v1.0.0\n\nRelease\n", msg)
+	require.Equal(t, sig, s)
+
+	msg, s = Split("v1.0.0 -----BEGIN SSH SIGNATURE-----\n")
+	require.Equal(t, "v1.0.0 -----BEGIN SSH SIGNATURE-----\n", msg)
+	require.Equal(t, "", s)
+}
+
+func TestVerifyGPG(t *testing.T) {
+	require := require.New(t)
+
+	entity, err := openpgp.NewEntity("John Doe", "", "john@doe.com", nil)
+	require.NoError(err)
+
+	c := testCommit()
+	payload, err := encode(c.EncodeWithoutSignature)
+	require.NoError(err)
+
+	var sig bytes.Buffer
+	require.NoError(openpgp.ArmoredDetachSign(&sig, entity, bytes.NewReader(payload), nil))
+	c.PGPSignature = sig.String()
+
+	s, signed, err := Commit(c)
+	require.NoError(err)
+	require.Equal(c.PGPSignature, s)
+	require.Equal(payload, signed)
+
+	var keys bytes.Buffer
+	w, err := armor.Encode(&keys, openpgp.PublicKeyType, nil)
+	require.NoError(err)
+	require.NoError(entity.Serialize(w))
+	require.NoError(w.Close())
+
+	var k Keyring
+	require.NoError(k.Read(&keys))
+
+	fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint[:])
+
+	result, err := k.Verify(signed, s)
+	require.NoError(err)
+	require.Equal(&Result{
+		Type:   GPG,
+		Signer: "John Doe <john@doe.com>",
+		Key:    fingerprint,
+		Valid:  true,
+	}, result)
+
+	result, err = k.Verify(append(signed, '\n'), s)
+	require.NoError(err)
+	require.Equal(&Result{
+		Type:   GPG,
+		Signer: "John Doe <john@doe.com>",
+		Key:    fingerprint,
+		Valid:  false,
+	}, result)
+
+	result, err = new(Keyring).Verify(signed, s)
+	require.NoError(err)
+	require.Equal(&Result{
+		Type: GPG,
+		Key:  fmt.Sprintf("%016X", entity.PrimaryKey.KeyId),
+	}, result)
+}
+
+func TestVerifySSH(t *testing.T) {
+	require := require.New(t)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(err)
+
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(err)
+	other, err := ssh.NewSignerFromKey(otherPriv)
+	require.NoError(err)
+
+	tag := &object.Tag{
+		Name:       "v1.0.0",
+		Tagger:     testCommit().Author,
+		Target:     plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+		TargetType: plumbing.CommitObject,
+		Message:    "Release\n",
+	}
+
+	payload, err := encode(tag.EncodeWithoutSignature)
+	require.NoError(err)
+	tag.Message += sshSign(t, signer, "git", payload)
+
+	s, signed, err := Tag(tag)
+	require.NoError(err)
+	require.Equal(SSH, Type(s))
+	require.Equal(payload, signed)
+
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	otherKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(other.PublicKey())))
+	fingerprint := ssh.FingerprintSHA256(signer.PublicKey())
+
+	testCases := []struct {
+		name     string
+		signers  string
+		payload  []byte
+		sig      string
+		expected *Result
+	}{
+		{
+			"valid",
+			fmt.Sprintf("# comment\n\njohn@doe.com,jd@doe.com %s john\n", key),
+			signed,
+			s,
+			&Result{SSH, "john@doe.com,jd@doe.com", fingerprint, true},
+		},
+		{
+			"valid with options",
+			fmt.Sprintf(`"john@doe.com" namespaces="file,git" %s`, key),
+			signed,
+			s,
+			&Result{SSH, "john@doe.com", fingerprint, true},
+		},
+		{
+			"other namespace",
+			fmt.Sprintf(`john@doe.com namespaces="file" %s`, key),
+			signed,
+			s,
+			&Result{SSH, "john@doe.com", fingerprint, false},
+		},
+		{
+			"wrong payload",
+			fmt.Sprintf("john@doe.com %s", key),
+			append(signed, '\n'),
+			s,
+			&Result{SSH, "john@doe.com", fingerprint, false},
+		},
+		{
+			"signed in other namespace",
+			fmt.Sprintf("john@doe.com %s", key),
+			signed,
+			sshSign(t, signer, "file", signed),
+			&Result{SSH, "john@doe.com", fingerprint, false},
+		},
+		{
+			"unknown key",
+			fmt.Sprintf("jane@doe.com %s", otherKey),
+			signed,
+			s,
+			&Result{SSH, "", fingerprint, false},
+		},
+		{
+			"certificate authority",
+			fmt.Sprintf("*@doe.com cert-authority %s", key),
+			signed,
+			s,
+			&Result{SSH, "", fingerprint, false},
+		},
+		{
+			"malformed signature",
+			fmt.Sprintf("john@doe.com %s", key),
+			signed,
+			"-----BEGIN SSH SIGNATURE-----\nfoo\n-----END SSH SIGNATURE-----\n",
+			&Result{Type: SSH},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var k Keyring
+			require.NoError(k.Read(strings.NewReader(tt.signers)))
+
+			result, err := k.Verify(tt.payload, tt.sig)
+			require.NoError(err)
+			require.Equal(tt.expected, result)
+		})
+	}
+}
+
+func TestKeyringRead(t *testing.T) {
+	var k Keyring
+	err := k.Read(strings.NewReader("john@doe.com ssh-ed25519 foo\n"))
+	require.True(t, ErrInvalidAllowedSigners.Is(err))
+}
+
+func TestVerifyUnsupported(t *testing.T) {
+	var k Keyring
+	_, err := k.Verify(nil, "-----BEGIN SIGNED MESSAGE-----\nfoo\n")
+	require.True(t, ErrUnsupportedType.Is(err))
+}
+
+func testCommit() *object.Commit {
+	sig := object.Signature{
+		Name:  "John Doe",
+		Email: "john@doe.com",
+		When:  time.Unix(1257894000, 0).UTC(),
+	}
+
+	return &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   "Initial commit\n",
+		TreeHash:  plumbing.NewHash("a8d315b2b1c615d43042c3a62402b8a54288cf5c"),
+	}
+}
+
+// sshSign returns the armored SSH signature of the payload in the
+// namespace, as made by `ssh-keygen -Y sign`.
+func sshSign(t *testing.T, signer ssh.Signer, namespace string, payload []byte) string {
+	t.Helper()
+
+	hash := sha512.Sum512(payload)
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, nil, "sha512", hash[:]})
+
+	sig, err := signer.Sign(rand.Reader, append([]byte(sshMagic), signed...))
+	require.NoError(t, err)
+
+	blob := ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}{1, signer.PublicKey().Marshal(), namespace, nil, "sha512", ssh.Marshal(sig)})
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "SSH SIGNATURE",
+		Bytes: append([]byte(sshMagic), blob...),
+	}))
+}
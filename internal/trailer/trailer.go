@@ -0,0 +1,162 @@
+// Package trailer parses the trailers of commit messages, like
+// Signed-off-by or Co-authored-by, following the rules of
+// git interpret-trailers.
+package trailer
+
+import "strings"
+
+// Trailer is a key and value pair of the trailer block of a message.
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// generatedPrefixes are the prefixes of the trailers added by git, which
+// make a block with other lines a trailer block.
+var generatedPrefixes = []string{
+	"Signed-off-by: ",
+	"(cherry picked from commit ",
+}
+
+// Parse returns the trailers of the message. As git does, trailers are
+// looked for in the last paragraph, which cannot be the title, and it is
+// a trailer block if all its lines are trailers, or at least 25% of them
+// are and one of them was added by git. Lines starting with whitespace
+// continue the previous trailer, and its value is unfolded with them.
+func Parse(msg string) []Trailer {
+	lines := strings.Split(msg, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+
+	end := len(lines)
+	for end > 0 && (isBlank(lines[end-1]) || isComment(lines[end-1])) {
+		end--
+	}
+	lines = lines[:end]
+
+	start := blockStart(lines)
+	if start < 0 {
+		return nil
+	}
+
+	var trailers []Trailer
+	var inTrailer bool
+	for _, l := range lines[start:] {
+		if isComment(l) || isBlank(l) {
+			continue
+		}
+
+		if isSpace(l[0]) {
+			if inTrailer {
+				t := &trailers[len(trailers)-1]
+				t.Value = strings.TrimSpace(t.Value + " " + strings.TrimSpace(l))
+			}
+			continue
+		}
+
+		sep := separatorIndex(l)
+		if sep < 1 {
+			inTrailer = false
+			continue
+		}
+
+		inTrailer = true
+		trailers = append(trailers, Trailer{
+			Key:   strings.TrimSpace(l[:sep]),
+			Value: strings.TrimSpace(l[sep+1:]),
+		})
+	}
+
+	return trailers
+}
+
+// blockStart returns the index of the first line of the trailer block, or
+// -1 if there is none.
+func blockStart(lines []string) int {
+	// The first paragraph is the title and cannot be trailers.
+	title := 0
+	for ; title < len(lines); title++ {
+		if !isComment(lines[title]) && isBlank(lines[title]) {
+			break
+		}
+	}
+
+	var trailerLines, nonTrailerLines, continuationLines int
+	var generated bool
+	for i := len(lines) - 1; i >= title; i-- {
+		l := lines[i]
+		switch {
+		case isComment(l):
+			nonTrailerLines += continuationLines
+			continuationLines = 0
+		case isBlank(l):
+			if generated && trailerLines*3 >= nonTrailerLines {
+				return i + 1
+			}
+
+			if trailerLines > 0 && nonTrailerLines == 0 {
+				return i + 1
+			}
+
+			return -1
+		case hasGeneratedPrefix(l):
+			trailerLines++
+			continuationLines = 0
+			generated = true
+		case separatorIndex(l) >= 1 && !isSpace(l[0]):
+			trailerLines++
+			continuationLines = 0
+		case isSpace(l[0]):
+			continuationLines++
+		default:
+			nonTrailerLines += 1 + continuationLines
+			continuationLines = 0
+		}
+	}
+
+	return -1
+}
+
+// separatorIndex returns the index of the separator of the key and the
+// value of a trailer line, or -1 if it's not a trailer. Keys are made of
+// alphanumeric characters and hyphens, and may be followed by whitespace.
+func separatorIndex(l string) int {
+	var whitespace bool
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case c == ':':
+			return i
+		case !whitespace && (isAlnum(c) || c == '-'):
+		case i > 0 && (c == ' ' || c == '\t'):
+			whitespace = true
+		default:
+			return -1
+		}
+	}
+
+	return -1
+}
+
+func hasGeneratedPrefix(l string) bool {
+	for _, p := range generatedPrefixes {
+		if strings.HasPrefix(l, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isComment(l string) bool { return strings.HasPrefix(l, "#") }
+
+func isBlank(l string) bool { return strings.TrimSpace(l) == "" }
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
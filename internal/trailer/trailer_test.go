@@ -0,0 +1,73 @@
+package trailer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		msg      string
+		expected []Trailer
+	}{
+		{
+			"trailers",
+			"Fix foo\n\nSome details.\n\nSigned-off-by: John Doe <john@doe.com>\nCo-authored-by: Jane Doe <jane@doe.com>\n",
+			[]Trailer{
+				{"Signed-off-by", "John Doe <john@doe.com>"},
+				{"Co-authored-by", "Jane Doe <jane@doe.com>"},
+			},
+		},
+		{
+			"no body",
+			"Fix foo\n\nChange-Id: I1234\n\n",
+			[]Trailer{{"Change-Id", "I1234"}},
+		},
+		{
+			"only title",
+			"Fix: foo\n",
+			nil,
+		},
+		{
+			"title and trailer without blank line",
+			"Fix foo\nReviewed-by: John Doe <john@doe.com>\n",
+			nil,
+		},
+		{
+			"last paragraph is not a trailer block",
+			"Fix foo\n\nReviewed-by: John Doe\n\nSome details.\nNote: this is not a trailer\n",
+			nil,
+		},
+		{
+			"block with lines added by git",
+			"Fix foo\n\nSome details\nin the last paragraph.\n(cherry picked from commit 6ecf0ef2c2dffb796033e5a02219af86ec6584e5)\nSigned-off-by: John Doe <john@doe.com>\n",
+			[]Trailer{{"Signed-off-by", "John Doe <john@doe.com>"}},
+		},
+		{
+			"continuation lines",
+			"Fix foo\n\nReviewed-by: John Doe\n  <john@doe.com>\nKey : value\r\n# comment\n",
+			[]Trailer{
+				{"Reviewed-by", "John Doe <john@doe.com>"},
+				{"Key", "value"},
+			},
+		},
+		{
+			"invalid keys",
+			"Fix foo\n\nSome key: value\n",
+			nil,
+		},
+		{
+			"empty",
+			"",
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, Parse(tt.msg))
+		})
+	}
+}
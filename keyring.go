@@ -0,0 +1,56 @@
+package gitbase
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/src-d/gitbase/internal/signature"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrNoKeyringsDir is returned when a keyring is requested in a session
+	// with no keyrings directory.
+	ErrNoKeyringsDir = errors.NewKind("no keyrings directory configured")
+	// ErrInvalidKeyring is returned when the name of a keyring is not the
+	// name of a file.
+	ErrInvalidKeyring = errors.NewKind("invalid keyring name: %q")
+)
+
+// Keyring returns the keyring with the given name, which is the name of a
+// file in the keyrings directory of the session with OpenPGP armored keys
+// or SSH allowed signers. It's read only once in the session.
+func (s *Session) Keyring(name string) (*signature.Keyring, error) {
+	if s.keyringsDir == "" {
+		return nil, ErrNoKeyringsDir.New()
+	}
+
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return nil, ErrInvalidKeyring.New(name)
+	}
+
+	s.keyringsMu.Lock()
+	defer s.keyringsMu.Unlock()
+
+	if k, ok := s.keyrings[name]; ok {
+		return k, nil
+	}
+
+	f, err := os.Open(filepath.Join(s.keyringsDir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	k := new(signature.Keyring)
+	if err := k.Read(f); err != nil {
+		return nil, err
+	}
+
+	if s.keyrings == nil {
+		s.keyrings = make(map[string]*signature.Keyring)
+	}
+
+	s.keyrings[name] = k
+	return k, nil
+}
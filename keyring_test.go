@@ -0,0 +1,46 @@
+package gitbase
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionKeyring(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewSession(nil).Keyring("signers")
+	require.True(ErrNoKeyringsDir.Is(err))
+
+	dir, err := ioutil.TempDir("", "gitbase-keyrings")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "signers")
+	require.NoError(ioutil.WriteFile(
+		file,
+		[]byte("john@doe.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAzBFv1Ug4fuwG0Ssl0syIUP9K/d3q4sh6Km/s4Hx1A0\n"),
+		0644,
+	))
+
+	session := NewSession(nil, WithKeyringsDir(dir))
+	for _, name := range []string{"", ".", "..", "../signers", "foo/signers"} {
+		_, err = session.Keyring(name)
+		require.True(ErrInvalidKeyring.Is(err), name)
+	}
+
+	_, err = session.Keyring("missing")
+	require.Error(err)
+
+	k, err := session.Keyring("signers")
+	require.NoError(err)
+
+	// The keyring is cached in the session.
+	require.NoError(os.Remove(file))
+	cached, err := session.Keyring("signers")
+	require.NoError(err)
+	require.True(k == cached)
+}
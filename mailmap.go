@@ -0,0 +1,114 @@
+package gitbase
+
+import (
+	"os"
+
+	"github.com/src-d/gitbase/internal/mailmap"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Mailmap returns the mailmap of the repository, made of its .mailmap file
+// at HEAD and the mailmap file of the session, whose entries take
+// precedence, as git does with mailmap.file. The mailmap file of the
+// session is read only once, and the mailmap of each repository is read
+// only once in the session.
+func (s *Session) Mailmap(repo *Repository) (*mailmap.Mailmap, error) {
+	s.mailmapsMu.Lock()
+	m, ok := s.mailmaps[repo.ID()]
+	s.mailmapsMu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	file, err := s.fileMailmap()
+	if err != nil {
+		return nil, err
+	}
+
+	m = new(mailmap.Mailmap)
+	if err := readRepositoryMailmap(m, repo); err != nil {
+		return nil, err
+	}
+	m.Merge(file)
+
+	s.mailmapsMu.Lock()
+	defer s.mailmapsMu.Unlock()
+
+	// Another query may have read it in the meantime.
+	if cached, ok := s.mailmaps[repo.ID()]; ok {
+		return cached, nil
+	}
+
+	if s.mailmaps == nil {
+		s.mailmaps = make(map[string]*mailmap.Mailmap)
+	}
+
+	s.mailmaps[repo.ID()] = m
+	return m, nil
+}
+
+// fileMailmap returns the mailmap of the mailmap file of the session, or
+// nil if it has none. The file is read the first time it's requested.
+func (s *Session) fileMailmap() (*mailmap.Mailmap, error) {
+	s.mailmapFileOnce.Do(func() {
+		if s.mailmapFile == "" {
+			return
+		}
+
+		m := new(mailmap.Mailmap)
+		if err := readMailmapFile(m, s.mailmapFile); err != nil {
+			s.mailmapFileErr = err
+			return
+		}
+
+		s.mailmapFileMap = m
+	})
+
+	return s.mailmapFileMap, s.mailmapFileErr
+}
+
+// readRepositoryMailmap adds to the mailmap the entries of the .mailmap
+// file of the commit HEAD points to, if any.
+func readRepositoryMailmap(m *mailmap.Mailmap, repo *Repository) error {
+	ref, err := repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	f, err := c.File(mailmap.FileName)
+	if err == object.ErrFileNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return m.Read(r)
+}
+
+func readMailmapFile(m *mailmap.Mailmap, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return m.Read(f)
+}
@@ -0,0 +1,70 @@
+package gitbase
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionMailmap(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	repo, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(err)
+	defer repo.Close()
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+
+	// The fixture does not have a .mailmap file.
+	m, err := session.Mailmap(repo)
+	require.NoError(err)
+
+	name, email := m.Map("Máximo Cuadros", "mcuadros@gmail.com")
+	require.Equal("Máximo Cuadros", name)
+	require.Equal("mcuadros@gmail.com", email)
+
+	dir, err := ioutil.TempDir("", "gitbase-mailmap")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "mailmap")
+	require.NoError(ioutil.WriteFile(
+		file,
+		[]byte("Maximo Cuadros <mcuadros@example.com> <mcuadros@gmail.com>\n"),
+		0644,
+	))
+
+	session = NewSession(session.Pool, WithMailmapFile(file))
+	m, err = session.Mailmap(repo)
+	require.NoError(err)
+
+	name, email = m.Map("Máximo Cuadros", "MCuadros@gmail.com")
+	require.Equal("Maximo Cuadros", name)
+	require.Equal("mcuadros@example.com", email)
+
+	// The mailmap is cached in the session.
+	require.NoError(os.Remove(file))
+	cached, err := session.Mailmap(repo)
+	require.NoError(err)
+	require.True(m == cached)
+
+	// The mailmap file is only read once in the session.
+	session.mailmaps = nil
+	m, err = session.Mailmap(repo)
+	require.NoError(err)
+	require.False(m == cached)
+
+	name, email = m.Map("Máximo Cuadros", "mcuadros@gmail.com")
+	require.Equal("Maximo Cuadros", name)
+	require.Equal("mcuadros@example.com", email)
+
+	_, err = NewSession(session.Pool, WithMailmapFile(file)).Mailmap(repo)
+	require.Error(err)
+}
@@ -0,0 +1,528 @@
+package gitbase
+
+import (
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/sql"
+	sivafs "gopkg.in/src-d/go-billy-siva.v4"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/idxfile"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/objfile"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/packfile"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem/dotgit"
+)
+
+// Storages of the objects in the object_storage column.
+const (
+	objectStorageLoose  = "loose"
+	objectStoragePacked = "packed"
+)
+
+type objectsTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// ObjectsSchema is the schema for the objects table.
+var ObjectsSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: ObjectsTableName},
+	{Name: "object_hash", Type: sql.VarChar(40), Nullable: false, Source: ObjectsTableName},
+	{Name: "object_type", Type: sql.Text, Nullable: false, Source: ObjectsTableName},
+	{Name: "object_size", Type: sql.Int64, Nullable: false, Source: ObjectsTableName},
+	{Name: "object_storage", Type: sql.Text, Nullable: false, Source: ObjectsTableName},
+	{Name: "packfile_hash", Type: sql.VarChar(40), Nullable: true, Source: ObjectsTableName},
+	{Name: "packfile_offset", Type: sql.Int64, Nullable: true, Source: ObjectsTableName},
+	{Name: "delta_depth", Type: sql.Int64, Nullable: false, Source: ObjectsTableName},
+}
+
+func newObjectsTable(pool *RepositoryPool) *objectsTable {
+	return &objectsTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*objectsTable)(nil)
+
+func (objectsTable) isGitbaseTable() {}
+
+func (t objectsTable) String() string {
+	return printTable(
+		ObjectsTableName,
+		ObjectsSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (objectsTable) Name() string { return ObjectsTableName }
+
+func (objectsTable) Schema() sql.Schema { return ObjectsSchema }
+
+func (t *objectsTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *objectsTable) Filters() []sql.Expression { return t.filters }
+
+func (t *objectsTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.ObjectsTable")
+	iter, err := rowIterWithSelectors(
+		ctx, ObjectsSchema, ObjectsTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("object_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			var storages []string
+			storages, err = selectors.textValues("object_storage")
+			if err != nil {
+				return nil, err
+			}
+
+			return &objectsRowIter{
+				repo:    repo,
+				hashes:  stringsToHashes(hashes),
+				loose:   len(storages) == 0 || stringContains(storages, objectStorageLoose),
+				packed:  len(storages) == 0 || stringContains(storages, objectStoragePacked),
+				skipper: newGitErrorSkipper(ctx, ObjectsTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (objectsTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(ObjectsTableName, ObjectsSchema, filters)
+}
+
+func (objectsTable) handledColumns() []string {
+	return []string{"object_hash", "object_storage", "repository_id"}
+}
+
+// packedObject is the type of an object stored in a packfile and the number
+// of deltas that must be applied to get it.
+type packedObject struct {
+	typ   plumbing.ObjectType
+	depth int64
+}
+
+// packObjects reads the objects of a packfile.
+type packObjects struct {
+	hash     plumbing.Hash
+	idx      idxfile.Index
+	packfile *packfile.Packfile
+	objects  map[int64]packedObject
+}
+
+func openPackObjects(
+	fs billy.Filesystem,
+	dot *dotgit.DotGit,
+	hash plumbing.Hash,
+) (*packObjects, error) {
+	idx, err := openPackfileIndex(dot, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := dot.ObjectPack(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &packObjects{
+		hash:     hash,
+		idx:      idx,
+		packfile: packfile.NewPackfile(idx, fs, f),
+		objects:  make(map[int64]packedObject),
+	}, nil
+}
+
+// object returns the object at the given offset. Deltified objects take the
+// type of the object at the end of their delta chain.
+func (p *packObjects) object(offset int64) (packedObject, error) {
+	if obj, ok := p.objects[offset]; ok {
+		return obj, nil
+	}
+
+	h, err := p.packfile.Scanner().SeekObjectHeader(offset)
+	if err != nil {
+		return packedObject{}, err
+	}
+
+	var base int64
+	switch h.Type {
+	case plumbing.OFSDeltaObject:
+		base = h.OffsetReference
+	case plumbing.REFDeltaObject:
+		base, err = p.idx.FindOffset(h.Reference)
+		if err != nil {
+			return packedObject{}, err
+		}
+	default:
+		obj := packedObject{typ: h.Type}
+		p.objects[offset] = obj
+		return obj, nil
+	}
+
+	obj, err := p.object(base)
+	if err != nil {
+		return packedObject{}, err
+	}
+
+	obj.depth++
+	p.objects[offset] = obj
+	return obj, nil
+}
+
+func (p *packObjects) row(repoID string, hash plumbing.Hash, offset int64) (sql.Row, error) {
+	obj, err := p.object(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := p.packfile.GetSizeByOffset(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.NewRow(
+		repoID,
+		hash.String(),
+		obj.typ.String(),
+		size,
+		objectStoragePacked,
+		p.hash.String(),
+		offset,
+		obj.depth,
+	), nil
+}
+
+func (p *packObjects) Close() error {
+	return p.packfile.Close()
+}
+
+func looseObjectRow(dot *dotgit.DotGit, repoID string, hash plumbing.Hash) (sql.Row, error) {
+	f, err := dot.Object(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := objfile.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	typ, size, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.NewRow(
+		repoID,
+		hash.String(),
+		typ.String(),
+		size,
+		objectStorageLoose,
+		nil,
+		nil,
+		int64(0),
+	), nil
+}
+
+type objectsRowIter struct {
+	repo    *Repository
+	skipper *gitErrorSkipper
+	fs      billy.Filesystem
+	dot     *dotgit.DotGit
+	sync    func()
+
+	packfiles []plumbing.Hash
+	pack      *packObjects
+	entries   idxfile.EntryIter
+	loose     bool
+	packed    bool
+	looseObjs []plumbing.Hash
+
+	// selectors for faster filtering
+	hashes []plumbing.Hash
+}
+
+func (i *objectsRowIter) init() error {
+	fs, err := i.repo.FS()
+	if err != nil {
+		return err
+	}
+
+	if s, ok := fs.(sivafs.SivaSync); ok {
+		i.sync = func() { s.Sync() }
+	}
+
+	i.fs, err = findDotGit(fs)
+	if err != nil {
+		return err
+	}
+
+	i.dot = dotgit.New(i.fs)
+	if i.packed {
+		i.packfiles, err = i.dot.ObjectPacks()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !i.loose {
+		return nil
+	}
+
+	if len(i.hashes) > 0 {
+		for _, h := range i.hashes {
+			f, err := i.dot.Object(h)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+
+				return err
+			}
+
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			i.looseObjs = append(i.looseObjs, h)
+		}
+
+		return nil
+	}
+
+	i.looseObjs, err = i.dot.Objects()
+	return err
+}
+
+// nextPackfile opens the next packfile, returning false when there are no
+// more packfiles.
+func (i *objectsRowIter) nextPackfile() (bool, error) {
+	if err := i.closePackfile(); err != nil {
+		return false, err
+	}
+
+	if len(i.packfiles) == 0 {
+		return false, nil
+	}
+
+	hash := i.packfiles[0]
+	i.packfiles = i.packfiles[1:]
+
+	var err error
+	i.pack, err = openPackObjects(i.fs, i.dot, hash)
+	if err != nil {
+		return false, err
+	}
+
+	if len(i.hashes) > 0 {
+		i.entries = &hashEntriesIter{idx: i.pack.idx, hashes: i.hashes}
+		return true, nil
+	}
+
+	// Objects are read by offset to read the packfile sequentially.
+	i.entries, err = i.pack.idx.EntriesByOffset()
+	return true, err
+}
+
+func (i *objectsRowIter) closePackfile() error {
+	if i.entries != nil {
+		if err := i.entries.Close(); err != nil {
+			return err
+		}
+
+		i.entries = nil
+	}
+
+	if i.pack != nil {
+		if err := i.pack.Close(); err != nil {
+			return err
+		}
+
+		i.pack = nil
+	}
+
+	return nil
+}
+
+func (i *objectsRowIter) nextPacked() (sql.Row, error) {
+	for {
+		if i.entries == nil {
+			ok, err := i.nextPackfile()
+			if err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					logrus.WithFields(logrus.Fields{
+						"repo": i.repo.ID(),
+						"err":  err,
+					}).Error("can't read packfile")
+					i.entries = nil
+					continue
+				}
+
+				return nil, err
+			}
+
+			if !ok {
+				return nil, io.EOF
+			}
+		}
+
+		e, err := i.entries.Next()
+		if err == io.EOF {
+			if err := i.closePackfile(); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		row, err := i.pack.row(i.repo.ID(), e.Hash, int64(e.Offset))
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), e.Hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo":     i.repo.ID(),
+					"err":      err,
+					"packfile": i.pack.hash.String(),
+					"object":   e.Hash.String(),
+				}).Error("can't read packed object")
+				continue
+			}
+
+			return nil, err
+		}
+
+		return row, nil
+	}
+}
+
+func (i *objectsRowIter) nextLoose() (sql.Row, error) {
+	for {
+		if len(i.looseObjs) == 0 {
+			return nil, io.EOF
+		}
+
+		hash := i.looseObjs[0]
+		i.looseObjs = i.looseObjs[1:]
+
+		row, err := looseObjectRow(i.dot, i.repo.ID(), hash)
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo":   i.repo.ID(),
+					"err":    err,
+					"object": hash.String(),
+				}).Error("can't read loose object")
+				continue
+			}
+
+			return nil, err
+		}
+
+		return row, nil
+	}
+}
+
+func (i *objectsRowIter) Next() (sql.Row, error) {
+	if i.dot == nil {
+		if err := i.init(); err != nil {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				return nil, io.EOF
+			}
+
+			return nil, err
+		}
+	}
+
+	row, err := i.nextPacked()
+	if err != io.EOF {
+		return row, err
+	}
+
+	return i.nextLoose()
+}
+
+func (i *objectsRowIter) Close() error {
+	err := i.closePackfile()
+
+	if i.sync != nil {
+		i.sync()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return err
+}
+
+// hashEntriesIter iterates the entries of a packfile index with the given
+// hashes.
+type hashEntriesIter struct {
+	idx    idxfile.Index
+	hashes []plumbing.Hash
+}
+
+func (i *hashEntriesIter) Next() (*idxfile.Entry, error) {
+	for len(i.hashes) > 0 {
+		hash := i.hashes[0]
+		i.hashes = i.hashes[1:]
+
+		offset, err := i.idx.FindOffset(hash)
+		if err == plumbing.ErrObjectNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &idxfile.Entry{Hash: hash, Offset: uint64(offset)}, nil
+	}
+
+	return nil, io.EOF
+}
+
+func (i *hashEntriesIter) Close() error { return nil }
@@ -0,0 +1,92 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestObjectsTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	pool := poolFromCtx(t, ctx)
+	table := newObjectsTable(pool)
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.NotEmpty(rows)
+
+	r, err := pool.GetRepo(path)
+	require.NoError(err)
+	defer r.Close()
+
+	var deltified int
+	for idx, row := range rows {
+		require.NoError(ObjectsSchema.CheckRow(row), "row %d doesn't conform to schema", idx)
+		require.Equal(row[4] == objectStoragePacked, row[5] != nil)
+		require.Equal(row[4] == objectStoragePacked, row[6] != nil)
+
+		obj, err := r.Storer.EncodedObject(plumbing.AnyObject, plumbing.NewHash(row[1].(string)))
+		require.NoError(err)
+		require.Equal(obj.Type().String(), row[2])
+		require.Equal(obj.Size(), row[3])
+
+		if row[7].(int64) > 0 {
+			deltified++
+		}
+	}
+	require.NotZero(deltified)
+}
+
+func TestObjectsPushdown(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	pool := poolFromCtx(t, ctx)
+	r, err := pool.GetRepo(path)
+	require.NoError(err)
+	loose := storeBlob(t, r.Storer, "loose object\n")
+	require.NoError(r.Close())
+
+	table := newObjectsTable(pool)
+
+	rows, err := tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, ObjectsTableName, "object_hash", false),
+			expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("commit", rows[0][2])
+	require.Equal(objectStoragePacked, rows[0][4])
+	require.Equal(int64(0), rows[0][7])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(4, sql.Text, ObjectsTableName, "object_storage", false),
+			expression.NewLiteral(objectStorageLoose, sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		sql.NewRow(path, loose.String(), "blob", int64(13), objectStorageLoose, nil, nil, int64(0)),
+	}, rows)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, ObjectsTableName, "object_hash", false),
+			expression.NewLiteral(loose.String(), sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal(objectStorageLoose, rows[0][4])
+}
@@ -36,7 +36,7 @@ func repositoryPackfiles(fs billy.Filesystem) (*dotgit.DotGit, []plumbing.Hash,
 
 type packfileIndex struct {
 	packfile plumbing.Hash
-	idx      idxfile.Index
+	idx      *idxfile.MemoryIndex
 }
 
 type repositoryIndex struct {
@@ -6,6 +6,7 @@ import (
 	"github.com/src-d/go-borges"
 	"github.com/src-d/go-mysql-server/sql"
 	errors "gopkg.in/src-d/go-errors.v1"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 // partitioned is an embeddable helper that contains the methods for a table
@@ -22,7 +23,7 @@ func (partitioned) PartitionCount(ctx *sql.Context) (int64, error) {
 		return 0, err
 	}
 
-	it, err := s.Pool.RepoIter()
+	it, err := s.QueryPool(ctx).RepoIter()
 	if err != nil {
 		return 0, err
 	}
@@ -49,9 +50,9 @@ func (p RepositoryPartition) Key() []byte {
 }
 
 type repositoryPartitionIter struct {
-	repoIter   borges.RepositoryIterator
-	lib        borges.Library
-	skipErrors bool
+	repoIter borges.RepositoryIterator
+	lib      borges.Library
+	skipper  *gitErrorSkipper
 }
 
 func newRepositoryPartitionIter(ctx *sql.Context) (sql.PartitionIter, error) {
@@ -60,15 +61,16 @@ func newRepositoryPartitionIter(ctx *sql.Context) (sql.PartitionIter, error) {
 		return nil, err
 	}
 
-	it, err := s.Pool.library.Repositories(borges.ReadOnlyMode)
+	lib := s.QueryPool(ctx).Library()
+	it, err := lib.Repositories(borges.ReadOnlyMode)
 	if err != nil {
 		return nil, err
 	}
 
 	return &repositoryPartitionIter{
-		repoIter:   it,
-		lib:        s.Pool.library,
-		skipErrors: s.SkipGitErrors,
+		repoIter: it,
+		lib:      lib,
+		skipper:  newGitErrorSkipper(ctx, ""),
 	}, nil
 }
 
@@ -80,7 +82,7 @@ func (i *repositoryPartitionIter) Next() (sql.Partition, error) {
 		if err == nil {
 			break
 		}
-		if err == io.EOF || !i.skipErrors {
+		if err == io.EOF || !i.skipper.skip("", plumbing.ZeroHash, err) {
 			return nil, err
 		}
 	}
@@ -114,7 +116,14 @@ func getPartitionRepo(ctx *sql.Context, p sql.Partition) (*Repository, error) {
 		return nil, err
 	}
 
-	return s.Pool.GetRepo(string(rp))
+	return s.QueryPool(ctx).GetRepo(string(rp))
+}
+
+// partitionRepoID returns the id of the repository of the partition, or an
+// empty string if it's not a repository partition.
+func partitionRepoID(p sql.Partition) string {
+	rp, _ := p.(RepositoryPartition)
+	return string(rp)
 }
 
 var errColumnNotFound = errors.NewKind("column %s not found in table %s")
@@ -257,7 +266,7 @@ func (i *partitionedIndexKeyValueIter) Next() (sql.Partition, sql.IndexKeyValueI
 		return nil, nil, err
 	}
 
-	iter, err := i.builder(i.session.Pool, repo, i.columns)
+	iter, err := i.builder(i.session.QueryPool(i.ctx), repo, i.columns)
 	if err != nil {
 		return nil, nil, err
 	}
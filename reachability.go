@@ -0,0 +1,226 @@
+package gitbase
+
+import (
+	"container/list"
+	"io"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// ReachableObjects is the set of objects of a repository reachable from its
+// references or the entries of its reflogs.
+type ReachableObjects struct {
+	objects map[plumbing.Hash]struct{}
+}
+
+// Contains returns whether the object with the given hash is reachable.
+func (r *ReachableObjects) Contains(hash plumbing.Hash) bool {
+	_, ok := r.objects[hash]
+	return ok
+}
+
+// Len returns the number of reachable objects.
+func (r *ReachableObjects) Len() int {
+	return len(r.objects)
+}
+
+const reachableCacheSizeKey = "GITBASE_REACHABLE_CACHE_SIZE"
+
+// reachableCacheSize is the maximum number of repositories whose reachable
+// objects are kept in a session.
+var reachableCacheSize = getIntEnv(reachableCacheSizeKey, 16)
+
+// reachableEntry holds the reachable objects of a repository in the cache of
+// the session. done is closed once they are found.
+type reachableEntry struct {
+	repo    string
+	done    chan struct{}
+	objects *ReachableObjects
+	err     error
+}
+
+// ReachableObjects returns the objects of the repository reachable from its
+// references, including HEAD when it's detached, or from the old and new
+// values of the entries of its reflogs. Objects referenced by reflogs are
+// kept by git until the entries expire, so they are not dangling yet. The
+// objects of the last GITBASE_REACHABLE_CACHE_SIZE repositories used are
+// kept in the session, so each one is only walked once while it's in the
+// cache, and changes made to it later are not seen. The history is walked
+// without holding the lock of the session, so several repositories can be
+// walked at the same time.
+func (s *Session) ReachableObjects(repo *Repository) (*ReachableObjects, error) {
+	id := repo.ID()
+
+	s.reachableMu.Lock()
+	if s.reachable == nil {
+		s.reachable = make(map[string]*list.Element)
+		s.reachableLRU = list.New()
+	}
+
+	if e, ok := s.reachable[id]; ok {
+		s.reachableLRU.MoveToFront(e)
+		s.reachableMu.Unlock()
+
+		entry := e.Value.(*reachableEntry)
+		<-entry.done
+		return entry.objects, entry.err
+	}
+
+	entry := &reachableEntry{repo: id, done: make(chan struct{})}
+	s.reachable[id] = s.reachableLRU.PushFront(entry)
+	for s.reachableLRU.Len() > reachableCacheSize && s.reachableLRU.Len() > 1 {
+		oldest := s.reachableLRU.Back()
+		s.reachableLRU.Remove(oldest)
+		delete(s.reachable, oldest.Value.(*reachableEntry).repo)
+	}
+	s.reachableMu.Unlock()
+
+	entry.objects, entry.err = reachableObjects(repo)
+	if entry.err != nil {
+		// Errors are not cached, so the repository is walked again the
+		// next time.
+		s.reachableMu.Lock()
+		if e, ok := s.reachable[id]; ok && e.Value == entry {
+			s.reachableLRU.Remove(e)
+			delete(s.reachable, id)
+		}
+		s.reachableMu.Unlock()
+	}
+
+	close(entry.done)
+	return entry.objects, entry.err
+}
+
+func reachableObjects(repo *Repository) (*ReachableObjects, error) {
+	roots, err := reachabilityRoots(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReachableObjects{objects: make(map[plumbing.Hash]struct{})}
+	for _, hash := range roots {
+		if err := r.walk(repo, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// reachabilityRoots returns the hashes the references and the entries of
+// the reflogs point to.
+func reachabilityRoots(repo *Repository) ([]plumbing.Hash, error) {
+	var roots []plumbing.Hash
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			roots = append(roots, ref.Hash())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// HEAD is not returned with the references when it's detached.
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil && err != plumbing.ErrReferenceNotFound {
+		return nil, err
+	}
+
+	if head != nil && head.Type() == plumbing.HashReference {
+		roots = append(roots, head.Hash())
+	}
+
+	hashes, err := reflogHashes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(roots, hashes...), nil
+}
+
+// reflogHashes returns the old and new hashes of the entries of the reflogs
+// of the repository.
+func reflogHashes(repo *Repository) ([]plumbing.Hash, error) {
+	// The iterator is not closed, as that would close the repository.
+	iter := &reflogRowIter{repo: repo}
+	defer func() {
+		if iter.sync != nil {
+			iter.sync()
+		}
+	}()
+
+	var hashes []plumbing.Hash
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			return hashes, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range row[2:4] {
+			if h := plumbing.NewHash(v.(string)); !h.IsZero() {
+				hashes = append(hashes, h)
+			}
+		}
+	}
+}
+
+// walk adds the object with the given hash and all the objects reachable
+// from it. Missing objects are ignored, like the commits of shallow
+// repositories or the ones pruned while their reflog entries were kept.
+func (r *ReachableObjects) walk(repo *Repository, hash plumbing.Hash) error {
+	pending := []plumbing.Hash{hash}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if r.Contains(hash) {
+			continue
+		}
+
+		obj, err := repo.Object(plumbing.AnyObject, hash)
+		if err == plumbing.ErrObjectNotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		r.objects[hash] = struct{}{}
+
+		switch obj := obj.(type) {
+		case *object.Commit:
+			pending = append(pending, obj.TreeHash)
+			pending = append(pending, obj.ParentHashes...)
+		case *object.Tree:
+			for _, e := range obj.Entries {
+				switch e.Mode {
+				case filemode.Dir:
+					pending = append(pending, e.Hash)
+				case filemode.Submodule:
+					// Submodules point to commits of other repositories.
+				default:
+					r.objects[e.Hash] = struct{}{}
+				}
+			}
+		case *object.Tag:
+			pending = append(pending, obj.Target)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package gitbase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/util"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestReachableObjects(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+
+	r, err := session.Pool.GetRepo(path)
+	require.NoError(err)
+	defer r.Close()
+
+	dangling := storeBlob(t, r.Storer, "dangling\n")
+
+	// A commit only referenced by a reflog entry.
+	logged := storeBlob(t, r.Storer, "logged\n")
+	c := &object.Commit{
+		Message: "Reset later\n",
+		TreeHash: storeTree(t, r.Storer, object.TreeEntry{
+			Name: "logged", Mode: filemode.Regular, Hash: logged,
+		}),
+	}
+	commit := storeCommit(t, r, c)
+
+	fs, err := r.FS()
+	require.NoError(err)
+	fs, err = findDotGit(fs)
+	require.NoError(err)
+	require.NoError(util.WriteFile(
+		fs, fs.Join("logs", "refs", "heads", "reset"),
+		[]byte(fmt.Sprintf(
+			"%s %s John Doe <john@doe.com> 1585000000 +0100\tcommit\n",
+			plumbing.ZeroHash, commit,
+		)),
+		0644,
+	))
+
+	reachable, err := session.ReachableObjects(r)
+	require.NoError(err)
+
+	head, err := r.Head()
+	require.NoError(err)
+	headCommit, err := r.CommitObject(head.Hash())
+	require.NoError(err)
+
+	require.True(reachable.Contains(head.Hash()))
+	require.True(reachable.Contains(headCommit.TreeHash))
+	require.True(reachable.Contains(commit))
+	require.True(reachable.Contains(logged))
+	require.False(reachable.Contains(dangling))
+
+	// Objects stored later are not seen in the same session.
+	c.TreeHash = storeTree(t, r.Storer, object.TreeEntry{
+		Name: "dangling", Mode: filemode.Regular, Hash: dangling,
+	})
+	ref := plumbing.NewHashReference("refs/heads/dangling", storeCommit(t, r, c))
+	require.NoError(r.Storer.SetReference(ref))
+
+	cached, err := session.ReachableObjects(r)
+	require.NoError(err)
+	require.Equal(reachable, cached)
+	require.False(cached.Contains(dangling))
+
+	reachable, err = reachableObjects(r)
+	require.NoError(err)
+	require.True(reachable.Contains(dangling))
+}
+
+func TestReachableObjectsCache(t *testing.T) {
+	require := require.New(t)
+
+	defer func(size int) { reachableCacheSize = size }(reachableCacheSize)
+	reachableCacheSize = 1
+
+	ctx, paths, cleanup := setupRepos(t)
+	defer cleanup()
+	require.True(len(paths) > 1)
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+
+	reachable := func(path string) *ReachableObjects {
+		r, err := session.Pool.GetRepo(path)
+		require.NoError(err)
+		defer r.Close()
+
+		objects, err := session.ReachableObjects(r)
+		require.NoError(err)
+		return objects
+	}
+
+	first := reachable(paths[0])
+	require.True(first == reachable(paths[0]))
+	require.Len(session.reachable, 1)
+
+	// The least recently used repository is evicted.
+	second := reachable(paths[1])
+	require.Len(session.reachable, 1)
+	require.True(second == reachable(paths[1]))
+	require.False(first == reachable(paths[0]))
+}
+
+func storeCommit(t *testing.T, r *Repository, c *object.Commit) plumbing.Hash {
+	t.Helper()
+
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(t, c.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/src-d/go-mysql-server/sql"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/object/commitgraph"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 )
 
@@ -110,11 +111,11 @@ func (t *refCommitsTable) PartitionRows(
 			}
 
 			return &refCommitsRowIter{
-				ctx:           ctx,
-				refNames:      names,
-				repo:          repo,
-				index:         indexValues,
-				skipGitErrors: shouldSkipErrors(ctx),
+				ctx:      ctx,
+				refNames: names,
+				repo:     repo,
+				index:    indexValues,
+				skipper:  newGitErrorSkipper(ctx, RefCommitsTableName),
 			}, nil
 		},
 	)
@@ -212,15 +213,15 @@ func (refCommitsRowKeyMapper) toRow(data []byte) (sql.Row, error) {
 }
 
 type refCommitsRowIter struct {
-	ctx           *sql.Context
-	repo          *Repository
-	refs          storer.ReferenceIter
-	head          *plumbing.Reference
-	commits       *indexedCommitIter
-	ref           *plumbing.Reference
-	index         sql.IndexValueIter
-	skipGitErrors bool
-	mapper        refCommitsRowKeyMapper
+	ctx     *sql.Context
+	repo    *Repository
+	refs    storer.ReferenceIter
+	head    *plumbing.Reference
+	commits *indexedCommitIter
+	ref     *plumbing.Reference
+	index   sql.IndexValueIter
+	skipper *gitErrorSkipper
+	mapper  refCommitsRowKeyMapper
 
 	// selectors for faster filtering
 	refNames []string
@@ -271,7 +272,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 			if err != nil {
 				i.repo.Close()
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
@@ -280,7 +281,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 
 			i.head, err = i.repo.Head()
 			if err != nil && err != plumbing.ErrReferenceNotFound {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -300,7 +301,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 						return nil, io.EOF
 					}
 
-					if i.skipGitErrors {
+					if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 						continue
 					}
 
@@ -319,7 +320,8 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 
 			commit, err := resolveCommit(i.repo, ref.Hash())
 			if err != nil {
-				if errInvalidCommit.Is(err) || i.skipGitErrors {
+				if errInvalidCommit.Is(err) ||
+					i.skipper.skip(i.repo.ID(), ref.Hash(), err) {
 					continue
 				}
 
@@ -327,7 +329,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 				return nil, err
 			}
 
-			i.commits = newIndexedCommitIter(i.skipGitErrors, i.repo, commit)
+			i.commits = newIndexedCommitIter(i.skipper, i.repo, commit)
 		}
 
 		commit, idx, err := i.commits.Next()
@@ -337,7 +339,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 				continue
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 				continue
 			}
 
@@ -347,7 +349,7 @@ func (i *refCommitsRowIter) next() (sql.Row, error) {
 
 		return sql.NewRow(
 			i.repo.ID(),
-			commit.Hash.String(),
+			commit.ID().String(),
 			i.ref.Name().String(),
 			int64(idx),
 		), nil
@@ -370,21 +372,27 @@ func (i *refCommitsRowIter) Close() error {
 	return nil
 }
 
+// indexedCommitIter walks the history of a commit returning its commits
+// along with their index. Commits are read with the commit node index of
+// the repository, so the commit-graph file is used when it exists and
+// commit objects are only decoded when needed.
 type indexedCommitIter struct {
-	skipGitErrors bool
-	repo          *Repository
-	stack         []*stackFrame
-	seen          map[plumbing.Hash]struct{}
+	skipper *gitErrorSkipper
+	repo    *Repository
+	nodes   commitgraph.CommitNodeIndex
+	stack   []*stackFrame
+	seen    map[plumbing.Hash]struct{}
 }
 
 func newIndexedCommitIter(
-	skipGitErrors bool,
+	skipper *gitErrorSkipper,
 	repo *Repository,
 	start *object.Commit,
 ) *indexedCommitIter {
 	return &indexedCommitIter{
-		skipGitErrors: skipGitErrors,
-		repo:          repo,
+		skipper: skipper,
+		repo:    repo,
+		nodes:   repo.CommitNodeIndex(),
 		stack: []*stackFrame{
 			{0, 0, []plumbing.Hash{start.Hash}},
 		},
@@ -398,7 +406,7 @@ type stackFrame struct {
 	hashes []plumbing.Hash
 }
 
-func (i *indexedCommitIter) Next() (*object.Commit, int, error) {
+func (i *indexedCommitIter) Next() (commitgraph.CommitNode, int, error) {
 	for {
 		if len(i.stack) == 0 {
 			i.repo.Close()
@@ -417,9 +425,9 @@ func (i *indexedCommitIter) Next() (*object.Commit, int, error) {
 			i.stack = i.stack[:len(i.stack)-1]
 		}
 
-		c, err := i.repo.CommitObject(h)
+		c, err := i.nodes.Get(h)
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), h, err) {
 				continue
 			}
 
@@ -429,7 +437,7 @@ func (i *indexedCommitIter) Next() (*object.Commit, int, error) {
 
 		if c.NumParents() > 0 {
 			parents := make([]plumbing.Hash, 0, c.NumParents())
-			for _, h = range c.ParentHashes {
+			for _, h = range c.ParentHashes() {
 				if _, ok := i.seen[h]; !ok {
 					parents = append(parents, h)
 				}
@@ -108,11 +108,11 @@ func (r *referencesTable) PartitionRows(
 			}
 
 			return &refRowIter{
-				hashes:        stringsToHashes(hashes),
-				repo:          repo,
-				names:         names,
-				index:         indexValues,
-				skipGitErrors: shouldSkipErrors(ctx),
+				hashes:  stringsToHashes(hashes),
+				repo:    repo,
+				names:   names,
+				index:   indexValues,
+				skipper: newGitErrorSkipper(ctx, ReferencesTableName),
 			}, nil
 		},
 	)
@@ -200,11 +200,11 @@ func (refRowKeyMapper) toRow(data []byte) (sql.Row, error) {
 }
 
 type refRowIter struct {
-	repo          *Repository
-	hashes        []plumbing.Hash
-	names         []string
-	index         sql.IndexValueIter
-	skipGitErrors bool
+	repo    *Repository
+	hashes  []plumbing.Hash
+	names   []string
+	index   sql.IndexValueIter
+	skipper *gitErrorSkipper
 
 	head   *plumbing.Reference
 	iter   storer.ReferenceIter
@@ -269,7 +269,7 @@ func (i *refRowIter) next() (sql.Row, error) {
 	for {
 		if i.iter == nil {
 			if err := i.init(); err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
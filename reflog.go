@@ -0,0 +1,521 @@
+package gitbase
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-borges/siva"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// reflogDir is the directory, inside the git directory, with the logs of
+// the references.
+const reflogDir = "logs"
+
+type reflogTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// ReflogSchema is the schema for the reflog table.
+var ReflogSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: ReflogTableName},
+	{Name: "ref_name", Type: sql.Text, Nullable: false, Source: ReflogTableName},
+	{Name: "old_hash", Type: sql.VarChar(40), Nullable: false, Source: ReflogTableName},
+	{Name: "new_hash", Type: sql.VarChar(40), Nullable: false, Source: ReflogTableName},
+	{Name: "committer_name", Type: sql.Text, Nullable: false, Source: ReflogTableName},
+	{Name: "committer_email", Type: sql.Text, Nullable: false, Source: ReflogTableName},
+	{Name: "committer_when", Type: sql.Timestamp, Nullable: false, Source: ReflogTableName},
+	{Name: "message", Type: sql.Text, Nullable: false, Source: ReflogTableName},
+}
+
+func newReflogTable(pool *RepositoryPool) *reflogTable {
+	return &reflogTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*reflogTable)(nil)
+
+func (reflogTable) isGitbaseTable() {}
+
+func (t reflogTable) String() string {
+	return printTable(
+		ReflogTableName,
+		ReflogSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (reflogTable) Name() string { return ReflogTableName }
+
+func (reflogTable) Schema() sql.Schema { return ReflogSchema }
+
+func (t *reflogTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *reflogTable) Filters() []sql.Expression { return t.filters }
+
+func (t *reflogTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.ReflogTable")
+	iter, err := rowIterWithSelectors(
+		ctx, ReflogSchema, ReflogTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var refNames []string
+			refNames, err = selectors.textValues("ref_name")
+			if err != nil {
+				return nil, err
+			}
+
+			return &reflogRowIter{
+				repo:     repo,
+				refNames: refNames,
+				when:     committerWhenRange(t.filters),
+				skipper:  newGitErrorSkipper(ctx, ReflogTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (reflogTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(ReflogTableName, ReflogSchema, filters)
+}
+
+func (reflogTable) handledColumns() []string {
+	return []string{"ref_name", "repository_id"}
+}
+
+// timeRange is a range of committer_when values. A zero bound leaves that
+// side of the range open.
+type timeRange struct {
+	since, until         time.Time
+	sinceIncl, untilIncl bool
+}
+
+// contains returns whether the given time is inside the range.
+func (r timeRange) contains(t time.Time) bool {
+	if !r.since.IsZero() &&
+		(t.Before(r.since) || (!r.sinceIncl && t.Equal(r.since))) {
+		return false
+	}
+
+	if !r.until.IsZero() &&
+		(t.After(r.until) || (!r.untilIncl && t.Equal(r.until))) {
+		return false
+	}
+
+	return true
+}
+
+// restrictSince narrows the range to the times after t, or equal to it if
+// incl is true.
+func (r *timeRange) restrictSince(t time.Time, incl bool) {
+	if r.since.IsZero() || t.After(r.since) || (t.Equal(r.since) && !incl) {
+		r.since, r.sinceIncl = t, incl
+	}
+}
+
+// restrictUntil narrows the range to the times before t, or equal to it if
+// incl is true.
+func (r *timeRange) restrictUntil(t time.Time, incl bool) {
+	if r.until.IsZero() || t.Before(r.until) || (t.Equal(r.until) && !incl) {
+		r.until, r.untilIncl = t, incl
+	}
+}
+
+// committerWhenRange returns the range of committer_when values allowed by
+// the comparisons and BETWEEN expressions of the given filters against
+// literals, so the entries out of it are discarded while the logs are
+// parsed. The range may be wider than the filters, as they are still
+// evaluated for every row.
+func committerWhenRange(filters []sql.Expression) timeRange {
+	var r timeRange
+	for _, f := range filters {
+		for _, e := range splitAnd(f) {
+			switch e := e.(type) {
+			case *expression.GreaterThan:
+				restrictComparison(&r, e.Left(), e.Right(), false, true)
+			case *expression.GreaterThanOrEqual:
+				restrictComparison(&r, e.Left(), e.Right(), true, true)
+			case *expression.LessThan:
+				restrictComparison(&r, e.Left(), e.Right(), false, false)
+			case *expression.LessThanOrEqual:
+				restrictComparison(&r, e.Left(), e.Right(), true, false)
+			case *expression.Between:
+				if isCommitterWhen(e.Val) {
+					restrictBound(&r, e.Lower, true, true)
+					restrictBound(&r, e.Upper, true, false)
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// restrictComparison narrows the range with a comparison between
+// committer_when and a literal, in any order. greater is whether the left
+// side of the comparison is the greatest one.
+func restrictComparison(
+	r *timeRange,
+	left, right sql.Expression,
+	incl, greater bool,
+) {
+	if isCommitterWhen(right) {
+		left, right = right, left
+		greater = !greater
+	}
+
+	if isCommitterWhen(left) {
+		restrictBound(r, right, incl, greater)
+	}
+}
+
+// restrictBound narrows the lower or upper bound of the range with the time
+// of a literal. Literals other than timestamps are compared as text with
+// committer_when in its own time zone, so their bound is widened a day to
+// keep every entry that may match.
+func restrictBound(r *timeRange, e sql.Expression, incl, lower bool) {
+	lit, ok := e.(*expression.Literal)
+	if !ok {
+		return
+	}
+
+	v, err := lit.Eval(nil, nil)
+	if err != nil || v == nil {
+		return
+	}
+
+	v, err = sql.Timestamp.Convert(v)
+	if err != nil {
+		return
+	}
+
+	t, ok := v.(time.Time)
+	if !ok || t.IsZero() {
+		return
+	}
+
+	if lit.Type() != sql.Timestamp {
+		incl = true
+		if lower {
+			t = t.Add(-24 * time.Hour)
+		} else {
+			t = t.Add(24 * time.Hour)
+		}
+	}
+
+	if lower {
+		r.restrictSince(t, incl)
+	} else {
+		r.restrictUntil(t, incl)
+	}
+}
+
+func isCommitterWhen(e sql.Expression) bool {
+	gf, ok := e.(*expression.GetField)
+	return ok && gf.Table() == ReflogTableName && gf.Name() == "committer_when"
+}
+
+// reflogFiles returns the names of the references with a log in the git
+// directory. In rooted siva files the logs of all the repositories are kept
+// together and their references are suffixed with the repository ID, so
+// only the names with the given suffix are returned, without it.
+func reflogFiles(fs billy.Filesystem, suffix string) ([]string, error) {
+	var names []string
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		files, err := fs.ReadDir(fs.Join(reflogDir, dir))
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			name := f.Name()
+			if dir != "" {
+				name = dir + "/" + name
+			}
+
+			if f.IsDir() {
+				if err := walk(name); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if strings.HasSuffix(name, suffix) {
+				names = append(names, strings.TrimSuffix(name, suffix))
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// parseReflog returns the rows of the entries of the log of a reference,
+// from the oldest to the newest, with a committer_when in the given range.
+// Malformed entries are ignored.
+func parseReflog(
+	repoID, refName string,
+	r io.Reader,
+	when timeRange,
+) ([]sql.Row, error) {
+	var rows []sql.Row
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			row, ok := parseReflogEntry(repoID, refName, line)
+			if !ok {
+				logrus.WithFields(logrus.Fields{
+					"repo":  repoID,
+					"ref":   refName,
+					"entry": line,
+				}).Warn("invalid reflog entry, ignoring it")
+			} else if when.contains(row[6].(time.Time)) {
+				rows = append(rows, row)
+			}
+		}
+
+		if err == io.EOF {
+			return rows, nil
+		}
+	}
+}
+
+// parseReflogEntry parses an entry of a reflog, which has the format:
+//
+//	<old hash> SP <new hash> SP <committer> SP <timestamp> SP <tz> TAB <message>
+func parseReflogEntry(repoID, refName, line string) (sql.Row, bool) {
+	var message string
+	if idx := strings.IndexByte(line, '\t'); idx >= 0 {
+		line, message = line[:idx], line[idx+1:]
+	}
+
+	const hashLen = 40
+	if len(line) < 2*hashLen+2 || line[hashLen] != ' ' || line[2*hashLen+1] != ' ' {
+		return nil, false
+	}
+
+	oldHash, newHash := line[:hashLen], line[hashLen+1:2*hashLen+1]
+	if !isHexHash(oldHash) || !isHexHash(newHash) {
+		return nil, false
+	}
+
+	var committer object.Signature
+	committer.Decode([]byte(line[2*hashLen+2:]))
+	if committer.When.IsZero() {
+		return nil, false
+	}
+
+	return sql.NewRow(
+		repoID,
+		refName,
+		oldHash,
+		newHash,
+		committer.Name,
+		committer.Email,
+		committer.When,
+		message,
+	), true
+}
+
+func isHexHash(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// reflogSuffix returns the suffix of the log files of the repository. Rooted
+// siva files keep the logs of all their repositories together, suffixed
+// with the repository ID. Legacy siva files and plain repositories have a
+// single repository, so their logs have no suffix.
+func reflogSuffix(repo *Repository) (string, error) {
+	lib, err := repositoryLibrary(repo)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := lib.(*siva.Library); ok {
+		return "/" + repo.ID(), nil
+	}
+
+	return "", nil
+}
+
+type reflogRowIter struct {
+	repo    *Repository
+	skipper *gitErrorSkipper
+	// fs is the git directory of the repository.
+	fs   billy.Filesystem
+	sync func()
+	// suffix of the log files of the repository, only used in rooted siva
+	// files.
+	suffix string
+	refs   []string
+	rows   []sql.Row
+
+	// selectors for faster filtering
+	refNames []string
+	when     timeRange
+}
+
+func (i *reflogRowIter) init() error {
+	fs, sync, err := repositoryFS(i.repo)
+	if err != nil {
+		return err
+	}
+	i.sync = sync
+
+	i.suffix, err = reflogSuffix(i.repo)
+	if err != nil {
+		return err
+	}
+
+	i.fs, err = findDotGit(fs)
+	if err != nil {
+		return err
+	}
+
+	if len(i.refNames) > 0 {
+		// Names out of the logs directory can't be references.
+		for _, name := range i.refNames {
+			if path.IsAbs(name) || path.Clean(name) != name ||
+				name == ".." || strings.HasPrefix(name, "../") {
+				continue
+			}
+
+			i.refs = append(i.refs, name)
+		}
+
+		return nil
+	}
+
+	i.refs, err = reflogFiles(i.fs, i.suffix)
+	return err
+}
+
+func (i *reflogRowIter) readReflog(refName string) ([]sql.Row, error) {
+	f, err := i.fs.Open(i.fs.Join(reflogDir, refName+i.suffix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseReflog(i.repo.ID(), refName, f, i.when)
+}
+
+func (i *reflogRowIter) Next() (sql.Row, error) {
+	for {
+		if i.fs == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if len(i.rows) > 0 {
+			row := i.rows[0]
+			i.rows = i.rows[1:]
+			return row, nil
+		}
+
+		if len(i.refs) == 0 {
+			return nil, io.EOF
+		}
+
+		ref := i.refs[0]
+		i.refs = i.refs[1:]
+
+		var err error
+		i.rows, err = i.readReflog(ref)
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo": i.repo.ID(),
+					"err":  err,
+					"ref":  ref,
+				}).Error("can't read reflog of reference")
+				continue
+			}
+
+			return nil, err
+		}
+	}
+}
+
+func (i *reflogRowIter) Close() error {
+	if i.sync != nil {
+		i.sync()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
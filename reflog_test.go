@@ -0,0 +1,344 @@
+package gitbase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-borges"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+const testReflog = `0000000000000000000000000000000000000000 918c48b83bd081e863dbe1b80f8998f058cd8294 John Doe <john@doe.com> 1585000000 +0100	branch: Created from HEAD
+not a valid entry
+918c48b83bd081e863dbe1b80f8998f058cd8294 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 Jane Doe <jane@doe.com> 1585003600 -0200	reset: moving to 6ecf0ef
+`
+
+func TestReflogTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	pool := poolFromCtx(t, ctx)
+	r, err := pool.GetRepo(path)
+	require.NoError(err)
+
+	fs, err := r.FS()
+	require.NoError(err)
+	fs, err = findDotGit(fs)
+	require.NoError(err)
+	require.NoError(util.WriteFile(
+		fs, fs.Join("logs", "refs", "heads", "reflog-test"), []byte(testReflog), 0644,
+	))
+	require.NoError(r.Close())
+
+	table := newReflogTable(pool)
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+
+	var found int
+	for _, row := range rows {
+		require.NoError(ReflogSchema.CheckRow(row))
+		if row[1] == "refs/heads/reflog-test" {
+			found++
+		}
+	}
+	require.Equal(2, found)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, ReflogTableName, "ref_name", false),
+			expression.NewLiteral("refs/heads/reflog-test", sql.Text),
+		),
+		expression.NewGreaterThan(
+			expression.NewGetFieldWithTable(6, sql.Timestamp, ReflogTableName, "committer_when", false),
+			expression.NewLiteral(time.Unix(1585001000, 0), sql.Timestamp),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", rows[0][3])
+	require.Equal("reset: moving to 6ecf0ef", rows[0][7])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, ReflogTableName, "ref_name", false),
+			expression.NewLiteral("../config", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestReflogTableSiva(t *testing.T) {
+	require := require.New(t)
+
+	lib, pool, err := newMultiPool()
+	require.NoError(err)
+
+	cwd, err := os.Getwd()
+	require.NoError(err)
+	require.NoError(lib.AddSiva(filepath.Join(cwd, testSivaFilePath), nil))
+
+	r, err := pool.GetRepo(testSivaRepoID)
+	require.NoError(err)
+	suffix, err := reflogSuffix(r)
+	require.NoError(err)
+	require.Equal("/"+testSivaRepoID, suffix)
+	require.NoError(r.Close())
+
+	bRepo, err := lib.Get(borges.RepositoryID(testSivaRepoID), borges.RWMode)
+	require.NoError(err)
+	require.NoError(util.WriteFile(
+		bRepo.FS(),
+		"logs/refs/heads/master"+suffix,
+		[]byte(testReflog),
+		0644,
+	))
+	require.NoError(bRepo.Close())
+
+	session := NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	rows, err := tableToRows(ctx, newReflogTable(pool))
+	require.NoError(err)
+	require.Len(rows, 2)
+	for _, row := range rows {
+		require.Equal(testSivaRepoID, row[0])
+		require.Equal("refs/heads/master", row[1])
+	}
+}
+
+func TestCommitterWhenRange(t *testing.T) {
+	committerWhen := expression.NewGetFieldWithTable(
+		6, sql.Timestamp, ReflogTableName, "committer_when", false,
+	)
+	since := time.Unix(1585000000, 0).UTC()
+	until := time.Unix(1585003600, 0).UTC()
+	day := 24 * time.Hour
+
+	testCases := []struct {
+		name     string
+		filters  []sql.Expression
+		expected timeRange
+	}{
+		{
+			"no filters",
+			nil,
+			timeRange{},
+		},
+		{
+			"greater than",
+			[]sql.Expression{
+				expression.NewGreaterThan(
+					committerWhen,
+					expression.NewLiteral(since, sql.Timestamp),
+				),
+			},
+			timeRange{since: since},
+		},
+		{
+			"literal on the left",
+			[]sql.Expression{
+				expression.NewGreaterThanOrEqual(
+					expression.NewLiteral(until, sql.Timestamp),
+					committerWhen,
+				),
+			},
+			timeRange{until: until, untilIncl: true},
+		},
+		{
+			"between",
+			[]sql.Expression{
+				expression.NewBetween(
+					committerWhen,
+					expression.NewLiteral(since, sql.Timestamp),
+					expression.NewLiteral(until, sql.Timestamp),
+				),
+			},
+			timeRange{since: since, sinceIncl: true, until: until, untilIncl: true},
+		},
+		{
+			"narrowest bounds",
+			[]sql.Expression{
+				expression.NewAnd(
+					expression.NewGreaterThanOrEqual(
+						committerWhen,
+						expression.NewLiteral(since, sql.Timestamp),
+					),
+					expression.NewGreaterThan(
+						committerWhen,
+						expression.NewLiteral(since, sql.Timestamp),
+					),
+				),
+				expression.NewLessThan(
+					committerWhen,
+					expression.NewLiteral(until.Add(time.Hour), sql.Timestamp),
+				),
+				expression.NewLessThanOrEqual(
+					committerWhen,
+					expression.NewLiteral(until, sql.Timestamp),
+				),
+			},
+			timeRange{since: since, until: until, untilIncl: true},
+		},
+		{
+			"text literal",
+			[]sql.Expression{
+				expression.NewLessThan(
+					committerWhen,
+					expression.NewLiteral("2020-03-23 21:46:40", sql.Text),
+				),
+			},
+			timeRange{until: since.Add(day), untilIncl: true},
+		},
+		{
+			"other filters",
+			[]sql.Expression{
+				expression.NewOr(
+					expression.NewGreaterThan(
+						committerWhen,
+						expression.NewLiteral(since, sql.Timestamp),
+					),
+					expression.NewLiteral(true, sql.Boolean),
+				),
+				expression.NewGreaterThan(
+					expression.NewGetFieldWithTable(
+						4, sql.Text, ReflogTableName, "committer_name", false,
+					),
+					expression.NewLiteral("foo", sql.Text),
+				),
+			},
+			timeRange{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := committerWhenRange(tt.filters)
+			require.True(t, tt.expected.since.Equal(r.since))
+			require.True(t, tt.expected.until.Equal(r.until))
+			require.Equal(t, tt.expected.sinceIncl, r.sinceIncl)
+			require.Equal(t, tt.expected.untilIncl, r.untilIncl)
+		})
+	}
+}
+
+func TestParseReflog(t *testing.T) {
+	require := require.New(t)
+
+	rows, err := parseReflog(
+		"foo", "refs/heads/master", strings.NewReader(testReflog), timeRange{},
+	)
+	require.NoError(err)
+	require.Len(rows, 2)
+
+	require.Equal(sql.NewRow(
+		"foo",
+		"refs/heads/master",
+		"0000000000000000000000000000000000000000",
+		"918c48b83bd081e863dbe1b80f8998f058cd8294",
+		"John Doe",
+		"john@doe.com",
+	), rows[0][:6])
+	require.Equal("branch: Created from HEAD", rows[0][7])
+
+	when := rows[0][6].(time.Time)
+	require.True(when.Equal(time.Unix(1585000000, 0)))
+	_, offset := when.Zone()
+	require.Equal(3600, offset)
+
+	require.Equal("Jane Doe", rows[1][4])
+	require.Equal("reset: moving to 6ecf0ef", rows[1][7])
+
+	rows, err = parseReflog(
+		"foo", "refs/heads/master", strings.NewReader(testReflog),
+		timeRange{since: time.Unix(1585000000, 0)},
+	)
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("Jane Doe", rows[0][4])
+}
+
+func TestParseReflogEntry(t *testing.T) {
+	testCases := []struct {
+		name  string
+		entry string
+		ok    bool
+	}{
+		{
+			"valid",
+			"918c48b83bd081e863dbe1b80f8998f058cd8294 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 John Doe <john@doe.com> 1585000000 +0100\tcommit: foo",
+			true,
+		},
+		{
+			"without message",
+			"918c48b83bd081e863dbe1b80f8998f058cd8294 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 John Doe <john@doe.com> 1585000000 +0100",
+			true,
+		},
+		{
+			"invalid hash",
+			"918c48b83bd081e863dbe1b80f8998f058cd829z 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 John Doe <john@doe.com> 1585000000 +0100\tcommit: foo",
+			false,
+		},
+		{
+			"short hash",
+			"918c48b8 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 John Doe <john@doe.com> 1585000000 +0100\tcommit: foo",
+			false,
+		},
+		{
+			"without time",
+			"918c48b83bd081e863dbe1b80f8998f058cd8294 6ecf0ef2c2dffb796033e5a02219af86ec6584e5 John Doe <john@doe.com>\tcommit: foo",
+			false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := parseReflogEntry("foo", "HEAD", tt.entry)
+			require.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestReflogFiles(t *testing.T) {
+	require := require.New(t)
+
+	fs := memfs.New()
+	names, err := reflogFiles(fs, "")
+	require.NoError(err)
+	require.Len(names, 0)
+
+	for _, f := range []string{
+		"logs/HEAD",
+		"logs/refs/heads/master/foo",
+		"logs/refs/heads/master/bar",
+		"logs/refs/remotes/origin/master/foo",
+	} {
+		require.NoError(util.WriteFile(fs, f, []byte(testReflog), 0644))
+	}
+
+	names, err = reflogFiles(fs, "")
+	require.NoError(err)
+	require.Equal([]string{
+		"HEAD",
+		"refs/heads/master/bar",
+		"refs/heads/master/foo",
+		"refs/remotes/origin/master/foo",
+	}, names)
+
+	names, err = reflogFiles(fs, "/foo")
+	require.NoError(err)
+	require.Equal([]string{
+		"refs/heads/master",
+		"refs/remotes/origin/master",
+	}, names)
+}
@@ -2,22 +2,51 @@ package gitbase
 
 import (
 	"io"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-borges"
+	"github.com/src-d/go-borges/legacysiva"
+	"github.com/src-d/go-borges/plain"
+	"github.com/src-d/go-borges/siva"
 	"github.com/src-d/go-mysql-server/sql"
+	sivafs "gopkg.in/src-d/go-billy-siva.v4"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 type repositoriesTable struct {
 	checksumable
 	partitioned
-	filters []sql.Expression
-	index   sql.IndexLookup
+	filters    []sql.Expression
+	projection []string
+	index      sql.IndexLookup
 }
 
 // RepositoriesSchema is the schema for the repositories table.
 var RepositoriesSchema = sql.Schema{
 	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: RepositoriesTableName},
+	{Name: "library_id", Type: sql.Text, Nullable: true, Source: RepositoriesTableName},
+	{Name: "repository_path", Type: sql.Text, Nullable: true, Source: RepositoriesTableName},
+	{Name: "repository_format", Type: sql.Text, Nullable: true, Source: RepositoriesTableName},
+	{Name: "is_bare", Type: sql.Boolean, Nullable: true, Source: RepositoriesTableName},
+	{Name: "head_ref", Type: sql.Text, Nullable: true, Source: RepositoriesTableName},
+	{Name: "default_branch", Type: sql.Text, Nullable: true, Source: RepositoriesTableName},
+	{Name: "ref_count", Type: sql.Int64, Nullable: true, Source: RepositoriesTableName},
+	{Name: "packfile_count", Type: sql.Int64, Nullable: true, Source: RepositoriesTableName},
+	{Name: "object_bytes", Type: sql.Int64, Nullable: true, Source: RepositoriesTableName},
+	{Name: "last_commit_when", Type: sql.Timestamp, Nullable: true, Source: RepositoriesTableName},
 }
 
+// Formats of the repositories in the repository_format column.
+const (
+	repositoryFormatPlain      = "plain"
+	repositoryFormatSiva       = "siva"
+	repositoryFormatLegacySiva = "legacy-siva"
+)
+
 func newRepositoriesTable(pool *RepositoryPool) *repositoriesTable {
 	return &repositoriesTable{checksumable: checksumable{pool}}
 }
@@ -40,7 +69,7 @@ func (r repositoriesTable) String() string {
 	return printTable(
 		RepositoriesTableName,
 		RepositoriesSchema,
-		nil,
+		r.projection,
 		r.filters,
 		r.index,
 	)
@@ -56,6 +85,12 @@ func (r *repositoriesTable) WithFilters(filters []sql.Expression) sql.Table {
 	return &nt
 }
 
+func (r *repositoriesTable) WithProjection(colNames []string) sql.Table {
+	nt := *r
+	nt.projection = colNames
+	return &nt
+}
+
 func (r *repositoriesTable) WithIndexLookup(idx sql.IndexLookup) sql.Table {
 	nt := *r
 	nt.index = idx
@@ -77,15 +112,22 @@ func (r *repositoriesTable) PartitionRows(
 		r.filters,
 		r.handledColumns(),
 		func(_ selectors) (sql.RowIter, error) {
+			skipper := newGitErrorSkipper(ctx, RepositoriesTableName)
 			if r.index != nil {
 				values, err := r.index.Values(p)
 				if err != nil {
 					return nil, err
 				}
-				return &rowIndexIter{new(repoRowKeyMapper), values}, nil
+
+				mapper := &repoRowKeyMapper{repo, r.projection, skipper}
+				return &rowIndexIter{mapper, values}, nil
 			}
 
-			return &repositoriesRowIter{repo: repo}, nil
+			return &repositoriesRowIter{
+				repo:    repo,
+				columns: r.projection,
+				skipper: skipper,
+			}, nil
 		},
 	)
 
@@ -101,6 +143,7 @@ func (repositoriesTable) handledColumns() []string { return nil }
 
 func (r *repositoriesTable) IndexLookup() sql.IndexLookup { return r.index }
 func (r *repositoriesTable) Filters() []sql.Expression    { return r.filters }
+func (r *repositoriesTable) Projection() []string         { return r.projection }
 
 // IndexKeyValues implements the sql.IndexableTable interface.
 func (r *repositoriesTable) IndexKeyValues(
@@ -109,18 +152,25 @@ func (r *repositoriesTable) IndexKeyValues(
 ) (sql.PartitionIndexKeyValueIter, error) {
 	return newTablePartitionIndexKeyValueIter(
 		ctx,
-		newRepositoriesTable(r.pool),
+		newRepositoriesTable(r.pool).WithProjection(colNames),
 		RepositoriesTableName,
 		colNames,
 		new(repoRowKeyMapper),
 	)
 }
 
-type repoRowKeyMapper struct{}
+// repoRowKeyMapper maps the rows of the repositories table to the IDs of
+// the repositories. Rows are built with the metadata columns of the repo,
+// if any.
+type repoRowKeyMapper struct {
+	repo    *Repository
+	columns []string
+	skipper *gitErrorSkipper
+}
 
 func (repoRowKeyMapper) fromRow(row sql.Row) ([]byte, error) {
-	if len(row) != 1 {
-		return nil, errRowKeyMapperRowLength.New(1, len(row))
+	if len(row) != len(RepositoriesSchema) {
+		return nil, errRowKeyMapperRowLength.New(len(RepositoriesSchema), len(row))
 	}
 
 	repo, ok := row[0].(string)
@@ -131,12 +181,14 @@ func (repoRowKeyMapper) fromRow(row sql.Row) ([]byte, error) {
 	return []byte(repo), nil
 }
 
-func (repoRowKeyMapper) toRow(data []byte) (sql.Row, error) {
-	return sql.Row{string(data)}, nil
+func (m repoRowKeyMapper) toRow(data []byte) (sql.Row, error) {
+	return repositoryRow(m.repo, string(data), m.columns, m.skipper)
 }
 
 type repositoriesRowIter struct {
 	repo    *Repository
+	columns []string
+	skipper *gitErrorSkipper
 	visited bool
 }
 
@@ -146,7 +198,7 @@ func (i *repositoriesRowIter) Next() (sql.Row, error) {
 	}
 
 	i.visited = true
-	return sql.NewRow(i.repo.ID()), nil
+	return repositoryRow(i.repo, i.repo.ID(), i.columns, i.skipper)
 }
 
 func (i *repositoriesRowIter) Close() error {
@@ -156,3 +208,317 @@ func (i *repositoriesRowIter) Close() error {
 	}
 	return nil
 }
+
+// repositoryColumns are the functions computing the metadata columns of the
+// repositories table by column name.
+var repositoryColumns = map[string]func(*Repository) (interface{}, error){
+	"library_id":        repositoryLibraryID,
+	"repository_path":   repositoryPath,
+	"repository_format": repositoryFormat,
+	"is_bare":           repositoryIsBare,
+	"head_ref":          repositoryHeadRef,
+	"default_branch":    repositoryDefaultBranch,
+	"ref_count":         repositoryRefCount,
+	"packfile_count":    repositoryPackfileCount,
+	"object_bytes":      repositoryObjectBytes,
+	"last_commit_when":  repositoryLastCommitWhen,
+}
+
+// repositoryRow returns the row of the repository with the given ID. Some
+// metadata columns need to read the whole repository, so they are only
+// computed if they are in the given columns, or if no columns are given,
+// and are NULL otherwise. Columns that can't be read are NULL too if the
+// error is skipped by the given skipper.
+func repositoryRow(
+	repo *Repository,
+	id string,
+	columns []string,
+	skipper *gitErrorSkipper,
+) (sql.Row, error) {
+	row := make(sql.Row, len(RepositoriesSchema))
+	row[0] = id
+	if repo == nil {
+		return row, nil
+	}
+
+	for i, col := range RepositoriesSchema[1:] {
+		if columns != nil && !stringContains(columns, col.Name) {
+			continue
+		}
+
+		v, err := repositoryColumns[col.Name](repo)
+		if err != nil {
+			if !skipper.skip(id, plumbing.ZeroHash, err) {
+				return nil, err
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"repo":   id,
+				"err":    err,
+				"column": col.Name,
+			}).Warn("unable to read repository metadata")
+			continue
+		}
+
+		row[i+1] = v
+	}
+
+	return row, nil
+}
+
+// repositoryLibrary returns the library with the repository, which is one
+// of the libraries of the pool library when it has several.
+func repositoryLibrary(repo *Repository) (borges.Library, error) {
+	if repo.lib == nil {
+		return nil, nil
+	}
+
+	ok, id, _, err := repo.lib.Has(borges.RepositoryID(repo.ID()))
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if id == repo.lib.ID() {
+		return repo.lib, nil
+	}
+
+	return repo.lib.Library(id)
+}
+
+func repositoryLibraryID(repo *Repository) (interface{}, error) {
+	lib, err := repositoryLibrary(repo)
+	if err != nil || lib == nil {
+		return nil, err
+	}
+
+	return lib.ID().String(), nil
+}
+
+func repositoryFormat(repo *Repository) (interface{}, error) {
+	lib, err := repositoryLibrary(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	switch lib.(type) {
+	case *plain.Library:
+		return repositoryFormatPlain, nil
+	case *siva.Library:
+		return repositoryFormatSiva, nil
+	case *legacysiva.Library:
+		return repositoryFormatLegacySiva, nil
+	default:
+		return nil, nil
+	}
+}
+
+// repositoryFS returns the filesystem of the repository and a function to
+// call when it's not used anymore.
+func repositoryFS(repo *Repository) (billy.Filesystem, func(), error) {
+	fs, err := repo.FS()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s, ok := fs.(sivafs.SivaSync); ok {
+		return fs, func() { s.Sync() }, nil
+	}
+
+	return fs, func() {}, nil
+}
+
+// repositoryPath returns the path of plain repositories. Repositories in
+// siva files have no path of their own.
+func repositoryPath(repo *Repository) (interface{}, error) {
+	fs, done, err := repositoryFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if _, ok := fs.(sivafs.SivaSync); ok || fs.Root() == "" {
+		return nil, nil
+	}
+
+	return fs.Root(), nil
+}
+
+func repositoryIsBare(repo *Repository) (interface{}, error) {
+	fs, done, err := repositoryFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if _, ok := fs.(sivafs.SivaSync); ok {
+		return true, nil
+	}
+
+	// Worktrees have a .git directory, or a file pointing to it.
+	_, err = fs.Stat(".git")
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return false, nil
+}
+
+func repositoryHead(repo *Repository) (*plumbing.Reference, error) {
+	ref, err := repo.Storer.Reference(plumbing.HEAD)
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, nil
+	}
+
+	return ref, err
+}
+
+// repositoryHeadRef returns the reference HEAD points to, or the commit
+// hash if it's detached.
+func repositoryHeadRef(repo *Repository) (interface{}, error) {
+	ref, err := repositoryHead(repo)
+	if err != nil || ref == nil {
+		return nil, err
+	}
+
+	if ref.Type() == plumbing.SymbolicReference {
+		return ref.Target().String(), nil
+	}
+
+	return ref.Hash().String(), nil
+}
+
+// repositoryDefaultBranch returns the name of the branch HEAD points to.
+func repositoryDefaultBranch(repo *Repository) (interface{}, error) {
+	ref, err := repositoryHead(repo)
+	if err != nil || ref == nil {
+		return nil, err
+	}
+
+	if ref.Type() != plumbing.SymbolicReference || !ref.Target().IsBranch() {
+		return nil, nil
+	}
+
+	return ref.Target().Short(), nil
+}
+
+func repositoryRefCount(repo *Repository) (interface{}, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	err = iter.ForEach(func(*plumbing.Reference) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return count, nil
+}
+
+func repositoryPackfileCount(repo *Repository) (interface{}, error) {
+	fs, done, err := repositoryFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	_, packfiles, err := repositoryPackfiles(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	return int64(len(packfiles)), nil
+}
+
+// repositoryObjectBytes returns the size of the packfiles and the loose
+// objects of the repository.
+func repositoryObjectBytes(repo *Repository) (interface{}, error) {
+	fs, done, err := repositoryFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	fs, err = findDotGit(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := fs.ReadDir("objects")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return int64(0), nil
+		}
+
+		return nil, err
+	}
+
+	var size int64
+	for _, dir := range dirs {
+		name := dir.Name()
+		isPack := name == "pack"
+		if !dir.IsDir() || (!isPack && (len(name) != 2 || !isHexHash(name))) {
+			continue
+		}
+
+		files, err := fs.ReadDir(fs.Join("objects", name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			if f.IsDir() || (isPack && !strings.HasSuffix(f.Name(), ".pack")) {
+				continue
+			}
+
+			size += f.Size()
+		}
+	}
+
+	return size, nil
+}
+
+// repositoryLastCommitWhen returns the newest committer date of the commits
+// the references of the repository point to.
+func repositoryLastCommitWhen(repo *Repository) (interface{}, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var last interface{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		commit, err := resolveCommit(repo, ref.Hash())
+		if errInvalidCommit.Is(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		when := commit.Committer.When
+		if last == nil || when.After(last.(time.Time)) {
+			last = when
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return last, nil
+}
@@ -93,6 +93,56 @@ func TestRepositoriesPushdown(t *testing.T) {
 	require.Len(rows, 1)
 }
 
+func TestRepositoriesMetadata(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	table := newRepositoriesTable(poolFromCtx(t, ctx))
+
+	idRow, err := repositoryRow(nil, path, nil, nil)
+	require.NoError(err)
+
+	rows, err := tableToRows(ctx, table.WithProjection([]string{"repository_id"}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal(idRow, rows[0])
+
+	var columns []string
+	for _, col := range RepositoriesSchema {
+		columns = append(columns, col.Name)
+	}
+
+	rows, err = tableToRows(ctx, table.WithProjection(columns))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.NoError(RepositoriesSchema.CheckRow(rows[0]))
+
+	// no projection means all the columns
+	allRows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Equal(rows, allRows)
+
+	row := rows[0]
+	require.Equal(path, row[0])
+	require.Equal("plain", row[1])
+	require.NotNil(row[2])
+	require.Equal(repositoryFormatPlain, row[3])
+	require.Equal(false, row[4])
+	require.Equal("refs/heads/master", row[5])
+	require.Equal("master", row[6])
+	require.NotZero(row[7])
+	require.NotZero(row[8])
+	require.NotZero(row[9])
+	require.NotNil(row[10])
+
+	rows, err = tableToRows(ctx, table.WithProjection([]string{"repository_id", "default_branch"}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("master", rows[0][6])
+	require.Nil(rows[0][7])
+}
+
 func TestRepositoriesIndexKeyValueIter(t *testing.T) {
 	require := require.New(t)
 	ctx, path, cleanup := setup(t)
@@ -101,10 +151,13 @@ func TestRepositoriesIndexKeyValueIter(t *testing.T) {
 	iter, err := new(repositoriesTable).IndexKeyValues(ctx, []string{"repository_id"})
 	require.NoError(err)
 
+	row, err := repositoryRow(nil, path, nil, nil)
+	require.NoError(err)
+
 	assertIndexKeyValueIter(t, iter,
 		[]keyValue{
 			{
-				assertEncodeRepoRow(t, sql.NewRow(path)),
+				assertEncodeRepoRow(t, row),
 				[]interface{}{path},
 			},
 		},
@@ -3,12 +3,14 @@ package gitbase
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/src-d/go-borges"
 	billy "gopkg.in/src-d/go-billy.v4"
 	errors "gopkg.in/src-d/go-errors.v1"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/object/commitgraph"
 	"gopkg.in/src-d/go-git.v4/storage/filesystem"
 )
 
@@ -27,6 +29,14 @@ type Repository struct {
 	cache cache.Object
 	repo  borges.Repository
 	lib   borges.Library
+
+	// commitNodes is loaded the first time the history is traversed, see
+	// CommitNodeIndex.
+	commitNodesOnce sync.Once
+	commitNodes     commitgraph.CommitNodeIndex
+	// commitGraphs are the commit-graph files cached by the pool of the
+	// repository, if any.
+	commitGraphs *commitGraphCache
 }
 
 func NewRepository(
@@ -71,7 +81,10 @@ func (r *Repository) Close() error {
 // RepositoryPool holds a pool git repository paths and
 // functionality to open and iterate them.
 type RepositoryPool struct {
-	cache   cache.Object
+	cache        cache.Object
+	commitGraphs *commitGraphCache
+
+	mu      sync.RWMutex
 	library borges.Library
 }
 
@@ -81,8 +94,35 @@ func NewRepositoryPool(
 	lib borges.Library,
 ) *RepositoryPool {
 	return &RepositoryPool{
-		cache:   c,
-		library: lib,
+		cache:        c,
+		commitGraphs: newCommitGraphCache(),
+		library:      lib,
+	}
+}
+
+// Library returns the library holding the repositories of the pool.
+func (p *RepositoryPool) Library() borges.Library {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.library
+}
+
+// SetLibrary replaces the library holding the repositories of the pool.
+// Iterators and repositories already open keep using the previous one, and
+// queries in flight keep using the pool returned by Session.QueryPool.
+func (p *RepositoryPool) SetLibrary(lib borges.Library) {
+	p.mu.Lock()
+	p.library = lib
+	p.mu.Unlock()
+}
+
+// pinned returns a pool with the current library of the pool and its
+// caches, which is not changed when the library of the pool is replaced.
+func (p *RepositoryPool) pinned() *RepositoryPool {
+	return &RepositoryPool{
+		cache:        p.cache,
+		commitGraphs: p.commitGraphs,
+		library:      p.Library(),
 	}
 }
 
@@ -92,8 +132,9 @@ var ErrPoolRepoNotFound = errors.NewKind("repository id %s not found in the pool
 // GetRepo returns a repository with the given id from the pool.
 func (p *RepositoryPool) GetRepo(id string) (*Repository, error) {
 	i := borges.RepositoryID(id)
+	lib := p.Library()
 
-	repo, err := p.library.Get(i, borges.ReadOnlyMode)
+	repo, err := lib.Get(i, borges.ReadOnlyMode)
 	if err != nil {
 		if borges.ErrRepositoryNotExists.Is(err) {
 			return nil, ErrPoolRepoNotFound.New(id)
@@ -102,19 +143,22 @@ func (p *RepositoryPool) GetRepo(id string) (*Repository, error) {
 		return nil, err
 	}
 
-	r := NewRepository(p.library, repo, p.cache)
+	r := NewRepository(lib, repo, p.cache)
+	r.commitGraphs = p.commitGraphs
 	return r, nil
 }
 
 // RepoIter creates a new Repository iterator
 func (p *RepositoryPool) RepoIter() (*RepositoryIter, error) {
-	it, err := p.library.Repositories(borges.ReadOnlyMode)
+	lib := p.Library()
+	it, err := lib.Repositories(borges.ReadOnlyMode)
 	if err != nil {
 		return nil, err
 	}
 
 	iter := &RepositoryIter{
 		pool: p,
+		lib:  lib,
 		iter: it,
 	}
 
@@ -124,6 +168,7 @@ func (p *RepositoryPool) RepoIter() (*RepositoryIter, error) {
 // RepositoryIter iterates over all repositories in the pool
 type RepositoryIter struct {
 	pool *RepositoryPool
+	lib  borges.Library
 	iter borges.RepositoryIterator
 }
 
@@ -135,7 +180,8 @@ func (i *RepositoryIter) Next() (*Repository, error) {
 		return nil, err
 	}
 
-	r := NewRepository(i.pool.library, repo, i.pool.cache)
+	r := NewRepository(i.lib, repo, i.pool.cache)
+	r.commitGraphs = i.pool.commitGraphs
 	return r, nil
 }
 
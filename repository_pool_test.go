@@ -1,6 +1,7 @@
 package gitbase
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	fixtures "github.com/src-d/go-git-fixtures"
+	"github.com/src-d/go-mysql-server/sql"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
@@ -77,7 +79,7 @@ func TestRepositoryPoolGit(t *testing.T) {
 	require.NotNil(repo)
 	require.NoError(err)
 
-	iter, err := newCommitIter(repo, false)
+	iter, err := newCommitIter(repo, nil)
 	require.NoError(err)
 
 	count := 0
@@ -180,7 +182,7 @@ func TestRepositoryPoolSiva(t *testing.T) {
 		}
 		require.NoError(err)
 
-		iter, err := newCommitIter(repo, false)
+		iter, err := newCommitIter(repo, nil)
 		require.NoError(err)
 
 		id := repo.ID()
@@ -196,3 +198,74 @@ func TestRepositoryPoolSiva(t *testing.T) {
 	require.Equal(expectedRepos, i)
 	require.Equal(expected, result)
 }
+
+func TestRepositoryPoolSetLibrary(t *testing.T) {
+	require := require.New(t)
+
+	path := fixtures.Basic().ByTag("worktree").One().Worktree().Root()
+
+	lib1, err := newMultiLibrary()
+	require.NoError(err)
+	require.NoError(lib1.AddPlain("1", path, nil))
+
+	lib2, err := newMultiLibrary()
+	require.NoError(err)
+	require.NoError(lib2.AddPlain("2", path, nil))
+
+	pool := NewRepositoryPool(cache.NewObjectLRUDefault(), lib1)
+	require.Equal(lib1, pool.Library())
+
+	iter, err := pool.RepoIter()
+	require.NoError(err)
+
+	pool.SetLibrary(lib2)
+	require.Equal(lib2, pool.Library())
+
+	repo, err := iter.Next()
+	require.NoError(err)
+	require.Equal("1", repo.ID())
+
+	_, err = iter.Next()
+	require.Equal(io.EOF, err)
+
+	_, err = pool.GetRepo("1")
+	require.True(ErrPoolRepoNotFound.Is(err))
+
+	repo, err = pool.GetRepo("2")
+	require.NoError(err)
+	require.Equal("2", repo.ID())
+}
+
+func TestSessionQueryPool(t *testing.T) {
+	require := require.New(t)
+
+	path := fixtures.Basic().ByTag("worktree").One().Worktree().Root()
+
+	lib1, err := newMultiLibrary()
+	require.NoError(err)
+	require.NoError(lib1.AddPlain("1", path, nil))
+
+	lib2, err := newMultiLibrary()
+	require.NoError(err)
+	require.NoError(lib2.AddPlain("2", path, nil))
+
+	session := NewSession(NewRepositoryPool(cache.NewObjectLRUDefault(), lib1))
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session), sql.WithPid(1))
+
+	pool := session.QueryPool(ctx)
+	session.Pool.SetLibrary(lib2)
+
+	// The query keeps using the library it started with.
+	require.Equal(pool, session.QueryPool(ctx))
+	repo, err := session.QueryPool(ctx).GetRepo("1")
+	require.NoError(err)
+	require.Equal("1", repo.ID())
+
+	ctx = sql.NewContext(context.TODO(), sql.WithSession(session), sql.WithPid(2))
+	_, err = session.QueryPool(ctx).GetRepo("1")
+	require.True(ErrPoolRepoNotFound.Is(err))
+
+	repo, err = session.QueryPool(ctx).GetRepo("2")
+	require.NoError(err)
+	require.Equal("2", repo.ID())
+}
@@ -0,0 +1,451 @@
+package gitbase
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	errors "gopkg.in/src-d/go-errors.v1"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/idxfile"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+var (
+	// ErrUnsupportedRevision is returned when a revision uses a syntax
+	// that cannot be resolved to a commit.
+	ErrUnsupportedRevision = errors.NewKind("unsupported revision %q: %s")
+	// ErrAmbiguousRevision is returned when an abbreviated hash matches
+	// more than one commit.
+	ErrAmbiguousRevision = errors.NewKind("abbreviated hash %q is ambiguous")
+
+	errNoUpstream = errors.NewKind("branch %s has no upstream")
+)
+
+var (
+	shortHashRegexp = regexp.MustCompile(`^[0-9a-fA-F]{4,39}$`)
+	upstreamRegexp  = regexp.MustCompile(`(?i)^@\{(upstream|u)\}`)
+	// caretRegRegexp matches ^{/regexp}, whose content may have any
+	// character, and caretTypeRegexp ^{} and ^{type}.
+	caretRegRegexp  = regexp.MustCompile(`\^\{/[^}]*\}`)
+	caretTypeRegexp = regexp.MustCompile(`\^\{([^}]*)\}`)
+)
+
+// ResolveRevision returns the hash of the commit the revision points to in
+// the repository. Besides the revisions go-git resolves, which are
+// references, full hashes, ~n, ^n and ^{/regexp}, it resolves abbreviated
+// hashes, @{upstream} and ^{commit}. Revisions selecting paths, the reflog
+// or objects other than commits return ErrUnsupportedRevision.
+func ResolveRevision(repo *Repository, rev string) (plumbing.Hash, error) {
+	base, suffix := splitRevision(rev)
+	if strings.HasPrefix(suffix, "@{") {
+		m := upstreamRegexp.FindString(suffix)
+		if m == "" {
+			return plumbing.ZeroHash, ErrUnsupportedRevision.New(rev,
+				"the reflog is not available")
+		}
+
+		upstream, err := upstreamReference(repo, base)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		base, suffix = upstream.String(), suffix[len(m):]
+	}
+
+	if err := checkRevisionSuffix(rev, suffix); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	switch {
+	case base == "":
+		return plumbing.ZeroHash, ErrUnsupportedRevision.New(rev,
+			"it must start with a reference or hash")
+	case base == "@":
+		base = "HEAD"
+	case shortHashRegexp.MatchString(base):
+		// References take precedence over abbreviated hashes, as in git.
+		if _, err := resolveShortReference(repo, base); err != nil {
+			if err != plumbing.ErrReferenceNotFound {
+				return plumbing.ZeroHash, err
+			}
+
+			hash, err := expandShortHash(repo, base)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+
+			base = hash.String()
+		}
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(base + suffix))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return *hash, nil
+}
+
+// splitRevision splits the revision in the reference or hash and the
+// rest of the revision. Reference names cannot have the characters used
+// after them.
+func splitRevision(rev string) (string, string) {
+	idx := strings.IndexAny(rev, "~^:")
+	if at := strings.Index(rev, "@{"); at >= 0 && (idx < 0 || at < idx) {
+		idx = at
+	}
+
+	if idx < 0 {
+		return rev, ""
+	}
+
+	return rev[:idx], rev[idx:]
+}
+
+// checkRevisionSuffix returns an error if the part of the revision after
+// the reference has syntax go-git does not resolve. It would be ignored
+// otherwise, giving a wrong commit.
+func checkRevisionSuffix(rev, suffix string) error {
+	suffix = caretRegRegexp.ReplaceAllString(suffix, "")
+	for _, m := range caretTypeRegexp.FindAllStringSubmatch(suffix, -1) {
+		if m[1] != "" && m[1] != "commit" {
+			return ErrUnsupportedRevision.New(rev, "only commits can be resolved")
+		}
+	}
+
+	suffix = caretTypeRegexp.ReplaceAllString(suffix, "")
+	if strings.Contains(suffix, ":") {
+		return ErrUnsupportedRevision.New(rev, "paths cannot be resolved")
+	}
+
+	if strings.Contains(suffix, "@{") {
+		return ErrUnsupportedRevision.New(rev, "the reflog is not available")
+	}
+
+	return nil
+}
+
+// upstreamReference returns the reference of the upstream of the given
+// branch, or of the one checked out if it's empty.
+func upstreamReference(repo *Repository, branch string) (plumbing.ReferenceName, error) {
+	name := plumbing.ReferenceName(branch)
+	if branch == "" || branch == "HEAD" || branch == "@" {
+		head, err := repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return "", err
+		}
+
+		if head.Type() != plumbing.SymbolicReference {
+			return "", errNoUpstream.New("HEAD")
+		}
+
+		name = head.Target()
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+
+	short := strings.TrimPrefix(name.String(), "refs/heads/")
+	b, ok := cfg.Branches[short]
+	if !ok || b.Merge == "" {
+		return "", errNoUpstream.New(short)
+	}
+
+	if b.Remote == "" || b.Remote == "." {
+		return b.Merge, nil
+	}
+
+	return plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short()), nil
+}
+
+// resolveShortReference resolves the reference with the given name using
+// the same rules as git.
+func resolveShortReference(repo *Repository, name string) (*plumbing.Reference, error) {
+	for _, rule := range append([]string{"%s"}, plumbing.RefRevParseRules...) {
+		refName := plumbing.ReferenceName(fmt.Sprintf(rule, name))
+		ref, err := storer.ResolveReference(repo.Storer, refName)
+		if err == nil {
+			return ref, nil
+		}
+
+		if err != plumbing.ErrReferenceNotFound {
+			return nil, err
+		}
+	}
+
+	return nil, plumbing.ErrReferenceNotFound
+}
+
+// expandShortHash returns the commit or tag whose hash starts with the
+// given prefix. Hashes are read from the packfile indexes and loose objects
+// so objects are not decoded, and only the ones sharing the first byte of
+// the prefix are checked.
+func expandShortHash(repo *Repository, prefix string) (plumbing.Hash, error) {
+	prefix = strings.ToLower(prefix)
+
+	first, err := strconv.ParseUint(prefix[:2], 16, 8)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	idx, err := newRepositoryIndex(repo)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer idx.Close()
+
+	var hashes []plumbing.Hash
+	for _, p := range idx.indexes {
+		hashes = append(hashes, indexHashesWithPrefix(p.idx, byte(first), prefix)...)
+	}
+
+	loose, err := idx.dir.Objects()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	for _, h := range loose {
+		if strings.HasPrefix(h.String(), prefix) {
+			hashes = append(hashes, h)
+		}
+	}
+
+	// Other objects with the same prefix are ignored, as git does when a
+	// commit is expected.
+	var found plumbing.Hash
+	seen := make(map[plumbing.Hash]struct{})
+	for _, h := range hashes {
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+
+		obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, h)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		if obj.Type() != plumbing.CommitObject && obj.Type() != plumbing.TagObject {
+			continue
+		}
+
+		if !found.IsZero() {
+			return plumbing.ZeroHash, ErrAmbiguousRevision.New(prefix)
+		}
+
+		found = h
+	}
+
+	if found.IsZero() {
+		return plumbing.ZeroHash, plumbing.ErrReferenceNotFound
+	}
+
+	return found, nil
+}
+
+// indexHashesWithPrefix returns the hashes in the packfile index starting
+// with the given lowercase hexadecimal prefix, whose first byte is given
+// too. Only the hashes with the same first byte are read from the index,
+// and as they are sorted, the search stops after the last one with the
+// prefix.
+func indexHashesWithPrefix(
+	idx *idxfile.MemoryIndex,
+	first byte,
+	prefix string,
+) []plumbing.Hash {
+	k := idx.FanoutMapping[first]
+	if k < 0 || k >= len(idx.Names) {
+		return nil
+	}
+
+	var result []plumbing.Hash
+	names := idx.Names[k]
+	for i := 0; i+len(plumbing.ZeroHash) <= len(names); i += len(plumbing.ZeroHash) {
+		var h plumbing.Hash
+		copy(h[:], names[i:])
+
+		s := h.String()
+		if strings.HasPrefix(s, prefix) {
+			result = append(result, h)
+		} else if s > prefix {
+			break
+		}
+	}
+
+	return result
+}
+
+// RevisionExpression is an expression resolving a revision to the hash of
+// a commit, as the rev_parse function does. In the filters of the commits
+// table, they are resolved once for each repository instead of for each
+// row when their repository is the one of the row and the revision a
+// literal.
+type RevisionExpression interface {
+	sql.Expression
+	// RevisionArguments returns the expressions of the repository id and
+	// the revision.
+	RevisionArguments() (repository, revision sql.Expression)
+}
+
+// resolveRevisionFilters replaces the revision expressions of the filters
+// that can be resolved in the repository, that is, the ones with a literal
+// revision in the repository of the row, with the hash of their commit, or
+// NULL if the revision does not exist, as they would be evaluated for every
+// row. AND expressions are split in several filters, and NULL values are
+// removed from IN expressions. It returns false if a filter compares with
+// NULL, as no row can match.
+func resolveRevisionFilters(
+	repo *Repository,
+	filters []sql.Expression,
+) ([]sql.Expression, bool) {
+	var result = make([]sql.Expression, 0, len(filters))
+	for _, f := range filters {
+		resolved, err := expression.TransformUp(f, func(e sql.Expression) (sql.Expression, error) {
+			re, ok := e.(RevisionExpression)
+			if !ok {
+				return e, nil
+			}
+
+			rev, ok := literalRevision(re)
+			if !ok {
+				return e, nil
+			}
+
+			hash, err := ResolveRevision(repo, rev)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"repo":     repo.ID(),
+					"revision": rev,
+					"error":    err,
+				}).Debug("unable to resolve revision of filter")
+				return expression.NewLiteral(nil, sql.Text), nil
+			}
+
+			return expression.NewLiteral(hash.String(), sql.Text), nil
+		})
+		if err != nil {
+			result = append(result, f)
+			continue
+		}
+
+		for _, e := range splitAnd(resolved) {
+			e, ok := withoutNullComparisons(e)
+			if !ok {
+				return nil, false
+			}
+
+			result = append(result, e)
+		}
+	}
+
+	return result, true
+}
+
+// resolveRevisionFilter resolves the revisions of the filter of a squashed
+// iterator as resolveRevisionFilters does. The filter may be nil.
+func resolveRevisionFilter(
+	repo *Repository,
+	filter sql.Expression,
+) (sql.Expression, bool) {
+	if filter == nil {
+		return nil, true
+	}
+
+	filters, ok := resolveRevisionFilters(repo, []sql.Expression{filter})
+	if !ok {
+		return nil, false
+	}
+
+	return expression.JoinAnd(filters...), true
+}
+
+// splitAnd returns the expressions joined by the AND expressions of e.
+func splitAnd(e sql.Expression) []sql.Expression {
+	and, ok := e.(*expression.And)
+	if !ok {
+		return []sql.Expression{e}
+	}
+
+	return append(splitAnd(and.Left), splitAnd(and.Right)...)
+}
+
+// withoutNullComparisons returns the filter without the NULL values of an
+// IN expression, which can't match, or false if the filter is an equality
+// with NULL or an IN expression with only NULL values. As filters are only
+// used to select rows, a NULL result is the same as false.
+func withoutNullComparisons(e sql.Expression) (sql.Expression, bool) {
+	switch e := e.(type) {
+	case *expression.Equals:
+		return e, !isNullLiteral(e.Left()) && !isNullLiteral(e.Right())
+	case *expression.In:
+		tuple, ok := e.Right().(expression.Tuple)
+		if !ok {
+			return e, true
+		}
+
+		var values []sql.Expression
+		for _, v := range tuple {
+			if !isNullLiteral(v) {
+				values = append(values, v)
+			}
+		}
+
+		if len(values) == 0 {
+			return nil, false
+		}
+
+		if len(values) == len(tuple) {
+			return e, true
+		}
+
+		return expression.NewIn(e.Left(), expression.NewTuple(values...)), true
+	default:
+		return e, true
+	}
+}
+
+func isNullLiteral(e sql.Expression) bool {
+	lit, ok := e.(*expression.Literal)
+	if !ok {
+		return false
+	}
+
+	v, err := lit.Eval(nil, nil)
+	return err == nil && v == nil
+}
+
+// literalRevision returns the revision of a revision expression in the
+// repository of the row with a literal revision. All the rows read at once
+// are of the same repository, so its repository_id column may be the one of
+// any table.
+func literalRevision(re RevisionExpression) (string, bool) {
+	repo, rev := re.RevisionArguments()
+	repoField, ok := repo.(*expression.GetField)
+	if !ok || repoField.Name() != "repository_id" {
+		return "", false
+	}
+
+	lit, ok := rev.(*expression.Literal)
+	if !ok {
+		return "", false
+	}
+
+	v, err := lit.Eval(nil, nil)
+	if err != nil || v == nil {
+		return "", false
+	}
+
+	v, err = sql.Text.Convert(v)
+	if err != nil {
+		return "", false
+	}
+
+	return v.(string), true
+}
@@ -0,0 +1,237 @@
+package gitbase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestResolveRevision(t *testing.T) {
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	repo, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	testCases := []struct {
+		rev      string
+		expected string
+		err      bool
+	}{
+		{"HEAD", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", false},
+		{"@", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", false},
+		{"master", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", false},
+		{"origin/branch", "e8d3ffab552895c19b9fcf7aa264d277cde33881", false},
+		{"HEAD~3", "1669dce138d9b841a518c64b10914d88f5e488ea", false},
+		{"@~1", "918c48b83bd081e863dbe1b80f8998f058cd8294", false},
+		{"1669dce^2", "a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69", false},
+		{"HEAD^{commit}", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", false},
+		{"6ECF0EF2", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", false},
+		{"6ecf0ef2c2dffb796033e5a02219af86ec6584e5~2", "af2d6a6954d532f8ffb47615169c8fdf9d383a1a", false},
+		{"HEAD^{tree}", "", true},
+		{"HEAD:CHANGELOG", "", true},
+		{"HEAD@{1}", "", true},
+		{":/vendor", "", true},
+		{"foo", "", true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.rev, func(t *testing.T) {
+			hash, err := ResolveRevision(repo, tt.rev)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, hash.String())
+		})
+	}
+}
+
+func TestResolveRevisionUpstream(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	repo, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(err)
+	defer repo.Close()
+
+	cfg, err := repo.Config()
+	require.NoError(err)
+
+	cfg.Branches["master"] = &config.Branch{
+		Name:   "master",
+		Remote: "origin",
+		Merge:  plumbing.NewBranchReferenceName("branch"),
+	}
+	require.NoError(repo.Storer.SetConfig(cfg))
+
+	for _, rev := range []string{"master@{upstream}", "HEAD@{u}", "@{U}"} {
+		hash, err := ResolveRevision(repo, rev)
+		require.NoError(err)
+		require.Equal("e8d3ffab552895c19b9fcf7aa264d277cde33881", hash.String())
+	}
+
+	hash, err := ResolveRevision(repo, "master@{upstream}~1")
+	require.NoError(err)
+	require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", hash.String())
+}
+
+// testRevision is a revision expression that fails when it's evaluated, so
+// it's only valid in filters resolved for each repository.
+type testRevision struct {
+	repository sql.Expression
+	revision   sql.Expression
+}
+
+var _ RevisionExpression = (*testRevision)(nil)
+
+func (r *testRevision) RevisionArguments() (sql.Expression, sql.Expression) {
+	return r.repository, r.revision
+}
+
+func (r *testRevision) Resolved() bool   { return true }
+func (r *testRevision) IsNullable() bool { return true }
+func (r *testRevision) Type() sql.Type   { return sql.Text }
+func (r *testRevision) String() string   { return "test_revision" }
+func (r *testRevision) Children() []sql.Expression {
+	return []sql.Expression{r.repository, r.revision}
+}
+
+func (r *testRevision) Eval(*sql.Context, sql.Row) (interface{}, error) {
+	return nil, fmt.Errorf("revision evaluated for a row")
+}
+
+func (r *testRevision) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	return &testRevision{children[0], children[1]}, nil
+}
+
+func TestCommitsRevisionPushdown(t *testing.T) {
+	require := require.New(t)
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newCommitsTable(poolFromCtx(t, ctx))
+	revision := func(rev string) sql.Expression {
+		return expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitsTableName, "commit_hash", false),
+			&testRevision{
+				expression.NewGetFieldWithTable(0, sql.Text, CommitsTableName, "repository_id", false),
+				expression.NewLiteral(rev, sql.Text),
+			},
+		)
+	}
+
+	rows, err := tableToRows(ctx, table.WithFilters([]sql.Expression{
+		revision("HEAD~1"),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", rows[0][1])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		revision("refs/heads/missing"),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewAnd(
+			revision("HEAD~1"),
+			expression.NewEquals(
+				expression.NewGetFieldWithTable(6, sql.Text, CommitsTableName, "committer_email", false),
+				expression.NewLiteral("mcuadros@gmail.com", sql.Text),
+			),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", rows[0][1])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewAnd(
+			revision("HEAD~1"),
+			revision("refs/heads/missing"),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+
+	in := func(revs ...string) sql.Expression {
+		var values []sql.Expression
+		for _, rev := range revs {
+			values = append(values, &testRevision{
+				expression.NewGetFieldWithTable(0, sql.Text, CommitsTableName, "repository_id", false),
+				expression.NewLiteral(rev, sql.Text),
+			})
+		}
+
+		return expression.NewIn(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitsTableName, "commit_hash", false),
+			expression.NewTuple(values...),
+		)
+	}
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		in("HEAD", "HEAD~1", "refs/heads/missing"),
+	}))
+	require.NoError(err)
+	require.Len(rows, 2)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		in("refs/heads/missing"),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestSquashCommitsRevisionFilters(t *testing.T) {
+	require := require.New(t)
+	ctx, cleanup := setupIter(t)
+	defer cleanup()
+
+	revision := func(rev string) sql.Expression {
+		return expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, CommitsTableName, "commit_hash", false),
+			&testRevision{
+				expression.NewGetFieldWithTable(0, sql.Text, CommitsTableName, "repository_id", false),
+				expression.NewLiteral(rev, sql.Text),
+			},
+		)
+	}
+
+	rows := chainableIterRows(t, ctx, NewAllCommitsIter(revision("HEAD~1"), false))
+	require.NotEmpty(rows)
+	for _, row := range rows {
+		repo, err := poolFromCtx(t, ctx).GetRepo(row[0].(string))
+		require.NoError(err)
+
+		hash, err := ResolveRevision(repo, "HEAD~1")
+		repo.Close()
+		require.NoError(err)
+		require.Equal(hash.String(), row[1])
+	}
+
+	rows = chainableIterRows(t, ctx, NewAllCommitsIter(revision("refs/heads/missing"), false))
+	require.Len(rows, 0)
+
+	rows = chainableIterRows(t, ctx, NewRepoCommitsIter(
+		NewAllReposIter(nil, []string{"repository_id"}),
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(len(RepositoriesSchema)+1, sql.Text, CommitsTableName, "commit_hash", false),
+			&testRevision{
+				expression.NewGetFieldWithTable(0, sql.Text, RepositoriesTableName, "repository_id", false),
+				expression.NewLiteral("refs/heads/missing", sql.Text),
+			},
+		),
+	))
+	require.Len(rows, 0)
+}
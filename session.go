@@ -1,17 +1,22 @@
 package gitbase
 
 import (
+	"container/list"
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	bblfsh "github.com/bblfsh/go-client/v4"
 	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/mailmap"
+	"github.com/src-d/gitbase/internal/signature"
 	"github.com/src-d/go-mysql-server/server"
 	"github.com/src-d/go-mysql-server/sql"
 	"google.golang.org/grpc/connectivity"
 	errors "gopkg.in/src-d/go-errors.v1"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"vitess.io/vitess/go/mysql"
 )
 
@@ -25,8 +30,81 @@ type Session struct {
 	bblfshClient   *BblfshClient
 
 	SkipGitErrors bool
+
+	mailmapFile     string
+	mailmapFileOnce sync.Once
+	mailmapFileMap  *mailmap.Mailmap
+	mailmapFileErr  error
+	mailmapsMu      sync.Mutex
+	mailmaps        map[string]*mailmap.Mailmap
+
+	keyringsDir string
+	keyringsMu  sync.Mutex
+	keyrings    map[string]*signature.Keyring
+
+	queryPoolMu sync.Mutex
+	queryPoolID uint64
+	queryPool   *RepositoryPool
+
+	reachableMu  sync.Mutex
+	reachable    map[string]*list.Element
+	reachableLRU *list.List
+
+	gitErrorsMu sync.Mutex
+	gitErrors   []GitError
+	warnedQuery uint64
+	warnings    int
 }
 
+// QueryPool returns the pool with the repositories used by the query of the
+// context. The library of the pool is pinned the first time the query asks
+// for it, so queries in flight keep reading the same repositories when the
+// library of the session pool is replaced. Contexts without a query id get
+// the session pool.
+func (s *Session) QueryPool(ctx *sql.Context) *RepositoryPool {
+	if ctx.Pid() == 0 {
+		return s.Pool
+	}
+
+	s.queryPoolMu.Lock()
+	defer s.queryPoolMu.Unlock()
+
+	if s.queryPool == nil || s.queryPoolID != ctx.Pid() {
+		s.queryPoolID = ctx.Pid()
+		s.queryPool = s.Pool.pinned()
+	}
+
+	return s.queryPool
+}
+
+// GitError is a git error skipped because the session has SkipGitErrors
+// enabled.
+type GitError struct {
+	// QueryID is the id of the query where the error was found.
+	QueryID uint64
+	// RepositoryID is the repository with the error. It's empty if the
+	// repository could not be opened.
+	RepositoryID string
+	// Table is the table being read when the error was found. It's empty if
+	// the error was found listing the repositories.
+	Table string
+	// Hash is the hash of the object with the error, if known.
+	Hash plumbing.Hash
+	// Error is the text of the error.
+	Error string
+	// Time is when the error was found.
+	Time time.Time
+}
+
+const (
+	// maxGitErrors is the maximum number of skipped git errors kept in a
+	// session. The oldest ones are dropped when there are more.
+	maxGitErrors = 10000
+	// maxGitErrorWarnings is the maximum number of warnings added for the
+	// skipped git errors of a query, the default max_error_count of MySQL.
+	maxGitErrorWarnings = 64
+)
+
 // getSession returns the gitbase session from a context or an error if there
 // is no session or is not of the matching type inside the context.
 func getSession(ctx *sql.Context) (*Session, error) {
@@ -64,6 +142,22 @@ func WithSkipGitErrors(enabled bool) SessionOption {
 	}
 }
 
+// WithMailmapFile configures a mailmap file used for all repositories,
+// whose entries take precedence over the ones of their .mailmap files.
+func WithMailmapFile(path string) SessionOption {
+	return func(s *Session) {
+		s.mailmapFile = path
+	}
+}
+
+// WithKeyringsDir configures the directory with the keyrings used to
+// verify the signatures of commits and tags.
+func WithKeyringsDir(dir string) SessionOption {
+	return func(s *Session) {
+		s.keyringsDir = dir
+	}
+}
+
 // WithBaseSession sets the given session as the base session.
 func WithBaseSession(sess sql.Session) SessionOption {
 	return func(s *Session) {
@@ -255,10 +349,105 @@ var ErrInvalidContext = errors.NewKind("invalid context received: %v")
 // ErrBblfshConnection is returned when it's impossible to connect to bblfsh.
 var ErrBblfshConnection = errors.NewKind("unable to establish a connection with the bblfsh server: %s")
 
-func shouldSkipErrors(ctx *sql.Context) bool {
+// GitErrors returns the git errors skipped by the queries of the session,
+// from the oldest to the newest.
+func (s *Session) GitErrors() []GitError {
+	s.gitErrorsMu.Lock()
+	defer s.gitErrorsMu.Unlock()
+
+	var errs = make([]GitError, len(s.gitErrors))
+	copy(errs, s.gitErrors)
+	return errs
+}
+
+// addGitError records a skipped git error and adds a warning for it to the
+// query.
+func (s *Session) addGitError(e GitError) {
+	s.gitErrorsMu.Lock()
+	defer s.gitErrorsMu.Unlock()
+
+	if len(s.gitErrors) >= maxGitErrors {
+		s.gitErrors = s.gitErrors[1:]
+	}
+	s.gitErrors = append(s.gitErrors, e)
+
+	if e.QueryID != s.warnedQuery {
+		s.warnedQuery = e.QueryID
+		s.warnings = 0
+	}
+
+	if s.warnings >= maxGitErrorWarnings {
+		return
+	}
+	s.warnings++
+
+	msg := fmt.Sprintf("skipped git error in repository %q: %s",
+		e.RepositoryID, e.Error)
+	if e.Table != "" {
+		msg = fmt.Sprintf("skipped git error reading table %s in repository %q: %s",
+			e.Table, e.RepositoryID, e.Error)
+	}
+
+	s.Warn(&sql.Warning{
+		Level:   "Warning",
+		Code:    mysql.ERUnknownError,
+		Message: msg,
+	})
+}
+
+// gitErrorSkipper decides whether the git errors found reading a table are
+// skipped, which happens when the session has SkipGitErrors enabled, and
+// records the skipped ones in the session. A nil skipper never skips.
+type gitErrorSkipper struct {
+	session *Session
+	queryID uint64
+	table   string
+}
+
+func newGitErrorSkipper(ctx *sql.Context, table string) *gitErrorSkipper {
 	s, err := getSession(ctx)
 	if err != nil {
+		return nil
+	}
+
+	return s.gitErrorSkipper(ctx, table)
+}
+
+// gitErrorSkipper returns the skipper for the errors found reading the given
+// table in the query of the context.
+func (s *Session) gitErrorSkipper(ctx *sql.Context, table string) *gitErrorSkipper {
+	if !s.SkipGitErrors {
+		return nil
+	}
+
+	return &gitErrorSkipper{session: s, queryID: ctx.Pid(), table: table}
+}
+
+// repositoryID returns the id of the repository, or an empty string if there
+// is no repository.
+func repositoryID(r *Repository) string {
+	if r == nil {
+		return ""
+	}
+	return r.ID()
+}
+
+// skip returns whether the error found in the given repository must be
+// skipped and records it if so. hash is the object with the error, or the
+// zero hash if it's not known.
+func (s *gitErrorSkipper) skip(repoID string, hash plumbing.Hash, err error) bool {
+	if s == nil {
 		return false
 	}
-	return s.SkipGitErrors
+
+	s.session.addGitError(GitError{
+		QueryID:      s.queryID,
+		RepositoryID: repoID,
+		Table:        s.table,
+		Hash:         hash,
+		Error:        err.Error(),
+		Time:         time.Now(),
+	})
+
+	return true
 }
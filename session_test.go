@@ -2,10 +2,13 @@ package gitbase
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/src-d/go-mysql-server/sql"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/connectivity"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 func TestSessionBblfshClient(t *testing.T) {
@@ -39,3 +42,37 @@ func TestSessionBblfshClientNoConnection(t *testing.T) {
 	require.Error(err)
 	require.True(ErrBblfshConnection.Is(err))
 }
+
+func TestSessionGitErrors(t *testing.T) {
+	require := require.New(t)
+
+	session := NewSession(nil, WithSkipGitErrors(true))
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session), sql.WithPid(1))
+
+	skipper := newGitErrorSkipper(ctx, CommitsTableName)
+	require.NotNil(skipper)
+
+	hash := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	for i := 0; i < maxGitErrors+1; i++ {
+		require.True(skipper.skip("repo", hash, fmt.Errorf("error %d", i)))
+	}
+
+	errs := session.GitErrors()
+	require.Len(errs, maxGitErrors)
+	require.Equal("error 1", errs[0].Error)
+	require.Equal(GitError{
+		QueryID:      1,
+		RepositoryID: "repo",
+		Table:        CommitsTableName,
+		Hash:         hash,
+		Error:        fmt.Sprintf("error %d", maxGitErrors),
+		Time:         errs[maxGitErrors-1].Time,
+	}, errs[maxGitErrors-1])
+
+	require.Len(session.Warnings(), maxGitErrorWarnings)
+
+	session.SkipGitErrors = false
+	require.Nil(newGitErrorSkipper(ctx, CommitsTableName))
+	require.False(newGitErrorSkipper(ctx, CommitsTableName).
+		skip("repo", hash, fmt.Errorf("error")))
+}
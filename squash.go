@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 // SquashedTable is a table that combines the output of some tables as the
@@ -65,15 +66,11 @@ func (t *SquashedTable) Schema() sql.Schema {
 func (t *SquashedTable) PartitionRows(ctx *sql.Context, p sql.Partition) (sql.RowIter, error) {
 	span, ctx := ctx.Span("gitbase.SquashedTable")
 
-	session, err := getSession(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	repo, err := getPartitionRepo(ctx, p)
 	if err != nil {
 		span.Finish()
-		if session.SkipGitErrors {
+		skipper := newGitErrorSkipper(ctx, t.tables[0])
+		if skipper.skip(partitionRepoID(p), plumbing.ZeroHash, err) {
 			return noRows, nil
 		}
 
@@ -41,18 +41,21 @@ type ReposIter interface {
 }
 
 type squashReposIter struct {
-	ctx           *sql.Context
-	filters       sql.Expression
-	done          bool
-	repo          *Repository
-	row           sql.Row
-	skipGitErrors bool
+	ctx     *sql.Context
+	filters sql.Expression
+	columns []string
+	done    bool
+	repo    *Repository
+	row     sql.Row
+	skipper *gitErrorSkipper
 }
 
 // NewAllReposIter returns an iterator that will return all repositories
-// that match the given filters.
-func NewAllReposIter(filters sql.Expression) ReposIter {
-	return &squashReposIter{filters: filters}
+// that match the given filters. Only the metadata columns of the
+// repositories in the given columns are computed, or all of them if
+// columns is nil.
+func NewAllReposIter(filters sql.Expression, columns []string) ReposIter {
+	return &squashReposIter{filters: filters, columns: columns}
 }
 
 func (i *squashReposIter) Repo() *Repository { return i.repo }
@@ -70,10 +73,11 @@ func (i *squashReposIter) New(ctx *sql.Context, repo *Repository) (ChainableIter
 	}
 
 	return &squashReposIter{
-		ctx:           ctx,
-		filters:       i.filters,
-		repo:          repo,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		filters: i.filters,
+		columns: i.columns,
+		repo:    repo,
+		skipper: session.gitErrorSkipper(ctx, RepositoriesTableName),
 	}, nil
 }
 func (i *squashReposIter) Repository() *Repository { return i.repo }
@@ -92,7 +96,12 @@ func (i *squashReposIter) Advance() error {
 
 		i.done = true
 
-		i.row = sql.NewRow(i.repo.ID())
+		row, err := repositoryRow(i.repo, i.repo.ID(), i.columns, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.row = row
 		if i.filters != nil {
 			ok, err := evalFilters(i.ctx, i.row, i.filters)
 			if err != nil {
@@ -136,7 +145,7 @@ type squashRemoteIter struct {
 	remotes           []*git.Remote
 	remote            *Remote
 	row               sql.Row
-	skipGitErrors     bool
+	skipper           *gitErrorSkipper
 }
 
 // NewAllRemotesIter returns an iterator that will return all remotes
@@ -159,19 +168,20 @@ func (i *squashRemoteIter) New(ctx *sql.Context, repo *Repository) (ChainableIte
 		return nil, err
 	}
 
+	skipper := session.gitErrorSkipper(ctx, RemotesTableName)
 	remotes, err := repo.Remotes()
 	if err != nil {
-		if !i.skipGitErrors {
+		if !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 			return nil, err
 		}
 	}
 
 	return &squashRemoteIter{
-		ctx:           ctx,
-		filters:       i.filters,
-		repo:          repo,
-		remotes:       remotes,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		filters: i.filters,
+		repo:    repo,
+		remotes: remotes,
+		skipper: skipper,
 	}, nil
 }
 func (i *squashRemoteIter) Repository() *Repository { return i.repo }
@@ -243,7 +253,7 @@ type squashRepoRemotesIter struct {
 	remotes           []*git.Remote
 	remote            *Remote
 	row               sql.Row
-	skipGitErrors     bool
+	skipper           *gitErrorSkipper
 }
 
 // NewRepoRemotesIter returns an iterator that will return all remotes for the
@@ -272,10 +282,10 @@ func (i *squashRepoRemotesIter) New(ctx *sql.Context, repo *Repository) (Chainab
 	}
 
 	return &squashRepoRemotesIter{
-		ctx:           ctx,
-		repos:         iter.(ReposIter),
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repos:   iter.(ReposIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, RemotesTableName),
 	}, nil
 }
 func (i *squashRepoRemotesIter) Row() sql.Row { return i.row }
@@ -295,7 +305,7 @@ func (i *squashRepoRemotesIter) Advance() error {
 					"error": err,
 				}).Error("unable to retrieve repository remotes")
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repos.Repository().ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -378,16 +388,16 @@ type RefsIter interface {
 }
 
 type squashRefIter struct {
-	ctx           *sql.Context
-	repo          *Repository
-	repos         *RepositoryIter
-	filters       sql.Expression
-	refs          storer.ReferenceIter
-	head          *plumbing.Reference
-	ref           *Ref
-	row           sql.Row
-	virtual       bool
-	skipGitErrors bool
+	ctx     *sql.Context
+	repo    *Repository
+	repos   *RepositoryIter
+	filters sql.Expression
+	refs    storer.ReferenceIter
+	head    *plumbing.Reference
+	ref     *Ref
+	row     sql.Row
+	virtual bool
+	skipper *gitErrorSkipper
 }
 
 // NewAllRefsIter returns an iterator that will return all references
@@ -416,25 +426,26 @@ func (i *squashRefIter) New(ctx *sql.Context, repo *Repository) (ChainableIter,
 		return nil, err
 	}
 
+	skipper := session.gitErrorSkipper(ctx, ReferencesTableName)
 	refs, err := repo.References()
-	if err != nil && !i.skipGitErrors {
+	if err != nil && !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 		return nil, err
 	}
 
 	head, err := repo.Head()
-	if err != nil && !i.skipGitErrors &&
-		err != plumbing.ErrReferenceNotFound {
+	if err != nil && err != plumbing.ErrReferenceNotFound &&
+		!skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 		return nil, err
 	}
 
 	return &squashRefIter{
-		ctx:           ctx,
-		repo:          repo,
-		filters:       i.filters,
-		virtual:       i.virtual,
-		head:          head,
-		refs:          refs,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repo:    repo,
+		filters: i.filters,
+		virtual: i.virtual,
+		head:    head,
+		refs:    refs,
+		skipper: skipper,
 	}, nil
 }
 
@@ -472,7 +483,7 @@ func (i *squashRefIter) Advance() error {
 					return io.EOF
 				}
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 				return err
@@ -508,15 +519,15 @@ func (i *squashRefIter) Schema() sql.Schema {
 }
 
 type squashRefIndexIter struct {
-	ctx           *sql.Context
-	index         sql.IndexLookup
-	iter          sql.RowIter
-	filters       sql.Expression
-	pool          *RepositoryPool
-	ref           *Ref
-	repo          *Repository
-	row           sql.Row
-	skipGitErrors bool
+	ctx     *sql.Context
+	index   sql.IndexLookup
+	iter    sql.RowIter
+	filters sql.Expression
+	pool    *RepositoryPool
+	ref     *Ref
+	repo    *Repository
+	row     sql.Row
+	skipper *gitErrorSkipper
 }
 
 // NewIndexRefsIter returns an iterator that will return all references
@@ -546,11 +557,11 @@ func (i *squashRefIndexIter) New(ctx *sql.Context, repo *Repository) (ChainableI
 	}
 
 	return &squashRefIndexIter{
-		ctx:           ctx,
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
-		pool:          session.Pool,
-		iter:          &rowIndexIter{new(refRowKeyMapper), values},
+		ctx:     ctx,
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, ReferencesTableName),
+		pool:    session.QueryPool(ctx),
+		iter:    &rowIndexIter{new(refRowKeyMapper), values},
 	}, nil
 }
 
@@ -576,7 +587,7 @@ func (i *squashRefIndexIter) Advance() error {
 		if i.repo == nil || repoID != i.repo.ID() {
 			i.repo, err = i.pool.GetRepo(repoID)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(repoID, plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -587,7 +598,7 @@ func (i *squashRefIndexIter) Advance() error {
 		refName := plumbing.ReferenceName(i.row[1].(string))
 		ref, err := i.repo.Reference(refName, true)
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(repoID, plumbing.ZeroHash, err) {
 				continue
 			}
 
@@ -615,15 +626,15 @@ func (i *squashRefIndexIter) Schema() sql.Schema {
 }
 
 type squashRepoRefsIter struct {
-	ctx           *sql.Context
-	repos         ReposIter
-	filters       sql.Expression
-	refs          storer.ReferenceIter
-	head          *plumbing.Reference
-	ref           *Ref
-	row           sql.Row
-	virtual       bool
-	skipGitErrors bool
+	ctx     *sql.Context
+	repos   ReposIter
+	filters sql.Expression
+	refs    storer.ReferenceIter
+	head    *plumbing.Reference
+	ref     *Ref
+	row     sql.Row
+	virtual bool
+	skipper *gitErrorSkipper
 }
 
 // NewRepoRefsIter returns an iterator that will return all references
@@ -666,11 +677,11 @@ func (i *squashRepoRefsIter) New(ctx *sql.Context, repo *Repository) (ChainableI
 	}
 
 	return &squashRepoRefsIter{
-		ctx:           ctx,
-		repos:         repos.(ReposIter),
-		filters:       i.filters,
-		virtual:       i.virtual,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repos:   repos.(ReposIter),
+		filters: i.filters,
+		virtual: i.virtual,
+		skipper: session.gitErrorSkipper(ctx, ReferencesTableName),
 	}, nil
 }
 func (i *squashRepoRefsIter) Row() sql.Row { return i.row }
@@ -689,7 +700,7 @@ func (i *squashRepoRefsIter) Advance() error {
 					"repo":  i.repos.Repository().ID(),
 				}).Error("unable to retrieve references")
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repos.Repository().ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -699,7 +710,7 @@ func (i *squashRepoRefsIter) Advance() error {
 			i.head, err = i.repos.Repository().Head()
 			if err != nil &&
 				err != plumbing.ErrReferenceNotFound &&
-				!i.skipGitErrors {
+				!i.skipper.skip(i.repos.Repository().ID(), plumbing.ZeroHash, err) {
 				return err
 			}
 		}
@@ -757,14 +768,14 @@ func (i *squashRepoRefsIter) Schema() sql.Schema {
 }
 
 type squashRemoteRefsIter struct {
-	ctx           *sql.Context
-	remotes       RemotesIter
-	filters       sql.Expression
-	refs          storer.ReferenceIter
-	head          *plumbing.Reference
-	ref           *Ref
-	row           sql.Row
-	skipGitErrors bool
+	ctx     *sql.Context
+	remotes RemotesIter
+	filters sql.Expression
+	refs    storer.ReferenceIter
+	head    *plumbing.Reference
+	ref     *Ref
+	row     sql.Row
+	skipper *gitErrorSkipper
 }
 
 // NewRemoteRefsIter returns an iterator that will return all references
@@ -805,10 +816,10 @@ func (i *squashRemoteRefsIter) New(ctx *sql.Context, repo *Repository) (Chainabl
 	}
 
 	return &squashRemoteRefsIter{
-		ctx:           ctx,
-		remotes:       iter.(RemotesIter),
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		remotes: iter.(RemotesIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, ReferencesTableName),
 	}, nil
 }
 func (i *squashRemoteRefsIter) Row() sql.Row { return i.row }
@@ -827,7 +838,7 @@ func (i *squashRemoteRefsIter) Advance() error {
 					"repo":  i.Repository().ID(),
 				}).Error("unable to retrieve references")
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -837,7 +848,7 @@ func (i *squashRemoteRefsIter) Advance() error {
 			i.head, err = i.Repository().Head()
 			if err != nil &&
 				err != plumbing.ErrReferenceNotFound &&
-				!i.skipGitErrors {
+				!i.skipper.skip(i.Repository().ID(), plumbing.ZeroHash, err) {
 				return err
 			}
 		}
@@ -909,13 +920,13 @@ func NewAllRefCommitsIter(filters sql.Expression) CommitsIter {
 }
 
 type squashRefRefCommitsIter struct {
-	ctx           *sql.Context
-	refs          RefsIter
-	skipGitErrors bool
-	filters       sql.Expression
-	commits       *indexedCommitIter
-	commit        *object.Commit
-	row           sql.Row
+	ctx     *sql.Context
+	refs    RefsIter
+	skipper *gitErrorSkipper
+	filters sql.Expression
+	commits *indexedCommitIter
+	commit  *object.Commit
+	row     sql.Row
 }
 
 // NewRefRefCommitsIter returns an iterator that will return all ref_commits
@@ -949,10 +960,10 @@ func (i *squashRefRefCommitsIter) New(ctx *sql.Context, repo *Repository) (Chain
 	}
 
 	return &squashRefRefCommitsIter{
-		ctx:           ctx,
-		skipGitErrors: session.SkipGitErrors,
-		refs:          refs.(RefsIter),
-		filters:       i.filters,
+		ctx:     ctx,
+		skipper: session.gitErrorSkipper(ctx, RefCommitsTableName),
+		refs:    refs.(RefsIter),
+		filters: i.filters,
 	}, nil
 }
 
@@ -973,17 +984,18 @@ func (i *squashRefRefCommitsIter) Advance() error {
 					"error": err,
 				}).Error("unable to get commit")
 
-				if errInvalidCommit.Is(err) || i.skipGitErrors {
+				if errInvalidCommit.Is(err) ||
+					i.skipper.skip(i.Repository().ID(), i.refs.Ref().Hash(), err) {
 					continue
 				}
 
 				return err
 			}
 
-			i.commits = newIndexedCommitIter(i.skipGitErrors, i.Repository(), commit)
+			i.commits = newIndexedCommitIter(i.skipper, i.Repository(), commit)
 		}
 
-		commit, idx, err := i.commits.Next()
+		node, idx, err := i.commits.Next()
 		if err != nil {
 			if err == io.EOF {
 				i.commits = nil
@@ -993,11 +1005,10 @@ func (i *squashRefRefCommitsIter) Advance() error {
 			return err
 		}
 
-		i.commit = commit
 		i.row = append(
 			i.refs.Row(),
 			i.Repository().ID(),
-			commit.Hash.String(),
+			node.ID().String(),
 			i.refs.Ref().Name().String(),
 			int64(idx),
 		)
@@ -1013,6 +1024,17 @@ func (i *squashRefRefCommitsIter) Advance() error {
 			}
 		}
 
+		// The commit is only decoded once the row passed the filters, as
+		// the ones after it in the chain may need it.
+		i.commit, err = node.Commit()
+		if err != nil {
+			if i.skipper.skip(i.Repository().ID(), node.ID(), err) {
+				continue
+			}
+
+			return err
+		}
+
 		return nil
 	}
 }
@@ -1021,12 +1043,12 @@ func (i *squashRefRefCommitsIter) Schema() sql.Schema {
 }
 
 type squashRefHeadRefCommitsIter struct {
-	skipGitErrors bool
-	ctx           *sql.Context
-	filters       sql.Expression
-	refs          RefsIter
-	row           sql.Row
-	commit        *object.Commit
+	skipper *gitErrorSkipper
+	ctx     *sql.Context
+	filters sql.Expression
+	refs    RefsIter
+	row     sql.Row
+	commit  *object.Commit
 }
 
 // NewRefHeadRefCommitsIter returns an iterator that will return all ref_commit
@@ -1056,10 +1078,10 @@ func (i *squashRefHeadRefCommitsIter) New(ctx *sql.Context, repo *Repository) (C
 	}
 
 	return &squashRefHeadRefCommitsIter{
-		ctx:           ctx,
-		skipGitErrors: session.SkipGitErrors,
-		refs:          refs.(RefsIter),
-		filters:       i.filters,
+		ctx:     ctx,
+		skipper: session.gitErrorSkipper(ctx, RefCommitsTableName),
+		refs:    refs.(RefsIter),
+		filters: i.filters,
 	}, nil
 }
 
@@ -1079,7 +1101,8 @@ func (i *squashRefHeadRefCommitsIter) Advance() error {
 				"error": err,
 			}).Error("unable to get commit")
 
-			if errInvalidCommit.Is(err) || i.skipGitErrors {
+			if errInvalidCommit.Is(err) ||
+				i.skipper.skip(i.Repository().ID(), i.refs.Ref().Hash(), err) {
 				continue
 			}
 
@@ -1113,15 +1136,15 @@ func (i *squashRefHeadRefCommitsIter) Schema() sql.Schema {
 }
 
 type squashRefCommitsIndexIter struct {
-	ctx           *sql.Context
-	pool          *RepositoryPool
-	repo          *Repository
-	commit        *object.Commit
-	row           sql.Row
-	index         sql.IndexLookup
-	iter          sql.RowIter
-	filters       sql.Expression
-	skipGitErrors bool
+	ctx     *sql.Context
+	pool    *RepositoryPool
+	repo    *Repository
+	commit  *object.Commit
+	row     sql.Row
+	index   sql.IndexLookup
+	iter    sql.RowIter
+	filters sql.Expression
+	skipper *gitErrorSkipper
 }
 
 // NewIndexRefCommitsIter returns an iterator that will return all results in
@@ -1148,12 +1171,12 @@ func (i *squashRefCommitsIndexIter) New(ctx *sql.Context, repo *Repository) (Cha
 	}
 
 	return &squashRefCommitsIndexIter{
-		ctx:           ctx,
-		index:         i.index,
-		iter:          &rowIndexIter{new(refCommitsRowKeyMapper), values},
-		filters:       i.filters,
-		pool:          session.Pool,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		index:   i.index,
+		iter:    &rowIndexIter{new(refCommitsRowKeyMapper), values},
+		filters: i.filters,
+		pool:    session.QueryPool(ctx),
+		skipper: session.gitErrorSkipper(ctx, RefCommitsTableName),
 	}, nil
 }
 func (i *squashRefCommitsIndexIter) Advance() error {
@@ -1170,7 +1193,7 @@ func (i *squashRefCommitsIndexIter) Advance() error {
 
 			i.repo, err = i.pool.GetRepo(i.row[0].(string))
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.row[0].(string), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -1181,7 +1204,7 @@ func (i *squashRefCommitsIndexIter) Advance() error {
 		commitHash := plumbing.NewHash(i.row[1].(string))
 		i.commit, err = i.repo.CommitObject(commitHash)
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), commitHash, err) {
 				continue
 			}
 
@@ -1218,6 +1241,8 @@ type squashRefCommitCommitsIter struct {
 	row        sql.Row
 	filters    sql.Expression
 	ctx        *sql.Context
+	// empty is whether no commit can match the filters.
+	empty bool
 }
 
 // NewRefCommitCommitsIter returns an iterator that will return commits
@@ -1241,16 +1266,22 @@ func (i *squashRefCommitCommitsIter) New(ctx *sql.Context, repo *Repository) (Ch
 		return nil, err
 	}
 
+	filters, ok := resolveRevisionFilter(repo, i.filters)
 	return &squashRefCommitCommitsIter{
 		ctx:        ctx,
 		refCommits: iter.(CommitsIter),
-		filters:    i.filters,
+		filters:    filters,
+		empty:      !ok,
 	}, nil
 }
 
 func (i *squashRefCommitCommitsIter) Row() sql.Row { return i.row }
 
 func (i *squashRefCommitCommitsIter) Advance() error {
+	if i.empty {
+		return io.EOF
+	}
+
 	for {
 		if err := i.refCommits.Advance(); err != nil {
 			return err
@@ -1281,14 +1312,14 @@ func (i *squashRefCommitCommitsIter) Schema() sql.Schema {
 }
 
 type squashCommitsIter struct {
-	ctx           *sql.Context
-	repo          *Repository
-	filters       sql.Expression
-	commits       object.CommitIter
-	commit        *object.Commit
-	row           sql.Row
-	virtual       bool
-	skipGitErrors bool
+	ctx     *sql.Context
+	repo    *Repository
+	filters sql.Expression
+	commits object.CommitIter
+	commit  *object.Commit
+	row     sql.Row
+	virtual bool
+	skipper *gitErrorSkipper
 }
 
 // NewAllCommitsIter returns an iterator that will return all commits
@@ -1315,6 +1346,17 @@ func (i *squashCommitsIter) New(ctx *sql.Context, repo *Repository) (ChainableIt
 		return nil, err
 	}
 
+	skipper := session.gitErrorSkipper(ctx, CommitsTableName)
+	filters, ok := resolveRevisionFilter(repo, i.filters)
+	if !ok {
+		return &squashCommitsIter{
+			ctx:     ctx,
+			repo:    repo,
+			virtual: i.virtual,
+			skipper: skipper,
+		}, nil
+	}
+
 	commits, err := repo.
 		Log(&git.LogOptions{
 			All: true,
@@ -1325,18 +1367,18 @@ func (i *squashCommitsIter) New(ctx *sql.Context, repo *Repository) (ChainableIt
 			"error": err,
 		}).Error("unable to get commit iterator")
 
-		if !session.SkipGitErrors {
+		if !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 			return nil, err
 		}
 	}
 
 	return &squashCommitsIter{
-		ctx:           ctx,
-		repo:          repo,
-		commits:       commits,
-		filters:       i.filters,
-		virtual:       i.virtual,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repo:    repo,
+		commits: commits,
+		filters: filters,
+		virtual: i.virtual,
+		skipper: skipper,
 	}, nil
 }
 
@@ -1391,14 +1433,14 @@ func (i *squashCommitsIter) Schema() sql.Schema {
 }
 
 type squashCommitsIndexIter struct {
-	ctx           *sql.Context
-	pool          *RepositoryPool
-	repo          *Repository
-	row           sql.Row
-	index         sql.IndexLookup
-	iter          *commitsIndexIter
-	filters       sql.Expression
-	skipGitErrors bool
+	ctx     *sql.Context
+	pool    *RepositoryPool
+	repo    *Repository
+	row     sql.Row
+	index   sql.IndexLookup
+	iter    *commitsIndexIter
+	filters sql.Expression
+	skipper *gitErrorSkipper
 }
 
 // NewIndexCommitsIter returns an iterator that will return all results in
@@ -1424,12 +1466,12 @@ func (i *squashCommitsIndexIter) New(ctx *sql.Context, repo *Repository) (Chaina
 	}
 
 	return &squashCommitsIndexIter{
-		ctx:           ctx,
-		index:         i.index,
-		iter:          newCommitsIndexIter(values, session.Pool, nil),
-		filters:       i.filters,
-		pool:          session.Pool,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		index:   i.index,
+		iter:    newCommitsIndexIter(values, session.QueryPool(ctx), nil),
+		filters: i.filters,
+		pool:    session.QueryPool(ctx),
+		skipper: session.gitErrorSkipper(ctx, CommitsTableName),
 	}, nil
 }
 func (i *squashCommitsIndexIter) Advance() error {
@@ -1437,7 +1479,7 @@ func (i *squashCommitsIndexIter) Advance() error {
 		var err error
 		i.row, err = i.iter.Next()
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.iter.repoID, plumbing.ZeroHash, err) {
 				logrus.WithField("err", err).
 					Error("unable to get next commit")
 				continue
@@ -1450,7 +1492,7 @@ func (i *squashCommitsIndexIter) Advance() error {
 
 			i.repo, err = i.pool.GetRepo(i.iter.repoID)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.iter.repoID, plumbing.ZeroHash, err) {
 					logrus.WithFields(logrus.Fields{
 						"err":  err,
 						"repo": i.iter.repoID,
@@ -1488,13 +1530,15 @@ func (i *squashCommitsIndexIter) Close() error {
 }
 
 type squashRepoCommitsIter struct {
-	repos         ReposIter
-	commits       object.CommitIter
-	ctx           *sql.Context
-	filters       sql.Expression
-	commit        *object.Commit
-	row           sql.Row
-	skipGitErrors bool
+	repos   ReposIter
+	commits object.CommitIter
+	ctx     *sql.Context
+	filters sql.Expression
+	commit  *object.Commit
+	row     sql.Row
+	skipper *gitErrorSkipper
+	// empty is whether no commit can match the filters.
+	empty bool
 }
 
 // NewRepoCommitsIter is an iterator that returns all commits for the
@@ -1527,15 +1571,21 @@ func (i *squashRepoCommitsIter) New(ctx *sql.Context, repo *Repository) (Chainab
 		return nil, err
 	}
 
+	filters, ok := resolveRevisionFilter(repo, i.filters)
 	return &squashRepoCommitsIter{
-		repos:         iter.(ReposIter),
-		ctx:           ctx,
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
+		repos:   iter.(ReposIter),
+		ctx:     ctx,
+		filters: filters,
+		skipper: session.gitErrorSkipper(ctx, CommitsTableName),
+		empty:   !ok,
 	}, nil
 }
 func (i *squashRepoCommitsIter) Row() sql.Row { return i.row }
 func (i *squashRepoCommitsIter) Advance() error {
+	if i.empty {
+		return io.EOF
+	}
+
 	for {
 		if i.commits == nil {
 			i.Repository().Close()
@@ -1555,7 +1605,7 @@ func (i *squashRepoCommitsIter) Advance() error {
 					"error": err,
 				}).Error("unable to get commit iterator")
 
-				if !i.skipGitErrors {
+				if !i.skipper.skip(i.repos.Repository().ID(), plumbing.ZeroHash, err) {
 					return err
 				}
 
@@ -1598,13 +1648,15 @@ func (i *squashRepoCommitsIter) Schema() sql.Schema {
 }
 
 type squashRefHeadCommitsIter struct {
-	ctx           *sql.Context
-	filters       sql.Expression
-	refs          RefsIter
-	commit        *object.Commit
-	row           sql.Row
-	virtual       bool
-	skipGitErrors bool
+	ctx     *sql.Context
+	filters sql.Expression
+	refs    RefsIter
+	commit  *object.Commit
+	row     sql.Row
+	virtual bool
+	skipper *gitErrorSkipper
+	// empty is whether no commit can match the filters.
+	empty bool
 }
 
 // NewRefHEADCommitsIter returns an iterator that will return the commit
@@ -1637,16 +1689,22 @@ func (i *squashRefHeadCommitsIter) New(ctx *sql.Context, repo *Repository) (Chai
 		return nil, err
 	}
 
+	filters, ok := resolveRevisionFilter(repo, i.filters)
 	return &squashRefHeadCommitsIter{
-		ctx:           ctx,
-		refs:          iter.(RefsIter),
-		filters:       i.filters,
-		virtual:       i.virtual,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		refs:    iter.(RefsIter),
+		filters: filters,
+		virtual: i.virtual,
+		skipper: session.gitErrorSkipper(ctx, CommitsTableName),
+		empty:   !ok,
 	}, nil
 }
 func (i *squashRefHeadCommitsIter) Row() sql.Row { return i.row }
 func (i *squashRefHeadCommitsIter) Advance() error {
+	if i.empty {
+		return io.EOF
+	}
+
 	for {
 		err := i.refs.Advance()
 		if err != nil {
@@ -1669,7 +1727,7 @@ func (i *squashRefHeadCommitsIter) Advance() error {
 				"error": err,
 			}).Error("unable to resolve commit")
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.Repository().ID(), i.refs.Ref().Hash(), err) {
 				continue
 			}
 
@@ -1721,15 +1779,15 @@ func NewAllCommitTreesIter(filters sql.Expression) TreesIter {
 }
 
 type squashCommitTreesIndexIter struct {
-	ctx           *sql.Context
-	pool          *RepositoryPool
-	repo          *Repository
-	tree          *object.Tree
-	row           sql.Row
-	index         sql.IndexLookup
-	iter          sql.RowIter
-	filters       sql.Expression
-	skipGitErrors bool
+	ctx     *sql.Context
+	pool    *RepositoryPool
+	repo    *Repository
+	tree    *object.Tree
+	row     sql.Row
+	index   sql.IndexLookup
+	iter    sql.RowIter
+	filters sql.Expression
+	skipper *gitErrorSkipper
 }
 
 // NewIndexCommitTreesIter returns an iterator that will return all results in
@@ -1755,12 +1813,12 @@ func (i *squashCommitTreesIndexIter) New(ctx *sql.Context, repo *Repository) (Ch
 	}
 
 	return &squashCommitTreesIndexIter{
-		ctx:           ctx,
-		index:         i.index,
-		iter:          &rowIndexIter{new(commitTreesRowKeyMapper), values},
-		filters:       i.filters,
-		pool:          session.Pool,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		index:   i.index,
+		iter:    &rowIndexIter{new(commitTreesRowKeyMapper), values},
+		filters: i.filters,
+		pool:    session.QueryPool(ctx),
+		skipper: session.gitErrorSkipper(ctx, CommitTreesTableName),
 	}, nil
 }
 func (i *squashCommitTreesIndexIter) Advance() error {
@@ -1775,7 +1833,7 @@ func (i *squashCommitTreesIndexIter) Advance() error {
 		if i.repo == nil || repoID != i.repo.ID() {
 			i.repo, err = i.pool.GetRepo(i.row[0].(string))
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.row[0].(string), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -1786,7 +1844,7 @@ func (i *squashCommitTreesIndexIter) Advance() error {
 		treeHash := plumbing.NewHash(i.row[2].(string))
 		i.tree, err = i.repo.TreeObject(treeHash)
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), treeHash, err) {
 				continue
 			}
 
@@ -1820,14 +1878,14 @@ func (i *squashCommitTreesIndexIter) Close() error {
 }
 
 type squashCommitTreesIter struct {
-	ctx           *sql.Context
-	commits       CommitsIter
-	trees         *commitTreeIter
-	filters       sql.Expression
-	tree          *object.Tree
-	row           sql.Row
-	skipGitErrors bool
-	virtual       bool
+	ctx     *sql.Context
+	commits CommitsIter
+	trees   *commitTreeIter
+	filters sql.Expression
+	tree    *object.Tree
+	row     sql.Row
+	skipper *gitErrorSkipper
+	virtual bool
 }
 
 // NewCommitTreesIter returns all trees from the commits returned by the given
@@ -1868,11 +1926,11 @@ func (i *squashCommitTreesIter) New(ctx *sql.Context, repo *Repository) (Chainab
 	}
 
 	return &squashCommitTreesIter{
-		ctx:           ctx,
-		commits:       commits.(CommitsIter),
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
-		virtual:       i.virtual,
+		ctx:     ctx,
+		commits: commits.(CommitsIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, CommitTreesTableName),
+		virtual: i.virtual,
 	}, nil
 }
 func (i *squashCommitTreesIter) Row() sql.Row { return i.row }
@@ -1889,10 +1947,10 @@ func (i *squashCommitTreesIter) Advance() error {
 				i.Repository(),
 				commit,
 				make(map[plumbing.Hash]struct{}),
-				i.skipGitErrors,
+				i.skipper,
 			)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), commit.Hash, err) {
 					continue
 				}
 
@@ -1942,15 +2000,15 @@ func (i *squashCommitTreesIter) Advance() error {
 }
 
 type squashRepoTreeEntriesIter struct {
-	ctx           *sql.Context
-	filters       sql.Expression
-	repos         ReposIter
-	trees         *object.TreeIter
-	tree          *object.Tree
-	cursor        int
-	entry         *TreeEntry
-	row           sql.Row
-	skipGitErrors bool
+	ctx     *sql.Context
+	filters sql.Expression
+	repos   ReposIter
+	trees   *object.TreeIter
+	tree    *object.Tree
+	cursor  int
+	entry   *TreeEntry
+	row     sql.Row
+	skipper *gitErrorSkipper
 }
 
 // NewRepoTreeEntriesIter returns an iterator that will return all tree entries
@@ -1984,10 +2042,10 @@ func (i *squashRepoTreeEntriesIter) New(ctx *sql.Context, repo *Repository) (Cha
 	}
 
 	return &squashRepoTreeEntriesIter{
-		ctx:           ctx,
-		repos:         iter.(ReposIter),
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repos:   iter.(ReposIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, TreeEntriesTableName),
 	}, nil
 }
 func (i *squashRepoTreeEntriesIter) Row() sql.Row { return i.row }
@@ -2003,7 +2061,7 @@ func (i *squashRepoTreeEntriesIter) Advance() error {
 
 			i.trees, err = i.Repository().TreeObjects()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -2020,7 +2078,7 @@ func (i *squashRepoTreeEntriesIter) Advance() error {
 					continue
 				}
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -2067,14 +2125,14 @@ func (i *squashCommitTreesIter) Schema() sql.Schema {
 }
 
 type squashCommitMainTreeIter struct {
-	ctx           *sql.Context
-	commits       CommitsIter
-	filters       sql.Expression
-	tree          *object.Tree
-	row           sql.Row
-	seen          map[plumbing.Hash]struct{}
-	skipGitErrors bool
-	virtual       bool
+	ctx     *sql.Context
+	commits CommitsIter
+	filters sql.Expression
+	tree    *object.Tree
+	row     sql.Row
+	seen    map[plumbing.Hash]struct{}
+	skipper *gitErrorSkipper
+	virtual bool
 }
 
 // NewCommitMainTreeIter returns all main trees from the commits returned by the given
@@ -2112,12 +2170,12 @@ func (i *squashCommitMainTreeIter) New(ctx *sql.Context, repo *Repository) (Chai
 	}
 
 	return &squashCommitMainTreeIter{
-		ctx:           ctx,
-		commits:       commits.(CommitsIter),
-		filters:       i.filters,
-		seen:          make(map[plumbing.Hash]struct{}),
-		skipGitErrors: session.SkipGitErrors,
-		virtual:       i.virtual,
+		ctx:     ctx,
+		commits: commits.(CommitsIter),
+		filters: i.filters,
+		seen:    make(map[plumbing.Hash]struct{}),
+		skipper: session.gitErrorSkipper(ctx, CommitTreesTableName),
+		virtual: i.virtual,
 	}, nil
 }
 func (i *squashCommitMainTreeIter) Row() sql.Row { return i.row }
@@ -2130,7 +2188,7 @@ func (i *squashCommitMainTreeIter) Advance() error {
 
 		i.tree, err = i.commits.Commit().Tree()
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.Repository().ID(), i.commits.Commit().Hash, err) {
 				continue
 			}
 			return err
@@ -2169,11 +2227,11 @@ func (i *squashCommitMainTreeIter) Schema() sql.Schema {
 }
 
 type commitTreeIter struct {
-	skipGitErrors bool
-	tree          *object.Tree
-	repo          *Repository
-	stack         []*commitTreeStackFrame
-	seen          map[plumbing.Hash]struct{}
+	skipper *gitErrorSkipper
+	tree    *object.Tree
+	repo    *Repository
+	stack   []*commitTreeStackFrame
+	seen    map[plumbing.Hash]struct{}
 }
 
 type commitTreeStackFrame struct {
@@ -2185,7 +2243,7 @@ func newCommitTreeIter(
 	repo *Repository,
 	commit *object.Commit,
 	seen map[plumbing.Hash]struct{},
-	skipGitErrors bool,
+	skipper *gitErrorSkipper,
 ) (*commitTreeIter, error) {
 	tree, err := commit.Tree()
 	if err != nil {
@@ -2197,11 +2255,11 @@ func newCommitTreeIter(
 	}
 
 	return &commitTreeIter{
-		tree:          tree,
-		repo:          repo,
-		stack:         []*commitTreeStackFrame{{entries: tree.Entries}},
-		seen:          seen,
-		skipGitErrors: skipGitErrors,
+		tree:    tree,
+		repo:    repo,
+		stack:   []*commitTreeStackFrame{{entries: tree.Entries}},
+		seen:    seen,
+		skipper: skipper,
 	}, nil
 }
 
@@ -2233,7 +2291,7 @@ func (i *commitTreeIter) Next() (*object.Tree, error) {
 
 			tree, err := i.repo.TreeObject(entry.Hash)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), entry.Hash, err) {
 					logrus.WithFields(logrus.Fields{
 						"tree": entry.Hash,
 					}).Debug("skipping tree entry, can't get tree")
@@ -2272,15 +2330,15 @@ type TreeEntriesIter interface {
 }
 
 type squashTreeEntriesIter struct {
-	ctx           *sql.Context
-	repo          *Repository
-	filters       sql.Expression
-	trees         *object.TreeIter
-	tree          *object.Tree
-	cursor        int
-	entry         *TreeEntry
-	row           sql.Row
-	skipGitErrors bool
+	ctx     *sql.Context
+	repo    *Repository
+	filters sql.Expression
+	trees   *object.TreeIter
+	tree    *object.Tree
+	cursor  int
+	entry   *TreeEntry
+	row     sql.Row
+	skipper *gitErrorSkipper
 }
 
 // NewAllTreeEntriesIter returns an iterator that will return all tree entries
@@ -2308,17 +2366,18 @@ func (i *squashTreeEntriesIter) New(ctx *sql.Context, repo *Repository) (Chainab
 		return nil, err
 	}
 
+	skipper := session.gitErrorSkipper(ctx, TreeEntriesTableName)
 	trees, err := repo.TreeObjects()
-	if err != nil && !session.SkipGitErrors {
+	if err != nil && !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
 		return nil, err
 	}
 
 	return &squashTreeEntriesIter{
-		ctx:           ctx,
-		repo:          repo,
-		filters:       i.filters,
-		trees:         trees,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		repo:    repo,
+		filters: i.filters,
+		trees:   trees,
+		skipper: skipper,
 	}, nil
 }
 func (i *squashTreeEntriesIter) Row() sql.Row { return i.row }
@@ -2342,7 +2401,7 @@ func (i *squashTreeEntriesIter) Advance() error {
 					return io.EOF
 				}
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -2377,14 +2436,14 @@ func (i *squashTreeEntriesIter) Advance() error {
 func (i *squashTreeEntriesIter) Schema() sql.Schema { return TreeEntriesSchema }
 
 type squashTreeEntriesIndexIter struct {
-	ctx           *sql.Context
-	pool          *RepositoryPool
-	repo          *Repository
-	row           sql.Row
-	index         sql.IndexLookup
-	iter          *treeEntriesIndexIter
-	filters       sql.Expression
-	skipGitErrors bool
+	ctx     *sql.Context
+	pool    *RepositoryPool
+	repo    *Repository
+	row     sql.Row
+	index   sql.IndexLookup
+	iter    *treeEntriesIndexIter
+	filters sql.Expression
+	skipper *gitErrorSkipper
 }
 
 // NewIndexTreeEntriesIter returns an iterator that will return all results in
@@ -2410,13 +2469,13 @@ func (i *squashTreeEntriesIndexIter) New(ctx *sql.Context, repo *Repository) (Ch
 	}
 
 	return &squashTreeEntriesIndexIter{
-		ctx:           ctx,
-		index:         i.index,
-		iter:          newTreeEntriesIndexIter(values, session.Pool, nil),
-		filters:       i.filters,
-		pool:          session.Pool,
-		repo:          repo,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		index:   i.index,
+		iter:    newTreeEntriesIndexIter(values, session.QueryPool(ctx), nil),
+		filters: i.filters,
+		pool:    session.QueryPool(ctx),
+		repo:    repo,
+		skipper: session.gitErrorSkipper(ctx, TreeEntriesTableName),
 	}, nil
 }
 func (i *squashTreeEntriesIndexIter) Advance() error {
@@ -2424,7 +2483,7 @@ func (i *squashTreeEntriesIndexIter) Advance() error {
 		var err error
 		i.row, err = i.iter.Next()
 		if err != nil {
-			if i.skipGitErrors && err != io.EOF {
+			if err != io.EOF && i.skipper.skip(i.iter.repoID, plumbing.ZeroHash, err) {
 				logrus.WithField("err", err).
 					Error("unable to get next commit")
 				continue
@@ -2435,7 +2494,7 @@ func (i *squashTreeEntriesIndexIter) Advance() error {
 		if i.repo == nil || i.repo.ID() != i.iter.repoID {
 			i.repo, err = i.pool.GetRepo(i.iter.repoID)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.iter.repoID, plumbing.ZeroHash, err) {
 					logrus.WithFields(logrus.Fields{
 						"err":  err,
 						"repo": i.iter.repoID,
@@ -2577,15 +2636,15 @@ type BlobsIter interface {
 }
 
 type squashCommitBlobsIndexIter struct {
-	ctx           *sql.Context
-	pool          *RepositoryPool
-	repo          *Repository
-	blob          *object.Blob
-	row           sql.Row
-	index         sql.IndexLookup
-	iter          sql.RowIter
-	filters       sql.Expression
-	skipGitErrors bool
+	ctx     *sql.Context
+	pool    *RepositoryPool
+	repo    *Repository
+	blob    *object.Blob
+	row     sql.Row
+	index   sql.IndexLookup
+	iter    sql.RowIter
+	filters sql.Expression
+	skipper *gitErrorSkipper
 }
 
 // NewIndexCommitBlobsIter returns an iterator that will return all results in
@@ -2611,12 +2670,12 @@ func (i *squashCommitBlobsIndexIter) New(ctx *sql.Context, repo *Repository) (Ch
 	}
 
 	return &squashCommitBlobsIndexIter{
-		ctx:           ctx,
-		index:         i.index,
-		iter:          &rowIndexIter{new(commitBlobsRowKeyMapper), values},
-		filters:       i.filters,
-		pool:          session.Pool,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		index:   i.index,
+		iter:    &rowIndexIter{new(commitBlobsRowKeyMapper), values},
+		filters: i.filters,
+		pool:    session.QueryPool(ctx),
+		skipper: session.gitErrorSkipper(ctx, CommitBlobsTableName),
 	}, nil
 }
 func (i *squashCommitBlobsIndexIter) Advance() error {
@@ -2631,7 +2690,7 @@ func (i *squashCommitBlobsIndexIter) Advance() error {
 		if i.repo == nil || repoID != i.repo.ID() {
 			i.repo, err = i.pool.GetRepo(i.row[0].(string))
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.row[0].(string), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -2642,7 +2701,7 @@ func (i *squashCommitBlobsIndexIter) Advance() error {
 		blobHash := plumbing.NewHash(i.row[2].(string))
 		i.blob, err = i.repo.BlobObject(blobHash)
 		if err != nil {
-			if i.skipGitErrors {
+			if i.skipper.skip(i.repo.ID(), blobHash, err) {
 				continue
 			}
 
@@ -2675,15 +2734,15 @@ func (i *squashCommitBlobsIndexIter) Close() error {
 }
 
 type squashCommitBlobsIter struct {
-	ctx           *sql.Context
-	filters       sql.Expression
-	commits       CommitsIter
-	files         *object.FileIter
-	file          *object.File
-	tree          *object.Tree
-	row           sql.Row
-	skipGitErrors bool
-	seen          map[plumbing.Hash]struct{}
+	ctx     *sql.Context
+	filters sql.Expression
+	commits CommitsIter
+	files   *object.FileIter
+	file    *object.File
+	tree    *object.Tree
+	row     sql.Row
+	skipper *gitErrorSkipper
+	seen    map[plumbing.Hash]struct{}
 }
 
 // NewAllCommitBlobsIter returns all commit_blobs.
@@ -2729,10 +2788,10 @@ func (i *squashCommitBlobsIter) New(ctx *sql.Context, repo *Repository) (Chainab
 	}
 
 	return &squashCommitBlobsIter{
-		ctx:           ctx,
-		commits:       iter.(CommitsIter),
-		filters:       i.filters,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:     ctx,
+		commits: iter.(CommitsIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, CommitBlobsTableName),
 	}, nil
 }
 
@@ -2755,7 +2814,7 @@ func (i *squashCommitBlobsIter) Advance() error {
 					"error":     err,
 				}).Error("unable to retrieve tree object")
 
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), i.commits.Commit().TreeHash, err) {
 					continue
 				}
 
@@ -2910,13 +2969,13 @@ func (i *squashRepoBlobsIter) Schema() sql.Schema {
 }
 
 type squashTreeEntryBlobsIter struct {
-	ctx           *sql.Context
-	filters       sql.Expression
-	treeEntries   TreeEntriesIter
-	blob          *object.Blob
-	row           sql.Row
-	readContent   bool
-	skipGitErrors bool
+	ctx         *sql.Context
+	filters     sql.Expression
+	treeEntries TreeEntriesIter
+	blob        *object.Blob
+	row         sql.Row
+	readContent bool
+	skipper     *gitErrorSkipper
 }
 
 // NewTreeEntryBlobsIter returns an iterator that will return all blobs
@@ -2955,11 +3014,11 @@ func (i *squashTreeEntryBlobsIter) New(ctx *sql.Context, repo *Repository) (Chai
 	}
 
 	return &squashTreeEntryBlobsIter{
-		ctx:           ctx,
-		treeEntries:   iter.(TreeEntriesIter),
-		filters:       i.filters,
-		readContent:   i.readContent,
-		skipGitErrors: session.SkipGitErrors,
+		ctx:         ctx,
+		treeEntries: iter.(TreeEntriesIter),
+		filters:     i.filters,
+		readContent: i.readContent,
+		skipper:     session.gitErrorSkipper(ctx, BlobsTableName),
 	}, nil
 }
 func (i *squashTreeEntryBlobsIter) Row() sql.Row { return i.row }
@@ -2983,7 +3042,7 @@ func (i *squashTreeEntryBlobsIter) Advance() error {
 				"blob":  entry.Hash,
 			}).Error("blob object found not be found")
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.Repository().ID(), entry.Hash, err) {
 				continue
 			}
 
@@ -3101,26 +3160,35 @@ type FilesIter interface {
 	TreeHash() plumbing.Hash
 }
 
+// CommitFilesIter is a chainable iterator that operates on the files of
+// commits.
+type CommitFilesIter interface {
+	FilesIter
+	// Commit returns the commit of the current file. All calls to Commit
+	// return the same commit until another call to Advance.
+	Commit() *object.Commit
+}
+
 type squashCommitFilesIter struct {
-	commits       CommitsIter
-	files         *object.FileIter
-	file          *object.File
-	commit        *object.Commit
-	row           sql.Row
-	filters       sql.Expression
-	ctx           *sql.Context
-	treeHash      plumbing.Hash
-	skipGitErrors bool
+	commits  CommitsIter
+	files    *object.FileIter
+	file     *object.File
+	commit   *object.Commit
+	row      sql.Row
+	filters  sql.Expression
+	ctx      *sql.Context
+	treeHash plumbing.Hash
+	skipper  *gitErrorSkipper
 }
 
 // NewAllCommitFilesIter returns an iterator that will return all commit files.
-func NewAllCommitFilesIter(filters sql.Expression) FilesIter {
+func NewAllCommitFilesIter(filters sql.Expression) CommitFilesIter {
 	return NewCommitFilesIter(NewAllCommitsIter(nil, true), filters)
 }
 
 // NewCommitFilesIter returns an iterator that will return all commit files
 // for the commits in the given iterator.
-func NewCommitFilesIter(iter CommitsIter, filters sql.Expression) FilesIter {
+func NewCommitFilesIter(iter CommitsIter, filters sql.Expression) CommitFilesIter {
 	return &squashCommitFilesIter{commits: iter, filters: filters}
 }
 
@@ -3136,10 +3204,10 @@ func (i *squashCommitFilesIter) New(ctx *sql.Context, repo *Repository) (Chainab
 	}
 
 	return &squashCommitFilesIter{
-		ctx:           ctx,
-		skipGitErrors: session.SkipGitErrors,
-		commits:       iter.(CommitsIter),
-		filters:       i.filters,
+		ctx:     ctx,
+		skipper: session.gitErrorSkipper(ctx, CommitFilesTableName),
+		commits: iter.(CommitsIter),
+		filters: i.filters,
 	}, nil
 }
 
@@ -3148,7 +3216,8 @@ func (i *squashCommitFilesIter) Advance() error {
 		if i.files == nil {
 			err := i.commits.Advance()
 			if err != nil {
-				if err != io.EOF && i.skipGitErrors {
+				if err != io.EOF &&
+					i.skipper.skip(repositoryID(i.Repository()), plumbing.ZeroHash, err) {
 					logrus.WithField("err", err).Error("could not get next commit")
 					continue
 				}
@@ -3159,7 +3228,7 @@ func (i *squashCommitFilesIter) Advance() error {
 			i.commit = i.commits.Commit()
 			i.files, err = i.commit.Files()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.Repository().ID(), i.commit.Hash, err) {
 					logrus.WithFields(logrus.Fields{
 						"err":    err,
 						"repo":   i.Repository().ID(),
@@ -3180,7 +3249,7 @@ func (i *squashCommitFilesIter) Advance() error {
 				continue
 			}
 
-			if i.skipGitErrors {
+			if i.skipper.skip(i.Repository().ID(), i.commit.Hash, err) {
 				logrus.WithFields(logrus.Fields{
 					"err":    err,
 					"repo":   i.Repository().ID(),
@@ -3216,6 +3285,7 @@ func (i *squashCommitFilesIter) Advance() error {
 func (i *squashCommitFilesIter) Repository() *Repository { return i.commits.Repository() }
 func (i *squashCommitFilesIter) File() *object.File      { return i.file }
 func (i *squashCommitFilesIter) TreeHash() plumbing.Hash { return i.treeHash }
+func (i *squashCommitFilesIter) Commit() *object.Commit  { return i.commit }
 func (i *squashCommitFilesIter) Row() sql.Row            { return i.row }
 func (i *squashCommitFilesIter) Close() error {
 	if i.files != nil {
@@ -3229,16 +3299,16 @@ func (i *squashCommitFilesIter) Schema() sql.Schema {
 }
 
 type squashIndexCommitFilesIter struct {
-	index         sql.IndexLookup
-	pool          *RepositoryPool
-	repo          *Repository
-	iter          *commitFilesIndexIter
-	file          *object.File
-	row           sql.Row
-	filters       sql.Expression
-	ctx           *sql.Context
-	treeHash      plumbing.Hash
-	skipGitErrors bool
+	index    sql.IndexLookup
+	pool     *RepositoryPool
+	repo     *Repository
+	iter     *commitFilesIndexIter
+	file     *object.File
+	row      sql.Row
+	filters  sql.Expression
+	ctx      *sql.Context
+	treeHash plumbing.Hash
+	skipper  *gitErrorSkipper
 }
 
 // NewIndexCommitFilesIter returns an iterator that will return all commit
@@ -3259,11 +3329,11 @@ func (i *squashIndexCommitFilesIter) New(ctx *sql.Context, repo *Repository) (Ch
 	}
 
 	return &squashIndexCommitFilesIter{
-		iter:          newCommitFilesIndexIter(values, session.Pool),
-		pool:          session.Pool,
-		ctx:           ctx,
-		skipGitErrors: session.SkipGitErrors,
-		filters:       i.filters,
+		iter:    newCommitFilesIndexIter(values, session.QueryPool(ctx)),
+		pool:    session.QueryPool(ctx),
+		ctx:     ctx,
+		skipper: session.gitErrorSkipper(ctx, CommitFilesTableName),
+		filters: i.filters,
 	}, nil
 }
 
@@ -3271,7 +3341,8 @@ func (i *squashIndexCommitFilesIter) Advance() error {
 	for {
 		commitFile, err := i.iter.NextCommitFile()
 		if err != nil {
-			if err != io.EOF && i.skipGitErrors {
+			if err != io.EOF &&
+				i.skipper.skip(repositoryID(i.repo), plumbing.ZeroHash, err) {
 				logrus.WithField("err", err).Error("unable to get next file")
 				continue
 			}
@@ -3286,7 +3357,7 @@ func (i *squashIndexCommitFilesIter) Advance() error {
 
 			i.repo, err = i.pool.GetRepo(commitFile.Repository)
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(commitFile.Repository, plumbing.ZeroHash, err) {
 					logrus.WithFields(logrus.Fields{
 						"err":  err,
 						"repo": commitFile.Repository,
@@ -3485,6 +3556,697 @@ func (i *squashCommitFileBlobsIter) Close() error {
 	return i.files.Close()
 }
 
+type squashCommitDiffsIter struct {
+	ctx       *sql.Context
+	filters   sql.Expression
+	commits   CommitsIter
+	lineStats bool
+	diffs     []commitDiff
+	pos       int
+	row       sql.Row
+	skipper   *gitErrorSkipper
+}
+
+// NewAllCommitDiffsIter returns an iterator that will return all commit
+// diffs. The lines added and deleted are only computed if lineStats is true.
+func NewAllCommitDiffsIter(filters sql.Expression, lineStats bool) ChainableIter {
+	return NewCommitDiffsIter(NewAllCommitsIter(nil, true), filters, lineStats)
+}
+
+// NewCommitDiffsIter returns an iterator that will return all the file
+// changes of each commit in the given iterator. The lines added and deleted
+// are only computed if lineStats is true.
+func NewCommitDiffsIter(
+	commits CommitsIter,
+	filters sql.Expression,
+	lineStats bool,
+) ChainableIter {
+	return &squashCommitDiffsIter{
+		commits:   commits,
+		filters:   filters,
+		lineStats: lineStats,
+	}
+}
+
+func (i *squashCommitDiffsIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.commits.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &squashCommitDiffsIter{
+		ctx:       ctx,
+		commits:   iter.(CommitsIter),
+		filters:   i.filters,
+		lineStats: i.lineStats,
+		skipper:   session.gitErrorSkipper(ctx, CommitDiffsTableName),
+	}, nil
+}
+
+func (i *squashCommitDiffsIter) Advance() error {
+	for {
+		if i.pos >= len(i.diffs) {
+			err := i.commits.Advance()
+			if err != nil {
+				if err != io.EOF &&
+					i.skipper.skip(repositoryID(i.Repository()), plumbing.ZeroHash, err) {
+					logrus.WithField("err", err).Error("could not get next commit")
+					continue
+				}
+
+				return err
+			}
+
+			i.pos = 0
+			i.diffs, err = commitDiffs(i.Repository(), i.commits.Commit(), i.lineStats)
+			if err != nil {
+				if i.skipper.skip(i.Repository().ID(), i.commits.Commit().Hash, err) {
+					logrus.WithFields(logrus.Fields{
+						"err":    err,
+						"repo":   i.Repository().ID(),
+						"commit": i.commits.Commit().Hash.String(),
+					}).Error("could not get diffs for commit")
+					continue
+				}
+
+				return err
+			}
+
+			continue
+		}
+
+		d := i.diffs[i.pos]
+		i.pos++
+
+		i.row = append(
+			i.commits.Row(),
+			newCommitDiffsRow(i.Repository().ID(), i.commits.Commit(), d)...,
+		)
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+
+func (i *squashCommitDiffsIter) Repository() *Repository { return i.commits.Repository() }
+func (i *squashCommitDiffsIter) Row() sql.Row            { return i.row }
+func (i *squashCommitDiffsIter) Close() error            { return i.commits.Close() }
+func (i *squashCommitDiffsIter) Schema() sql.Schema {
+	return append(i.commits.Schema(), CommitDiffsSchema...)
+}
+
+type squashFileBlameIter struct {
+	ctx     *sql.Context
+	filters sql.Expression
+	commits CommitsIter
+	paths   []string
+	lines   *fileBlameLines
+	row     sql.Row
+	skipper *gitErrorSkipper
+}
+
+// NewAllFileBlameIter returns an iterator that will return the blamed lines
+// of the files of all commits.
+func NewAllFileBlameIter(filters sql.Expression) ChainableIter {
+	return NewFileBlameIter(NewAllCommitsIter(nil, true), filters)
+}
+
+// NewFileBlameIter returns an iterator that will return the blamed lines of
+// the files of each commit in the given iterator. If the filters compare
+// file_path with some paths, only those files are blamed.
+func NewFileBlameIter(commits CommitsIter, filters sql.Expression) ChainableIter {
+	return &squashFileBlameIter{commits: commits, filters: filters}
+}
+
+func (i *squashFileBlameIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.commits.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := fileBlamePaths(i.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &squashFileBlameIter{
+		ctx:     ctx,
+		commits: iter.(CommitsIter),
+		filters: i.filters,
+		paths:   paths,
+		skipper: session.gitErrorSkipper(ctx, FileBlameTableName),
+	}, nil
+}
+
+func (i *squashFileBlameIter) Advance() error {
+	for {
+		if i.lines == nil {
+			err := i.commits.Advance()
+			if err != nil {
+				if err != io.EOF &&
+					i.skipper.skip(repositoryID(i.Repository()), plumbing.ZeroHash, err) {
+					logrus.WithField("err", err).Error("could not get next commit")
+					continue
+				}
+
+				return err
+			}
+
+			i.lines, err = newFileBlameLines(i.commits.Commit(), i.paths)
+			if err != nil {
+				if i.skipper.skip(i.Repository().ID(), i.commits.Commit().Hash, err) {
+					logrus.WithFields(logrus.Fields{
+						"err":    err,
+						"repo":   i.Repository().ID(),
+						"commit": i.commits.Commit().Hash.String(),
+					}).Error("could not get files for commit")
+					i.lines = nil
+					continue
+				}
+
+				return err
+			}
+		}
+
+		path, line, err := i.lines.next()
+		if err == io.EOF {
+			i.lines = nil
+			continue
+		}
+
+		if err != nil {
+			if i.skipper.skip(i.Repository().ID(), i.commits.Commit().Hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"err":    err,
+					"repo":   i.Repository().ID(),
+					"commit": i.commits.Commit().Hash.String(),
+					"path":   path,
+				}).Error("could not blame file")
+				continue
+			}
+
+			return err
+		}
+
+		i.row = append(
+			i.commits.Row(),
+			newFileBlameRow(i.Repository().ID(), i.commits.Commit(), path, line)...,
+		)
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+
+func (i *squashFileBlameIter) Repository() *Repository { return i.commits.Repository() }
+func (i *squashFileBlameIter) Row() sql.Row            { return i.row }
+func (i *squashFileBlameIter) Close() error            { return i.commits.Close() }
+func (i *squashFileBlameIter) Schema() sql.Schema {
+	return append(i.commits.Schema(), FileBlameSchema...)
+}
+
+type squashCommitFileBlameIter struct {
+	ctx     *sql.Context
+	filters sql.Expression
+	files   CommitFilesIter
+	lines   *fileBlameLines
+	row     sql.Row
+	skipper *gitErrorSkipper
+}
+
+// NewCommitFileBlameIter returns an iterator that will return the blamed
+// lines of each commit file in the given iterator.
+func NewCommitFileBlameIter(files CommitFilesIter, filters sql.Expression) ChainableIter {
+	return &squashCommitFileBlameIter{files: files, filters: filters}
+}
+
+func (i *squashCommitFileBlameIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.files.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &squashCommitFileBlameIter{
+		ctx:     ctx,
+		files:   iter.(CommitFilesIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, FileBlameTableName),
+	}, nil
+}
+
+func (i *squashCommitFileBlameIter) Advance() error {
+	for {
+		if i.lines == nil {
+			err := i.files.Advance()
+			if err != nil {
+				if err != io.EOF &&
+					i.skipper.skip(repositoryID(i.Repository()), plumbing.ZeroHash, err) {
+					logrus.WithField("err", err).Error("could not get next file")
+					continue
+				}
+
+				return err
+			}
+
+			i.lines = &fileBlameLines{
+				commit: i.files.Commit(),
+				paths:  []string{i.files.File().Name},
+			}
+		}
+
+		path, line, err := i.lines.next()
+		if err == io.EOF {
+			i.lines = nil
+			continue
+		}
+
+		if err != nil {
+			if i.skipper.skip(i.Repository().ID(), i.files.Commit().Hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"err":    err,
+					"repo":   i.Repository().ID(),
+					"commit": i.files.Commit().Hash.String(),
+					"path":   path,
+				}).Error("could not blame file")
+				continue
+			}
+
+			return err
+		}
+
+		i.row = append(
+			i.files.Row(),
+			newFileBlameRow(i.Repository().ID(), i.files.Commit(), path, line)...,
+		)
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+
+func (i *squashCommitFileBlameIter) Repository() *Repository { return i.files.Repository() }
+func (i *squashCommitFileBlameIter) Row() sql.Row            { return i.row }
+func (i *squashCommitFileBlameIter) Close() error            { return i.files.Close() }
+func (i *squashCommitFileBlameIter) Schema() sql.Schema {
+	return append(i.files.Schema(), FileBlameSchema...)
+}
+
+type squashCommitNotesIter struct {
+	ctx     *sql.Context
+	filters sql.Expression
+	commits CommitsIter
+	refs    []*notesRef
+	rows    []sql.Row
+	row     sql.Row
+	skipper *gitErrorSkipper
+}
+
+// NewCommitNotesIter returns an iterator that will return the notes of
+// each commit in the given iterator.
+func NewCommitNotesIter(commits CommitsIter, filters sql.Expression) ChainableIter {
+	return &squashCommitNotesIter{commits: commits, filters: filters}
+}
+
+func (i *squashCommitNotesIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.commits.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	skipper := session.gitErrorSkipper(ctx, CommitNotesTableName)
+	refs, err := repositoryNotes(repo, nil)
+	if err != nil {
+		if !skipper.skip(repo.ID(), plumbing.ZeroHash, err) {
+			return nil, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"repo": repo.ID(),
+		}).Error("could not get notes of repository")
+	}
+
+	return &squashCommitNotesIter{
+		ctx:     ctx,
+		commits: iter.(CommitsIter),
+		filters: i.filters,
+		refs:    refs,
+		skipper: skipper,
+	}, nil
+}
+
+func (i *squashCommitNotesIter) Advance() error {
+	for {
+		if len(i.rows) == 0 {
+			err := i.commits.Advance()
+			if err != nil {
+				if err != io.EOF &&
+					i.skipper.skip(repositoryID(i.Repository()), plumbing.ZeroHash, err) {
+					logrus.WithField("err", err).Error("could not get next commit")
+					continue
+				}
+
+				return err
+			}
+
+			hash := i.commits.Commit().Hash
+			for _, ref := range i.refs {
+				blob, ok := ref.notes[hash]
+				if !ok {
+					continue
+				}
+
+				row, err := newCommitNotesRow(i.Repository(), ref.name, hash, blob)
+				if err != nil {
+					if i.skipper.skip(i.Repository().ID(), blob, err) {
+						logrus.WithFields(logrus.Fields{
+							"err":    err,
+							"repo":   i.Repository().ID(),
+							"ref":    ref.name,
+							"commit": hash.String(),
+						}).Error("could not get note of commit")
+						continue
+					}
+
+					return err
+				}
+
+				i.rows = append(i.rows, row)
+			}
+
+			continue
+		}
+
+		i.row = append(i.commits.Row(), i.rows[0]...)
+		i.rows = i.rows[1:]
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+
+func (i *squashCommitNotesIter) Repository() *Repository { return i.commits.Repository() }
+func (i *squashCommitNotesIter) Row() sql.Row            { return i.row }
+func (i *squashCommitNotesIter) Close() error            { return i.commits.Close() }
+func (i *squashCommitNotesIter) Schema() sql.Schema {
+	return append(i.commits.Schema(), CommitNotesSchema...)
+}
+
+// Tag is a tag reference with the repo id and the annotated tag object it
+// points to, if any.
+type Tag struct {
+	RepoID string
+	*plumbing.Reference
+	// Object is the annotated tag object, or nil if it's a lightweight tag.
+	Object *object.Tag
+	// TargetType is the type of the object the tag points to.
+	TargetType plumbing.ObjectType
+}
+
+// Target returns the hash of the object the tag points to.
+func (t *Tag) Target() plumbing.Hash {
+	if t.Object != nil {
+		return t.Object.Target
+	}
+
+	return t.Hash()
+}
+
+// TagsIter is a chainable iterator that operates on tags.
+type TagsIter interface {
+	ChainableIter
+	// Tag returns the current tag. All calls to Tag return the same tag
+	// until another call to Advance. Advance should be called before
+	// calling Tag.
+	Tag() *Tag
+}
+
+type squashRefTagsIter struct {
+	ctx     *sql.Context
+	refs    RefsIter
+	filters sql.Expression
+	tag     *Tag
+	row     sql.Row
+	skipper *gitErrorSkipper
+}
+
+// NewAllTagsIter returns an iterator that will return all tags that match
+// the given filters.
+func NewAllTagsIter(filters sql.Expression) TagsIter {
+	return NewRefTagsIter(NewAllRefsIter(nil, true), filters)
+}
+
+// NewRefTagsIter returns an iterator that will return the tag of each tag
+// reference in the given iterator. References that are not tags are
+// skipped.
+func NewRefTagsIter(refs RefsIter, filters sql.Expression) TagsIter {
+	return &squashRefTagsIter{refs: refs, filters: filters}
+}
+
+func (i *squashRefTagsIter) Repository() *Repository { return i.refs.Repository() }
+func (i *squashRefTagsIter) Tag() *Tag               { return i.tag }
+func (i *squashRefTagsIter) Close() error            { return i.refs.Close() }
+func (i *squashRefTagsIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.refs.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &squashRefTagsIter{
+		ctx:     ctx,
+		refs:    iter.(RefsIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, TagsTableName),
+	}, nil
+}
+func (i *squashRefTagsIter) Row() sql.Row { return i.row }
+func (i *squashRefTagsIter) Advance() error {
+	for {
+		select {
+		case <-i.ctx.Done():
+			return ErrSessionCanceled.New()
+		default:
+		}
+
+		err := i.refs.Advance()
+		if err != nil {
+			return err
+		}
+
+		ref := i.refs.Ref()
+		if !isTagReference(ref.Reference) {
+			continue
+		}
+
+		i.tag, err = newTag(i.Repository(), ref.Reference)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"ref":   ref.Name(),
+				"hash":  ref.Hash(),
+				"error": err,
+			}).Error("unable to get tag")
+
+			if i.skipper.skip(i.Repository().ID(), ref.Hash(), err) {
+				continue
+			}
+
+			return err
+		}
+
+		i.row = append(i.refs.Row(), tagToRow(i.tag)...)
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+func (i *squashRefTagsIter) Schema() sql.Schema {
+	return append(i.refs.Schema(), TagsSchema...)
+}
+
+type squashTagCommitsIter struct {
+	ctx     *sql.Context
+	tags    TagsIter
+	filters sql.Expression
+	commit  *object.Commit
+	row     sql.Row
+	skipper *gitErrorSkipper
+}
+
+// NewTagCommitsIter returns an iterator that will return the commit each
+// tag of the given iterator points to, peeling the annotated tags pointing
+// to other tags. Tags that don't end up pointing to a commit are skipped.
+func NewTagCommitsIter(tags TagsIter, filters sql.Expression) CommitsIter {
+	return &squashTagCommitsIter{tags: tags, filters: filters}
+}
+
+func (i *squashTagCommitsIter) Repository() *Repository { return i.tags.Repository() }
+func (i *squashTagCommitsIter) Commit() *object.Commit  { return i.commit }
+func (i *squashTagCommitsIter) Close() error            { return i.tags.Close() }
+func (i *squashTagCommitsIter) New(ctx *sql.Context, repo *Repository) (ChainableIter, error) {
+	iter, err := i.tags.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &squashTagCommitsIter{
+		ctx:     ctx,
+		tags:    iter.(TagsIter),
+		filters: i.filters,
+		skipper: session.gitErrorSkipper(ctx, CommitsTableName),
+	}, nil
+}
+func (i *squashTagCommitsIter) Row() sql.Row { return i.row }
+func (i *squashTagCommitsIter) Advance() error {
+	for {
+		err := i.tags.Advance()
+		if err != nil {
+			return err
+		}
+
+		tag := i.tags.Tag()
+		hash, ok, err := tagCommitHash(i.Repository(), tag)
+		if err == nil && !ok {
+			continue
+		}
+
+		if err == nil {
+			i.commit, err = i.Repository().CommitObject(hash)
+		}
+
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tag":   tag.Name(),
+				"hash":  tag.Target(),
+				"error": err,
+			}).Error("unable to get tag commit")
+
+			if i.skipper.skip(i.Repository().ID(), tag.Target(), err) {
+				continue
+			}
+
+			return err
+		}
+
+		i.row = append(
+			i.tags.Row(),
+			commitToRow(i.Repository().ID(), i.commit)...,
+		)
+
+		if i.filters != nil {
+			ok, err := evalFilters(i.ctx, i.row, i.filters)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		return nil
+	}
+}
+func (i *squashTagCommitsIter) Schema() sql.Schema {
+	return append(i.tags.Schema(), CommitsSchema...)
+}
+
+// tagCommitHash returns the hash of the commit the tag points to, peeling
+// the annotated tags it points to, and false if it doesn't point to a
+// commit in the end.
+func tagCommitHash(repo *Repository, tag *Tag) (plumbing.Hash, bool, error) {
+	hash, typ := tag.Target(), tag.TargetType
+	for typ == plumbing.TagObject {
+		t, err := repo.TagObject(hash)
+		if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		hash, typ = t.Target, t.TargetType
+	}
+
+	return hash, typ == plumbing.CommitObject, nil
+}
+
 func evalFilters(ctx *sql.Context, row sql.Row, filters sql.Expression) (bool, error) {
 	return sql.EvaluateCondition(ctx, filters, row)
 }
@@ -15,6 +15,8 @@ import (
 	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func TestAllReposIter(t *testing.T) {
@@ -22,7 +24,7 @@ func TestAllReposIter(t *testing.T) {
 	ctx, cleanup := setupIter(t)
 	defer cleanup()
 
-	require.Len(chainableIterRows(t, ctx, NewAllReposIter(nil)), 2)
+	require.Len(chainableIterRows(t, ctx, NewAllReposIter(nil, nil)), 2)
 }
 
 func TestSquashedTableString(t *testing.T) {
@@ -30,14 +32,14 @@ func TestSquashedTableString(t *testing.T) {
 	ctx, cleanup := setupIter(t)
 	defer cleanup()
 
-	const expected = `SquashedTable(test)├─Columns│└─Column(repository_id,TEXT,nullable=false)└─Filters└─NOT(1)`
+	const expected = `SquashedTable(test)├─Columns│├─Column(repository_id,TEXT,nullable=false)│├─Column(library_id,TEXT,nullable=true)│├─Column(repository_path,TEXT,nullable=true)│├─Column(repository_format,TEXT,nullable=true)│├─Column(is_bare,BIT,nullable=true)│├─Column(head_ref,TEXT,nullable=true)│├─Column(default_branch,TEXT,nullable=true)│├─Column(ref_count,INT64,nullable=true)│├─Column(packfile_count,INT64,nullable=true)│├─Column(object_bytes,INT64,nullable=true)│└─Column(last_commit_when,TIMESTAMP,nullable=true)└─Filters└─NOT(1)`
 
 	notTrue := expression.NewNot(
 		expression.NewLiteral(1, sql.Int64),
 	)
 
 	st := &SquashedTable{
-		iter:           NewAllReposIter(notTrue),
+		iter:           NewAllReposIter(notTrue, nil),
 		tables:         []string{"test"},
 		schemaMappings: nil,
 		filters:        []sql.Expression{notTrue},
@@ -67,7 +69,7 @@ func TestSquashContextCancelled(t *testing.T) {
 	cancel()
 
 	iters := []ChainableIter{
-		NewAllReposIter(nil),
+		NewAllReposIter(nil, nil),
 		NewAllRemotesIter(nil),
 		NewAllRefsIter(nil, false),
 		NewAllCommitsIter(nil, false),
@@ -131,16 +133,16 @@ func TestRepoRemotesIter(t *testing.T) {
 
 	require.Len(chainableIterRows(
 		t, ctx,
-		NewRepoRemotesIter(NewAllReposIter(nil), nil),
+		NewRepoRemotesIter(NewAllReposIter(nil, nil), nil),
 	), 2)
 
 	require.Len(chainableIterRows(
 		t, ctx,
 		NewRepoRemotesIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			expression.NewNot(
 				expression.NewEquals(
-					expression.NewGetField(3, sql.Text, "push_url", false),
+					expression.NewGetField(len(RepositoriesSchema)+2, sql.Text, "push_url", false),
 					expression.NewLiteral("git@github.com:git-fixtures/submodule.git", sql.Text),
 				),
 			),
@@ -152,7 +154,7 @@ func TestRepoRemotesIter(t *testing.T) {
 
 	require.Len(chainableIterRows(
 		t, ctx,
-		NewRepoRemotesIter(NewAllReposIter(nil), nil),
+		NewRepoRemotesIter(NewAllReposIter(nil, nil), nil),
 	), 2)
 
 	ctx, cleanup3 := setupIterWithErrors(t, true, false)
@@ -160,7 +162,7 @@ func TestRepoRemotesIter(t *testing.T) {
 
 	chainableIterRowsError(
 		t, ctx,
-		NewRepoRemotesIter(NewAllReposIter(nil), nil),
+		NewRepoRemotesIter(NewAllReposIter(nil, nil), nil),
 	)
 }
 
@@ -222,7 +224,7 @@ func TestRepoRefsIter(t *testing.T) {
 	rows := chainableIterRows(
 		t, ctx,
 		NewRepoRefsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 			false,
 		),
@@ -236,7 +238,7 @@ func TestRepoRefsIter(t *testing.T) {
 	)
 
 	for i := range rows {
-		rows[i] = rows[i][1:]
+		rows[i] = rows[i][len(RepositoriesSchema):]
 	}
 
 	require.ElementsMatch(expected, rows)
@@ -244,9 +246,9 @@ func TestRepoRefsIter(t *testing.T) {
 	rows = chainableIterRows(
 		t, ctx,
 		NewRepoRefsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			expression.NewEquals(
-				expression.NewGetField(2, sql.Text, "name", false),
+				expression.NewGetField(len(RepositoriesSchema)+1, sql.Text, "name", false),
 				expression.NewLiteral("HEAD", sql.Text),
 			),
 			false,
@@ -261,7 +263,7 @@ func TestRepoRefsIter(t *testing.T) {
 	rows = chainableIterRows(
 		t, ctx,
 		NewRepoRefsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 			false,
 		),
@@ -275,7 +277,7 @@ func TestRepoRefsIter(t *testing.T) {
 	chainableIterRowsError(
 		t, ctx,
 		NewRepoRefsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 			false,
 		),
@@ -406,7 +408,7 @@ func TestRepoCommitsIter(t *testing.T) {
 	rows := chainableIterRows(
 		t, ctx,
 		NewRepoCommitsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 		),
 	)
@@ -417,7 +419,7 @@ func TestRepoCommitsIter(t *testing.T) {
 	)
 
 	for i := range rows {
-		rows[i] = rows[i][1:]
+		rows[i] = rows[i][len(RepositoriesSchema):]
 	}
 
 	require.ElementsMatch(expected, rows)
@@ -425,9 +427,9 @@ func TestRepoCommitsIter(t *testing.T) {
 	rows = chainableIterRows(
 		t, ctx,
 		NewRepoCommitsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			expression.NewEquals(
-				expression.NewGetField(2, sql.Text, "commit_hash", false),
+				expression.NewGetField(len(RepositoriesSchema)+1, sql.Text, "commit_hash", false),
 				expression.NewLiteral("918c48b83bd081e863dbe1b80f8998f058cd8294", sql.Text),
 			),
 		),
@@ -549,7 +551,7 @@ func TestRepoTreeEntriesIter(t *testing.T) {
 	rows := chainableIterRows(
 		t, ctx,
 		NewRepoTreeEntriesIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 		),
 	)
@@ -560,7 +562,7 @@ func TestRepoTreeEntriesIter(t *testing.T) {
 	)
 
 	for i := range rows {
-		rows[i] = rows[i][1:]
+		rows[i] = rows[i][len(RepositoriesSchema):]
 	}
 
 	require.ElementsMatch(expected, rows)
@@ -568,9 +570,9 @@ func TestRepoTreeEntriesIter(t *testing.T) {
 	rows = chainableIterRows(
 		t, ctx,
 		NewRepoTreeEntriesIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			expression.NewEquals(
-				expression.NewGetField(2, sql.Text, "tree_entry_name", false),
+				expression.NewGetField(len(RepositoriesSchema)+1, sql.Text, "tree_entry_name", false),
 				expression.NewLiteral("LICENSE", sql.Text),
 			),
 		),
@@ -691,7 +693,7 @@ func TestRepoBlobsIter(t *testing.T) {
 	rows := chainableIterRows(
 		t, ctx,
 		NewRepoBlobsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			nil,
 			false,
 		),
@@ -704,7 +706,7 @@ func TestRepoBlobsIter(t *testing.T) {
 	require.NoError(err)
 
 	for i := range rows {
-		rows[i] = rows[i][1:]
+		rows[i] = rows[i][len(RepositoriesSchema):]
 	}
 
 	require.ElementsMatch(expected, rows)
@@ -712,9 +714,9 @@ func TestRepoBlobsIter(t *testing.T) {
 	rows = chainableIterRows(
 		t, ctx,
 		NewRepoBlobsIter(
-			NewAllReposIter(nil),
+			NewAllReposIter(nil, nil),
 			expression.NewEquals(
-				expression.NewGetField(2, sql.Text, "hash", false),
+				expression.NewGetField(len(RepositoriesSchema)+1, sql.Text, "hash", false),
 				expression.NewLiteral("d3ff53e0564a9f87d8e84b6e28e5060e517008aa", sql.Text),
 			),
 			false,
@@ -779,6 +781,192 @@ func TestCommitFileBlobsIter(t *testing.T) {
 	require.ElementsMatch(expected, rows)
 }
 
+func TestCommitDiffsIter(t *testing.T) {
+	require := require.New(t)
+	ctx, cleanup := setupIter(t)
+	defer cleanup()
+
+	rows := chainableIterRows(
+		t, ctx,
+		NewAllCommitDiffsIter(nil, true),
+	)
+
+	expected, err := tableToRows(ctx, newCommitDiffsTable(poolFromCtx(t, ctx)))
+	require.NoError(err)
+
+	require.ElementsMatch(expected, rows)
+
+	rows = chainableIterRows(
+		t, ctx,
+		NewCommitDiffsIter(
+			NewAllCommitsIter(nil, false),
+			expression.NewEquals(
+				expression.NewGetField(len(CommitsSchema)+3, sql.Text, "change_type", false),
+				expression.NewLiteral(changeTypeDelete, sql.Text),
+			),
+			false,
+		),
+	)
+
+	for _, row := range rows {
+		require.Len(row, len(CommitsSchema)+len(CommitDiffsSchema))
+		require.Equal(row[1], row[len(CommitsSchema)+1])
+		require.Equal(changeTypeDelete, row[len(CommitsSchema)+3])
+		require.Equal(int64(0), row[len(CommitsSchema)+8])
+		require.Equal(int64(0), row[len(CommitsSchema)+9])
+	}
+}
+
+func TestFileBlameIter(t *testing.T) {
+	require := require.New(t)
+	ctx, cleanup := setupIter(t)
+	defer cleanup()
+
+	filter := expression.NewEquals(
+		expression.NewGetFieldWithTable(2, sql.Text, FileBlameTableName, "file_path", false),
+		expression.NewLiteral(".gitignore", sql.Text),
+	)
+
+	rows := chainableIterRows(
+		t, ctx,
+		NewAllFileBlameIter(filter),
+	)
+
+	table := newFileBlameTable(poolFromCtx(t, ctx)).
+		WithFilters([]sql.Expression{filter})
+	expected, err := tableToRows(ctx, table)
+	require.NoError(err)
+
+	require.NotEmpty(rows)
+	require.ElementsMatch(expected, rows)
+
+	rows = chainableIterRows(
+		t, ctx,
+		NewCommitFileBlameIter(
+			NewAllCommitFilesIter(expression.NewEquals(
+				expression.NewGetField(2, sql.Text, "file_path", false),
+				expression.NewLiteral("LICENSE", sql.Text),
+			)),
+			nil,
+		),
+	)
+
+	require.NotEmpty(rows)
+	for _, row := range rows {
+		require.Len(row, len(CommitFilesSchema)+len(FileBlameSchema))
+		require.Equal(row[1], row[len(CommitFilesSchema)+1])
+		require.Equal("LICENSE", row[len(CommitFilesSchema)+2])
+	}
+}
+
+func TestCommitNotesIter(t *testing.T) {
+	require := require.New(t)
+	ctx, cleanup := setupNotes(t)
+	defer cleanup()
+
+	rows := chainableIterRows(
+		t, ctx,
+		NewCommitNotesIter(NewAllCommitsIter(nil, true), nil),
+	)
+
+	expected, err := tableToRows(ctx, newCommitNotesTable(poolFromCtx(t, ctx)))
+	require.NoError(err)
+
+	require.ElementsMatch(expected, rows)
+
+	rows = chainableIterRows(
+		t, ctx,
+		NewCommitNotesIter(
+			NewAllCommitsIter(nil, false),
+			expression.NewEquals(
+				expression.NewGetField(len(CommitsSchema)+1, sql.Text, "notes_ref", false),
+				expression.NewLiteral("refs/notes/review", sql.Text),
+			),
+		),
+	)
+
+	require.Len(rows, 1)
+	require.Len(rows[0], len(CommitsSchema)+len(CommitNotesSchema))
+	require.Equal(notedCommit, rows[0][1])
+	require.Equal(rows[0][1], rows[0][len(CommitsSchema)+2])
+}
+
+func TestTagsIter(t *testing.T) {
+	require := require.New(t)
+	ctx, _, cleanup := setupTags(t)
+	defer cleanup()
+
+	rows := chainableIterRows(t, ctx, NewAllTagsIter(nil))
+
+	expected, err := tableToRows(ctx, newTagsTable(poolFromCtx(t, ctx)))
+	require.NoError(err)
+
+	require.ElementsMatch(expected, rows)
+
+	rows = chainableIterRows(
+		t, ctx,
+		NewRefTagsIter(NewAllRefsIter(nil, false), nil),
+	)
+
+	require.Len(rows, 5)
+	for _, row := range rows {
+		require.Len(row, len(RefsSchema)+len(TagsSchema))
+		require.Equal(row[1], row[len(RefsSchema)+1])
+	}
+}
+
+func TestTagCommitsIter(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setupTags(t)
+	defer cleanup()
+
+	r, err := poolFromCtx(t, ctx).GetRepo(path)
+	require.NoError(err)
+
+	ref, err := r.Reference("refs/tags/annotated-tag", false)
+	require.NoError(err)
+	inner, err := r.TagObject(ref.Hash())
+	require.NoError(err)
+
+	nested := &object.Tag{
+		Name:       "nested-tag",
+		Tagger:     inner.Tagger,
+		Message:    "Tag of a tag\n",
+		TargetType: plumbing.TagObject,
+		Target:     inner.Hash,
+	}
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(nested.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(err)
+	require.NoError(r.Storer.SetReference(
+		plumbing.NewHashReference("refs/tags/nested-tag", hash),
+	))
+	require.NoError(r.Close())
+
+	rows := chainableIterRows(
+		t, ctx,
+		NewTagCommitsIter(NewAllTagsIter(nil), nil),
+	)
+
+	var names []interface{}
+	for _, row := range rows {
+		require.Len(row, len(TagsSchema)+len(CommitsSchema))
+		if row[2] == "nested-tag" {
+			require.Equal(inner.Hash.String(), row[4])
+			require.Equal(inner.Target.String(), row[len(TagsSchema)+1])
+		} else {
+			require.Equal(row[4], row[len(TagsSchema)+1])
+		}
+		names = append(names, row[2])
+	}
+
+	require.ElementsMatch(
+		[]interface{}{"annotated-tag", "commit-tag", "lightweight-tag", "nested-tag"},
+		names,
+	)
+}
+
 func chainableIterRowsError(t *testing.T, ctx *sql.Context, iter ChainableIter) {
 	t.Helper()
 	table := newSquashTable(iter)
@@ -1020,7 +1208,7 @@ func TestRefsIterSiva(t *testing.T) {
 		iter ChainableIter
 	}{
 		{"all refs", NewAllRefsIter(nil, false)},
-		{"repo refs", NewRepoRefsIter(NewAllReposIter(nil), nil, false)},
+		{"repo refs", NewRepoRefsIter(NewAllReposIter(nil, nil), nil, false)},
 	}
 
 	expected := []sql.Row{
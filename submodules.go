@@ -0,0 +1,371 @@
+package gitbase
+
+import (
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-borges"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// gitmodulesFile is the name of the file, in the root directory of the
+// repository, with the submodules.
+const gitmodulesFile = ".gitmodules"
+
+type submodulesTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// SubmodulesSchema is the schema for the submodules table.
+var SubmodulesSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: SubmodulesTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: SubmodulesTableName},
+	{Name: "submodule_name", Type: sql.Text, Nullable: false, Source: SubmodulesTableName},
+	{Name: "submodule_path", Type: sql.Text, Nullable: false, Source: SubmodulesTableName},
+	{Name: "submodule_url", Type: sql.Text, Nullable: false, Source: SubmodulesTableName},
+	{Name: "submodule_branch", Type: sql.Text, Nullable: true, Source: SubmodulesTableName},
+	{Name: "pinned_commit_hash", Type: sql.VarChar(40), Nullable: true, Source: SubmodulesTableName},
+	{Name: "submodule_repository_id", Type: sql.Text, Nullable: true, Source: SubmodulesTableName},
+}
+
+func newSubmodulesTable(pool *RepositoryPool) *submodulesTable {
+	return &submodulesTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*submodulesTable)(nil)
+
+func (submodulesTable) isGitbaseTable() {}
+
+func (t submodulesTable) String() string {
+	return printTable(
+		SubmodulesTableName,
+		SubmodulesSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (submodulesTable) Name() string { return SubmodulesTableName }
+
+func (submodulesTable) Schema() sql.Schema { return SubmodulesSchema }
+
+func (t *submodulesTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *submodulesTable) Filters() []sql.Expression { return t.filters }
+
+func (t *submodulesTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.SubmodulesTable")
+	iter, err := rowIterWithSelectors(
+		ctx, SubmodulesSchema, SubmodulesTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var hashes []string
+			hashes, err = selectors.textValues("commit_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			return &submodulesRowIter{
+				repo:         repo,
+				commitHashes: stringsToHashes(hashes),
+				repoIDs:      make(map[string]interface{}),
+				skipper:      newGitErrorSkipper(ctx, SubmodulesTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (submodulesTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(SubmodulesTableName, SubmodulesSchema, filters)
+}
+
+func (submodulesTable) handledColumns() []string {
+	return []string{"commit_hash", "repository_id"}
+}
+
+// newSubmodulesRows returns the rows of the submodules in the .gitmodules
+// file of the commit, with the commit each one is pinned to in the tree of
+// the commit, if any. submoduleRepoID returns the ID of the repository of
+// a submodule URL in the library, or nil if it's not there. Submodules
+// without path or URL, or with an invalid path, are ignored, as git does.
+func newSubmodulesRows(
+	repoID string,
+	c *object.Commit,
+	submoduleRepoID func(url string) (interface{}, error),
+) ([]sql.Row, error) {
+	f, err := c.File(gitmodulesFile)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := config.NewModules()
+	if err := modules.Unmarshal([]byte(content)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":   repoID,
+			"err":    err,
+			"commit": c.Hash.String(),
+		}).Warn("invalid .gitmodules file, ignoring submodules")
+		return nil, nil
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(modules.Submodules))
+	for name, m := range modules.Submodules {
+		if m.Validate() == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	rows := make([]sql.Row, 0, len(names))
+	for _, name := range names {
+		m := modules.Submodules[name]
+
+		var branch interface{}
+		if m.Branch != "" {
+			branch = m.Branch
+		}
+
+		pinned, err := pinnedCommit(tree, m.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := submoduleRepoID(m.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, sql.NewRow(
+			repoID,
+			c.Hash.String(),
+			m.Name,
+			m.Path,
+			m.URL,
+			branch,
+			pinned,
+			id,
+		))
+	}
+
+	return rows, nil
+}
+
+// pinnedCommit returns the hash of the commit of the submodule in the path
+// of the tree, or nil if there is no submodule there.
+func pinnedCommit(tree *object.Tree, p string) (interface{}, error) {
+	entry, err := tree.FindEntry(strings.Trim(p, "/"))
+	switch err {
+	case nil:
+	case object.ErrEntryNotFound, object.ErrDirectoryNotFound,
+		plumbing.ErrObjectNotFound:
+		// Parent directories which are submodules are not found as trees.
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	if entry.Mode != filemode.Submodule {
+		return nil, nil
+	}
+
+	return entry.Hash.String(), nil
+}
+
+// submoduleRepositoryIDs returns the IDs the repository with the given
+// submodule URL may have in the library, made of the host and path of the
+// URL, with and without the .git suffix. Relative URLs are resolved against
+// the ID of the repository with the submodule, as git does with the URL of
+// its remote.
+func submoduleRepositoryIDs(repoID, url string) []string {
+	var id string
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		id = path.Join(strings.TrimSuffix(repoID, ".git"), url)
+	} else {
+		ep, err := transport.NewEndpoint(url)
+		if err != nil {
+			return nil
+		}
+
+		id = strings.Trim(ep.Path, "/")
+		if ep.Host != "" {
+			id = ep.Host + "/" + id
+		}
+	}
+
+	id = strings.TrimSuffix(strings.TrimSuffix(id, "/"), ".git")
+	if id == "" {
+		return nil
+	}
+
+	return []string{id, id + ".git"}
+}
+
+type submodulesRowIter struct {
+	repo    *Repository
+	commits object.CommitIter
+	rows    []sql.Row
+	skipper *gitErrorSkipper
+	// repoIDs are the IDs of the repositories of the submodule URLs found,
+	// or nil if they are not in the library.
+	repoIDs map[string]interface{}
+
+	// selectors for faster filtering
+	commitHashes []plumbing.Hash
+}
+
+func (i *submodulesRowIter) init() error {
+	if len(i.commitHashes) > 0 {
+		i.commits = newCommitsByHashIter(i.repo, i.commitHashes)
+	} else {
+		iter, err := newCommitIter(i.repo, i.skipper)
+		if err != nil {
+			return err
+		}
+
+		i.commits = iter
+	}
+
+	return nil
+}
+
+// submoduleRepoID returns the ID of the repository of the library with
+// the given submodule URL, or nil if there is none.
+func (i *submodulesRowIter) submoduleRepoID(url string) (interface{}, error) {
+	if id, ok := i.repoIDs[url]; ok {
+		return id, nil
+	}
+
+	if i.repo.lib == nil {
+		return nil, nil
+	}
+
+	var result interface{}
+	for _, id := range submoduleRepositoryIDs(i.repo.ID(), url) {
+		ok, _, _, err := i.repo.lib.Has(borges.RepositoryID(id))
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			result = id
+			break
+		}
+	}
+
+	i.repoIDs[url] = result
+	return result, nil
+}
+
+func (i *submodulesRowIter) Next() (sql.Row, error) {
+	for {
+		if i.commits == nil {
+			if err := i.init(); err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		if len(i.rows) > 0 {
+			row := i.rows[0]
+			i.rows = i.rows[1:]
+			return row, nil
+		}
+
+		c, err := i.commits.Next()
+		if err != nil {
+			if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo": i.repo.ID(),
+					"err":  err,
+				}).Error("skipped commit in submodules")
+				continue
+			}
+
+			return nil, err
+		}
+
+		i.rows, err = newSubmodulesRows(i.repo.ID(), c, i.submoduleRepoID)
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), c.Hash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo":   i.repo.ID(),
+					"err":    err,
+					"commit": c.Hash.String(),
+				}).Error("can't read submodules of commit")
+				continue
+			}
+
+			return nil, err
+		}
+	}
+}
+
+func (i *submodulesRowIter) Close() error {
+	if i.commits != nil {
+		i.commits.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestSubmodulesTableRowIter(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, cleanup := setupRepos(t)
+	defer cleanup()
+
+	table := newSubmodulesTable(poolFromCtx(t, ctx))
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.NotEmpty(rows)
+
+	urls := map[string]string{
+		"basic":  "https://github.com/git-fixtures/basic.git",
+		"itself": "https://github.com/git-fixtures/submodule.git",
+	}
+
+	var pinned int
+	for _, row := range rows {
+		require.NoError(SubmodulesSchema.CheckRow(row))
+		require.Equal(urls[row[2].(string)], row[4])
+		require.Equal(row[2], row[3])
+		require.Nil(row[5])
+		// The repositories of the fixtures are not added with their URLs.
+		require.Nil(row[7])
+		if row[6] != nil {
+			pinned++
+		}
+	}
+	require.NotZero(pinned)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, SubmodulesTableName, "commit_hash", false),
+			expression.NewLiteral("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestNewSubmodulesRows(t *testing.T) {
+	require := require.New(t)
+
+	s := memory.NewStorage()
+	gitmodules := storeBlob(t, s, `[submodule "lib"]
+	path = vendor/lib
+	url = https://github.com/foo/lib.git
+	branch = stable
+[submodule "docs"]
+	path = docs
+	url = ../docs
+[submodule "missing"]
+	path = missing
+	url = git@github.com:foo/missing.git
+[submodule "invalid"]
+	path = ../invalid
+	url = https://github.com/foo/invalid.git
+[submodule "nourl"]
+	path = nourl
+`)
+
+	libHash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	docsHash := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	vendor := storeTree(t, s, object.TreeEntry{
+		Name: "lib", Mode: filemode.Submodule, Hash: libHash,
+	})
+	tree := storeTree(t, s,
+		object.TreeEntry{Name: ".gitmodules", Mode: filemode.Regular, Hash: gitmodules},
+		object.TreeEntry{Name: "docs", Mode: filemode.Submodule, Hash: docsHash},
+		object.TreeEntry{Name: "missing", Mode: filemode.Regular, Hash: gitmodules},
+		object.TreeEntry{Name: "vendor", Mode: filemode.Dir, Hash: vendor},
+	)
+
+	c := &object.Commit{Message: "Add submodules\n", TreeHash: tree}
+	obj := s.NewEncodedObject()
+	require.NoError(c.Encode(obj))
+	hash, err := s.SetEncodedObject(obj)
+	require.NoError(err)
+	c, err = object.GetCommit(s, hash)
+	require.NoError(err)
+
+	ids := map[string]interface{}{
+		"https://github.com/foo/lib.git": "github.com/foo/lib",
+	}
+	rows, err := newSubmodulesRows("github.com/foo/bar", c, func(url string) (interface{}, error) {
+		return ids[url], nil
+	})
+	require.NoError(err)
+
+	expected := []sql.Row{
+		sql.NewRow("github.com/foo/bar", hash.String(), "docs", "docs", "../docs", nil, docsHash.String(), nil),
+		sql.NewRow("github.com/foo/bar", hash.String(), "lib", "vendor/lib", "https://github.com/foo/lib.git", "stable", libHash.String(), "github.com/foo/lib"),
+		sql.NewRow("github.com/foo/bar", hash.String(), "missing", "missing", "git@github.com:foo/missing.git", nil, nil, nil),
+	}
+	require.Equal(expected, rows)
+
+	for idx, row := range rows {
+		require.NoError(SubmodulesSchema.CheckRow(row), "row %d doesn't conform to schema", idx)
+	}
+}
+
+func TestSubmoduleRepositoryIDs(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected []string
+	}{
+		{"https://github.com/foo/lib.git", []string{"github.com/foo/lib", "github.com/foo/lib.git"}},
+		{"git@github.com:foo/lib.git", []string{"github.com/foo/lib", "github.com/foo/lib.git"}},
+		{"ssh://git@github.com:22/foo/lib", []string{"github.com/foo/lib", "github.com/foo/lib.git"}},
+		{"../lib.git", []string{"github.com/foo/lib", "github.com/foo/lib.git"}},
+		{"./lib", []string{"github.com/foo/bar/lib", "github.com/foo/bar/lib.git"}},
+		{"/srv/git/lib.git", []string{"srv/git/lib", "srv/git/lib.git"}},
+		{"", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.url, func(t *testing.T) {
+			require.Equal(t, tt.expected, submoduleRepositoryIDs("github.com/foo/bar.git", tt.url))
+		})
+	}
+}
+
+func storeBlob(t *testing.T, s storer.EncodedObjectStorer, content string) plumbing.Hash {
+	t.Helper()
+
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	hash, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
+
+func storeTree(t *testing.T, s storer.EncodedObjectStorer, entries ...object.TreeEntry) plumbing.Hash {
+	t.Helper()
+
+	tree := &object.Tree{Entries: entries}
+	obj := s.NewEncodedObject()
+	require.NoError(t, tree.Encode(obj))
+
+	hash, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
@@ -0,0 +1,420 @@
+package gitbase
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/signature"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+type tagsTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+	index   sql.IndexLookup
+}
+
+// TagsSchema is the schema for the tags table.
+var TagsSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: TagsTableName},
+	{Name: "ref_name", Type: sql.Text, Nullable: false, Source: TagsTableName},
+	{Name: "tag_name", Type: sql.Text, Nullable: false, Source: TagsTableName},
+	{Name: "tag_hash", Type: sql.VarChar(40), Nullable: true, Source: TagsTableName},
+	{Name: "target_hash", Type: sql.VarChar(40), Nullable: false, Source: TagsTableName},
+	{Name: "target_type", Type: sql.Text, Nullable: false, Source: TagsTableName},
+	{Name: "tagger_name", Type: sql.Text, Nullable: true, Source: TagsTableName},
+	{Name: "tagger_email", Type: sql.VarChar(254), Nullable: true, Source: TagsTableName},
+	{Name: "tagger_when", Type: sql.Timestamp, Nullable: true, Source: TagsTableName},
+	{Name: "tag_message", Type: sql.Text, Nullable: true, Source: TagsTableName},
+	{Name: "tag_signature", Type: sql.Text, Nullable: true, Source: TagsTableName},
+	{Name: "tag_signature_type", Type: sql.Text, Nullable: true, Source: TagsTableName},
+}
+
+func newTagsTable(pool *RepositoryPool) *tagsTable {
+	return &tagsTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*tagsTable)(nil)
+var _ Squashable = (*tagsTable)(nil)
+
+func (tagsTable) isSquashable()   {}
+func (tagsTable) isGitbaseTable() {}
+
+func (t tagsTable) String() string {
+	return printTable(
+		TagsTableName,
+		TagsSchema,
+		nil,
+		t.filters,
+		t.index,
+	)
+}
+
+func (tagsTable) Name() string { return TagsTableName }
+
+func (tagsTable) Schema() sql.Schema { return TagsSchema }
+
+func (t *tagsTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *tagsTable) WithIndexLookup(idx sql.IndexLookup) sql.Table {
+	nt := *t
+	nt.index = idx
+	return &nt
+}
+
+func (t *tagsTable) IndexLookup() sql.IndexLookup { return t.index }
+func (t *tagsTable) Filters() []sql.Expression    { return t.filters }
+
+func (t *tagsTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.TagsTable")
+	iter, err := rowIterWithSelectors(
+		ctx, TagsSchema, TagsTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			var names []string
+			names, err = selectors.textValues("ref_name")
+			if err != nil {
+				return nil, err
+			}
+
+			var index sql.IndexValueIter
+			if t.index != nil {
+				if index, err = t.index.Values(p); err != nil {
+					return nil, err
+				}
+			}
+
+			return &tagsRowIter{
+				repo:    repo,
+				index:   index,
+				names:   names,
+				skipper: newGitErrorSkipper(ctx, TagsTableName),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (tagsTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(TagsTableName, TagsSchema, filters)
+}
+
+func (tagsTable) handledColumns() []string { return []string{"ref_name", "repository_id"} }
+
+// IndexKeyValues implements the sql.IndexableTable interface.
+func (t *tagsTable) IndexKeyValues(
+	ctx *sql.Context,
+	colNames []string,
+) (sql.PartitionIndexKeyValueIter, error) {
+	return newPartitionedIndexKeyValueIter(
+		ctx,
+		newTagsTable(t.pool),
+		colNames,
+		newTagsKeyValueIter,
+	)
+}
+
+func isTagReference(ref *plumbing.Reference) bool {
+	return ref.Type() == plumbing.HashReference && ref.Name().IsTag()
+}
+
+// newTag returns the tag for the given tag reference, resolving its
+// annotated tag object if there is one.
+func newTag(repo *Repository, ref *plumbing.Reference) (*Tag, error) {
+	tag, err := repo.TagObject(ref.Hash())
+	if err == nil {
+		return &Tag{repo.ID(), ref, tag, tag.TargetType}, nil
+	}
+
+	if err != plumbing.ErrObjectNotFound {
+		return nil, err
+	}
+
+	obj, err := repo.Object(plumbing.AnyObject, ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tag{repo.ID(), ref, nil, obj.Type()}, nil
+}
+
+func tagToRow(t *Tag) sql.Row {
+	var (
+		hash, taggerName, taggerEmail interface{}
+		taggerWhen, message           interface{}
+		sig, sigType                  interface{}
+	)
+
+	if t.Object != nil {
+		hash = t.Object.Hash.String()
+		taggerName = t.Object.Tagger.Name
+		taggerEmail = t.Object.Tagger.Email
+		taggerWhen = t.Object.Tagger.When
+		msg, s := signature.TagMessage(t.Object)
+		message = msg
+		sig, sigType = signatureValues(s)
+	}
+
+	return sql.NewRow(
+		t.RepoID,
+		t.Name().String(),
+		t.Name().Short(),
+		hash,
+		t.Target().String(),
+		t.TargetType.String(),
+		taggerName,
+		taggerEmail,
+		taggerWhen,
+		message,
+		sig,
+		sigType,
+	)
+}
+
+type tagsRowIter struct {
+	repo    *Repository
+	index   sql.IndexValueIter
+	refs    storer.ReferenceIter
+	skipper *gitErrorSkipper
+
+	// selectors for faster filtering
+	names []string
+}
+
+func (i *tagsRowIter) Next() (sql.Row, error) {
+	if i.index != nil {
+		return i.nextFromIndex()
+	}
+
+	return i.next()
+}
+
+func (i *tagsRowIter) nextFromIndex() (sql.Row, error) {
+	for {
+		var err error
+		var data []byte
+		defer closeIndexOnError(&err, i.index)
+
+		data, err = i.index.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var key tagIndexKey
+		if err = decodeIndexKey(data, &key); err != nil {
+			return nil, err
+		}
+
+		if len(i.names) > 0 && !stringContains(i.names, key.Name) {
+			continue
+		}
+
+		var ref *plumbing.Reference
+		ref, err = i.repo.Reference(plumbing.ReferenceName(key.Name), false)
+		if err != nil {
+			return nil, err
+		}
+
+		var tag *Tag
+		tag, err = newTag(i.repo, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		return tagToRow(tag), nil
+	}
+}
+
+func (i *tagsRowIter) next() (sql.Row, error) {
+	for {
+		if i.refs == nil {
+			var err error
+			i.refs, err = i.repo.References()
+			if err != nil {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+					return nil, io.EOF
+				}
+
+				return nil, err
+			}
+		}
+
+		ref, err := i.refs.Next()
+		if err != nil {
+			if err != io.EOF && i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
+				logrus.WithFields(logrus.Fields{
+					"repo": i.repo.ID(),
+					"err":  err,
+				}).Error("skipped reference in tags")
+				continue
+			}
+
+			return nil, err
+		}
+
+		if !isTagReference(ref) {
+			continue
+		}
+
+		if len(i.names) > 0 && !stringContains(i.names, ref.Name().String()) {
+			continue
+		}
+
+		tag, err := newTag(i.repo, ref)
+		if err != nil {
+			if i.skipper.skip(i.repo.ID(), ref.Hash(), err) {
+				logrus.WithFields(logrus.Fields{
+					"repo": i.repo.ID(),
+					"err":  err,
+					"ref":  ref.Name().String(),
+				}).Error("can't get tag")
+				continue
+			}
+
+			return nil, err
+		}
+
+		return tagToRow(tag), nil
+	}
+}
+
+func (i *tagsRowIter) Close() error {
+	if i.refs != nil {
+		i.refs.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	if i.index != nil {
+		return i.index.Close()
+	}
+
+	return nil
+}
+
+type tagIndexKey struct {
+	Repository string
+	Name       string
+}
+
+func (k *tagIndexKey) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, k.Repository)
+	writeString(&buf, k.Name)
+	return buf.Bytes(), nil
+}
+
+func (k *tagIndexKey) decode(data []byte) error {
+	var buf = bytes.NewBuffer(data)
+	var err error
+
+	if k.Repository, err = readString(buf); err != nil {
+		return err
+	}
+
+	if k.Name, err = readString(buf); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type tagsKeyValueIter struct {
+	repo    *Repository
+	refs    storer.ReferenceIter
+	columns []string
+}
+
+func newTagsKeyValueIter(
+	_ *RepositoryPool,
+	repo *Repository,
+	columns []string,
+) (sql.IndexKeyValueIter, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tagsKeyValueIter{
+		repo:    repo,
+		refs:    refs,
+		columns: columns,
+	}, nil
+}
+
+func (i *tagsKeyValueIter) Next() ([]interface{}, []byte, error) {
+	for {
+		ref, err := i.refs.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !isTagReference(ref) {
+			continue
+		}
+
+		tag, err := newTag(i.repo, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key, err := encodeIndexKey(&tagIndexKey{i.repo.ID(), ref.Name().String()})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		values, err := rowIndexValues(tagToRow(tag), i.columns, TagsSchema)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return values, key, nil
+	}
+}
+
+func (i *tagsKeyValueIter) Close() error {
+	if i.refs != nil {
+		i.refs.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package gitbase
+
+import (
+	"context"
+	"testing"
+
+	fixtures "github.com/src-d/go-git-fixtures"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTags returns a context with a pool containing only the tags
+// fixture, which has annotated and lightweight tags pointing to commits,
+// trees and blobs.
+func setupTags(t *testing.T) (*sql.Context, string, CleanupFunc) {
+	require := require.New(t)
+	t.Helper()
+
+	lib, pool, err := newMultiPool()
+	require.NoError(err)
+
+	path := fixtures.ByTag("tags").One().DotGit().Root()
+	require.NoError(lib.AddPlain("tags/.git", path, nil))
+
+	cleanup := func() {
+		t.Helper()
+		require.NoError(fixtures.Clean())
+	}
+
+	session := NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	return ctx, "tags", cleanup
+}
+
+func TestTagsTable(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setupTags(t)
+	defer cleanup()
+
+	table := newTagsTable(poolFromCtx(t, ctx))
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 5)
+
+	schema := table.Schema()
+	require.Equal("tag_signature", schema[10].Name)
+	require.Equal("tag_signature_type", schema[11].Name)
+
+	var names []interface{}
+	var types = make(map[interface{}]int)
+	for idx, row := range rows {
+		require.NoError(schema.CheckRow(row), "row %d doesn't conform to schema", idx)
+		require.Equal(path, row[0])
+		names = append(names, row[2])
+		types[row[5]]++
+	}
+
+	require.ElementsMatch([]interface{}{
+		"annotated-tag",
+		"blob-tag",
+		"commit-tag",
+		"lightweight-tag",
+		"tree-tag",
+	}, names)
+
+	require.Equal(map[interface{}]int{
+		"commit": 3,
+		"tree":   1,
+		"blob":   1,
+	}, types)
+}
+
+func TestTagsPushdown(t *testing.T) {
+	require := require.New(t)
+
+	ctx, path, cleanup := setupTags(t)
+	defer cleanup()
+
+	table := new(tagsTable)
+
+	rows, err := tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, TagsTableName, "ref_name", false),
+			expression.NewLiteral("refs/tags/annotated-tag", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 1)
+
+	row := rows[0]
+	require.Equal(path, row[0])
+	require.Equal("annotated-tag", row[2])
+	require.Equal("b742a2a9fa0afcfa9a6fad080980fbc26b007c69", row[3])
+	require.Equal("f7b877701fbf855b44c0a9e86f3fdce2c298b07f", row[4])
+	require.Equal("commit", row[5])
+	require.Equal("Máximo Cuadros", row[6])
+	require.Equal("mcuadros@gmail.com", row[7])
+	require.NotNil(row[8])
+	require.Equal("example annotated tag\n", row[9])
+	require.Nil(row[10])
+	require.Nil(row[11])
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, TagsTableName, "ref_name", false),
+			expression.NewLiteral("refs/tags/lightweight-tag", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		sql.NewRow(
+			path,
+			"refs/tags/lightweight-tag",
+			"lightweight-tag",
+			nil,
+			"f7b877701fbf855b44c0a9e86f3fdce2c298b07f",
+			"commit",
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		),
+	}, rows)
+
+	rows, err = tableToRows(ctx, table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(0, sql.Text, TagsTableName, "repository_id", false),
+			expression.NewLiteral("foo", sql.Text),
+		),
+	}))
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestTagsIndexKeyValueIter(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setupTags(t)
+	defer cleanup()
+
+	table := new(tagsTable)
+	iter, err := table.IndexKeyValues(ctx, []string{"tag_name", "target_type"})
+	require.NoError(err)
+
+	var expected = []keyValue{
+		{
+			key:    assertEncodeKey(t, &tagIndexKey{path, "refs/tags/annotated-tag"}),
+			values: []interface{}{"annotated-tag", "commit"},
+		},
+		{
+			key:    assertEncodeKey(t, &tagIndexKey{path, "refs/tags/blob-tag"}),
+			values: []interface{}{"blob-tag", "blob"},
+		},
+		{
+			key:    assertEncodeKey(t, &tagIndexKey{path, "refs/tags/commit-tag"}),
+			values: []interface{}{"commit-tag", "commit"},
+		},
+		{
+			key:    assertEncodeKey(t, &tagIndexKey{path, "refs/tags/lightweight-tag"}),
+			values: []interface{}{"lightweight-tag", "commit"},
+		},
+		{
+			key:    assertEncodeKey(t, &tagIndexKey{path, "refs/tags/tree-tag"}),
+			values: []interface{}{"tree-tag", "tree"},
+		},
+	}
+
+	assertIndexKeyValueIter(t, iter, expected)
+}
+
+func TestTagsIndex(t *testing.T) {
+	testTableIndex(
+		t,
+		new(tagsTable),
+		[]sql.Expression{expression.NewEquals(
+			expression.NewGetField(2, sql.Text, "tag_name", false),
+			expression.NewLiteral("v1.0.0", sql.Text),
+		)},
+	)
+}
+
+func TestEncodeTagIndexKey(t *testing.T) {
+	require := require.New(t)
+
+	k := tagIndexKey{
+		Repository: "repo1",
+		Name:       "refs/tags/v1.0.0",
+	}
+
+	data, err := k.encode()
+	require.NoError(err)
+
+	var k2 tagIndexKey
+	require.NoError(k2.decode(data))
+	require.Equal(k, k2)
+}
+
+func TestTagsIndexIterClosed(t *testing.T) {
+	testTableIndexIterClosed(t, new(tagsTable))
+}
+
+func TestTagsIterClosed(t *testing.T) {
+	testTableIterClosed(t, new(tagsTable))
+}
@@ -93,15 +93,15 @@ func (r *treeEntriesTable) PartitionRows(
 
 				return newTreeEntriesIndexIter(
 					values,
-					session.Pool,
+					session.QueryPool(ctx),
 					stringsToHashes(hashes),
 				), nil
 			}
 
 			return &treeEntriesRowIter{
-				repo:          repo,
-				hashes:        stringsToHashes(hashes),
-				skipGitErrors: shouldSkipErrors(ctx),
+				repo:    repo,
+				hashes:  stringsToHashes(hashes),
+				skipper: newGitErrorSkipper(ctx, TreeEntriesTableName),
 			}, nil
 		},
 	)
@@ -146,13 +146,13 @@ func (r *treeEntriesTable) IndexKeyValues(
 }
 
 type treeEntriesRowIter struct {
-	hashes        []plumbing.Hash
-	pos           int
-	tree          *object.Tree
-	iter          *object.TreeIter
-	cursor        int
-	repo          *Repository
-	skipGitErrors bool
+	hashes  []plumbing.Hash
+	pos     int
+	tree    *object.Tree
+	iter    *object.TreeIter
+	cursor  int
+	repo    *Repository
+	skipper *gitErrorSkipper
 }
 
 func (i *treeEntriesRowIter) Next() (sql.Row, error) {
@@ -169,7 +169,7 @@ func (i *treeEntriesRowIter) next() (sql.Row, error) {
 			var err error
 			i.iter, err = i.repo.TreeObjects()
 			if err != nil {
-				if i.skipGitErrors {
+				if i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					return nil, io.EOF
 				}
 
@@ -181,7 +181,8 @@ func (i *treeEntriesRowIter) next() (sql.Row, error) {
 			var err error
 			i.tree, err = i.iter.Next()
 			if err != nil {
-				if err != io.EOF && i.skipGitErrors {
+				if err != io.EOF &&
+					i.skipper.skip(i.repo.ID(), plumbing.ZeroHash, err) {
 					continue
 				}
 
@@ -214,7 +215,8 @@ func (i *treeEntriesRowIter) nextByHash() (sql.Row, error) {
 			i.tree, err = i.repo.TreeObject(i.hashes[i.pos])
 			i.pos++
 			if err != nil {
-				if err == plumbing.ErrObjectNotFound || i.skipGitErrors {
+				if err == plumbing.ErrObjectNotFound ||
+					i.skipper.skip(i.repo.ID(), i.hashes[i.pos-1], err) {
 					continue
 				}
 				return nil, err